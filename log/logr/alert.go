@@ -0,0 +1,385 @@
+package logr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// defaultAlertWindow is the window of a rule unless WithAlertThreshold says otherwise
+	defaultAlertWindow       = time.Minute
+	defaultPagerDutyEndpoint = "https://events.pagerduty.com/v2/enqueue"
+	// alertQueueSize is how many alerts wait for their handlers, more are dropped
+	alertQueueSize = 100
+	// alertHandlerTimeout is how long a handler is given
+	alertHandlerTimeout = 10 * time.Second
+)
+
+// Alert is what the handler of an alert rule is called with when the rule fires
+type Alert struct {
+	// Rule is the name of the rule, see WithAlertName
+	Rule string
+	// Count is how many entries matched the rule within Window
+	Count  int
+	Window time.Duration
+	// Entry is the entry that made the rule fire, Fields are its fields including the ones added with WithValues
+	Entry  zapcore.Entry
+	Fields map[string]interface{}
+}
+
+// summary describes the alert in a line, such as for the text of a chat message
+func (a Alert) summary() string {
+	if a.Count == 1 {
+		return fmt.Sprintf("[%s] %s: %s", a.Rule, a.Entry.Level.CapitalString(), a.Entry.Message)
+	}
+	return fmt.Sprintf("[%s] %d %s entries in %s, the last one: %s", a.Rule, a.Count, levelName(a.Entry.Level), a.Window, a.Entry.Message)
+}
+
+// AlertMatcher reports whether an entry, with its fields, counts towards an alert rule
+type AlertMatcher func(ent zapcore.Entry, fields map[string]interface{}) bool
+
+// AlertHandler is called when an alert rule fires, such as WebhookAlertHandler, SlackAlertHandler, or PagerDutyAlertHandler.
+// It is called from a background goroutine and is given 10s, the returned error is written to the error output
+// along with the next entry that is matched against the rules.
+type AlertHandler func(ctx context.Context, alert Alert) error
+
+// AlertOption customizes an alert rule, see WithAlertRule
+type AlertOption func(*alertRule)
+
+// WithAlertName names the rule, the name is Alert.Rule, it is the level of the rule by default
+func WithAlertName(name string) AlertOption {
+	return func(r *alertRule) { r.name = name }
+}
+
+// WithAlertThreshold fires the rule once count entries match it within window, instead of on the first entry that matches
+func WithAlertThreshold(count int, window time.Duration) AlertOption {
+	return func(r *alertRule) { r.threshold, r.window = count, window }
+}
+
+// WithAlertCooldown keeps the rule from firing again for cooldown after it fires, it is the window of the threshold by default
+func WithAlertCooldown(cooldown time.Duration) AlertOption {
+	return func(r *alertRule) { r.cooldown = cooldown }
+}
+
+// WithAlertRule calls handler when the entries at or above level that match, with matcher returning true, reach
+// the threshold of the rule, see WithAlertThreshold, a nil matcher matches every entry. Rules are meant for small
+// agents without an alerting stack, such as paging when a job keeps failing:
+//
+//	WithAlertRule("error", func(ent zapcore.Entry, _ map[string]interface{}) bool { return ent.LoggerName == "sync" },
+//		PagerDutyAlertHandler(routingKey, ""), WithAlertName("sync failing"), WithAlertThreshold(5, 10*time.Minute))
+//
+// By default a rule fires on the first entry that matches, and then not again for a minute, see WithAlertCooldown.
+// The handlers are called one at a time, alerts are dropped when too many are waiting. Close waits for the pending ones.
+func WithAlertRule(level string, matcher AlertMatcher, handler AlertHandler, opts ...AlertOption) LoggerOption {
+	return func(args *PacketLogr) {
+		lvl, err := parseLevel(level)
+		if err != nil {
+			args.errs = multierr.Append(args.errs, errors.WithMessage(err, "WithAlertRule"))
+			return
+		}
+		r := &alertRule{name: levelName(lvl), level: lvl, matcher: matcher, handler: handler, threshold: 1, window: defaultAlertWindow, now: time.Now}
+		for _, opt := range opts {
+			opt(r)
+		}
+		switch {
+		case handler == nil:
+			args.errs = multierr.Append(args.errs, errors.New("WithAlertRule: handler must not be nil"))
+			return
+		case r.threshold <= 0 || r.window <= 0:
+			args.errs = multierr.Append(args.errs, errors.Errorf("WithAlertRule: threshold must be > 0 within a window > 0, got: %d within %s", r.threshold, r.window))
+			return
+		case r.cooldown < 0:
+			args.errs = multierr.Append(args.errs, errors.Errorf("WithAlertRule: cooldown must be >= 0, got: %s", r.cooldown))
+			return
+		}
+		if r.cooldown == 0 {
+			r.cooldown = r.window
+		}
+		args.alertRules = append(args.alertRules, r)
+	}
+}
+
+// alertRule counts the entries that match it and fires once they reach the threshold
+type alertRule struct {
+	name      string
+	level     zapcore.Level
+	matcher   AlertMatcher
+	handler   AlertHandler
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu sync.Mutex
+	// matched are when the entries within the window matched, at most threshold of them
+	matched []time.Time
+	// quietUntil is when the rule can fire again after firing
+	quietUntil time.Time
+}
+
+// match counts ent and reports whether the rule fires, with the number of entries that matched within the window
+func (r *alertRule) match(ent zapcore.Entry, fields map[string]interface{}) (int, bool) {
+	if ent.Level < r.level || (r.matcher != nil && !r.matcher(ent, fields)) {
+		return 0, false
+	}
+	now := r.now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now.Before(r.quietUntil) {
+		return 0, false
+	}
+	i := 0
+	for i < len(r.matched) && now.Sub(r.matched[i]) >= r.window {
+		i++
+	}
+	r.matched = append(r.matched[:copy(r.matched, r.matched[i:])], now)
+	if len(r.matched) < r.threshold {
+		return 0, false
+	}
+	count := len(r.matched)
+	r.matched, r.quietUntil = r.matched[:0], now.Add(r.cooldown)
+	return count, true
+}
+
+// alerter calls the handlers of the rules that fire from a background goroutine, one at a time
+type alerter struct {
+	rules []*alertRule
+	// level is the lowest level of the rules
+	level zapcore.Level
+	queue *queue[firedAlert]
+	err   lastErr
+}
+
+// firedAlert is an alert waiting for its handler
+type firedAlert struct {
+	alert   Alert
+	handler AlertHandler
+}
+
+func newAlerter(rules []*alertRule) *alerter {
+	a := &alerter{rules: rules, level: zapcore.FatalLevel, queue: newQueue[firedAlert](alertQueueSize)}
+	for _, r := range rules {
+		if r.level < a.level {
+			a.level = r.level
+		}
+	}
+	a.queue.start(a.run)
+	return a
+}
+
+func (a *alerter) run(queue <-chan firedAlert) {
+	for f := range queue {
+		ctx, cancel := context.WithTimeout(context.Background(), alertHandlerTimeout)
+		if err := f.handler(ctx, f.alert); err != nil {
+			a.err.set(errors.WithMessagef(err, "alert handler of %q failed", f.alert.Rule))
+		}
+		cancel()
+	}
+}
+
+// check matches ent against the rules and queues the alerts of the ones that fire
+func (a *alerter) check(ent zapcore.Entry, fields []zapcore.Field) {
+	var m map[string]interface{}
+	for _, r := range a.rules {
+		if ent.Level < r.level {
+			continue
+		}
+		if m == nil {
+			m, _ = reportFields(fields)
+		}
+		count, ok := r.match(ent, m)
+		if !ok {
+			continue
+		}
+		a.send(firedAlert{alert: Alert{Rule: r.name, Count: count, Window: r.window, Entry: ent, Fields: m}, handler: r.handler})
+	}
+}
+
+// send queues f, dropping it when the queue is full or the alerter is closed
+func (a *alerter) send(f firedAlert) {
+	if a.queue.put(f, 0) != queued {
+		a.queue.dropped.Add(1)
+	}
+}
+
+// takeErr returns the last error of the handlers and how many alerts were dropped since the last call
+func (a *alerter) takeErr() error {
+	err := a.err.take()
+	if dropped := a.queue.dropped.Swap(0); dropped > 0 {
+		err = multierr.Append(err, fmt.Errorf("dropped %d alerts", dropped))
+	}
+	return err
+}
+
+// close waits for the queued alerts to be handled, giving up when ctx is done, and returns the last error of the handlers
+func (a *alerter) close(ctx context.Context) error {
+	a.queue.close()
+	select {
+	case <-a.queue.stopped:
+		return a.takeErr()
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "waiting for the alert handlers")
+	}
+}
+
+// alertOn tees an alertCore for the rules of a
+func alertOn(a *alerter) zap.Option {
+	return zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(c, &alertCore{LevelEnabler: a.level, alerter: a})
+	})
+}
+
+// alertCore matches the entries at or above the lowest level of the rules, including the fields added with With
+type alertCore struct {
+	zapcore.LevelEnabler
+	fields  []zapcore.Field
+	alerter *alerter
+}
+
+func (c *alertCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	return &clone
+}
+
+func (c *alertCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write returns the errors of the handlers since the last entry was matched, so they are written to the error output
+func (c *alertCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.alerter.check(ent, append(c.fields[:len(c.fields):len(c.fields)], fields...))
+	return c.alerter.takeErr()
+}
+
+func (c *alertCore) Sync() error { return nil }
+
+// alertClient sends the requests of the built-in handlers
+var alertClient = &http.Client{Timeout: reporterRequestTimeout, Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}}
+
+// postAlert posts body as JSON to url
+func postAlert(ctx context.Context, url string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the alert")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err, "failed to make the alert request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := alertClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to send the alert")
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("failed to send the alert: %s", resp.Status)
+	}
+	return nil
+}
+
+// webhookAlert is the body WebhookAlertHandler posts
+type webhookAlert struct {
+	Rule    string                 `json:"rule"`
+	Count   int                    `json:"count"`
+	Window  string                 `json:"window"`
+	Level   string                 `json:"level"`
+	Logger  string                 `json:"logger,omitempty"`
+	Message string                 `json:"msg"`
+	Time    time.Time              `json:"time"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// WebhookAlertHandler posts the alerts to url as JSON, with the rule, count, window, level, logger, msg, time, and fields
+// of the entry that made the rule fire
+func WebhookAlertHandler(url string) AlertHandler {
+	return func(ctx context.Context, a Alert) error {
+		return postAlert(ctx, url, webhookAlert{
+			Rule:    a.Rule,
+			Count:   a.Count,
+			Window:  a.Window.String(),
+			Level:   levelName(a.Entry.Level),
+			Logger:  a.Entry.LoggerName,
+			Message: a.Entry.Message,
+			Time:    a.Entry.Time,
+			Fields:  a.Fields,
+		})
+	}
+}
+
+// SlackAlertHandler posts the alerts as messages to a Slack incoming webhook
+func SlackAlertHandler(webhookURL string) AlertHandler {
+	return func(ctx context.Context, a Alert) error {
+		return postAlert(ctx, webhookURL, struct {
+			Text string `json:"text"`
+		}{a.summary()})
+	}
+}
+
+// pagerDutyEvent is a trigger event of the PagerDuty Events API v2
+type pagerDutyEvent struct {
+	RoutingKey  string `json:"routing_key"`
+	EventAction string `json:"event_action"`
+	DedupKey    string `json:"dedup_key"`
+	Payload     struct {
+		Summary       string                 `json:"summary"`
+		Source        string                 `json:"source"`
+		Severity      string                 `json:"severity"`
+		Timestamp     time.Time              `json:"timestamp"`
+		Component     string                 `json:"component,omitempty"`
+		CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+	} `json:"payload"`
+}
+
+// PagerDutyAlertHandler triggers a PagerDuty incident for the alerts, with the integration's routingKey. The alerts
+// of a rule are grouped in one incident, by its name. endpoint is the Events API v2 URL, when empty it is
+// https://events.pagerduty.com/v2/enqueue.
+func PagerDutyAlertHandler(routingKey, endpoint string) AlertHandler {
+	if endpoint == "" {
+		endpoint = defaultPagerDutyEndpoint
+	}
+	return func(ctx context.Context, a Alert) error {
+		e := pagerDutyEvent{RoutingKey: routingKey, EventAction: "trigger", DedupKey: a.Rule}
+		e.Payload.Summary = a.summary()
+		e.Payload.Source, _ = a.Fields["service"].(string)
+		if e.Payload.Source == "" {
+			e.Payload.Source, _ = os.Hostname()
+		}
+		e.Payload.Severity = pagerDutySeverity(a.Entry.Level)
+		e.Payload.Timestamp = a.Entry.Time
+		e.Payload.Component = a.Entry.LoggerName
+		e.Payload.CustomDetails = a.Fields
+		return postAlert(ctx, endpoint, e)
+	}
+}
+
+// pagerDutySeverity is the PagerDuty severity of lvl
+func pagerDutySeverity(lvl zapcore.Level) string {
+	switch {
+	case lvl >= zapcore.DPanicLevel:
+		return "critical"
+	case lvl == zapcore.ErrorLevel:
+		return "error"
+	case lvl == zapcore.WarnLevel:
+		return "warning"
+	default:
+		return "info"
+	}
+}
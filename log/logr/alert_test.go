@@ -0,0 +1,154 @@
+package logr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestPacketLogrAlertRule(t *testing.T) {
+	var mu sync.Mutex
+	var alerts []Alert
+	handler := func(_ context.Context, a Alert) error {
+		mu.Lock()
+		defer mu.Unlock()
+		alerts = append(alerts, a)
+		return nil
+	}
+	l, err := New(
+		WithOutputPaths([]string{t.TempDir() + "/log"}),
+		WithServiceName("agent"),
+		WithAlertRule("error", func(_ zapcore.Entry, fields map[string]interface{}) bool { return fields["job"] == "sync" }, handler,
+			WithAlertName("sync failing"), WithAlertThreshold(3, time.Hour)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := l.Logger.WithValues("job", "sync")
+	job.Info("not an error")
+	l.Error(errors.New("boom"), "another job failed", "job", "backup")
+	for i := 0; i < 4; i++ {
+		job.Error(errors.New("boom"), "sync failed")
+	}
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(alerts) != 1 {
+		t.Fatalf("expected the rule to fire once, got: %v", alerts)
+	}
+	a := alerts[0]
+	if a.Rule != "sync failing" || a.Count != 3 || a.Entry.Message != "sync failed" || a.Fields["service"] != "agent" || a.Fields["error"] != "boom" {
+		t.Fatalf("expected the alert of the third sync error, got: %+v", a)
+	}
+}
+
+func TestAlertRuleWindow(t *testing.T) {
+	now := time.Unix(0, 0)
+	r := &alertRule{threshold: 2, window: time.Minute, cooldown: 5 * time.Minute, now: func() time.Time { return now }}
+	ent := zapcore.Entry{Level: zapcore.ErrorLevel}
+	for _, step := range []struct {
+		after time.Duration
+		fires bool
+	}{
+		{0, false},
+		// the first match is out of the window
+		{time.Minute, false},
+		{30 * time.Second, true},
+		// the cooldown
+		{time.Minute, false},
+		{time.Second, false},
+		{5 * time.Minute, false},
+		{time.Second, true},
+	} {
+		now = now.Add(step.after)
+		if _, fires := r.match(ent, nil); fires != step.fires {
+			t.Fatalf("expected the rule to fire %v at %s, got: %v", step.fires, now.Sub(time.Unix(0, 0)), fires)
+		}
+	}
+}
+
+func TestAlertRuleErrors(t *testing.T) {
+	handler := func(context.Context, Alert) error { return nil }
+	for _, opt := range []LoggerOption{
+		WithAlertRule("loud", nil, handler),
+		WithAlertRule("error", nil, nil),
+		WithAlertRule("error", nil, handler, WithAlertThreshold(0, time.Minute)),
+		WithAlertRule("error", nil, handler, WithAlertCooldown(-time.Second)),
+	} {
+		if _, err := New(opt); err == nil || !strings.Contains(err.Error(), "WithAlertRule") {
+			t.Fatalf("expected a WithAlertRule error, got: %v", err)
+		}
+	}
+}
+
+func TestAlertHandlerErrors(t *testing.T) {
+	l, err := New(
+		WithOutputPaths([]string{t.TempDir() + "/log"}),
+		WithAlertRule("warn", nil, func(context.Context, Alert) error { return errors.New("pager is down") }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.V(-1).Info("first")
+	if err := l.Close(context.Background()); err == nil || !strings.Contains(err.Error(), `alert handler of "warn" failed: pager is down`) {
+		t.Fatalf("expected Close to return the handler error, got: %v", err)
+	}
+}
+
+func TestAlertHandlers(t *testing.T) {
+	bodies := make(chan map[string]interface{}, 3)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		if err := json.Unmarshal(b, &body); err != nil {
+			t.Error(err)
+		}
+		bodies <- body
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	a := Alert{
+		Rule:   "sync failing",
+		Count:  5,
+		Window: 10 * time.Minute,
+		Entry:  zapcore.Entry{Level: zapcore.ErrorLevel, LoggerName: "sync", Message: "sync failed", Time: time.Now()},
+		Fields: map[string]interface{}{"service": "agent"},
+	}
+	ctx := context.Background()
+	if err := WebhookAlertHandler(srv.URL)(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if body := <-bodies; body["rule"] != "sync failing" || body["count"] != 5.0 || body["window"] != "10m0s" || body["level"] != "error" || body["msg"] != "sync failed" {
+		t.Fatalf("expected the webhook to get the alert, got: %v", body)
+	}
+
+	if err := SlackAlertHandler(srv.URL)(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if body := <-bodies; body["text"] != "[sync failing] 5 error entries in 10m0s, the last one: sync failed" {
+		t.Fatalf("expected the Slack message, got: %v", body)
+	}
+
+	if err := PagerDutyAlertHandler("key", srv.URL+"/fail")(ctx, a); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Fatalf("expected the failed request to be an error, got: %v", err)
+	}
+	body := <-bodies
+	payload, _ := body["payload"].(map[string]interface{})
+	if body["routing_key"] != "key" || body["event_action"] != "trigger" || body["dedup_key"] != "sync failing" ||
+		payload["source"] != "agent" || payload["severity"] != "error" || payload["component"] != "sync" {
+		t.Fatalf("expected the PagerDuty event, got: %v", body)
+	}
+}
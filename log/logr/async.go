@@ -0,0 +1,172 @@
+package logr
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// asyncBatchSize is how many bytes the background writer collects before writing them out without waiting for the flush interval
+const asyncBatchSize = 256 * 1024
+
+// asyncWriter queues writes and writes them to ws from a background goroutine,
+// so a slow disk or socket only slows the logger down once the queue is full.
+type asyncWriter struct {
+	ws           zapcore.WriteSyncer
+	interval     time.Duration
+	dropWhenFull bool
+	queue        *queue[asyncItem]
+}
+
+// asyncItem is an entry to write, or a flush that is done once the entries queued before it are written
+type asyncItem struct {
+	entry   []byte
+	flushed chan error
+}
+
+func newAsyncWriter(ws zapcore.WriteSyncer, size int, interval time.Duration, dropWhenFull bool) *asyncWriter {
+	w := &asyncWriter{ws: ws, interval: interval, dropWhenFull: dropWhenFull, queue: newQueue[asyncItem](size)}
+	w.queue.start(w.run)
+	return w
+}
+
+// Write queues a copy of p, blocking while the queue is full unless dropWhenFull is set, in which case p is counted and dropped.
+// Once the writer is closed p is written straight to ws.
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	entry := make([]byte, len(p))
+	copy(entry, p)
+	wait := noTimeout
+	if w.dropWhenFull {
+		wait = 0
+	}
+	switch w.queue.put(asyncItem{entry: entry}, wait) {
+	case queueClosed:
+		return w.ws.Write(p)
+	case queueFull:
+		w.queue.dropped.Add(1)
+	}
+	return len(p), nil
+}
+
+// Flush waits for everything queued so far to be written to ws, it returns any write errors since the last flush
+func (w *asyncWriter) Flush() error {
+	flushed := make(chan error, 1)
+	if w.queue.put(asyncItem{flushed: flushed}, noTimeout) == queueClosed {
+		return nil
+	}
+	return <-flushed
+}
+
+// Sync flushes the queue and syncs ws
+func (w *asyncWriter) Sync() error {
+	return multierr.Append(w.Flush(), w.ws.Sync())
+}
+
+// Close writes out the queue and stops the background goroutine, it is safe to call more than once
+func (w *asyncWriter) Close() {
+	w.queue.close()
+	<-w.queue.stopped
+}
+
+func (w *asyncWriter) run(items <-chan asyncItem) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	var (
+		batch bytes.Buffer
+		err   error
+	)
+	write := func() {
+		if batch.Len() == 0 {
+			return
+		}
+		_, writeErr := w.ws.Write(batch.Bytes())
+		err = multierr.Append(err, errors.Wrap(writeErr, "async log writer"))
+		batch.Reset()
+	}
+	for {
+		select {
+		case item, ok := <-items:
+			switch {
+			case !ok:
+				write()
+				return
+			case item.flushed != nil:
+				write()
+				item.flushed <- err
+				err = nil
+			default:
+				batch.Write(item.entry)
+				if batch.Len() >= asyncBatchSize {
+					write()
+				}
+			}
+		case <-ticker.C:
+			write()
+		}
+	}
+}
+
+// asyncOutput replaces the core with one that writes to the output paths through an asyncWriter, the same as zap.Config.Build would otherwise
+func asyncOutput(c zap.Config, wrap func(zapcore.WriteSyncer) zapcore.WriteSyncer) (zap.Option, error) {
+	sink, _, err := zap.Open(c.OutputPaths...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open output paths")
+	}
+	encoder, err := NewEncoder(c)
+	if err != nil {
+		return nil, err
+	}
+	core := zapcore.NewCore(encoder, wrap(sink), c.Level)
+	return zap.WrapCore(func(zapcore.Core) zapcore.Core { return core }), nil
+}
+
+// Dropped returns how many entries have been dropped because the WithAsyncBuffer queue was full, see WithDropWhenFull
+func (p *PacketLogr) Dropped() uint64 {
+	var dropped uint64
+	for _, w := range p.asyncWriters {
+		dropped += w.queue.dropped.Load()
+	}
+	return dropped
+}
+
+// reportDrops writes a line to errOut, the error output, every interval in which entries were dropped, until stopDropReports is called.
+// It isn't logged as it would be queued behind the entries being dropped, and likely be dropped too.
+func (p *PacketLogr) reportDrops(interval time.Duration, errOut zapcore.WriteSyncer) {
+	stop := make(chan struct{})
+	var once sync.Once
+	p.stopDropReports = func() { once.Do(func() { close(stop) }) }
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var reported uint64
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if dropped := p.Dropped(); dropped > reported {
+					fmt.Fprintf(errOut, "%v dropped %d log entries because the async buffer was full, %d in total\n", time.Now(), dropped-reported, dropped)
+					_ = errOut.Sync()
+					reported = dropped
+				}
+			}
+		}
+	}()
+}
+
+// Flush waits for entries queued by WithAsyncBuffer to be written to the outputs, it does nothing otherwise.
+// Sync and Close flush too.
+func (p *PacketLogr) Flush() error {
+	var err error
+	for _, w := range p.asyncWriters {
+		err = multierr.Append(err, w.Flush())
+	}
+	return err
+}
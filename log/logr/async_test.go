@@ -0,0 +1,176 @@
+package logr
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// slowWriter blocks every write until release is closed
+type slowWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	release chan struct{}
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *slowWriter) Sync() error { return nil }
+
+func (w *slowWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncWriter(t *testing.T) {
+	out := &slowWriter{release: make(chan struct{})}
+	w := newAsyncWriter(out, 10, time.Hour, false)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := w.Write([]byte("entry\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Fatalf("expected writes not to wait on the output, took: %s", took)
+	}
+	close(out.release)
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(out.String(), "entry"); got != 5 {
+		t.Fatalf("expected 5 entries after flush, got: %d", got)
+	}
+
+	w.Close()
+	if _, err := w.Write([]byte("after close\n")); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "after close") {
+		t.Fatalf("expected writes after close to go straight to the output, got: %v", out.String())
+	}
+}
+
+func TestAsyncWriterInterval(t *testing.T) {
+	out := &slowWriter{release: make(chan struct{})}
+	close(out.release)
+	w := newAsyncWriter(zapcore.AddSync(out), 10, 10*time.Millisecond, false)
+	defer w.Close()
+
+	_, _ = w.Write([]byte("entry\n"))
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "entry") {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the entry to be written after the flush interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPacketLogrAsyncBuffer(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "out.log")
+	pl, err := New(WithOutputPaths([]string{logFile}), WithAsyncBuffer(100, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := func() string {
+		b, err := ioutil.ReadFile(logFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	pl.Info("flushed message")
+	if err := pl.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(read(), `"msg":"flushed message"`) {
+		t.Fatalf("expected entry after flush, got: %v", read())
+	}
+	pl.Info("closed message")
+	if err := pl.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(read(), `"msg":"closed message"`) {
+		t.Fatalf("expected entry after close, got: %v", read())
+	}
+}
+
+func TestPacketLogrAsyncBufferStderr(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		pl, err := New(WithEnableErrLogsToStderr(true), WithAsyncBuffer(100, time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pl.Info("buffered info")
+		pl.Error(nil, "buffered error")
+		if err := pl.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	})
+	for _, want := range []string{`"msg":"buffered info"`, `"msg":"buffered error"`} {
+		if !strings.Contains(capturedOutput, want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+		}
+	}
+}
+
+func TestAsyncWriterDropWhenFull(t *testing.T) {
+	// no background goroutine, so nothing drains the queue
+	w := &asyncWriter{queue: &queue[asyncItem]{items: make(chan asyncItem, 2)}, dropWhenFull: true}
+	for i := 0; i < 5; i++ {
+		if _, err := w.Write([]byte("entry\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(w.queue.items); got != 2 {
+		t.Fatalf("expected 2 queued entries, got: %d", got)
+	}
+	if got := w.queue.dropped.Load(); got != 3 {
+		t.Fatalf("expected 3 dropped entries, got: %d", got)
+	}
+}
+
+func TestPacketLogrDropWhenFull(t *testing.T) {
+	errorLog := filepath.Join(t.TempDir(), "errors.log")
+	pl, err := New(WithOutputPaths([]string{filepath.Join(t.TempDir(), "out.log")}), WithErrorOutputPaths([]string{errorLog}),
+		WithAsyncBuffer(100, time.Millisecond), WithDropWhenFull(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pl.Close(context.Background())
+
+	pl.asyncWriters[0].queue.dropped.Add(3)
+	if got := pl.Dropped(); got != 3 {
+		t.Fatalf("expected 3 dropped entries, got: %d", got)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		b, err := ioutil.ReadFile(errorLog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(b), " dropped 3 log entries because the async buffer was full, 3 in total\n") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a drop summary, got: %s", b)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
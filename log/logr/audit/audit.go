@@ -0,0 +1,246 @@
+// Package audit logs audit events, who did what to which resource and whether they were allowed to, apart from the application logs
+// so they can be kept, shipped, and searched on their own. Every event is written, whatever the application's log level,
+// and the events missing one of the mandatory fields are refused:
+//
+//	a, err := audit.New(audit.WithOutputPaths([]string{"/var/log/billing/audit.log"}), audit.WithServiceName("billing"))
+//	err = a.Log(ctx, audit.Event{Actor: user.ID, Action: "invoice.void", Resource: "invoice/" + id, Outcome: audit.OutcomeSuccess})
+//
+// With WithHashChain or WithHMACKey the events are chained by their hashes, and a Verifier finds the ones tampered with.
+package audit
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Outcome is how the action of an event turned out
+type Outcome string
+
+// The outcomes of events
+const (
+	// OutcomeSuccess is an action that was allowed and done
+	OutcomeSuccess Outcome = "success"
+	// OutcomeFailure is an action that was allowed but failed
+	OutcomeFailure Outcome = "failure"
+	// OutcomeDenied is an action the actor wasn't allowed to do
+	OutcomeDenied Outcome = "denied"
+)
+
+// Event is an audit event, Actor, Action, Resource, and Outcome are mandatory
+type Event struct {
+	// Time is when the action was done, it is when the event is logged when zero
+	Time time.Time
+	// Actor is who did the action, such as a user or API key ID
+	Actor string
+	// Action is what was done, such as invoice.void
+	Action string
+	// Resource is what it was done to, such as invoice/123
+	Resource string
+	Outcome  Outcome
+	// Reason is why the action had its outcome, such as the error or the policy denying it
+	Reason string
+	// RequestID is the ID of the request the action was done for, by default it is the one of the context
+	RequestID string
+	// Details are other attributes of the event, such as the values changed
+	Details map[string]string
+}
+
+// Validate checks that the event has the mandatory fields and a known outcome, all of the problems are returned together
+func (e Event) Validate() error {
+	var err error
+	for _, f := range []struct{ name, value string }{{"actor", e.Actor}, {"action", e.Action}, {"resource", e.Resource}} {
+		if f.value == "" {
+			err = multierr.Append(err, errors.Errorf("%s is required", f.name))
+		}
+	}
+	switch e.Outcome {
+	case OutcomeSuccess, OutcomeFailure, OutcomeDenied:
+	case "":
+		err = multierr.Append(err, errors.New("outcome is required"))
+	default:
+		err = multierr.Append(err, errors.Errorf("outcome must be one of success, failure, or denied, got: %q", e.Outcome))
+	}
+	return err
+}
+
+// fields are the zap fields the event is written as
+func (e Event) fields() []zapcore.Field {
+	fields := []zapcore.Field{
+		zap.String("actor", e.Actor),
+		zap.String("action", e.Action),
+		zap.String("resource", e.Resource),
+		zap.String("outcome", string(e.Outcome)),
+	}
+	if e.Reason != "" {
+		fields = append(fields, zap.String("reason", e.Reason))
+	}
+	if e.RequestID != "" {
+		fields = append(fields, zap.String(logr.RequestIDKey, e.RequestID))
+	}
+	if len(e.Details) > 0 {
+		fields = append(fields, zap.Object("details", details(e.Details)))
+	}
+	return fields
+}
+
+// details writes the details of an event sorted by key
+type details map[string]string
+
+func (d details) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	keys := make([]string, 0, len(d))
+	for k := range d {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		enc.AddString(k, d[k])
+	}
+	return nil
+}
+
+// Option customizes New
+type Option func(*Logger)
+
+// WithOutputPaths writes the events to paths, such as a file only for audit events, see zap.Open for the paths accepted
+func WithOutputPaths(paths []string) Option {
+	return func(l *Logger) { l.outputPaths = append(l.outputPaths, paths...) }
+}
+
+// WithWriter writes the events to ws, such as a sink shipping them to a separate index
+func WithWriter(ws zapcore.WriteSyncer) Option {
+	return func(l *Logger) { l.writers = append(l.writers, ws) }
+}
+
+// WithServiceName adds the service's name to every event as the service field
+func WithServiceName(name string) Option {
+	return func(l *Logger) { l.serviceName = name }
+}
+
+// WithHashChain makes the events tamper-evident: each one has the hash of the one before it as prev_hash,
+// and its own SHA-256 hash, of everything before it, as hash, so changing, adding, or removing an event breaks the chain
+// from there on, see Verifier. The chain carries on from the last event of the file at the first output path.
+func WithHashChain() Option {
+	return func(l *Logger) { l.chain = true }
+}
+
+// WithHMACKey chains the events as WithHashChain does but with HMAC-SHA256 hashes, so only the holders of key
+// can make a chain that verifies, the same key is needed by the Verifier. The key must not be empty.
+func WithHMACKey(key []byte) Option {
+	return func(l *Logger) { l.chain, l.keyed, l.key = true, true, key }
+}
+
+// Logger writes audit events, see New
+type Logger struct {
+	outputPaths []string
+	writers     []zapcore.WriteSyncer
+	serviceName string
+	chain       bool
+	keyed       bool
+	key         []byte
+
+	mu      sync.Mutex
+	encoder zapcore.Encoder
+	out     zapcore.WriteSyncer
+	closer  func()
+	// prev is the hash of the last event written, when chaining
+	prev string
+}
+
+// New returns a Logger writing the events as JSON lines to the outputs, at least one of WithOutputPaths or WithWriter is required
+func New(opts ...Option) (*Logger, error) {
+	l := &Logger{closer: func() {}}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if len(l.outputPaths) == 0 && len(l.writers) == 0 {
+		return nil, errors.New("audit events need an output, see WithOutputPaths and WithWriter")
+	}
+	if l.keyed && len(l.key) == 0 {
+		return nil, errors.New("WithHMACKey: the key must not be empty")
+	}
+	writers := l.writers
+	if len(l.outputPaths) > 0 {
+		ws, closer, err := zap.Open(l.outputPaths...)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open the audit output paths")
+		}
+		writers, l.closer = append(writers, ws), closer
+	}
+
+	if l.chain && len(l.outputPaths) > 0 {
+		prev, err := lastHash(l.outputPaths[0])
+		if err != nil {
+			l.closer()
+			return nil, err
+		}
+		l.prev = prev
+	}
+
+	// the events are encoded directly, rather than through a zapcore.Core, as they are written whatever the level
+	// and the hash of a chained event covers its encoding
+	l.encoder = zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		TimeKey:        "time",
+		EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		LineEnding:     zapcore.DefaultLineEnding,
+	})
+	if l.serviceName != "" {
+		l.encoder.AddString("service", l.serviceName)
+	}
+	l.out = zapcore.NewMultiWriteSyncer(writers...)
+	return l, nil
+}
+
+// Log validates and writes e, the RequestID is the one of ctx when it is empty, see logr.RequestIDFromContext.
+// The event is written before Log returns, and it isn't written when it is invalid.
+func (l *Logger) Log(ctx context.Context, e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.RequestID == "" {
+		e.RequestID = logr.RequestIDFromContext(ctx)
+	}
+	if err := e.Validate(); err != nil {
+		return errors.WithMessage(err, "invalid audit event")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fields := e.fields()
+	if l.chain {
+		fields = append(fields, zap.String(prevHashKey, l.prev))
+	}
+	buf, err := l.encoder.EncodeEntry(zapcore.Entry{Time: e.Time}, fields)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the audit event")
+	}
+	defer buf.Free()
+	line := buf.Bytes()
+	var hash string
+	if l.chain {
+		line, hash = l.seal(line)
+	}
+	if _, err := l.out.Write(line); err != nil {
+		return errors.Wrap(err, "failed to write the audit event")
+	}
+	if l.chain {
+		l.prev = hash
+	}
+	return nil
+}
+
+// Close syncs the outputs and closes the output paths
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	err := l.out.Sync()
+	l.closer()
+	return errors.Wrap(err, "failed to sync the audit events")
+}
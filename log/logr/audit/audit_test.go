@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/packethost/pkg/log/logr"
+)
+
+func TestLog(t *testing.T) {
+	path := t.TempDir() + "/audit.log"
+	a, err := New(WithOutputPaths([]string{path}), WithServiceName("billing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := logr.ContextWithRequestID(context.Background(), "req-1")
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := a.Log(ctx, Event{Time: at, Actor: "user/42", Action: "invoice.void", Resource: "invoice/7", Outcome: OutcomeDenied, Reason: "not an admin", Details: map[string]string{"b": "2", "a": "1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Log(ctx, Event{Actor: "user/42", Action: "invoice.void", Outcome: "maybe"}); err == nil || !strings.Contains(err.Error(), "resource is required") || !strings.Contains(err.Error(), "outcome must be one of") {
+		t.Fatalf("expected the invalid event to be refused, got: %v", err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := os.ReadFile(path)
+	want := `{"time":"2024-05-01T12:00:00Z","service":"billing","actor":"user/42","action":"invoice.void","resource":"invoice/7","outcome":"denied","reason":"not an admin","request_id":"req-1","details":{"a":"1","b":"2"}}` + "\n"
+	if string(b) != want {
+		t.Fatalf("expected only the valid event to be written, got: %s", b)
+	}
+	var event map[string]interface{}
+	if err := json.Unmarshal(b, &event); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewRequiresOutput(t *testing.T) {
+	if _, err := New(WithServiceName("billing")); err == nil {
+		t.Fatal("expected an error without an output")
+	}
+}
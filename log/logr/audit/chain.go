@@ -0,0 +1,180 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// prevHashKey is the field of a chained event with the hash of the event before it
+	prevHashKey = "prev_hash"
+	// hashSep comes before the hash of a chained event, which is its last field
+	hashSep = `,"hash":"`
+	// maxEventSize is the longest event lastHash and Verifier read
+	maxEventSize = 1 << 20
+)
+
+// seal adds the hash of line, an encoded event, as its last field
+func (l *Logger) seal(line []byte) ([]byte, string) {
+	content := bytes.TrimSuffix(line, []byte("}\n"))
+	sum := hashOf(l.key, content)
+	sealed := make([]byte, 0, len(content)+len(hashSep)+len(sum)+3)
+	sealed = append(sealed, content...)
+	sealed = append(sealed, hashSep...)
+	sealed = append(sealed, sum...)
+	return append(sealed, "\"}\n"...), sum
+}
+
+// hashOf is the hex SHA-256 of content, or its HMAC-SHA256 with key
+func hashOf(key, content []byte) string {
+	var h hash.Hash
+	if len(key) > 0 {
+		h = hmac.New(sha256.New, key)
+	} else {
+		h = sha256.New()
+	}
+	_, _ = h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// lastHash is the hash of the last event of the file at path, so the chain carries on from it, it is empty when there is no file yet.
+// The outputs that aren't files, such as stdout, start a new chain.
+func lastHash(path string) (string, error) {
+	if path == "stdout" || path == "stderr" || strings.Contains(path, "://") {
+		return "", nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the last audit event")
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the last audit event")
+	}
+	offset := info.Size() - maxEventSize
+	if offset < 0 {
+		offset = 0
+	}
+	tail := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(tail, offset); err != nil && err != io.EOF {
+		return "", errors.Wrap(err, "failed to read the last audit event")
+	}
+	tail = bytes.TrimRight(tail, "\n")
+	if len(tail) == 0 {
+		return "", nil
+	}
+	last := tail[bytes.LastIndexByte(tail, '\n')+1:]
+	_, sum, err := split(last)
+	if err != nil {
+		return "", errors.WithMessagef(err, "the last audit event of %s can't be chained to", path)
+	}
+	return sum, nil
+}
+
+// split splits a chained event into what its hash covers and the hash
+func split(line []byte) ([]byte, string, error) {
+	i := bytes.LastIndex(line, []byte(hashSep))
+	if i < 0 || !bytes.HasSuffix(line, []byte(`"}`)) {
+		return nil, "", errors.New("the event isn't chained, it has no hash")
+	}
+	return line[:i], string(line[i+len(hashSep) : len(line)-2]), nil
+}
+
+// Verifier checks that chained audit events haven't been tampered with, see WithHashChain
+type Verifier struct {
+	key    []byte
+	prev   string
+	events int
+}
+
+// VerifierOption customizes NewVerifier
+type VerifierOption func(*Verifier)
+
+// WithAnchor verifies events whose chain continues from an event that isn't verified, such as one in a file rotated out
+// and deleted, prevHash is the LastHash of that event
+func WithAnchor(prevHash string) VerifierOption {
+	return func(v *Verifier) { v.prev = prevHash }
+}
+
+// NewVerifier returns a Verifier of the events chained with the HMAC key, it is nil for the ones chained with WithHashChain
+func NewVerifier(key []byte, opts ...VerifierOption) *Verifier {
+	v := &Verifier{key: key}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Verify checks the events of r, an audit file, it returns an error with the line of the first event that was changed,
+// or that doesn't follow the one before it as events were added, removed, or reordered. The first event must start
+// the chain, with an empty prev_hash, unless the Verifier was given its anchor, see WithAnchor, so removing the first
+// events is found too. Files rotated out are verified in order with the same Verifier, which checks that they join up.
+//
+// Removing the last events can't be found from the events alone, it takes comparing LastHash with a copy of the hash
+// of the last event written kept elsewhere, such as from a previous verification.
+func (v *Verifier) Verify(r io.Reader) error {
+	if v.key != nil && len(v.key) == 0 {
+		return errors.New("the HMAC key must not be empty")
+	}
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+	for n := 1; s.Scan(); n++ {
+		line := s.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		content, sum, err := split(line)
+		if err != nil {
+			return errors.WithMessagef(err, "line %d", n)
+		}
+		if !hmac.Equal([]byte(sum), []byte(hashOf(v.key, content))) {
+			return errors.Errorf("line %d: the hash doesn't match, the event was changed", n)
+		}
+		var event struct {
+			PrevHash *string `json:"prev_hash"`
+		}
+		if err := json.Unmarshal(append(append([]byte{}, content...), '}'), &event); err != nil || event.PrevHash == nil {
+			return errors.Errorf("line %d: the event has no prev_hash", n)
+		}
+		if *event.PrevHash != v.prev {
+			return errors.Errorf("line %d: the prev_hash doesn't match the event before it, events were added, removed, or reordered", n)
+		}
+		v.prev = sum
+		v.events++
+	}
+	return errors.Wrap(s.Err(), "failed to read the audit events")
+}
+
+// VerifyFile verifies the events of the file at path, see Verify
+func (v *Verifier) VerifyFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to open the audit events")
+	}
+	defer f.Close()
+	return v.Verify(f)
+}
+
+// Events returns how many events have been verified
+func (v *Verifier) Events() int {
+	return v.events
+}
+
+// LastHash returns the hash of the last event verified, it is the prev_hash of the event after it
+func (v *Verifier) LastHash() string {
+	return v.prev
+}
@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func writeEvents(t *testing.T, path string, opts ...Option) {
+	a, err := New(append([]Option{WithOutputPaths([]string{path})}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range []string{"invoice.create", "invoice.void"} {
+		if err := a.Log(context.Background(), Event{Actor: "user/42", Action: action, Resource: "invoice/7", Outcome: OutcomeSuccess}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHashChain(t *testing.T) {
+	key := []byte("secret")
+	path := t.TempDir() + "/audit.log"
+	writeEvents(t, path, WithHMACKey(key))
+	// a new logger carries on with the chain of the file
+	writeEvents(t, path, WithHMACKey(key))
+
+	v := NewVerifier(key)
+	if err := v.VerifyFile(path); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(path)
+	lines := strings.SplitAfter(strings.TrimSpace(string(b)), "\n")
+	if v.Events() != 4 || !strings.HasSuffix(lines[3], `"hash":"`+v.LastHash()+`"}`) {
+		t.Fatalf("expected the 4 events to be verified, got: %d", v.Events())
+	}
+	if !strings.Contains(lines[0], `"prev_hash":"","hash":"`) {
+		t.Fatalf("expected the first event to start the chain, got: %s", lines[0])
+	}
+
+	if err := NewVerifier([]byte("wrong")).VerifyFile(path); err == nil || !strings.Contains(err.Error(), "line 1: the hash doesn't match") {
+		t.Fatalf("expected the wrong key to fail, got: %v", err)
+	}
+	for name, tc := range map[string]struct {
+		lines []string
+		want  string
+	}{
+		"changed":   {[]string{lines[0], strings.Replace(lines[1], "invoice.void", "invoice.view", 1), lines[2]}, "line 2: the hash doesn't match"},
+		"removed":   {[]string{lines[0], lines[2], lines[3]}, "line 2: the prev_hash doesn't match"},
+		"reordered": {[]string{lines[0], lines[2], lines[1]}, "line 2: the prev_hash doesn't match"},
+	} {
+		err := NewVerifier(key).Verify(strings.NewReader(strings.Join(tc.lines, "")))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("expected the %s event to be found, got: %v", name, err)
+		}
+	}
+
+	// removing the first events is found unless the verifier is given the hash they end with
+	if err := NewVerifier(key).Verify(strings.NewReader(strings.Join(lines[2:], ""))); err == nil || !strings.Contains(err.Error(), "line 1: the prev_hash doesn't match") {
+		t.Fatalf("expected the removed first events to be found, got: %v", err)
+	}
+	v = NewVerifier(key)
+	if err := v.Verify(strings.NewReader(strings.Join(lines[:2], ""))); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewVerifier(key, WithAnchor(v.LastHash())).Verify(strings.NewReader(strings.Join(lines[2:], ""))); err != nil {
+		t.Fatalf("expected the events after the anchor to verify, got: %v", err)
+	}
+	// rotated files are verified in order with the same verifier
+	if err := v.Verify(strings.NewReader(strings.Join(lines[2:], ""))); err != nil || v.Events() != 4 {
+		t.Fatalf("expected the rotated files to join up, got: %v", err)
+	}
+}
+
+func TestHashChainEmptyKey(t *testing.T) {
+	if _, err := New(WithOutputPaths([]string{t.TempDir() + "/audit.log"}), WithHMACKey([]byte{})); err == nil || !strings.Contains(err.Error(), "the key must not be empty") {
+		t.Fatalf("expected an error for an empty key, got: %v", err)
+	}
+	if err := NewVerifier([]byte{}).Verify(strings.NewReader("")); err == nil || !strings.Contains(err.Error(), "must not be empty") {
+		t.Fatalf("expected an error verifying with an empty key, got: %v", err)
+	}
+}
+
+func TestHashChainUnkeyed(t *testing.T) {
+	path := t.TempDir() + "/audit.log"
+	writeEvents(t, path, WithHashChain())
+	if err := NewVerifier(nil).VerifyFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	plain := t.TempDir() + "/audit.log"
+	writeEvents(t, plain)
+	if _, err := New(WithOutputPaths([]string{plain}), WithHashChain()); err == nil || !strings.Contains(err.Error(), "can't be chained to") {
+		t.Fatalf("expected an error chaining to events that aren't chained, got: %v", err)
+	}
+	if err := NewVerifier(nil).VerifyFile(plain); err == nil || !strings.Contains(err.Error(), "line 1: the event isn't chained") {
+		t.Fatalf("expected the events that aren't chained to fail, got: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+// Package awslog routes the logs of the AWS SDK for Go v2 to a logr.PacketLogr, so its retries, throttles, and request dumps
+// are structured entries with the fields of the request being handled:
+//
+//	cfg, err := config.LoadDefaultConfig(ctx,
+//		config.WithLogger(awslog.NewLogger(l)),
+//		config.WithClientLogMode(aws.LogRetries|aws.LogRequest|aws.LogResponse),
+//	)
+package awslog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/smithy-go/logging"
+	"github.com/go-logr/logr"
+	packetlogr "github.com/packethost/pkg/log/logr"
+)
+
+// The verbosities, as logr's V takes them, of the level names WithClassificationLevel takes, warn is logged with packetlogr.Warn
+var levelVerbosity = map[string]int{"warn": levelWarn, "info": 0, "debug": 1, "trace": 2, "off": levelOff}
+
+const (
+	// levelWarn is the verbosity of the classifications logged at warn
+	levelWarn = -1
+	// levelOff is the verbosity of the classifications that aren't logged
+	levelOff = -2
+)
+
+// The formats of the SDK's retry logs, see aws/retry
+const (
+	retryingFormat  = "retrying request %s/%s, attempt %d"
+	exhaustedPrefix = "max retry attempts exhausted"
+)
+
+// The headers of request and response dumps that are always redacted, as they are credentials
+var defaultRedactedHeaders = []string{"Authorization", "X-Amz-Security-Token"}
+
+// redacted replaces the values that are redacted
+const redacted = "[REDACTED]"
+
+// Option customizes NewLogger
+type Option func(*config)
+
+type config struct {
+	levels          map[logging.Classification]int
+	redactedHeaders map[string]bool
+	redactBodies    bool
+}
+
+// WithClassificationLevel logs the entries of classification at level, one of warn, info, debug, or trace,
+// or not at all with off, instead of WARN at warn and DEBUG at debug
+func WithClassificationLevel(classification logging.Classification, level string) Option {
+	return func(c *config) {
+		if v, ok := levelVerbosity[level]; ok {
+			c.levels[classification] = v
+		}
+	}
+}
+
+// WithRedactedHeaders redacts the values of the headers names in request and response dumps, besides Authorization and X-Amz-Security-Token
+func WithRedactedHeaders(names ...string) Option {
+	return func(c *config) {
+		for _, name := range names {
+			c.redactedHeaders[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+}
+
+// WithRedactedBodies redacts the bodies of request and response dumps, for the services whose payloads are secrets or personal data,
+// such as Secrets Manager
+func WithRedactedBodies() Option {
+	return func(c *config) { c.redactBodies = true }
+}
+
+// Logger is a smithy logging.Logger, for the Logger of aws.Config, logging with a logr.PacketLogr. Its WithContext, which the SDK
+// calls for each operation, logs with the logger of the operation's context, from logr.FromContext. WARN entries are logged at warn
+// and DEBUG ones at debug, unless WithClassificationLevel says otherwise, but retries are logged at info, as "retrying aws request"
+// with the aws_service, operation, and attempt, so throttling and failing calls show. Request and response dumps are logged as
+// "aws request" and "aws response" with the dump, its credentials redacted.
+type Logger struct {
+	p *packetlogr.PacketLogr
+	l logr.Logger
+	c config
+}
+
+var (
+	_ logging.Logger        = (*Logger)(nil)
+	_ logging.ContextLogger = (*Logger)(nil)
+)
+
+// NewLogger returns the Logger logging with p
+func NewLogger(p *packetlogr.PacketLogr, opts ...Option) *Logger {
+	c := config{
+		levels:          map[logging.Classification]int{logging.Warn: levelVerbosity["warn"], logging.Debug: levelVerbosity["debug"]},
+		redactedHeaders: map[string]bool{},
+	}
+	for _, name := range defaultRedactedHeaders {
+		c.redactedHeaders[name] = true
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &Logger{p: p, l: p.Logger, c: c}
+}
+
+// WithContext returns a Logger logging with the logger of ctx
+func (l *Logger) WithContext(ctx context.Context) logging.Logger {
+	return &Logger{p: l.p, l: l.p.FromContext(ctx), c: l.c}
+}
+
+// Logf logs an entry of the SDK
+func (l *Logger) Logf(classification logging.Classification, format string, v ...interface{}) {
+	switch {
+	case format == retryingFormat && len(v) == 3:
+		l.l.Info("retrying aws request", "aws_service", v[0], "operation", v[1], "attempt", v[2])
+		return
+	case strings.HasPrefix(format, exhaustedPrefix):
+		l.l.Info(fmt.Sprintf(format, v...))
+		return
+	}
+
+	verbosity, ok := l.c.levels[classification]
+	if !ok {
+		verbosity = levelVerbosity["info"]
+	}
+	if verbosity == levelOff {
+		return
+	}
+	switch {
+	case format == "Request\n%v" && len(v) == 1:
+		l.log(verbosity, "aws request", "dump", l.c.redactDump(fmt.Sprint(v[0])))
+	case format == "Response\n%v" && len(v) == 1:
+		l.log(verbosity, "aws response", "dump", l.c.redactDump(fmt.Sprint(v[0])))
+	default:
+		l.log(verbosity, fmt.Sprintf(format, v...))
+	}
+}
+
+func (l *Logger) log(verbosity int, msg string, keysAndValues ...interface{}) {
+	if verbosity == levelWarn {
+		packetlogr.Warn(l.l, msg, keysAndValues...)
+		return
+	}
+	l.l.V(verbosity).Info(msg, keysAndValues...)
+}
+
+// redactDump redacts the headers of an HTTP request or response dump, and its body with WithRedactedBodies
+func (c config) redactDump(dump string) string {
+	head, body, hasBody := strings.Cut(dump, "\r\n\r\n")
+	lines := strings.Split(head, "\r\n")
+	// the first line is the request or status line
+	for i := 1; i < len(lines); i++ {
+		name, _, ok := strings.Cut(lines[i], ":")
+		if ok && c.redactedHeaders[http.CanonicalHeaderKey(strings.TrimSpace(name))] {
+			lines[i] = name + ": " + redacted
+		}
+	}
+	head = strings.Join(lines, "\r\n")
+	if !hasBody {
+		return head
+	}
+	if c.redactBodies && body != "" {
+		body = redacted
+	}
+	return head + "\r\n\r\n" + body
+}
@@ -0,0 +1,81 @@
+package awslog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/logging"
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+)
+
+var noBackoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+
+func TestLoggerS3(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>`))
+			return
+		}
+		_, _ = w.Write([]byte("secret payload"))
+	}))
+	defer srv.Close()
+
+	l, logged := logtest.New(t, logr.WithLogLevel("debug"))
+	client := s3.New(s3.Options{
+		Region:        "us-east-1",
+		BaseEndpoint:  aws.String(srv.URL),
+		UsePathStyle:  true,
+		Credentials:   credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", "session-token"),
+		Logger:        NewLogger(l, WithRedactedBodies()),
+		ClientLogMode: aws.LogRetries | aws.LogRequest | aws.LogResponseWithBody,
+		Retryer:       retry.NewStandard(func(o *retry.StandardOptions) { o.Backoff = noBackoff }),
+	})
+	ctx := l.NewContextWithValues(context.Background(), "request_id", "r1")
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String("logs"), Key: aws.String("a.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Body.Close()
+
+	got := logged()
+	for _, want := range []string{
+		`"msg":"retrying aws request","service":"not/set","request_id":"r1","aws_service":"S3","operation":"GetObject","attempt":2`,
+		`"msg":"aws request","service":"not/set","request_id":"r1","dump":"GET /logs/a.log`,
+		`Authorization: [REDACTED]`,
+		`X-Amz-Security-Token: [REDACTED]`,
+		`"msg":"aws response"`,
+		`\r\n\r\n[REDACTED]"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %s to be logged, got: %v", want, got)
+		}
+	}
+	for _, secret := range []string{"AKIDEXAMPLE", "session-token", "secret payload"} {
+		if strings.Contains(got, secret) {
+			t.Fatalf("expected %s to be redacted, got: %v", secret, got)
+		}
+	}
+}
+
+func TestLoggerClassificationLevel(t *testing.T) {
+	l, logged := logtest.New(t, logr.WithLogLevel("debug"))
+	logger := NewLogger(l, WithClassificationLevel(logging.Debug, "off"))
+	logger.Logf(logging.Warn, "Response has no supported checksum. Not validating response payload.")
+	logger.Logf(logging.Debug, "request failed with unretryable error %v", "boom")
+	got := logged()
+	if !strings.Contains(got, `"level":"warn"`) || !strings.Contains(got, "Response has no supported checksum") || strings.Contains(got, "unretryable") {
+		t.Fatalf("expected only the warning to be logged, got: %v", got)
+	}
+}
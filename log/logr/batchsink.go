@@ -0,0 +1,351 @@
+package logr
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+)
+
+// batchConfig is how a batchSink groups entries and retries batches that fail
+type batchConfig struct {
+	size     int
+	bytes    int
+	interval time.Duration
+	retries  int
+	// custom is set when the sink's own option set the batch size, so WithBatchPolicy doesn't change it
+	custom bool
+}
+
+// WithBatchPolicy sets the most entries and bytes sent in one batch and how long entries wait for a batch to fill up,
+// for all of the sinks that send entries in batches: WithElasticsearch and the sinks of other packages that use SinkContext.BatchPolicy,
+// such as the ones of cloudloggingsink and kafkasink.
+// It replaces the sinks' defaults, and a sink's own batch option, such as WithElasticsearchBatch, takes precedence over it.
+// Smaller batches and intervals get entries to the remote end sooner, bigger ones make fewer requests.
+// S3 segments are set with s3sink.WithUpload as they are much bigger.
+func WithBatchPolicy(maxEntries, maxBytes int, maxInterval time.Duration) LoggerOption {
+	return func(args *PacketLogr) {
+		policy := batchConfig{size: maxEntries, bytes: maxBytes, interval: maxInterval}
+		if err := policy.validate(); err != nil {
+			args.errs = multierr.Append(args.errs, errors.WithMessage(err, "WithBatchPolicy"))
+			return
+		}
+		args.batchPolicy = &policy
+	}
+}
+
+// BatchPolicy is the batch size set with WithBatchPolicy, for the sinks of other packages, see SinkContext
+type BatchPolicy struct {
+	MaxEntries  int
+	MaxBytes    int
+	MaxInterval time.Duration
+}
+
+// BatchConfig is how a BatchSink groups entries into batches and retries the batches that fail
+type BatchConfig struct {
+	// MaxEntries and MaxBytes are the most entries and bytes sent in one batch
+	MaxEntries int
+	MaxBytes   int
+	// FlushInterval is how long entries wait for a batch to fill up
+	FlushInterval time.Duration
+	// Retries is how many times a batch that fails is sent again before its entries are spooled or dropped
+	Retries int
+}
+
+// WithPolicy is c with the size of policy, such as the one from SinkContext, unless it is nil
+func (c BatchConfig) WithPolicy(policy *BatchPolicy) BatchConfig {
+	if policy != nil {
+		c.MaxEntries, c.MaxBytes, c.FlushInterval = policy.MaxEntries, policy.MaxBytes, policy.MaxInterval
+	}
+	return c
+}
+
+// Validate checks that the sizes and the interval are > 0 and the retries >= 0
+func (c BatchConfig) Validate() error {
+	return c.batchConfig().validate()
+}
+
+func (c BatchConfig) batchConfig() batchConfig {
+	return batchConfig{size: c.MaxEntries, bytes: c.MaxBytes, interval: c.FlushInterval, retries: c.Retries}
+}
+
+// withPolicy is c with the size set by WithBatchPolicy, unless the sink's own option set it
+func (c batchConfig) withPolicy(policy *batchConfig) batchConfig {
+	if c.custom || policy == nil {
+		return c
+	}
+	c.size, c.bytes, c.interval = policy.size, policy.bytes, policy.interval
+	return c
+}
+
+func (c batchConfig) validate() error {
+	var err error
+	if c.size < 1 || c.bytes < 1 || c.interval <= 0 {
+		err = multierr.Append(err, errors.Errorf("batch entries, bytes, and flush interval must be > 0, got: %d, %d, and %s", c.size, c.bytes, c.interval))
+	}
+	if c.retries < 0 {
+		err = multierr.Append(err, errors.Errorf("retries must be >= 0, got: %d", c.retries))
+	}
+	return err
+}
+
+// batchSink is the base of the sinks that send entries to an API in batches.
+// Entries are queued, as configured by the network config, and grouped into batches that are sent from a background goroutine
+// once they are big enough or old enough. send returns the entries that should be sent again, which are retried with
+// exponential backoff up to the configured number of times.
+// With WithSinkSpool entries that would be dropped are spooled to disk instead, and replayed whenever the sink is idle.
+type batchSink struct {
+	name    string
+	config  batchConfig
+	timeout time.Duration
+	send    func(ctx context.Context, batch [][]byte) ([][]byte, error)
+	// probe checks that the API can be reached, see WithSinkFailover
+	probe func(ctx context.Context) error
+	queue *queue[[]byte]
+	spool *diskSpool
+	err   lastErr
+
+	// ctx is cancelled when Close gives up waiting, aborting the request in flight
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// BatchSink is the base of the sinks of other packages that send entries to an API in batches, like the built-in ones do, see NewBatchSink
+type BatchSink struct {
+	*batchSink
+}
+
+// NewBatchSink starts sending the entries given to Send in batches configured by c, and queued and spooled as configured by network.
+// send sends a batch and returns the entries that should be sent again, name is the sink in errors and SpoolStats.
+// probe checks that the API can be reached for WithSinkFailover, such as HTTPProbe, it can be nil.
+func NewBatchSink(name string, c BatchConfig, network NetworkConfig, send func(ctx context.Context, batch [][]byte) ([][]byte, error),
+	probe func(ctx context.Context) error) (*BatchSink, error) {
+	s, err := newBatchSink(name, c.batchConfig(), network.c, send)
+	if err != nil {
+		return nil, err
+	}
+	s.probe = probe
+	return &BatchSink{batchSink: s}, nil
+}
+
+// Send queues entry, which the sink keeps, waiting up to the queue timeout of the network config when the queue is full,
+// after which it is spooled or dropped. It also returns the last error the sink had sending entries.
+func (s *BatchSink) Send(entry []byte) error {
+	return s.write(entry)
+}
+
+func newBatchSink(name string, c batchConfig, n netSinkConfig, send func(context.Context, [][]byte) ([][]byte, error)) (*batchSink, error) {
+	spool, err := n.openSpool()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "%s sink", name)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &batchSink{
+		name:    name,
+		config:  c,
+		timeout: n.queueTimeout,
+		send:    send,
+		queue:   newQueue[[]byte](n.queueSize),
+		spool:   spool,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	s.queue.start(s.run)
+	return s, nil
+}
+
+// write queues entry, waiting up to the queue timeout when the queue is full, after which it is spooled or dropped
+func (s *batchSink) write(entry []byte) error {
+	result := s.queue.put(entry, s.timeout)
+	if result == queueClosed {
+		return errors.Errorf("%s sink is closed", s.name)
+	}
+	err := s.err.take()
+	if result == queued {
+		return err
+	}
+	if s.spool != nil {
+		return multierr.Append(err, errors.WithMessagef(s.spool.push(entry), "%s sink queue is full", s.name))
+	}
+	s.queue.dropped.Add(1)
+	return multierr.Append(err, errors.Errorf("%s sink queue is full, dropped the entry", s.name))
+}
+
+func (s *batchSink) run(queue <-chan []byte) {
+	ticker := time.NewTicker(s.config.interval)
+	defer ticker.Stop()
+	var batch [][]byte
+	size := 0
+	flush := func() {
+		s.deliver(batch)
+		batch, size = nil, 0
+	}
+	for {
+		select {
+		case entry, ok := <-queue:
+			if !ok {
+				if len(batch) > 0 {
+					flush()
+				}
+				return
+			}
+			batch = append(batch, entry)
+			if size += len(entry); len(batch) >= s.config.size || size >= s.config.bytes {
+				flush()
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				flush()
+			} else if len(queue) == 0 && s.spool != nil && s.spool.pending() {
+				s.replay()
+			}
+		}
+	}
+}
+
+// replay sends the oldest spool file in batches, what fails again is spooled again so the file can be removed
+func (s *batchSink) replay() {
+	r, err := s.spool.pop()
+	if err != nil {
+		s.err.set(errors.WithMessagef(err, "%s sink", s.name))
+	}
+	if r == nil {
+		return
+	}
+	var (
+		batch [][]byte
+		size  int
+	)
+	for entry, ok := r.next(); ok || len(batch) > 0; entry, ok = r.next() {
+		if ok {
+			batch = append(batch, entry)
+			size += len(entry)
+			if len(batch) < s.config.size && size < s.config.bytes {
+				continue
+			}
+		}
+		if s.ctx.Err() != nil {
+			s.spill(batch)
+			for entry, ok := r.next(); ok; entry, ok = r.next() {
+				s.spill([][]byte{entry})
+			}
+			break
+		}
+		s.deliver(batch)
+		batch, size = nil, 0
+	}
+	if err := r.done(); err != nil {
+		s.err.set(errors.WithMessagef(err, "%s sink", s.name))
+	}
+}
+
+// deliver sends batch, retrying what failed with exponential backoff
+func (s *batchSink) deliver(batch [][]byte) {
+	backoff := netSinkMinBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := s.send(s.ctx, batch)
+		if err != nil {
+			s.err.set(errors.Wrapf(err, "%s sink", s.name))
+		}
+		if len(retry) == 0 {
+			return
+		}
+		if attempt == s.config.retries {
+			if !s.spill(retry) {
+				s.queue.dropped.Add(uint64(len(retry)))
+				s.err.set(multierr.Append(err, errors.Errorf("%s sink dropped %d entries after %d retries", s.name, len(retry), attempt)))
+			}
+			return
+		}
+		batch = retry
+		select {
+		case <-s.ctx.Done():
+			if !s.spill(retry) {
+				s.err.set(multierr.Append(err, errors.Errorf("%s sink closed with %d entries not sent", s.name, len(retry))))
+			}
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > netSinkMaxBackoff {
+			backoff = netSinkMaxBackoff
+		}
+	}
+}
+
+// spill spools entries that couldn't be sent, it returns false when there is no spool
+func (s *batchSink) spill(entries [][]byte) bool {
+	if s.spool == nil {
+		return false
+	}
+	for _, entry := range entries {
+		if err := s.spool.push(entry); err != nil {
+			s.err.set(errors.WithMessagef(err, "%s sink", s.name))
+		}
+	}
+	return true
+}
+
+func (s *batchSink) sinkName() string { return s.name }
+
+func (s *batchSink) checkHealth(ctx context.Context) error {
+	if s.probe == nil {
+		return nil
+	}
+	return errors.WithMessagef(s.probe(ctx), "%s sink", s.name)
+}
+
+// HTTPProbe is a BatchSink probe that passes when a HEAD request to url gets a response that isn't a server error
+func HTTPProbe(client *http.Client, url string) func(context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			return errors.Wrap(err, "failed to create health check request")
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return errors.Wrap(err, "health check request failed")
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return errors.Errorf("health check request failed: %s", resp.Status)
+		}
+		return nil
+	}
+}
+
+func (s *batchSink) droppedEntries() uint64 {
+	return s.queue.dropped.Load()
+}
+
+func (s *batchSink) spoolStats() (SpoolStats, bool) {
+	if s.spool == nil {
+		return SpoolStats{}, false
+	}
+	stats := s.spool.stats(s.name)
+	stats.Dropped += s.queue.dropped.Load()
+	return stats, true
+}
+
+// Close waits up to netSinkCloseTimeout for queued entries to be sent, what is left after that is spooled when there is a spool
+func (s *batchSink) Close() error {
+	if !s.queue.close() {
+		return nil
+	}
+
+	timer := time.NewTimer(netSinkCloseTimeout)
+	defer timer.Stop()
+	select {
+	case <-s.queue.stopped:
+	case <-timer.C:
+		s.cancel()
+		<-s.queue.stopped
+	}
+	s.cancel()
+	err := s.err.take()
+	if s.spool != nil {
+		err = multierr.Append(err, s.spool.close())
+	}
+	return err
+}
@@ -0,0 +1,162 @@
+package logr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultBugsnagEndpoint     = "https://notify.bugsnag.com"
+	defaultBugsnagFlushTimeout = 5 * time.Second
+	bugsnagPayloadVersion      = "5"
+)
+
+// BugsnagConfig is where and how error logs are reported to Bugsnag when WithEnableBugsnag is set, see WithBugsnagConfig
+type BugsnagConfig struct {
+	// APIKey is the project's notifier API key, when it is empty it is read from the BUGSNAG_API_KEY environment variable
+	APIKey string
+	// ReleaseStage is the stage events are reported in, such as production or staging
+	ReleaseStage string
+	// AppVersion is the version of the service events are attributed to
+	AppVersion string
+	// Endpoint is the notify endpoint, https://notify.bugsnag.com when empty, set it for an on-premise Bugsnag
+	Endpoint string
+	// FlushTimeout is how long Close waits for pending events to be sent, 5s when 0
+	FlushTimeout time.Duration
+}
+
+// NewBugsnagConfig returns the config for reporting to Bugsnag with apiKey in releaseStage, as appVersion of the service
+func NewBugsnagConfig(apiKey, releaseStage, appVersion string) BugsnagConfig {
+	return BugsnagConfig{APIKey: apiKey, ReleaseStage: releaseStage, AppVersion: appVersion}
+}
+
+// WithEnableBugsnag sends error logs to Bugsnag, along with or instead of the other error reporters.
+// Events are sent from the background, Close waits for them to be sent.
+func WithEnableBugsnag(enable bool) LoggerOption {
+	return func(args *PacketLogr) { args.enableBugsnag = enable }
+}
+
+// WithBugsnagConfig customizes the Bugsnag details, see NewBugsnagConfig
+func WithBugsnagConfig(config BugsnagConfig) LoggerOption {
+	return func(args *PacketLogr) { args.bugsnagConfig = config }
+}
+
+// apiKey is APIKey, or else BUGSNAG_API_KEY
+func (c BugsnagConfig) apiKey() string {
+	if c.APIKey != "" {
+		return c.APIKey
+	}
+	return getEnv("BUGSNAG_API_KEY")
+}
+
+func (c BugsnagConfig) validate() error {
+	var err error
+	if c.apiKey() == "" {
+		err = multierr.Append(err, errors.New("bugsnag is enabled but no API key is set, set the config's APIKey or BUGSNAG_API_KEY"))
+	}
+	if c.FlushTimeout < 0 {
+		err = multierr.Append(err, errors.Errorf("bugsnag flush timeout must be >= 0, got: %s", c.FlushTimeout))
+	}
+	return err
+}
+
+// bugsnagReporter builds the Bugsnag events for the entries that are reported
+type bugsnagReporter struct {
+	config   BugsnagConfig
+	apiKey   string
+	endpoint string
+	hostname string
+	*httpReporter
+}
+
+func (c BugsnagConfig) setupBugsnag(spool *diskSpool) *bugsnagReporter {
+	r := &bugsnagReporter{config: c, apiKey: c.apiKey(), endpoint: c.Endpoint, httpReporter: newHTTPReporter("bugsnag", spool)}
+	if r.endpoint == "" {
+		r.endpoint = defaultBugsnagEndpoint
+	}
+	r.hostname, _ = os.Hostname()
+	return r
+}
+
+// Report queues the event for the entry to be sent
+func (r *bugsnagReporter) Report(ent zapcore.Entry, fields []zapcore.Field) error {
+	meta, err := reportFields(fields)
+	errorClass, message := "log", ent.Message
+	if err != nil {
+		errorClass, message = fmt.Sprintf("%T", errors.Cause(err)), err.Error()
+		meta["message"] = ent.Message
+	}
+	// the stack of where the error was made, or else where it was logged
+	var stacktrace []map[string]interface{}
+	for _, frame := range errorStack(err) {
+		stacktrace = append(stacktrace, map[string]interface{}{
+			"file":       frame.File,
+			"lineNumber": frame.Line,
+			"method":     frame.Function,
+			"inProject":  !strings.HasPrefix(frame.Function, "runtime."),
+		})
+	}
+	if stacktrace == nil && ent.Caller.Defined {
+		stacktrace = append(stacktrace, map[string]interface{}{
+			"file":       ent.Caller.TrimmedPath(),
+			"lineNumber": ent.Caller.Line,
+			"method":     ent.Caller.Function,
+			"inProject":  true,
+		})
+	}
+	severity := "error"
+	if ent.Level == zapcore.WarnLevel {
+		severity = "warning"
+	} else if ent.Level < zapcore.WarnLevel {
+		severity = "info"
+	}
+
+	event := map[string]interface{}{
+		"exceptions":     []map[string]interface{}{{"errorClass": errorClass, "message": message, "stacktrace": stacktrace}},
+		"severity":       severity,
+		"severityReason": map[string]interface{}{"type": "log", "attributes": map[string]string{"level": ent.Level.String()}},
+		"unhandled":      ent.Level >= zapcore.DPanicLevel,
+		"context":        ent.LoggerName,
+		"app":            map[string]string{"releaseStage": r.config.ReleaseStage, "version": r.config.AppVersion},
+		"device":         map[string]string{"hostname": r.hostname, "time": ent.Time.UTC().Format(time.RFC3339)},
+		"metaData":       map[string]interface{}{"fields": meta},
+	}
+	body, jerr := json.Marshal(map[string]interface{}{
+		"apiKey":         r.apiKey,
+		"payloadVersion": bugsnagPayloadVersion,
+		"notifier":       map[string]string{"name": "packethost/pkg logr", "version": "1", "url": "https://github.com/packethost/pkg"},
+		"events":         []interface{}{event},
+	})
+	if jerr != nil {
+		return errors.Wrap(jerr, "failed to encode bugsnag event")
+	}
+	req, rerr := http.NewRequest(http.MethodPost, r.endpoint, bytes.NewReader(body))
+	if rerr != nil {
+		return errors.Wrap(rerr, "failed to create bugsnag request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Bugsnag-Api-Key", r.apiKey)
+	req.Header.Set("Bugsnag-Payload-Version", bugsnagPayloadVersion)
+	r.send(req)
+	return nil
+}
+
+// Flush waits for the pending events to be sent, giving up after the flush timeout or when ctx is done
+func (r *bugsnagReporter) Flush(ctx context.Context) error {
+	ctx, cancel := reporterFlushContext(ctx, r.config.FlushTimeout, defaultBugsnagFlushTimeout)
+	defer cancel()
+	return r.httpReporter.flush(ctx)
+}
+
+// Close sends the pending events and stops sending more
+func (r *bugsnagReporter) Close() error { return r.close(context.Background()) }
@@ -0,0 +1,141 @@
+package logr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// reporterServer is an error reporter endpoint that passes on the requests it receives, responding with status
+func reporterServer(t *testing.T, status int) (url string, requests <-chan *http.Request, bodies <-chan []byte) {
+	t.Helper()
+	received := make(chan *http.Request, 10)
+	receivedBodies := make(chan []byte, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received <- r
+		receivedBodies <- b
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, received, receivedBodies
+}
+
+func TestPacketLogrBugsnag(t *testing.T) {
+	url, requests, bodies := reporterServer(t, http.StatusOK)
+	config := NewBugsnagConfig("apikey", "staging", "v1.2.3")
+	config.Endpoint = url
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithEnableBugsnag(true), WithBugsnagConfig(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("not reported")
+	l.WithName("provisioner").WithValues("device", "d1").Error(errors.New("boom"), "provisioning failed")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-requests
+	if req.Header.Get("Bugsnag-Api-Key") != "apikey" || req.Header.Get("Bugsnag-Payload-Version") != "5" {
+		t.Fatalf("expected the bugsnag headers, got: %v", req.Header)
+	}
+	var payload struct {
+		APIKey string `json:"apiKey"`
+		Events []struct {
+			Exceptions []struct {
+				ErrorClass string `json:"errorClass"`
+				Message    string `json:"message"`
+				Stacktrace []struct {
+					File string `json:"file"`
+				} `json:"stacktrace"`
+			} `json:"exceptions"`
+			Severity string            `json:"severity"`
+			Context  string            `json:"context"`
+			App      map[string]string `json:"app"`
+			MetaData struct {
+				Fields map[string]interface{} `json:"fields"`
+			} `json:"metaData"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal(<-bodies, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.APIKey != "apikey" || len(payload.Events) != 1 {
+		t.Fatalf("expected one event, got: %+v", payload)
+	}
+	event := payload.Events[0]
+	if len(event.Exceptions) != 1 || event.Exceptions[0].ErrorClass != "*errors.errorString" || event.Exceptions[0].Message != "boom" ||
+		len(event.Exceptions[0].Stacktrace) != 1 || !strings.HasPrefix(event.Exceptions[0].Stacktrace[0].File, "logr/bugsnag_test.go") {
+		t.Fatalf("expected the error as the exception, got: %+v", event.Exceptions)
+	}
+	if event.Severity != "error" || event.Context != "provisioner" || event.App["releaseStage"] != "staging" || event.App["version"] != "v1.2.3" {
+		t.Fatalf("expected the event details, got: %+v", event)
+	}
+	if f := event.MetaData.Fields; f["device"] != "d1" || f["message"] != "provisioning failed" {
+		t.Fatalf("expected the fields as metadata, got: %v", f)
+	}
+	select {
+	case <-requests:
+		t.Fatal("expected only the error to be reported")
+	default:
+	}
+}
+
+func TestPacketLogrBugsnagErrors(t *testing.T) {
+	url, _, _ := reporterServer(t, http.StatusBadRequest)
+	config := NewBugsnagConfig("apikey", "staging", "v1")
+	config.Endpoint = url
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithEnableBugsnag(true), WithBugsnagConfig(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Error(nil, "rejected")
+	if err := l.Close(context.Background()); err == nil || !strings.Contains(err.Error(), "failed to send event to bugsnag: 400 Bad Request") {
+		t.Fatalf("expected the delivery error, got: %v", err)
+	}
+
+	setenv(t, "BUGSNAG_API_KEY", "")
+	_, err = New(WithEnableBugsnag(true), WithBugsnagConfig(BugsnagConfig{FlushTimeout: -1}))
+	if err == nil || !strings.Contains(err.Error(), "bugsnag is enabled but no API key is set") || !strings.Contains(err.Error(), "bugsnag flush timeout must be >= 0") {
+		t.Fatalf("expected the config errors, got: %v", err)
+	}
+}
+
+func TestPacketLogrBugsnagErrorStack(t *testing.T) {
+	url, _, bodies := reporterServer(t, http.StatusOK)
+	config := NewBugsnagConfig("apikey", "staging", "v1.2.3")
+	config.Endpoint = url
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithEnableBugsnag(true), WithBugsnagConfig(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Error(newStackError(), "provisioning failed")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var payload struct {
+		Events []struct {
+			Exceptions []struct {
+				Stacktrace []struct {
+					Method string `json:"method"`
+				} `json:"stacktrace"`
+			} `json:"exceptions"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal(<-bodies, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Events) != 1 || len(payload.Events[0].Exceptions) != 1 {
+		t.Fatalf("expected one event, got: %+v", payload)
+	}
+	if st := payload.Events[0].Exceptions[0].Stacktrace; len(st) < 2 || !strings.HasSuffix(st[0].Method, ".newStackError") {
+		t.Fatalf("expected the stack of where the error was made, got: %+v", st)
+	}
+}
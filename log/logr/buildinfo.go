@@ -0,0 +1,35 @@
+package logr
+
+import (
+	"runtime/debug"
+	"strconv"
+)
+
+// WithBuildInfo adds build_version, build_revision, and build_dirty fields describing the binary, as recorded by the go toolchain.
+// Fields that aren't available, such as the vcs info when built with -buildvcs=false, are left out.
+func WithBuildInfo() LoggerOption {
+	return func(args *PacketLogr) {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			args.keysAndValues = append(args.keysAndValues, buildInfoFields(info)...)
+		}
+	}
+}
+
+func buildInfoFields(info *debug.BuildInfo) []interface{} {
+	var kvs []interface{}
+	if v := info.Main.Version; v != "" {
+		kvs = append(kvs, "build_version", v)
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			kvs = append(kvs, "build_revision", setting.Value)
+		case "vcs.modified":
+			dirty, err := strconv.ParseBool(setting.Value)
+			if err == nil {
+				kvs = append(kvs, "build_dirty", dirty)
+			}
+		}
+	}
+	return kvs
+}
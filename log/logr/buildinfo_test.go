@@ -0,0 +1,41 @@
+package logr
+
+import (
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestBuildInfoFields(t *testing.T) {
+	info := &debug.BuildInfo{
+		Main: debug.Module{Path: "github.com/packethost/example", Version: "v1.2.3"},
+		Settings: []debug.BuildSetting{
+			{Key: "-compiler", Value: "gc"},
+			{Key: "vcs.revision", Value: "4f34602"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+	want := []interface{}{"build_version", "v1.2.3", "build_revision", "4f34602", "build_dirty", true}
+	if got := buildInfoFields(info); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected: %v, got: %v", want, got)
+	}
+
+	if got := buildInfoFields(&debug.BuildInfo{}); len(got) != 0 {
+		t.Fatalf("expected no fields, got: %v", got)
+	}
+}
+
+func TestPacketLogrBuildInfo(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		l, _, err := NewPacketLogr(WithBuildInfo())
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Info("build info")
+	})
+	// test binaries are built without vcs info but always have a main module version
+	if !strings.Contains(capturedOutput, `"build_version":`) {
+		t.Fatalf("expected to contain: %v, got: %v", `"build_version":`, capturedOutput)
+	}
+}
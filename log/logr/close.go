@@ -0,0 +1,89 @@
+package logr
+
+import (
+	"context"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// Sync flushes any buffered log entries.
+// The errors returned by the OS when syncing stdout and stderr that aren't files, such as terminals and pipes, are ignored.
+func (p *PacketLogr) Sync() error {
+	var errs error
+	for _, err := range multierr.Errors(p.zap.Sync()) {
+		if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTTY) {
+			continue
+		}
+		errs = multierr.Append(errs, err)
+	}
+	return errs
+}
+
+// FlushRollbar waits for pending Rollbar items to be sent, giving up after RollbarConfig.FlushTimeout or when ctx is done,
+// it does nothing when Rollbar isn't enabled. Close calls it, call it before exiting without Close.
+func (p *PacketLogr) FlushRollbar(ctx context.Context) error {
+	if !p.enableRollbar {
+		return nil
+	}
+	return p.rollbarReporter.Flush(ctx)
+}
+
+// FlushErrorReporters waits for the pending events of the error reporters, including the ones added with WithErrorReporter,
+// to be sent, giving up after their flush timeouts or when ctx is done. Close calls it, call it before exiting without Close.
+func (p *PacketLogr) FlushErrorReporters(ctx context.Context) error {
+	var err error
+	for _, r := range p.reporters {
+		err = multierr.Append(err, r.Flush(ctx))
+	}
+	return err
+}
+
+// Close waits for the pending events of the error reporters, such as Rollbar, to be sent, giving up after their flush timeouts or when ctx is done,
+// waits for the pending alert handlers and hooks, flushes buffered log entries, and closes the error reporters and the sinks.
+// The built-in error reporters give up on the events being sent when ctx is done, and spool the ones left, or drop them without a spool.
+// Close is meant to be deferred in main, the logger can still be used afterwards but entries are no longer buffered by WithAsyncBuffer.
+func (p *PacketLogr) Close(ctx context.Context) error {
+	if p.stopSignalToggle != nil {
+		p.stopSignalToggle()
+	}
+	if p.stopDropReports != nil {
+		p.stopDropReports()
+	}
+	if p.stopSpoolReports != nil {
+		p.stopSpoolReports()
+	}
+	if p.stopDedupeFlushes != nil {
+		p.stopDedupeFlushes()
+	}
+	err := multierr.Combine(p.FlushErrorReporters(ctx), p.Sync())
+	if p.alerts != nil {
+		err = multierr.Append(err, p.alerts.close(ctx))
+	}
+	if p.hookRunner != nil {
+		err = multierr.Append(err, p.hookRunner.close(ctx))
+	}
+	for _, r := range p.reporters {
+		err = multierr.Append(err, closeReporter(ctx, r))
+	}
+	p.reporters = nil
+	for _, q := range p.reporterQueues {
+		err = multierr.Append(err, q.close(ctx))
+	}
+	p.reporterQueues = nil
+	for _, w := range p.asyncWriters {
+		w.Close()
+	}
+	return multierr.Append(err, p.closeSinks())
+}
+
+// closeOnError cleans up after New fails, the way Close does, and returns err along with the errors cleaning up
+func (p *PacketLogr) closeOnError(err error) error {
+	if p.zap == nil {
+		// the async writers are flushed when they are closed
+		p.zap = zap.NewNop()
+	}
+	return multierr.Append(err, p.Close(context.Background()))
+}
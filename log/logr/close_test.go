@@ -0,0 +1,38 @@
+package logr
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPacketLogrClose(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "out.log")
+	l, _, err := NewPacketLogr(WithOutputPaths([]string{"stdout", logFile}), WithSignalLevelToggle(os.Interrupt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl := l.(*PacketLogr)
+	captureOutput(func() {
+		l.Info("flushed on close")
+		if err := pl.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	b, err := ioutil.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "flushed on close") {
+		t.Fatalf("expected to contain: %v, got: %v", "flushed on close", string(b))
+	}
+
+	// closing twice must not panic on the already stopped signal handler
+	if err := pl.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,381 @@
+// Package cloudloggingsink writes the entries of a logr.PacketLogr to Google Cloud Logging:
+//
+//	l, err := logr.New(cloudloggingsink.WithSink("app/billing"))
+package cloudloggingsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	"github.com/packethost/pkg/log/logr"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	cloudLoggingEndpoint = "https://logging.googleapis.com/v2/entries:write"
+	cloudLoggingScope    = "https://www.googleapis.com/auth/logging.write"
+	// cloudLoggingBatchSize and cloudLoggingBatchBytes keep a request well within the API's limits of 10MB
+	cloudLoggingBatchSize  = 500
+	cloudLoggingBatchBytes = 5 * 1024 * 1024
+	// cloudLoggingFlushInterval is how long entries wait for a batch to fill up
+	cloudLoggingFlushInterval = time.Second
+	// cloudLoggingRetries is how many times a write is retried before its entries are dropped
+	cloudLoggingRetries = 5
+	// cloudLoggingRequestTimeout is how long a write can take
+	cloudLoggingRequestTimeout = 30 * time.Second
+)
+
+// The fields that link an entry to a Cloud Trace span, they are moved out of the payload into the entry
+const (
+	cloudLoggingTraceKey   = "trace_id"
+	cloudLoggingSpanKey    = "span_id"
+	cloudLoggingSampledKey = "trace_sampled"
+)
+
+// kubernetesNamespaceFile is where the namespace of a pod is mounted
+var kubernetesNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// Option configures WithSink
+type Option func(*cloudLoggingConfig)
+
+type cloudLoggingConfig struct {
+	logID       string
+	project     string
+	resource    *cloudLoggingResource
+	tokenSource oauth2.TokenSource
+	endpoint    string
+	batch       logr.BatchConfig
+	network     []logr.NetworkOption
+	// customBatch is set by WithBatch, so logr.WithBatchPolicy doesn't change the batch size
+	customBatch bool
+}
+
+// cloudLoggingResource is the monitored resource entries are written for
+type cloudLoggingResource struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// WithProject sets the project entries are written to,
+// by default the project of the credentials or of the GCE instance or GKE cluster the logger is running on.
+func WithProject(id string) Option {
+	return func(c *cloudLoggingConfig) { c.project = id }
+}
+
+// WithResource sets the monitored resource entries are written for, such as gce_instance or k8s_container,
+// which is detected from the metadata server by default, falling back to global.
+func WithResource(resourceType string, labels map[string]string) Option {
+	return func(c *cloudLoggingConfig) { c.resource = &cloudLoggingResource{Type: resourceType, Labels: labels} }
+}
+
+// WithTokenSource sets where the tokens used to write entries come from, instead of the application default credentials
+func WithTokenSource(ts oauth2.TokenSource) Option {
+	return func(c *cloudLoggingConfig) { c.tokenSource = ts }
+}
+
+// WithEndpoint sets the URL of the entries.write method, for a regional endpoint or an emulator
+func WithEndpoint(url string) Option {
+	return func(c *cloudLoggingConfig) { c.endpoint = url }
+}
+
+// WithBatch sets the most entries and bytes written in one request, 500 and 5MiB by default,
+// how long entries wait for a batch to fill up, 1s by default, and how many times a failed write is retried, 5 by default.
+func WithBatch(maxEntries, maxBytes int, flushInterval time.Duration, retries int) Option {
+	return func(c *cloudLoggingConfig) {
+		c.batch = logr.BatchConfig{MaxEntries: maxEntries, MaxBytes: maxBytes, FlushInterval: flushInterval, Retries: retries}
+		c.customBatch = true
+	}
+}
+
+// WithNetwork configures TLS, auth, and queueing, see logr.NetworkOption.
+// A token set with logr.WithSinkAuth is used instead of the application default credentials.
+func WithNetwork(opts ...logr.NetworkOption) Option {
+	return func(c *cloudLoggingConfig) { c.network = append(c.network, opts...) }
+}
+
+// WithSink also writes entries to the Google Cloud Logging log logID, using the application default credentials.
+// Levels are mapped to severities, the fields and message are the jsonPayload, and the caller is the source location.
+// The trace_id, span_id, and trace_sampled fields are used to link the entry to its Cloud Trace span.
+// Entries are queued and written in batches from the background, see WithBatch.
+func WithSink(logID string, opts ...Option) logr.LoggerOption {
+	c := cloudLoggingConfig{
+		logID:    logID,
+		endpoint: cloudLoggingEndpoint,
+		batch:    logr.BatchConfig{MaxEntries: cloudLoggingBatchSize, MaxBytes: cloudLoggingBatchBytes, FlushInterval: cloudLoggingFlushInterval, Retries: cloudLoggingRetries},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	network := logr.NewHTTPNetworkConfig(c.network...)
+	err := multierr.Append(network.Validate(), c.batch.Validate())
+	if a := network.Compression().Algorithm; a != "" && a != logr.CompressionGzip {
+		err = multierr.Append(err, errors.New("only gzip compression is accepted"))
+	}
+	if logID == "" {
+		err = multierr.Append(err, errors.New("log ID must not be empty"))
+	}
+	if c.endpoint == "" {
+		err = multierr.Append(err, errors.New("endpoint must not be empty"))
+	}
+	if c.resource != nil && c.resource.Type == "" {
+		err = multierr.Append(err, errors.New("resource type must not be empty"))
+	}
+	if err != nil {
+		return logr.WithOptionError(errors.WithMessage(err, "cloudloggingsink.WithSink"))
+	}
+	return logr.WithSink(func(sc logr.SinkContext) (zapcore.Core, io.Closer, error) {
+		c := c
+		if !c.customBatch {
+			c.batch = c.batch.WithPolicy(sc.BatchPolicy)
+		}
+		return c.open(sc.Config, network)
+	})
+}
+
+func (c cloudLoggingConfig) open(zc zap.Config, network logr.NetworkConfig) (zapcore.Core, io.Closer, error) {
+	ts := c.tokenSource
+	if ts != nil {
+		ts = oauth2.ReuseTokenSource(nil, ts)
+	}
+	if network.HasToken() {
+		ts = sinkTokenSource{network}
+	}
+	if ts == nil {
+		creds, err := google.FindDefaultCredentials(context.Background(), cloudLoggingScope)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to find credentials for cloud logging")
+		}
+		ts = creds.TokenSource
+		if c.project == "" {
+			c.project = creds.ProjectID
+		}
+	}
+	onGCE := (c.project == "" || c.resource == nil) && metadata.OnGCE()
+	if c.project == "" && onGCE {
+		c.project, _ = metadata.ProjectID()
+	}
+	if c.project == "" {
+		return nil, nil, errors.New("failed to detect the cloud logging project, use WithProject")
+	}
+	if c.resource == nil {
+		c.resource = detectCloudLoggingResource(c.project, onGCE)
+	}
+
+	header, err := json.Marshal(map[string]interface{}{
+		"logName":        "projects/" + c.project + "/logs/" + strings.ReplaceAll(c.logID, "/", "%2F"),
+		"resource":       c.resource,
+		"partialSuccess": true,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	client := &cloudLoggingClient{
+		endpoint: c.endpoint,
+		network:  network,
+		header:   header,
+		client: &http.Client{
+			Timeout: cloudLoggingRequestTimeout,
+			Transport: &oauth2.Transport{
+				Source: ts,
+				Base:   &http.Transport{TLSClientConfig: network.TLSConfig(), Proxy: http.ProxyFromEnvironment},
+			},
+		},
+	}
+	// the probe's request also fetches a token, so the check fails when the credentials do
+	s, err := logr.NewBatchSink("cloud logging", c.batch, network, client.write, logr.HTTPProbe(client.client, c.endpoint))
+	if err != nil {
+		return nil, nil, err
+	}
+	return &cloudLoggingCore{LevelEnabler: zc.Level, s: s, project: c.project, keys: zc.EncoderConfig}, s, nil
+}
+
+// sinkTokenSource is the token set with logr.WithSinkAuth as an oauth2.TokenSource, it never expires as it is read again when it changes
+type sinkTokenSource struct {
+	c logr.NetworkConfig
+}
+
+func (s sinkTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.c.BearerToken()
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{AccessToken: token}, nil
+}
+
+// detectCloudLoggingResource works out the monitored resource from the metadata server and the environment
+func detectCloudLoggingResource(project string, onGCE bool) *cloudLoggingResource {
+	if !onGCE {
+		return &cloudLoggingResource{Type: "global", Labels: map[string]string{"project_id": project}}
+	}
+	attr := func(name string) string {
+		v, _ := metadata.InstanceAttributeValue(name)
+		return strings.TrimSpace(v)
+	}
+	if cluster := attr("cluster-name"); cluster != "" {
+		namespace := os.Getenv("POD_NAMESPACE")
+		if namespace == "" {
+			b, _ := os.ReadFile(kubernetesNamespaceFile)
+			namespace = strings.TrimSpace(string(b))
+		}
+		pod := os.Getenv("POD_NAME")
+		if pod == "" {
+			pod, _ = os.Hostname()
+		}
+		return &cloudLoggingResource{Type: "k8s_container", Labels: map[string]string{
+			"project_id":     project,
+			"location":       attr("cluster-location"),
+			"cluster_name":   cluster,
+			"namespace_name": namespace,
+			"pod_name":       pod,
+			"container_name": os.Getenv("CONTAINER_NAME"),
+		}}
+	}
+	id, _ := metadata.InstanceID()
+	zone, _ := metadata.Zone()
+	return &cloudLoggingResource{Type: "gce_instance", Labels: map[string]string{"project_id": project, "instance_id": id, "zone": zone}}
+}
+
+// cloudLoggingSeverity maps zap levels to Cloud Logging severities
+func cloudLoggingSeverity(level zapcore.Level) string {
+	switch {
+	case level >= zapcore.FatalLevel:
+		return "EMERGENCY"
+	case level == zapcore.PanicLevel:
+		return "ALERT"
+	case level == zapcore.DPanicLevel:
+		return "CRITICAL"
+	case level == zapcore.ErrorLevel:
+		return "ERROR"
+	case level == zapcore.WarnLevel:
+		return "WARNING"
+	case level == zapcore.InfoLevel:
+		return "INFO"
+	}
+	return "DEBUG"
+}
+
+// cloudLoggingCore turns entries into LogEntry JSON
+type cloudLoggingCore struct {
+	zapcore.LevelEnabler
+	s       *logr.BatchSink
+	project string
+	keys    zapcore.EncoderConfig
+	fields  []zapcore.Field
+}
+
+func (c *cloudLoggingCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(append([]zapcore.Field{}, c.fields...), fields...)
+	return &clone
+}
+
+func (c *cloudLoggingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *cloudLoggingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	b, err := json.Marshal(c.entry(ent, fields))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode cloud logging entry")
+	}
+	return c.s.Send(b)
+}
+
+func (c *cloudLoggingCore) Sync() error { return nil }
+
+func (c *cloudLoggingCore) entry(ent zapcore.Entry, fields []zapcore.Field) map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	payload := enc.Fields
+	entry := map[string]interface{}{
+		"severity":  cloudLoggingSeverity(ent.Level),
+		"timestamp": ent.Time.UTC().Format(time.RFC3339Nano),
+	}
+	if trace, ok := payload[cloudLoggingTraceKey].(string); ok && trace != "" {
+		entry["trace"] = "projects/" + c.project + "/traces/" + trace
+		delete(payload, cloudLoggingTraceKey)
+		if span, ok := payload[cloudLoggingSpanKey].(string); ok {
+			entry["spanId"] = span
+			delete(payload, cloudLoggingSpanKey)
+		}
+		if sampled, ok := payload[cloudLoggingSampledKey].(bool); ok {
+			entry["traceSampled"] = sampled
+			delete(payload, cloudLoggingSampledKey)
+		}
+	}
+	if ent.Caller.Defined {
+		location := map[string]interface{}{"file": ent.Caller.File, "line": strconv.Itoa(ent.Caller.Line)}
+		if ent.Caller.Function != "" {
+			location["function"] = ent.Caller.Function
+		}
+		entry["sourceLocation"] = location
+	}
+
+	payload["message"] = ent.Message
+	if ent.LoggerName != "" && c.keys.NameKey != "" {
+		payload[c.keys.NameKey] = ent.LoggerName
+	}
+	if ent.Stack != "" && c.keys.StacktraceKey != "" {
+		payload[c.keys.StacktraceKey] = ent.Stack
+	}
+	entry["jsonPayload"] = payload
+	return entry
+}
+
+// cloudLoggingClient writes batches of entries with entries.write
+type cloudLoggingClient struct {
+	endpoint string
+	network  logr.NetworkConfig
+	// header is the request without the entries, as JSON
+	header []byte
+	client *http.Client
+}
+
+// write sends batch in one request, returning it to be retried when the API is unavailable
+func (c *cloudLoggingClient) write(ctx context.Context, batch [][]byte) ([][]byte, error) {
+	var body bytes.Buffer
+	body.Write(c.header[:len(c.header)-1])
+	body.WriteString(`,"entries":[`)
+	body.Write(bytes.Join(batch, []byte(",")))
+	body.WriteString("]}")
+	req, err := c.network.NewRequest(ctx, http.MethodPost, c.endpoint, body.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create write request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return batch, errors.Wrap(err, "write request failed")
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return batch, errors.Errorf("write request failed: %s", resp.Status)
+	case resp.StatusCode >= 300:
+		return nil, errors.Errorf("write request failed, some of %d entries were dropped: %s: %s", len(batch), resp.Status, respBody)
+	}
+	return nil, nil
+}
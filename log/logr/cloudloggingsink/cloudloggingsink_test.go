@@ -0,0 +1,117 @@
+package cloudloggingsink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/packethost/pkg/log/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/oauth2"
+)
+
+type cloudLoggingRequest struct {
+	LogName        string                   `json:"logName"`
+	Resource       cloudLoggingResource     `json:"resource"`
+	PartialSuccess bool                     `json:"partialSuccess"`
+	Entries        []map[string]interface{} `json:"entries"`
+}
+
+func TestWithSink(t *testing.T) {
+	reqs := make(chan cloudLoggingRequest, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("expected the token, got: %v", got)
+		}
+		var req cloudLoggingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		reqs <- req
+	}))
+	defer srv.Close()
+
+	l, err := logr.New(logr.WithServiceName("billing"), logr.WithOutputPaths([]string{os.DevNull}), WithSink("app/billing",
+		WithEndpoint(srv.URL),
+		WithProject("my-project"),
+		WithResource("k8s_container", map[string]string{"cluster_name": "prod"}),
+		WithTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"})),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("traced", "trace_id", "abc123", "span_id", "def456", "trace_sampled", true)
+	l.V(1).Info("debug")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-reqs
+	if req.LogName != "projects/my-project/logs/app%2Fbilling" || req.Resource.Type != "k8s_container" || req.Resource.Labels["cluster_name"] != "prod" || !req.PartialSuccess {
+		t.Fatalf("expected the log name and resource, got: %+v", req)
+	}
+	if len(req.Entries) != 1 {
+		t.Fatalf("expected 1 entry, got: %v", req.Entries)
+	}
+	entry := req.Entries[0]
+	if entry["severity"] != "INFO" || entry["trace"] != "projects/my-project/traces/abc123" || entry["spanId"] != "def456" || entry["traceSampled"] != true {
+		t.Fatalf("expected the severity and trace, got: %v", entry)
+	}
+	if location, _ := entry["sourceLocation"].(map[string]interface{}); location == nil || !strings.HasSuffix(location["file"].(string), "cloudloggingsink_test.go") {
+		t.Fatalf("expected the caller as the source location, got: %v", entry["sourceLocation"])
+	}
+	payload, _ := entry["jsonPayload"].(map[string]interface{})
+	if payload["message"] != "traced" || payload["service"] != "billing" {
+		t.Fatalf("expected the message and fields in the payload, got: %v", payload)
+	}
+	if _, ok := payload["trace_id"]; ok {
+		t.Fatalf("expected the trace fields to be moved out of the payload, got: %v", payload)
+	}
+}
+
+func TestCloudLoggingSeverity(t *testing.T) {
+	for level, want := range map[zapcore.Level]string{
+		zapcore.Level(-2):   "DEBUG",
+		zapcore.DebugLevel:  "DEBUG",
+		zapcore.InfoLevel:   "INFO",
+		zapcore.WarnLevel:   "WARNING",
+		zapcore.ErrorLevel:  "ERROR",
+		zapcore.DPanicLevel: "CRITICAL",
+		zapcore.PanicLevel:  "ALERT",
+		zapcore.FatalLevel:  "EMERGENCY",
+	} {
+		if got := cloudLoggingSeverity(level); got != want {
+			t.Fatalf("expected %v to be %v, got: %v", level, want, got)
+		}
+	}
+}
+
+func TestCloudLoggingCoreWith(t *testing.T) {
+	c := &cloudLoggingCore{LevelEnabler: zapcore.InfoLevel, project: "p", keys: zap.NewProductionEncoderConfig()}
+	with := c.With([]zapcore.Field{zap.String("trace_id", "t1")}).(*cloudLoggingCore)
+	entry := with.entry(zapcore.Entry{Message: "m", LoggerName: "api"}, []zapcore.Field{zap.Int("n", 1)})
+	payload := entry["jsonPayload"].(map[string]interface{})
+	if entry["trace"] != "projects/p/traces/t1" || payload["n"] != int64(1) || payload["logger"] != "api" {
+		t.Fatalf("expected fields from With and the entry, got: %v", entry)
+	}
+	if len(c.fields) != 0 {
+		t.Fatalf("expected With not to change the core, got: %v", c.fields)
+	}
+}
+
+func TestWithSinkInvalid(t *testing.T) {
+	_, err := logr.New(WithSink("", WithEndpoint(""), WithResource("", nil), WithBatch(0, 1, 0, -1)))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"cloudloggingsink.WithSink", "log ID must not be empty", "endpoint must not be empty", "resource type must not be empty", "batch entries, bytes, and flush interval must be > 0", "retries must be >= 0"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, err)
+		}
+	}
+}
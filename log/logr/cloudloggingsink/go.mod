@@ -0,0 +1,30 @@
+module github.com/packethost/pkg/log/logr/cloudloggingsink
+
+go 1.21
+
+require (
+	cloud.google.com/go/compute/metadata v0.2.3
+	github.com/packethost/pkg/log/logr v0.1.0
+	github.com/pkg/errors v0.9.1
+	go.uber.org/multierr v1.6.0
+	go.uber.org/zap v1.16.0
+	golang.org/x/oauth2 v0.10.0
+)
+
+require (
+	cloud.google.com/go/compute v1.20.1 // indirect
+	github.com/go-logr/logr v0.2.1 // indirect
+	github.com/go-logr/zapr v0.2.0 // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/rollbar/rollbar-go v1.2.0 // indirect
+	go.uber.org/atomic v1.9.0 // indirect
+	golang.org/x/mod v0.8.0 // indirect
+	golang.org/x/net v0.19.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	google.golang.org/appengine v1.6.7 // indirect
+	google.golang.org/protobuf v1.31.0 // indirect
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/packethost/pkg/log/logr => ../
@@ -0,0 +1,76 @@
+package logr
+
+import (
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// minLevel enables every level, including all of logr's V levels
+const minLevel = zapcore.Level(math.MinInt8)
+
+// componentLevelCore filters entries using the level configured for the entry's logger name,
+// falling back to the global level for components without an override
+type componentLevelCore struct {
+	zapcore.Core
+	global    zapcore.LevelEnabler
+	overrides map[string]zapcore.Level
+	// lowest is the most verbose of the overrides, used for the name-less Enabled check
+	lowest zapcore.Level
+}
+
+// componentLevels builds a zap.Option that filters entries by their logger name
+func componentLevels(global zapcore.LevelEnabler, levels map[string]string) (zap.Option, error) {
+	overrides := make(map[string]zapcore.Level, len(levels))
+	lowest := zapcore.Level(math.MaxInt8)
+	for name, level := range levels {
+		zLevel, err := parseLevel(level)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "component %q", name)
+		}
+		overrides[name] = zLevel
+		if zLevel < lowest {
+			lowest = zLevel
+		}
+	}
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &componentLevelCore{Core: core, global: global, overrides: overrides, lowest: lowest}
+	}), nil
+}
+
+// Enabled can't know which component is logging so it reports whether any component would log at lvl.
+// The per component decision is made in Check.
+func (c *componentLevelCore) Enabled(lvl zapcore.Level) bool {
+	return c.global.Enabled(lvl) || lvl >= c.lowest
+}
+
+func (c *componentLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	return &clone
+}
+
+func (c *componentLevelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.enabledFor(ent.LoggerName, ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// enabledFor walks up the dotted logger name looking for an override
+func (c *componentLevelCore) enabledFor(name string, lvl zapcore.Level) bool {
+	for name != "" {
+		if level, ok := c.overrides[name]; ok {
+			return lvl >= level
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return c.global.Enabled(lvl)
+}
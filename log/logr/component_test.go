@@ -0,0 +1,67 @@
+package logr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPacketLogrComponentLevels(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		l, _, err := NewPacketLogr(
+			WithComponentLevels(map[string]string{
+				"db":   "debug",
+				"http": "error",
+			}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.V(1).Info("root debug message")
+		l.Info("root info message")
+		l.WithName("db").V(1).Info("db debug message")
+		l.WithName("db").WithName("pool").V(1).Info("db pool debug message")
+		l.WithName("http").Info("http info message")
+		l.WithName("grpc").Info("grpc info message")
+	})
+
+	for _, want := range []string{"root info message", "db debug message", "db pool debug message", "grpc info message"} {
+		if !strings.Contains(capturedOutput, want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+		}
+	}
+	for _, unwanted := range []string{"root debug message", "http info message"} {
+		if strings.Contains(capturedOutput, unwanted) {
+			t.Fatalf("expected to not contain: %v, got: %v", unwanted, capturedOutput)
+		}
+	}
+}
+
+func TestPacketLogrComponentLevelsFollowGlobal(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		l, _, err := NewPacketLogr(
+			WithEnableErrLogsToStderr(true),
+			WithComponentLevels(map[string]string{"db": "warn"}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := l.(*PacketLogr).SetLevel("debug"); err != nil {
+			t.Fatal(err)
+		}
+		l.V(1).Info("root debug message")
+		l.WithName("db").Info("db info message")
+	})
+	if !strings.Contains(capturedOutput, "root debug message") {
+		t.Fatalf("expected to contain: %v, got: %v", "root debug message", capturedOutput)
+	}
+	if strings.Contains(capturedOutput, "db info message") {
+		t.Fatalf("expected to not contain: %v, got: %v", "db info message", capturedOutput)
+	}
+}
+
+func TestPacketLogrComponentLevelsInvalid(t *testing.T) {
+	_, _, err := NewPacketLogr(WithComponentLevels(map[string]string{"db": "noisy"}))
+	if err == nil {
+		t.Fatal("expected an error for an unknown component level")
+	}
+}
@@ -0,0 +1,182 @@
+package logr
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// The compression algorithms for Compression, zstd is only available once github.com/packethost/pkg/log/logr/zstd is imported
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// Compression is how a sink that batches entries compresses them, see WithSinkCompression
+type Compression struct {
+	// Algorithm is CompressionGzip, or one registered with RegisterCompression such as CompressionZstd
+	Algorithm string
+	// Level is the algorithm's compression level, 0 for its default.
+	// gzip levels go from 1, the fastest, to 9, the smallest.
+	Level int
+}
+
+// Compressor is a compression algorithm registered with RegisterCompression
+type Compressor struct {
+	// NewWriter returns a writer compressing to w at level, which is 0 for the algorithm's default
+	NewWriter func(w io.Writer, level int) (CompressWriter, error)
+	// MaxLevel is the highest level, levels go from 1 to MaxLevel
+	MaxLevel int
+	// Ext is the file extension of files compressed with the algorithm, such as .zst
+	Ext string
+	// ContentType is the media type of files compressed with the algorithm, such as application/zstd
+	ContentType string
+}
+
+// CompressWriter is a writer that compresses what is written to it
+type CompressWriter interface {
+	io.WriteCloser
+	// Flush writes what is compressed so far to the underlying writer
+	Flush() error
+}
+
+// compressors are the algorithms registered with RegisterCompression, gzip is built in
+var compressors struct {
+	sync.RWMutex
+	m map[string]Compressor
+}
+
+// RegisterCompression makes algorithm available to Compression, such as in an init func of the package implementing it,
+// so that the dependencies of the algorithms that aren't used aren't built in. An algorithm can only be registered once per process.
+func RegisterCompression(algorithm string, c Compressor) error {
+	if algorithm == "" || algorithm == CompressionGzip {
+		return errors.Errorf("RegisterCompression: invalid algorithm: %q", algorithm)
+	}
+	if c.NewWriter == nil || c.MaxLevel < 1 || c.Ext == "" || c.ContentType == "" {
+		return errors.Errorf("RegisterCompression: %s: new writer, max level, ext, and content type must be set", algorithm)
+	}
+	compressors.Lock()
+	defer compressors.Unlock()
+	if _, ok := compressors.m[algorithm]; ok {
+		return errors.Errorf("RegisterCompression: %s is already registered", algorithm)
+	}
+	if compressors.m == nil {
+		compressors.m = map[string]Compressor{}
+	}
+	compressors.m[algorithm] = c
+	return nil
+}
+
+// WithSinkCompression compresses the request bodies of the sinks that use HTTP, setting the Content-Encoding header.
+// Elasticsearch and Cloud Logging only accept gzip.
+func WithSinkCompression(compression Compression) NetworkOption {
+	return func(c *netSinkConfig) { c.compression = compression }
+}
+
+func (c Compression) enabled() bool { return c.Algorithm != "" }
+
+// compressor is the registered algorithm of c, ok is false for gzip
+func (c Compression) compressor() (compressor Compressor, ok bool) {
+	compressors.RLock()
+	defer compressors.RUnlock()
+	compressor, ok = compressors.m[c.Algorithm]
+	return compressor, ok
+}
+
+// Validate checks that the algorithm is gzip or registered, and that the level is one of its levels
+func (c Compression) Validate() error {
+	if c.Algorithm == CompressionGzip {
+		if c.Level < 0 || c.Level > gzip.BestCompression {
+			return errors.Errorf("gzip compression level must be between 1 and 9, got: %d", c.Level)
+		}
+		return nil
+	}
+	compressor, ok := c.compressor()
+	switch {
+	case !ok && c.Algorithm == CompressionZstd:
+		return errors.New("zstd compression isn't registered, import github.com/packethost/pkg/log/logr/zstd")
+	case !ok:
+		return errors.Errorf("unknown compression algorithm: %q", c.Algorithm)
+	case c.Level < 0 || c.Level > compressor.MaxLevel:
+		return errors.Errorf("%s compression level must be between 1 and %d, got: %d", c.Algorithm, compressor.MaxLevel, c.Level)
+	}
+	return nil
+}
+
+// NewWriter returns a writer compressing to w with c, which must be valid
+func (c Compression) NewWriter(w io.Writer) (CompressWriter, error) {
+	if compressor, ok := c.compressor(); ok {
+		return compressor.NewWriter(w, c.Level)
+	}
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return gzip.NewWriterLevel(w, level)
+}
+
+// compress returns b compressed
+func (c Compression) compress(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := c.NewWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Ext is the file extension of files compressed with c
+func (c Compression) Ext() string {
+	if compressor, ok := c.compressor(); ok {
+		return compressor.Ext
+	}
+	return ".gz"
+}
+
+// ContentType is the media type of files compressed with c
+func (c Compression) ContentType() string {
+	if compressor, ok := c.compressor(); ok {
+		return compressor.ContentType
+	}
+	return "application/gzip"
+}
+
+// validateCompression checks the compression set with WithSinkCompression
+func (c netSinkConfig) validateCompression() error {
+	if !c.compression.enabled() {
+		return nil
+	}
+	if !c.http {
+		return errors.New("compression is only supported by sinks that use HTTP")
+	}
+	return c.compression.Validate()
+}
+
+// newRequest creates a request with body compressed as set with WithSinkCompression
+func (c netSinkConfig) newRequest(ctx context.Context, method, url string, body []byte) (*http.Request, error) {
+	if c.compression.enabled() {
+		var err error
+		if body, err = c.compression.compress(body); err != nil {
+			return nil, errors.Wrap(err, "failed to compress request body")
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if c.compression.enabled() {
+		req.Header.Set("Content-Encoding", c.compression.Algorithm)
+	}
+	return req, nil
+}
@@ -0,0 +1,47 @@
+package logr
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	in := []byte(strings.Repeat(`{"level":"info","msg":"compress me"}`+"\n", 100))
+	for _, c := range []Compression{{Algorithm: CompressionGzip}, {Algorithm: CompressionGzip, Level: 1}, {Algorithm: CompressionGzip, Level: 9}} {
+		if err := c.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		b, err := c.compress(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) >= len(in) {
+			t.Fatalf("expected %+v to compress, got: %d bytes from %d", c, len(b), len(in))
+		}
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := io.ReadAll(r)
+		if err != nil || !bytes.Equal(out, in) {
+			t.Fatalf("expected %+v to round trip, got: %d bytes, %v", c, len(out), err)
+		}
+	}
+
+	for c, want := range map[Compression]string{
+		{Algorithm: "brotli"}:                   `unknown compression algorithm: "brotli"`,
+		{Algorithm: CompressionGzip, Level: 10}: "gzip compression level must be between 1 and 9",
+		{Algorithm: CompressionZstd}:            "zstd compression isn't registered",
+	} {
+		if err := c.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected: %v, got: %v", want, err)
+		}
+	}
+	if err := newNetSinkConfig([]NetworkOption{WithSinkCompression(Compression{Algorithm: CompressionGzip})}).validate(); err == nil ||
+		!strings.Contains(err.Error(), "compression is only supported by sinks that use HTTP") {
+		t.Fatalf("expected compression to need HTTP, got: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package logr
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
+)
+
+// loggerContextKey is the context key of the logger added with NewContext
+type loggerContextKey struct{}
+
+// fallback is the logger FromContext uses when ctx has none, see SetAsFallback
+var fallback atomic.Pointer[PacketLogr]
+
+// discard is what FromContext returns when ctx has no logger and there is no fallback
+var discard = zapr.NewLogger(zap.NewNop())
+
+// WithContextFields adds the keys and values fields returns for a context to the loggers FromContext returns,
+// such as a request ID or a trace ID set on the context by a middleware after the logger was added to it
+func WithContextFields(fields func(ctx context.Context) []interface{}) LoggerOption {
+	return func(args *PacketLogr) { args.contextFields = append(args.contextFields, fields) }
+}
+
+// NewContext returns a copy of ctx holding l, such as a logger with the fields of a request,
+// so that the functions it is passed to can log with FromContext without taking a logger argument
+func NewContext(ctx context.Context, l logr.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, l)
+}
+
+// NewContextWithValues returns a copy of ctx whose logger, from FromContext, also has keysAndValues, such as the fields of a request.
+// Unlike adding what FromContext returns with NewContext, the fields of WithContextFields aren't added twice.
+func (p *PacketLogr) NewContextWithValues(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	return NewContext(ctx, p.contextLogger(ctx).WithValues(keysAndValues...))
+}
+
+// FromContext returns the logger added to ctx with NewContext, or else the fallback set with SetAsFallback,
+// with the fields of the fallback's WithContextFields. It never returns nil, without a fallback the logger discards everything.
+func FromContext(ctx context.Context) logr.Logger {
+	return fallback.Load().FromContext(ctx)
+}
+
+// FromContext returns the logger added to ctx with NewContext, or else p, with the fields of WithContextFields,
+// such as the trace_id and span_id of the OpenTelemetry span ctx carries with otellog.WithTraceFields
+func (p *PacketLogr) FromContext(ctx context.Context) logr.Logger {
+	l := p.contextLogger(ctx)
+	var kvs []interface{}
+	if p != nil {
+		for _, fields := range p.contextFields {
+			kvs = append(kvs, fields(ctx)...)
+		}
+	}
+	if len(kvs) == 0 {
+		return l
+	}
+	return l.WithValues(kvs...)
+}
+
+// contextLogger is the logger of ctx, or else p, without the fields of WithContextFields so it can be added to a context again
+func (p *PacketLogr) contextLogger(ctx context.Context) logr.Logger {
+	if l, ok := ctx.Value(loggerContextKey{}).(logr.Logger); ok {
+		return l
+	}
+	if p != nil {
+		return p.Logger
+	}
+	return discard
+}
+
+// SetAsFallback makes p the logger FromContext returns for contexts without one, and whose WithContextFields it uses.
+// The returned func restores the previous fallback.
+func (p *PacketLogr) SetAsFallback() func() {
+	prev := fallback.Swap(p)
+	return func() { fallback.Store(prev) }
+}
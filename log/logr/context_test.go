@@ -0,0 +1,49 @@
+package logr
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type requestIDKey struct{}
+
+func TestFromContext(t *testing.T) {
+	requestID := func(ctx context.Context) []interface{} {
+		if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+			return []interface{}{"request_id", id}
+		}
+		return nil
+	}
+	capturedOutput := captureOutput(func() {
+		pl, err := New(WithServiceName("ctx"), WithContextFields(requestID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := NewContext(context.Background(), pl.WithValues("device", "d1"))
+		ctx = context.WithValue(ctx, requestIDKey{}, "r1")
+		pl.FromContext(ctx).Info("from the context")
+		pl.FromContext(context.Background()).Info("without a logger")
+		pl.FromContext(pl.NewContextWithValues(ctx, "port", "p1")).Info("with values")
+
+		FromContext(ctx).Info("without a fallback")
+		restore := pl.SetAsFallback()
+		FromContext(context.Background()).Info("from the fallback")
+		restore()
+		FromContext(context.Background()).Info("after restore")
+	})
+	for _, want := range []string{
+		`"msg":"from the context","service":"ctx","device":"d1","request_id":"r1"`,
+		`"msg":"without a logger","service":"ctx"}`,
+		`"msg":"with values","service":"ctx","device":"d1","port":"p1","request_id":"r1"}`,
+		`"msg":"without a fallback","service":"ctx","device":"d1"}`,
+		`"msg":"from the fallback","service":"ctx"}`,
+	} {
+		if !strings.Contains(capturedOutput, want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+		}
+	}
+	if strings.Contains(capturedOutput, "after restore") {
+		t.Fatalf("expected the fallback to be restored, got: %v", capturedOutput)
+	}
+}
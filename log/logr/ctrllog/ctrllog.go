@@ -0,0 +1,28 @@
+// Package ctrllog sets up the logging of Kubernetes operators built with controller-runtime with a logr.PacketLogr,
+// with one call before the manager is made:
+//
+//	log := ctrllog.SetLogger(l, "device-operator")
+//	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{})
+package ctrllog
+
+import (
+	"github.com/go-logr/logr"
+	packetlogr "github.com/packethost/pkg/log/logr"
+	crlog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// SetLogger sets the logger of NewLogger as the logger of controller-runtime, ctrl.SetLogger, so the entries of the manager,
+// controllers, and webhooks are logged by p, and returns it for the operator's own logs
+func SetLogger(p *packetlogr.PacketLogr, name string) logr.Logger {
+	l := NewLogger(p, name)
+	crlog.SetLogger(l)
+	return l
+}
+
+// NewLogger returns the logger of p for an operator named name, following controller-runtime's conventions: the names of
+// its loggers, such as controller-runtime.manager or controller.device, are joined to name with dots, and V(1) is debug.
+// Operators and controller-runtime log up to V(5) or so, which are logged at the levels of the same V, so WithMaxV(5)
+// shows all of them.
+func NewLogger(p *packetlogr.PacketLogr, name string) logr.Logger {
+	return p.Named(name)
+}
@@ -0,0 +1,47 @@
+package ctrllog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/packethost/pkg/log/logr"
+	crlog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestSetLogger(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.log")
+	l, err := logr.New(logr.WithOutputPaths([]string{out}), logr.WithMaxV(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := SetLogger(l, "device-operator")
+	log.Info("starting")
+	controller := crlog.Log.WithName("controller").WithName("device").WithValues("namespace", "default")
+	controller.V(1).Info("reconciling")
+	controller.V(1).V(4).Info("requeueing")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+	for _, want := range []string{
+		`"level":"info","ts":`,
+		`"logger":"device-operator","caller":"ctrllog/ctrllog_test.go:`,
+		`"msg":"starting","service":"not/set","component":"device-operator"`,
+		`"level":"debug"`,
+		`"logger":"device-operator.controller.device"`,
+		`"msg":"reconciling","service":"not/set","component":"device-operator","namespace":"default"`,
+		`"level":"v5"`,
+		`"msg":"requeueing"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %s to be logged, got: %v", want, got)
+		}
+	}
+}
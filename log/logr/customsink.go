@@ -0,0 +1,34 @@
+package logr
+
+import (
+	"net/url"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// RegisterSink lets output paths with scheme, such as "mysink://host:port", be opened by factory,
+// so they can be given to WithOutputPaths, WithErrorOutputPaths, or LOG_OUTPUT_PATHS like any other path.
+// It should be called once, from an init func or main before New, a scheme can only be registered once per process.
+//
+// The factory is given the output path parsed as a URL and is called every time a logger is built with it. By convention:
+//   - the address to connect to is the host, u.Host, and anything the sink needs to locate its destination, such as a topic or table, is the path
+//   - credentials are the user info, u.User, which is left out when the URL is included in an error
+//   - settings are query parameters, such as mysink://host:port/topic?timeout=5s&tls=true, using time.ParseDuration and strconv.ParseBool formats
+//   - unknown query parameters are an error, so that typos don't go unnoticed
+//
+// Sync is called on the sink when the logger is synced or closed, but it is not closed, so it shouldn't hold anything that
+// needs more than a Sync to be released.
+func RegisterSink(scheme string, factory func(*url.URL) (zap.Sink, error)) error {
+	if factory == nil {
+		return errors.Errorf("RegisterSink: factory for scheme %q must not be nil", scheme)
+	}
+	err := zap.RegisterSink(scheme, func(u *url.URL) (zap.Sink, error) {
+		s, err := factory(u)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open sink %s", u.Redacted())
+		}
+		return s, nil
+	})
+	return errors.WithMessage(err, "RegisterSink")
+}
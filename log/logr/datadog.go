@@ -0,0 +1,226 @@
+package logr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultDatadogSite         = "datadoghq.com"
+	defaultDatadogFlushTimeout = 5 * time.Second
+)
+
+// The fields that correlate an entry with its trace, Datadog's own or the hex OpenTelemetry IDs used by Cloud Logging,
+// which are converted to the 64 bit decimal IDs Datadog uses
+const (
+	datadogTraceKey = "dd.trace_id"
+	datadogSpanKey  = "dd.span_id"
+	otelTraceKey    = "trace_id"
+	otelSpanKey     = "span_id"
+)
+
+// DatadogConfig is where and how error logs are forwarded to Datadog when WithEnableDatadog is set, see WithDatadogConfig.
+// Empty fields fall back to Datadog's own environment variables.
+type DatadogConfig struct {
+	// APIKey is the organization's API key, DD_API_KEY when empty
+	APIKey string
+	// Site is the Datadog site, such as datadoghq.eu, DD_SITE and then datadoghq.com when empty
+	Site string
+	// Env is the env tag, DD_ENV when empty
+	Env string
+	// Version is the version tag, DD_VERSION when empty
+	Version string
+	// Tags are more tags, as key:value
+	Tags []string
+	// Endpoint is the logs intake URL, the one of Site when empty
+	Endpoint string
+	// FlushTimeout is how long Close waits for pending entries to be sent, 5s when 0
+	FlushTimeout time.Duration
+}
+
+// NewDatadogConfig returns the config for forwarding to Datadog with apiKey, tagged with env and version
+func NewDatadogConfig(apiKey, env, version string) DatadogConfig {
+	return DatadogConfig{APIKey: apiKey, Env: env, Version: version}
+}
+
+// WithEnableDatadog forwards error logs to the Datadog logs intake so they show up in Error Tracking,
+// along with or instead of the other error reporters. The error's type, message, and stack are sent as the error attributes
+// Error Tracking groups on, and the dd.trace_id and dd.span_id fields, or trace_id and span_id, correlate it with its trace.
+// Entries are sent from the background, Close waits for them to be sent.
+func WithEnableDatadog(enable bool) LoggerOption {
+	return func(args *PacketLogr) { args.enableDatadog = enable }
+}
+
+// WithDatadogConfig customizes the Datadog details, see NewDatadogConfig
+func WithDatadogConfig(config DatadogConfig) LoggerOption {
+	return func(args *PacketLogr) { args.datadogConfig = config }
+}
+
+// withEnv fills in the empty fields from the environment
+func (c DatadogConfig) withEnv() DatadogConfig {
+	for _, f := range []struct {
+		value *string
+		env   string
+	}{{&c.APIKey, "DD_API_KEY"}, {&c.Site, "DD_SITE"}, {&c.Env, "DD_ENV"}, {&c.Version, "DD_VERSION"}} {
+		if *f.value == "" {
+			*f.value = getEnv(f.env)
+		}
+	}
+	if c.Site == "" {
+		c.Site = defaultDatadogSite
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = "https://http-intake.logs." + c.Site + "/api/v2/logs"
+	}
+	return c
+}
+
+func (c DatadogConfig) validate() error {
+	var err error
+	if c.withEnv().APIKey == "" {
+		err = multierr.Append(err, errors.New("datadog is enabled but no API key is set, set the config's APIKey or DD_API_KEY"))
+	}
+	if c.FlushTimeout < 0 {
+		err = multierr.Append(err, errors.Errorf("datadog flush timeout must be >= 0, got: %s", c.FlushTimeout))
+	}
+	return err
+}
+
+// datadogReporter builds the Datadog logs for the entries that are reported
+type datadogReporter struct {
+	config   DatadogConfig
+	service  string
+	tags     string
+	hostname string
+	*httpReporter
+}
+
+func (c DatadogConfig) setupDatadog(service string, spool *diskSpool) *datadogReporter {
+	c = c.withEnv()
+	tags := append([]string{}, c.Tags...)
+	if c.Env != "" {
+		tags = append(tags, "env:"+c.Env)
+	}
+	if c.Version != "" {
+		tags = append(tags, "version:"+c.Version)
+	}
+	r := &datadogReporter{config: c, service: service, tags: strings.Join(tags, ","), httpReporter: newHTTPReporter("datadog", spool)}
+	r.hostname, _ = os.Hostname()
+	return r
+}
+
+// Report queues the Datadog log for the entry to be sent
+func (r *datadogReporter) Report(ent zapcore.Entry, fields []zapcore.Field) error {
+	entry, err := reportFields(fields)
+	entry["message"] = ent.Message
+	entry["status"] = datadogStatus(ent.Level)
+	entry["service"] = r.service
+	entry["hostname"] = r.hostname
+	entry["ddsource"] = "go"
+	entry["ddtags"] = r.tags
+	entry["date"] = ent.Time.UnixMilli()
+	if ent.LoggerName != "" {
+		entry["logger.name"] = ent.LoggerName
+	}
+	if ent.Caller.Defined {
+		entry["logger.caller"] = ent.Caller.TrimmedPath()
+	}
+	if err != nil {
+		// pkg/errors' verbose message has the stack of where the error was made
+		stack, _ := entry["errorVerbose"].(string)
+		delete(entry, "error")
+		delete(entry, "errorVerbose")
+		if stack == "" {
+			var b strings.Builder
+			for _, frame := range errorStack(err) {
+				fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+			}
+			stack = b.String()
+		}
+		if stack == "" {
+			stack = ent.Stack
+		}
+		if stack == "" && ent.Caller.Defined {
+			stack = ent.Caller.Function + "\n\t" + ent.Caller.FullPath()
+		}
+		entry["error.kind"] = fmt.Sprintf("%T", errors.Cause(err))
+		entry["error.message"] = err.Error()
+		entry["error.stack"] = stack
+	}
+	correlateDatadogTrace(entry)
+
+	body, jerr := json.Marshal([]interface{}{entry})
+	if jerr != nil {
+		return errors.Wrap(jerr, "failed to encode datadog log")
+	}
+	req, rerr := http.NewRequest(http.MethodPost, r.config.Endpoint, bytes.NewReader(body))
+	if rerr != nil {
+		return errors.Wrap(rerr, "failed to create datadog request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", r.config.APIKey)
+	r.send(req)
+	return nil
+}
+
+// correlateDatadogTrace sets dd.trace_id and dd.span_id from trace_id and span_id when they aren't set
+func correlateDatadogTrace(entry map[string]interface{}) {
+	for _, keys := range [][2]string{{datadogTraceKey, otelTraceKey}, {datadogSpanKey, otelSpanKey}} {
+		if _, ok := entry[keys[0]]; ok {
+			continue
+		}
+		if id, ok := entry[keys[1]].(string); ok {
+			if dd, ok := datadogID(id); ok {
+				entry[keys[0]] = dd
+			}
+		}
+	}
+}
+
+// datadogID converts a hex OpenTelemetry trace or span ID to the decimal Datadog ID, its low 64 bits
+func datadogID(id string) (string, bool) {
+	if len(id) > 16 {
+		id = id[len(id)-16:]
+	}
+	n, err := strconv.ParseUint(id, 16, 64)
+	if err != nil || n == 0 {
+		return "", false
+	}
+	return strconv.FormatUint(n, 10), true
+}
+
+func datadogStatus(lvl zapcore.Level) string {
+	switch {
+	case lvl < zapcore.InfoLevel:
+		return "debug"
+	case lvl == zapcore.InfoLevel:
+		return "info"
+	case lvl == zapcore.WarnLevel:
+		return "warn"
+	case lvl == zapcore.ErrorLevel:
+		return "error"
+	default:
+		return "critical"
+	}
+}
+
+// Flush waits for the pending entries to be sent, giving up after the flush timeout or when ctx is done
+func (r *datadogReporter) Flush(ctx context.Context) error {
+	ctx, cancel := reporterFlushContext(ctx, r.config.FlushTimeout, defaultDatadogFlushTimeout)
+	defer cancel()
+	return r.httpReporter.flush(ctx)
+}
+
+// Close sends the pending entries and stops sending more
+func (r *datadogReporter) Close() error { return r.close(context.Background()) }
@@ -0,0 +1,80 @@
+package logr
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestPacketLogrDatadog(t *testing.T) {
+	url, requests, bodies := reporterServer(t, http.StatusAccepted)
+	config := NewDatadogConfig("apikey", "staging", "v1.2.3")
+	config.Endpoint, config.Tags = url, []string{"team:metal"}
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithServiceName("github.com/packethost/pkg"),
+		WithEnableDatadog(true), WithDatadogConfig(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.WithValues("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736", "span_id", "00f067aa0ba902b7").Error(errors.New("boom"), "provisioning failed")
+	l.WithValues("dd.trace_id", "42").Error(nil, "already correlated")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-requests
+	if req.Header.Get("DD-API-KEY") != "apikey" {
+		t.Fatalf("expected the API key, got: %v", req.Header)
+	}
+	var logs []map[string]interface{}
+	if err := json.Unmarshal(<-bodies, &logs); err != nil {
+		t.Fatal(err)
+	}
+	entry := logs[0]
+	for k, want := range map[string]interface{}{
+		"message":       "provisioning failed",
+		"status":        "error",
+		"service":       "github.com/packethost/pkg",
+		"ddtags":        "team:metal,env:staging,version:v1.2.3",
+		"error.kind":    "*errors.fundamental",
+		"error.message": "boom",
+		"dd.trace_id":   "11803532876627986230",
+		"dd.span_id":    "67667974448284343",
+	} {
+		if entry[k] != want {
+			t.Fatalf("expected %v: %v, got: %v", k, want, entry)
+		}
+	}
+	if stack, _ := entry["error.stack"].(string); !strings.Contains(stack, "TestPacketLogrDatadog") || entry["error"] != nil {
+		t.Fatalf("expected the stack of the error, got: %v", entry)
+	}
+
+	<-requests
+	var correlated []map[string]interface{}
+	if err := json.Unmarshal(<-bodies, &correlated); err != nil {
+		t.Fatal(err)
+	}
+	if correlated[0]["dd.trace_id"] != "42" || correlated[0]["error.kind"] != nil {
+		t.Fatalf("expected the datadog trace ID to be kept, got: %v", correlated[0])
+	}
+}
+
+func TestDatadogConfigEnv(t *testing.T) {
+	setenv(t, "DD_API_KEY", "envkey")
+	setenv(t, "DD_SITE", "datadoghq.eu")
+	setenv(t, "DD_ENV", "production")
+	c := (DatadogConfig{Env: "staging"}).withEnv()
+	if c.APIKey != "envkey" || c.Env != "staging" || c.Endpoint != "https://http-intake.logs.datadoghq.eu/api/v2/logs" {
+		t.Fatalf("expected the environment to fill in the empty fields, got: %+v", c)
+	}
+
+	setenv(t, "DD_API_KEY", "")
+	if err := (DatadogConfig{FlushTimeout: -1}).validate(); err == nil || !strings.Contains(err.Error(), "datadog is enabled but no API key is set") ||
+		!strings.Contains(err.Error(), "datadog flush timeout must be >= 0") {
+		t.Fatalf("expected the config errors, got: %v", err)
+	}
+}
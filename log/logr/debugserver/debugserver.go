@@ -0,0 +1,196 @@
+// Package debugserver serves what is needed to debug a running service, on localhost:6060 by default:
+//
+//	/debug/pprof/     the net/http/pprof profiles
+//	/debug/vars       the expvar variables
+//	/debug/loglevel   the logger's level, see logr.PacketLogr.LevelHandler
+//	/debug/buildinfo  the build info of the binary, as JSON
+//
+// It is started with Run and stopped when the context passed to it is done:
+//
+//	s, err := debugserver.New(logger, debugserver.WithBearerToken(os.Getenv("DEBUG_TOKEN")))
+//	go s.Run(ctx)
+package debugserver
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime/debug"
+	"time"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultAddr is where the server listens unless WithAddr is used
+	defaultAddr = "localhost:6060"
+	// shutdownTimeout is how long Run waits for the requests being served once ctx is done, profiles can take a while
+	shutdownTimeout = 5 * time.Second
+)
+
+// Option customizes New
+type Option func(*config)
+
+type config struct {
+	addr     string
+	auth     func(*http.Request) error
+	handlers map[string]http.Handler
+	// noToken is whether the auth is the one of a WithBearerToken with an empty token
+	noToken bool
+}
+
+// WithAddr listens on addr instead of localhost:6060, addresses other than the loopback ones require WithAuth or WithBearerToken
+func WithAddr(addr string) Option {
+	return func(c *config) { c.addr = addr }
+}
+
+// WithAuth sets a func that must return nil for a request to be served, the error is sent back with a 401
+func WithAuth(auth func(*http.Request) error) Option {
+	return func(c *config) { c.auth, c.noToken = auth, false }
+}
+
+// WithBearerToken only serves the requests that send the token in an "Authorization: Bearer" header, see logr.BearerTokenAuth.
+// An empty token, such as the one of an unset environment variable, serves no request and doesn't allow other addresses than the loopback ones.
+func WithBearerToken(token string) Option {
+	return func(c *config) { c.auth, c.noToken = logr.BearerTokenAuth(token), token == "" }
+}
+
+// WithHandler also serves h at pattern, such as the metrics handler
+func WithHandler(pattern string, h http.Handler) Option {
+	return func(c *config) { c.handlers[pattern] = h }
+}
+
+// Server is the debug server, see New
+type Server struct {
+	p        *logr.PacketLogr
+	listener net.Listener
+	server   *http.Server
+}
+
+// New listens on the address of the debug server, it starts serving once Run is called
+func New(p *logr.PacketLogr, opts ...Option) (*Server, error) {
+	c := newConfig(opts)
+	if (c.auth == nil || c.noToken) && !loopback(c.addr) {
+		return nil, errors.Errorf("listening on %q requires WithAuth or WithBearerToken with a token, only localhost is allowed without", c.addr)
+	}
+	l, err := net.Listen("tcp", c.addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to listen")
+	}
+	return &Server{
+		p:        p,
+		listener: l,
+		server:   &http.Server{Handler: c.handler(p), ReadHeaderTimeout: 10 * time.Second},
+	}, nil
+}
+
+// Addr is the address the server listens on, such as the port picked for a WithAddr of localhost:0
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Run serves until ctx is done, and then waits for the requests being served to finish, giving up after 5s
+func (s *Server) Run(ctx context.Context) error {
+	l := s.p.Named("debugserver")
+	errs := make(chan error, 1)
+	go func() { errs <- s.server.Serve(s.listener) }()
+	l.Info("debug server listening", "addr", s.Addr().String())
+
+	select {
+	case err := <-errs:
+		return errors.Wrap(err, "debug server failed")
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(shutdownCtx); err != nil {
+		_ = s.server.Close()
+		return errors.Wrap(err, "failed to shut down the debug server")
+	}
+	l.Info("debug server stopped")
+	return nil
+}
+
+func newConfig(opts []Option) config {
+	c := config{addr: defaultAddr, handlers: map[string]http.Handler{}}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// Handler returns the handler of the debug server, for serving it alongside other handlers, WithAddr is ignored
+func Handler(p *logr.PacketLogr, opts ...Option) http.Handler {
+	return newConfig(opts).handler(p)
+}
+
+func (c config) handler(p *logr.PacketLogr) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle("/debug/loglevel", p.LevelHandler())
+	mux.HandleFunc("/debug/buildinfo", buildInfo)
+	for pattern, h := range c.handlers {
+		mux.Handle(pattern, h)
+	}
+	if c.auth == nil {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := c.auth(r); err != nil {
+			writeJSON(w, http.StatusUnauthorized, struct {
+				Error string `json:"error"`
+			}{err.Error()})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// buildInfo serves the build info of the binary, as recorded by the go toolchain
+func buildInfo(w http.ResponseWriter, _ *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, struct {
+			Error string `json:"error"`
+		}{"no build info"})
+		return
+	}
+	settings := make(map[string]string, len(info.Settings))
+	for _, s := range info.Settings {
+		settings[s.Key] = s.Value
+	}
+	writeJSON(w, http.StatusOK, struct {
+		GoVersion string            `json:"go_version"`
+		Path      string            `json:"path"`
+		Version   string            `json:"version"`
+		Settings  map[string]string `json:"settings"`
+	}{info.GoVersion, info.Main.Path, info.Main.Version, settings})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// loopback is whether addr is on a loopback address, only reachable from the host
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,78 @@
+package debugserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/packethost/pkg/log/logr"
+)
+
+func TestServer(t *testing.T) {
+	p, err := logr.New(logr.WithOutputPaths([]string{t.TempDir() + "/log"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(p, WithAddr("127.0.0.1:0"), WithBearerToken("secret"), WithHandler("/metrics", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "up 1")
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	get := func(path, token string) (int, string) {
+		req, _ := http.NewRequest("GET", "http://"+s.Addr().String()+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	for path, want := range map[string]string{
+		"/debug/pprof/":    "goroutine",
+		"/debug/vars":      `"memstats"`,
+		"/debug/loglevel":  `{"level":"info"}`,
+		"/debug/buildinfo": `"go_version":"go`,
+		"/metrics":         "up 1",
+	} {
+		if status, body := get(path, "secret"); status != http.StatusOK || !strings.Contains(body, want) {
+			t.Fatalf("expected %s to serve %s, got: %d %s", path, want, status, body)
+		}
+	}
+	if status, body := get("/debug/vars", "wrong"); status != http.StatusUnauthorized || !strings.Contains(body, "invalid bearer token") {
+		t.Fatalf("expected a 401 for a wrong token, got: %d %s", status, body)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("expected Run to stop without an error, got: %v", err)
+	}
+}
+
+func TestNewRequiresAuth(t *testing.T) {
+	p, err := logr.New(logr.WithOutputPaths([]string{t.TempDir() + "/log"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(p, WithAddr(":6060")); err == nil || !strings.Contains(err.Error(), "requires WithAuth") {
+		t.Fatalf("expected an error listening on every interface without auth, got: %v", err)
+	}
+	if _, err := New(p, WithAddr(":6060"), WithBearerToken("")); err == nil || !strings.Contains(err.Error(), "requires WithAuth") {
+		t.Fatalf("expected an error listening on every interface with an empty token, got: %v", err)
+	}
+	for _, addr := range []string{"localhost:0", "[::1]:0", "127.0.0.1:0"} {
+		if !loopback(addr) {
+			t.Fatalf("expected %s to be a loopback address", addr)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package logr
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxDedupeKeys bounds the number of distinct messages tracked before expired ones are swept
+const maxDedupeKeys = 10000
+
+// dedupeCore drops entries that repeat within a window and logs a summary of how many were dropped
+type dedupeCore struct {
+	zapcore.Core
+	state *dedupeState
+}
+
+type dedupeState struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu   sync.Mutex
+	seen map[dedupeKey]*dedupeEntry
+}
+
+type dedupeKey struct {
+	level zapcore.Level
+	name  string
+	msg   string
+}
+
+type dedupeEntry struct {
+	first      time.Time
+	suppressed int
+	// core and ent are the first occurrence, used to write the summary with the same fields
+	core zapcore.Core
+	ent  zapcore.Entry
+}
+
+// deduplicate drops the entries repeating within window, the summaries of the entries suppressed in the windows that have passed
+// are logged every window, until stopDedupeFlushes is called
+func (p *PacketLogr) deduplicate(window time.Duration) zap.Option {
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		c := newDedupeCore(core, window, time.Now)
+		stop := make(chan struct{})
+		var once sync.Once
+		p.stopDedupeFlushes = func() { once.Do(func() { close(stop) }) }
+		go func() {
+			ticker := time.NewTicker(window)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					c.state.flushExpired()
+				}
+			}
+		}()
+		return c
+	})
+}
+
+func newDedupeCore(core zapcore.Core, window time.Duration, now func() time.Time) *dedupeCore {
+	return &dedupeCore{
+		Core: core,
+		state: &dedupeState{
+			window: window,
+			now:    now,
+			seen:   map[dedupeKey]*dedupeEntry{},
+		},
+	}
+}
+
+func (c *dedupeCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dedupeCore{Core: c.Core.With(fields), state: c.state}
+}
+
+func (c *dedupeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	// entries that panic or exit are never dropped
+	if ent.Level >= zapcore.DPanicLevel || !c.Enabled(ent.Level) {
+		return c.Core.Check(ent, ce)
+	}
+
+	now := c.state.now()
+	key := dedupeKey{level: ent.Level, name: ent.LoggerName, msg: ent.Message}
+
+	s := c.state
+	s.mu.Lock()
+	prev, ok := s.seen[key]
+	if ok && now.Sub(prev.first) < s.window {
+		prev.suppressed++
+		s.mu.Unlock()
+		return ce
+	}
+	var summaries []dedupeEntry
+	if ok && prev.suppressed > 0 {
+		summaries = append(summaries, *prev)
+	}
+	s.seen[key] = &dedupeEntry{first: now, core: c.Core, ent: ent}
+	if len(s.seen) > maxDedupeKeys {
+		summaries = append(summaries, s.sweep(now)...)
+	}
+	s.mu.Unlock()
+
+	for _, summary := range summaries {
+		s.writeSummary(summary, now)
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// Sync logs the summaries of any suppressed entries before syncing the wrapped core
+func (c *dedupeCore) Sync() error {
+	s := c.state
+	s.mu.Lock()
+	var summaries []dedupeEntry
+	for _, prev := range s.seen {
+		if prev.suppressed > 0 {
+			summaries = append(summaries, *prev)
+			prev.suppressed = 0
+		}
+	}
+	s.mu.Unlock()
+
+	now := s.now()
+	for _, summary := range summaries {
+		s.writeSummary(summary, now)
+	}
+	return c.Core.Sync()
+}
+
+// flushExpired logs the summaries of the entries whose window has passed, so the ones that aren't logged again are summarized too
+func (s *dedupeState) flushExpired() {
+	now := s.now()
+	s.mu.Lock()
+	summaries := s.sweep(now)
+	s.mu.Unlock()
+	for _, summary := range summaries {
+		s.writeSummary(summary, now)
+	}
+}
+
+// sweep forgets about the entries whose window has passed and returns those that need a summary, s.mu must be held
+func (s *dedupeState) sweep(now time.Time) []dedupeEntry {
+	var summaries []dedupeEntry
+	for key, prev := range s.seen {
+		if now.Sub(prev.first) < s.window {
+			continue
+		}
+		if prev.suppressed > 0 {
+			summaries = append(summaries, *prev)
+		}
+		delete(s.seen, key)
+	}
+	return summaries
+}
+
+func (s *dedupeState) writeSummary(e dedupeEntry, now time.Time) {
+	ent := e.ent
+	ent.Time = now
+	ent.Message = fmt.Sprintf("%s (repeated %d times in %s)", e.ent.Message, e.suppressed, s.window)
+	if ce := e.core.Check(ent, nil); ce != nil {
+		ce.Write(zap.Int("repeated", e.suppressed))
+	}
+}
@@ -0,0 +1,104 @@
+package logr
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestDedupeCore(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	obs, logs := observer.New(zapcore.DebugLevel)
+	l := zap.New(newDedupeCore(obs, time.Minute, clock)).With(zap.String("service", "myservice"))
+
+	for i := 0; i < 10; i++ {
+		l.Error("tight loop")
+	}
+	l.Error("different message")
+	l.Named("other").Error("tight loop")
+	if got := logs.FilterMessage("tight loop").Len(); got != 2 {
+		t.Fatalf("expected 2 entries, got: %d", got)
+	}
+
+	now = now.Add(time.Minute)
+	l.Error("tight loop")
+	summaries := logs.FilterMessage("tight loop (repeated 9 times in 1m0s)").All()
+	if len(summaries) != 1 {
+		t.Fatalf("expected 1 summary, got: %v", logs.All())
+	}
+	if got := summaries[0].ContextMap(); got["repeated"] != int64(9) || got["service"] != "myservice" {
+		t.Fatalf("expected summary fields, got: %v", got)
+	}
+	if got := logs.FilterMessage("tight loop").Len(); got != 3 {
+		t.Fatalf("expected 3 entries, got: %d", got)
+	}
+}
+
+func TestDedupeCoreSync(t *testing.T) {
+	obs, logs := observer.New(zapcore.DebugLevel)
+	l := zap.New(newDedupeCore(obs, time.Hour, time.Now))
+
+	for i := 0; i < 5; i++ {
+		l.Warn("flood")
+	}
+	if err := l.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := logs.FilterMessage("flood (repeated 4 times in 1h0m0s)").Len(); got != 1 {
+		t.Fatalf("expected 1 summary, got: %v", logs.All())
+	}
+	if err := l.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := logs.Len(); got != 2 {
+		t.Fatalf("expected no more summaries, got: %v", logs.All())
+	}
+}
+
+func TestPacketLogrDeduplication(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		l, z, err := NewPacketLogr(WithDeduplication(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 50; i++ {
+			l.Info("repeated message")
+		}
+		_ = z.Sync()
+	})
+	if got := strings.Count(capturedOutput, "repeated message"); got != 2 {
+		t.Fatalf("expected the message and its summary, got: %v", capturedOutput)
+	}
+	if !strings.Contains(capturedOutput, `"repeated":49`) {
+		t.Fatalf("expected to contain: %v, got: %v", `"repeated":49`, capturedOutput)
+	}
+}
+
+func TestPacketLogrDeduplicationFlush(t *testing.T) {
+	obs, logs := observer.New(zapcore.InfoLevel)
+	pl, err := New(
+		WithOutputPaths([]string{t.TempDir() + "/log"}),
+		WithDeduplication(10*time.Millisecond),
+		WithCore(func(core zapcore.Core) zapcore.Core { return zapcore.NewTee(core, obs) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pl.Close(context.Background())
+	for i := 0; i < 5; i++ {
+		pl.Info("burst")
+	}
+	// the summary is logged without the message being logged again or the logger being synced
+	for deadline := time.Now().Add(time.Second); logs.FilterMessage("burst (repeated 4 times in 10ms)").Len() == 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the summary to be flushed, got: %v", logs.All())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,107 @@
+package logr
+
+import (
+	"runtime/debug"
+)
+
+// The environment variables the deploy environment, release, and code revision are detected from, in order, see WithDeployInfo
+var (
+	deployEnvVars      = []string{"ENV", "EQUINIX_ENV", "PACKET_ENV", "DEPLOY_ENV", "ENVIRONMENT", "APP_ENV"}
+	deployVersionVars  = []string{"VERSION", "EQUINIX_VERSION", "PACKET_VERSION", "APP_VERSION", "RELEASE"}
+	deployRevisionVars = []string{"GIT_COMMIT", "GIT_SHA", "COMMIT_SHA", "SOURCE_VERSION"}
+)
+
+// The Rollbar environment and version used when none are set or detected
+const (
+	defaultRollbarEnv     = "production"
+	defaultRollbarVersion = "1"
+)
+
+// DeployInfo is the deploy environment and release the occurrences sent to the error reporters are attributed to, see WithDeployInfo
+type DeployInfo struct {
+	// Env is the environment, such as production or staging
+	Env string
+	// Version is the release of the service, such as v1.2.3
+	Version string
+	// Revision is the version of the code, usually the git SHA
+	Revision string
+}
+
+// WithDeployInfo sets the deploy environment and release of the error reporters, for the fields of their configs that are empty,
+// such as the Rollbar code version or the Honeybadger revision. The fields of info that are empty are detected, from the first of these
+// environment variables that is set, and then from the build info recorded by the go toolchain for the version and revision:
+//
+//	Env      ENV, EQUINIX_ENV, PACKET_ENV, DEPLOY_ENV, ENVIRONMENT, or APP_ENV
+//	Version  VERSION, EQUINIX_VERSION, PACKET_VERSION, APP_VERSION, or RELEASE, then the main module's version
+//	Revision GIT_COMMIT, GIT_SHA, COMMIT_SHA, or SOURCE_VERSION, then vcs.revision
+//
+// They are detected without WithDeployInfo too.
+func WithDeployInfo(info DeployInfo) LoggerOption {
+	return func(args *PacketLogr) { args.deployInfo = info }
+}
+
+// detect fills in the empty fields from the environment and then from info, when it isn't nil
+func (d DeployInfo) detect(info *debug.BuildInfo) DeployInfo {
+	if d.Env == "" {
+		d.Env = getEnv(deployEnvVars...)
+	}
+	if d.Version == "" {
+		d.Version = getEnv(deployVersionVars...)
+	}
+	if d.Revision == "" {
+		d.Revision = getEnv(deployRevisionVars...)
+	}
+	if info == nil {
+		return d
+	}
+	// binaries built from a checkout, instead of with go install, have no version
+	if v := info.Main.Version; d.Version == "" && v != "(devel)" {
+		d.Version = v
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" && d.Revision == "" {
+			d.Revision = setting.Value
+		}
+	}
+	return d
+}
+
+// firstNonEmpty returns the first of values that isn't empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// withDeploy fills in the empty fields from d, and then from the defaults
+func (c RollbarConfig) withDeploy(d DeployInfo) RollbarConfig {
+	c.Env = firstNonEmpty(c.Env, d.Env, defaultRollbarEnv)
+	c.Version = firstNonEmpty(c.Version, d.Version, defaultRollbarVersion)
+	c.CodeVersion = firstNonEmpty(c.CodeVersion, d.Revision)
+	return c
+}
+
+// withDeploy fills in the empty fields from d
+func (c BugsnagConfig) withDeploy(d DeployInfo) BugsnagConfig {
+	c.ReleaseStage = firstNonEmpty(c.ReleaseStage, d.Env)
+	c.AppVersion = firstNonEmpty(c.AppVersion, d.Version, d.Revision)
+	return c
+}
+
+// withDeploy fills in the empty fields from d
+func (c HoneybadgerConfig) withDeploy(d DeployInfo) HoneybadgerConfig {
+	c.Env = firstNonEmpty(c.Env, d.Env)
+	c.Revision = firstNonEmpty(c.Revision, d.Revision, d.Version)
+	return c
+}
+
+// withDeploy fills in the empty fields from d, after the ones from Datadog's own environment variables
+func (c DatadogConfig) withDeploy(d DeployInfo) DatadogConfig {
+	c = c.withEnv()
+	c.Env = firstNonEmpty(c.Env, d.Env)
+	c.Version = firstNonEmpty(c.Version, d.Version, d.Revision)
+	return c
+}
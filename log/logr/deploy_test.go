@@ -0,0 +1,61 @@
+package logr
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestDeployInfoDetect(t *testing.T) {
+	for _, name := range append(append(append([]string{}, deployEnvVars...), deployVersionVars...), deployRevisionVars...) {
+		setenv(t, name, "")
+	}
+	info := &debug.BuildInfo{
+		Main:     debug.Module{Path: "github.com/packethost/pkg", Version: "v1.2.3"},
+		Settings: []debug.BuildSetting{{Key: "vcs.revision", Value: "abc123"}},
+	}
+	if d := (DeployInfo{}).detect(info); d != (DeployInfo{Version: "v1.2.3", Revision: "abc123"}) {
+		t.Fatalf("expected the version and revision from the build info, got: %+v", d)
+	}
+	if d := (DeployInfo{}).detect(&debug.BuildInfo{Main: debug.Module{Version: "(devel)"}}); d != (DeployInfo{}) {
+		t.Fatalf("expected no version for a devel build, got: %+v", d)
+	}
+
+	setenv(t, "DEPLOY_ENV", "staging")
+	setenv(t, "GIT_SHA", "def456")
+	if d := (DeployInfo{}).detect(info); d != (DeployInfo{Env: "staging", Version: "v1.2.3", Revision: "def456"}) {
+		t.Fatalf("expected the environment variables before the build info, got: %+v", d)
+	}
+	if d := (DeployInfo{Env: "canary", Revision: "fed789"}).detect(info); d != (DeployInfo{Env: "canary", Version: "v1.2.3", Revision: "fed789"}) {
+		t.Fatalf("expected the fields that are set to be kept, got: %+v", d)
+	}
+}
+
+func TestDeployInfoConfigs(t *testing.T) {
+	d := DeployInfo{Env: "staging", Version: "v1.2.3", Revision: "abc123"}
+	if c := (RollbarConfig{}).withDeploy(d); c.Env != "staging" || c.Version != "v1.2.3" || c.CodeVersion != "abc123" {
+		t.Fatalf("expected the rollbar config filled in, got: %+v", c)
+	}
+	if c := (RollbarConfig{}).withDeploy(DeployInfo{}); c.Env != "production" || c.Version != "1" || c.CodeVersion != "" {
+		t.Fatalf("expected the rollbar defaults, got: %+v", c)
+	}
+	if c := NewRollbarConfig("token", "test", "v2").withDeploy(d); c.Env != "test" || c.Version != "v2" || c.CodeVersion != "abc123" {
+		t.Fatalf("expected the rollbar config to be kept, got: %+v", c)
+	}
+	if c := (BugsnagConfig{}).withDeploy(d); c.ReleaseStage != "staging" || c.AppVersion != "v1.2.3" {
+		t.Fatalf("expected the bugsnag config filled in, got: %+v", c)
+	}
+	if c := (HoneybadgerConfig{}).withDeploy(d); c.Env != "staging" || c.Revision != "abc123" {
+		t.Fatalf("expected the honeybadger config filled in, got: %+v", c)
+	}
+}
+
+func TestPacketLogrDeployInfo(t *testing.T) {
+	l, err := New(WithDeployInfo(DeployInfo{Env: "staging", Version: "v1.2.3", Revision: "abc123"}),
+		WithBugsnagConfig(BugsnagConfig{APIKey: "key"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.rollbarConfig.CodeVersion != "abc123" || l.bugsnagConfig.AppVersion != "v1.2.3" || l.bugsnagConfig.ReleaseStage != "staging" {
+		t.Fatalf("expected the deploy info in the reporter configs, got: %+v %+v", l.rollbarConfig, l.bugsnagConfig)
+	}
+}
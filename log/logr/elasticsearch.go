@@ -0,0 +1,283 @@
+package logr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/packethost/pkg/log/logr/internal/fieldtemplate"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// elasticsearchBatchSize is the most entries sent in one bulk request
+	elasticsearchBatchSize = 500
+	// elasticsearchBatchBytes is the most bytes sent in one bulk request, the default http.max_content_length is 100MB
+	elasticsearchBatchBytes = 5 * 1024 * 1024
+	// elasticsearchFlushInterval is how long entries wait for a batch to fill up
+	elasticsearchFlushInterval = time.Second
+	// elasticsearchRetries is how many times a bulk request is retried before its entries are dropped
+	elasticsearchRetries = 5
+	// elasticsearchRequestTimeout is how long a bulk request can take
+	elasticsearchRequestTimeout = 30 * time.Second
+)
+
+// elasticsearchIndexReplacer replaces the characters that can't be in an index name
+var elasticsearchIndexReplacer = strings.NewReplacer(`\`, "_", "/", "_", "*", "_", "?", "_", `"`, "_", "<", "_", ">", "_", "|", "_", " ", "_", ",", "_", "#", "_", ":", "_")
+
+// ElasticsearchOption configures WithElasticsearch
+type ElasticsearchOption func(*elasticsearchConfig)
+
+type elasticsearchConfig struct {
+	urls     []string
+	index    string
+	username string
+	password string
+	apiKey   string
+	batch    batchConfig
+	network  netSinkConfig
+}
+
+// WithElasticsearchIndex sets the index entries are written to, a template filled in from each entry: {level} and {logger} are
+// the entry's level and logger name, {date} is its UTC date as YYYY.MM.DD, and any other name in braces is the value of the string
+// field with that key, from With or the entry. Missing values are "_".
+// It is {service}-{date} by default, which gives a daily index per service such as billing-2020.03.04.
+func WithElasticsearchIndex(index string) ElasticsearchOption {
+	return func(c *elasticsearchConfig) { c.index = index }
+}
+
+// WithElasticsearchBasicAuth authenticates bulk requests with username and password
+func WithElasticsearchBasicAuth(username, password string) ElasticsearchOption {
+	return func(c *elasticsearchConfig) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithElasticsearchAPIKey authenticates bulk requests with the base64 encoded API key
+func WithElasticsearchAPIKey(key string) ElasticsearchOption {
+	return func(c *elasticsearchConfig) { c.apiKey = key }
+}
+
+// WithElasticsearchBatch sets the most entries and bytes sent in one bulk request, 500 and 5MiB by default,
+// and how long entries wait for a batch to fill up, 1s by default.
+func WithElasticsearchBatch(maxEntries, maxBytes int, flushInterval time.Duration) ElasticsearchOption {
+	return func(c *elasticsearchConfig) {
+		c.batch.size = maxEntries
+		c.batch.bytes = maxBytes
+		c.batch.interval = flushInterval
+		c.batch.custom = true
+	}
+}
+
+// WithElasticsearchRetries sets how many times a failed bulk request, or entries rejected because the cluster is busy,
+// are retried with exponential backoff before being dropped, 5 by default.
+func WithElasticsearchRetries(retries int) ElasticsearchOption {
+	return func(c *elasticsearchConfig) { c.batch.retries = retries }
+}
+
+// WithElasticsearchNetwork configures TLS, auth, and queueing, see NetworkOption
+func WithElasticsearchNetwork(opts ...NetworkOption) ElasticsearchOption {
+	return func(c *elasticsearchConfig) {
+		for _, opt := range opts {
+			opt(&c.network)
+		}
+	}
+}
+
+// WithElasticsearch also writes entries to Elasticsearch or OpenSearch using bulk requests, cycling through urls.
+// Entries are JSON encoded, with the time under @timestamp, whatever the encoding of the output paths is.
+// While a batch is being sent entries are queued, and logging waits and then drops entries when the queue is full, see WithNetworkQueue.
+func WithElasticsearch(urls []string, opts ...ElasticsearchOption) LoggerOption {
+	return func(args *PacketLogr) {
+		c := elasticsearchConfig{
+			urls:    urls,
+			index:   "{service}-{date}",
+			batch:   batchConfig{size: elasticsearchBatchSize, bytes: elasticsearchBatchBytes, interval: elasticsearchFlushInterval, retries: elasticsearchRetries},
+			network: newHTTPSinkConfig(nil),
+		}
+		for _, opt := range opts {
+			opt(&c)
+		}
+		err := multierr.Append(c.network.validate(), c.batch.validate())
+		if a := c.network.compression.Algorithm; a != "" && a != CompressionGzip {
+			err = multierr.Append(err, errors.New("only gzip compression is accepted"))
+		}
+		if len(urls) == 0 {
+			err = multierr.Append(err, errors.New("at least one url is required"))
+		}
+		if _, indexErr := fieldtemplate.Parse(c.index); indexErr != nil {
+			err = multierr.Append(err, errors.WithMessage(indexErr, "invalid index"))
+		}
+		if err != nil {
+			args.errs = multierr.Append(args.errs, errors.WithMessage(err, "WithElasticsearch"))
+			return
+		}
+		args.newSinks = append(args.newSinks, func(zc zap.Config, wrap func(zapcore.WriteSyncer) zapcore.WriteSyncer) (zapcore.Core, io.Closer, error) {
+			c := c
+			c.batch = c.batch.withPolicy(args.batchPolicy)
+			return c.newSink(zc, wrap)
+		})
+	}
+}
+
+func (c elasticsearchConfig) newSink(zc zap.Config, _ func(zapcore.WriteSyncer) zapcore.WriteSyncer) (zapcore.Core, io.Closer, error) {
+	zc.Encoding = "json"
+	zc.EncoderConfig.TimeKey = "@timestamp"
+	zc.EncoderConfig.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+	encoder, err := NewEncoder(zc)
+	if err != nil {
+		return nil, nil, err
+	}
+	index, err := fieldtemplate.Parse(c.index)
+	if err != nil {
+		return nil, nil, err
+	}
+	client := &elasticsearchClient{
+		config: c,
+		client: &http.Client{Timeout: elasticsearchRequestTimeout, Transport: &http.Transport{TLSClientConfig: c.network.tlsConfig(), Proxy: http.ProxyFromEnvironment}},
+	}
+	s, err := newBatchSink("elasticsearch", c.batch, c.network, client.bulk)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.probe = HTTPProbe(client.client, c.urls[0])
+	return &elasticsearchCore{LevelEnabler: zc.Level, enc: encoder, s: s, index: index}, s, nil
+}
+
+// elasticsearchIndex cleans up an index name rendered from an entry, index names must be lowercase
+func elasticsearchIndex(v string) string {
+	return elasticsearchIndexReplacer.Replace(strings.ToLower(v))
+}
+
+// elasticsearchCore encodes entries as bulk create actions for the index rendered from them
+type elasticsearchCore struct {
+	zapcore.LevelEnabler
+	enc    zapcore.Encoder
+	s      *batchSink
+	index  fieldtemplate.Template
+	values map[string]string
+}
+
+func (c *elasticsearchCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	clone.values = c.index.Values(c.values, fields)
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+	}
+	return &clone
+}
+
+func (c *elasticsearchCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *elasticsearchCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	action, err := json.Marshal(map[string]map[string]string{"create": {"_index": c.index.Render(ent, c.index.Values(c.values, fields), elasticsearchIndex)}})
+	if err != nil {
+		return err
+	}
+	// the encoded entry ends with the newline the bulk API needs after each line
+	item := make([]byte, 0, len(action)+1+buf.Len())
+	item = append(append(append(item, action...), '\n'), buf.Bytes()...)
+	return c.s.write(item)
+}
+
+func (c *elasticsearchCore) Sync() error { return nil }
+
+// elasticsearchBulkResponse is the part of the bulk response needed to find the items that failed
+type elasticsearchBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// elasticsearchClient sends batches of bulk items, cycling through the urls
+type elasticsearchClient struct {
+	config elasticsearchConfig
+	client *http.Client
+	next   int
+}
+
+// bulk sends batch in one bulk request, returning the items that should be retried
+func (c *elasticsearchClient) bulk(ctx context.Context, batch [][]byte) ([][]byte, error) {
+	url := strings.TrimSuffix(c.config.urls[c.next%len(c.config.urls)], "/") + "/_bulk"
+	c.next++
+	req, err := c.config.network.newRequest(ctx, http.MethodPost, url, bytes.Join(batch, nil))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create bulk request")
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	token, err := c.config.network.bearerToken()
+	if err != nil {
+		return batch, err
+	}
+	switch {
+	case c.config.apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+c.config.apiKey)
+	case c.config.username != "":
+		req.SetBasicAuth(c.config.username, c.config.password)
+	case token != "":
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return batch, errors.Wrap(err, "bulk request failed")
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return batch, errors.Wrap(err, "failed to read bulk response")
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return batch, errors.Errorf("bulk request failed: %s", resp.Status)
+	case resp.StatusCode >= 300:
+		return nil, errors.Errorf("bulk request failed, dropped %d entries: %s: %s", len(batch), resp.Status, body)
+	}
+
+	var r elasticsearchBulkResponse
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, errors.Wrap(err, "failed to decode bulk response")
+	}
+	if !r.Errors {
+		return nil, nil
+	}
+	var retry [][]byte
+	rejected := 0
+	var reason json.RawMessage
+	for i, item := range r.Items {
+		for _, result := range item {
+			switch {
+			case result.Status == http.StatusTooManyRequests && i < len(batch):
+				retry = append(retry, batch[i])
+			case result.Status >= 300:
+				rejected++
+				reason = result.Error
+			}
+		}
+	}
+	if rejected > 0 {
+		err = errors.Errorf("rejected %d entries: %s", rejected, reason)
+	}
+	return retry, err
+}
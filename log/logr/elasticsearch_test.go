@@ -0,0 +1,183 @@
+package logr
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bulkServer records the body of every bulk request and responds with the next of responses, the last one once they run out
+func bulkServer(t *testing.T, responses ...string) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("unexpected request: %v %v", r.URL.Path, r.Header)
+		}
+		if user, pass, _ := r.BasicAuth(); user != "elastic" || pass != "secret" {
+			t.Errorf("expected basic auth, got: %v %v", user, pass)
+		}
+		var body []byte
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("expected a gzip body: %v", err)
+				return
+			}
+			body, _ = io.ReadAll(gz)
+		} else {
+			body, _ = io.ReadAll(r.Body)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, string(body))
+		resp := responses[0]
+		if len(responses) > 1 {
+			responses = responses[1:]
+		}
+		_, _ = w.Write([]byte(resp))
+	}))
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, bodies...)
+	}
+}
+
+func TestPacketLogrElasticsearch(t *testing.T) {
+	srv, bodies := bulkServer(t,
+		`{"errors":true,"items":[{"create":{"status":201}},{"create":{"status":429,"error":{"type":"es_rejected_execution_exception"}}}]}`,
+		`{"errors":false,"items":[{"create":{"status":201}}]}`,
+	)
+	defer srv.Close()
+
+	l, err := New(WithServiceName("Billing"), WithOutputPaths([]string{os.DevNull}),
+		WithElasticsearch([]string{srv.URL + "/"}, WithElasticsearchBasicAuth("elastic", "secret"), WithElasticsearchBatch(2, 1<<20, time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("one")
+	l.Info("two")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := bodies()
+	if len(got) != 2 {
+		t.Fatalf("expected the rejected entry to be retried, got: %v", got)
+	}
+	index := `{"create":{"_index":"billing-` + time.Now().UTC().Format("2006.01.02") + `"}}` + "\n"
+	lines := strings.Split(got[0], "\n")
+	if len(lines) != 5 || lines[0]+"\n" != index || !strings.Contains(lines[1], `"@timestamp":"`) || !strings.Contains(lines[1], `"msg":"one"`) {
+		t.Fatalf("expected a create action for the daily service index before each entry, got: %q", got[0])
+	}
+	if !strings.HasPrefix(got[1], index) || !strings.Contains(got[1], `"msg":"two"`) || strings.Contains(got[1], `"msg":"one"`) {
+		t.Fatalf("expected only the rejected entry in the retry, got: %q", got[1])
+	}
+}
+
+func TestPacketLogrElasticsearchRejected(t *testing.T) {
+	srv, _ := bulkServer(t, `{"errors":true,"items":[{"create":{"status":400,"error":{"type":"mapper_parsing_exception"}}}]}`)
+	defer srv.Close()
+
+	l, err := New(WithOutputPaths([]string{os.DevNull}),
+		WithElasticsearch([]string{srv.URL}, WithElasticsearchBasicAuth("elastic", "secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("bad")
+	err = l.Close(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "elasticsearch sink: rejected 1 entries") || !strings.Contains(err.Error(), "mapper_parsing_exception") {
+		t.Fatalf("expected the rejection, got: %v", err)
+	}
+}
+
+func TestPacketLogrElasticsearchCompression(t *testing.T) {
+	srv, bodies := bulkServer(t, `{"errors":false,"items":[{"create":{"status":201}}]}`)
+	defer srv.Close()
+
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithElasticsearch([]string{srv.URL},
+		WithElasticsearchBasicAuth("elastic", "secret"),
+		WithElasticsearchNetwork(WithSinkCompression(Compression{Algorithm: CompressionGzip, Level: 9})),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("compressed")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := bodies(); len(got) != 1 || !strings.Contains(got[0], `"msg":"compressed"`) {
+		t.Fatalf("expected the entry in the decompressed body, got: %v", got)
+	}
+
+	_, err = New(WithElasticsearch([]string{srv.URL}, WithElasticsearchNetwork(WithSinkCompression(Compression{Algorithm: CompressionZstd}))))
+	if err == nil || !strings.Contains(err.Error(), "only gzip compression is accepted") {
+		t.Fatalf("expected zstd to be rejected, got: %v", err)
+	}
+}
+
+func TestPacketLogrBatchPolicy(t *testing.T) {
+	srv, bodies := bulkServer(t, `{"errors":false}`)
+	defer srv.Close()
+
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithBatchPolicy(2, 1<<20, time.Hour),
+		WithElasticsearch([]string{srv.URL}, WithElasticsearchBasicAuth("elastic", "secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		l.Info("batched")
+	}
+	// the first two are sent without waiting for the interval
+	deadline := time.Now().Add(5 * time.Second)
+	for len(bodies()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := bodies(); len(got) != 1 || strings.Count(got[0], `"create"`) != 2 {
+		t.Fatalf("expected a batch of 2 entries, got: %v", got)
+	}
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the sink's own batch option takes precedence
+	srv2, bodies2 := bulkServer(t, `{"errors":false}`)
+	defer srv2.Close()
+	l, err = New(WithOutputPaths([]string{os.DevNull}), WithBatchPolicy(1, 1<<20, time.Hour),
+		WithElasticsearch([]string{srv2.URL}, WithElasticsearchBasicAuth("elastic", "secret"), WithElasticsearchBatch(10, 1<<20, time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("one")
+	l.Info("two")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := bodies2(); len(got) != 1 || strings.Count(got[0], `"create"`) != 2 {
+		t.Fatalf("expected one batch with both entries, got: %v", got)
+	}
+
+	if _, err := New(WithBatchPolicy(0, 1, time.Second)); err == nil || !strings.Contains(err.Error(), "WithBatchPolicy: batch entries, bytes, and flush interval must be > 0") {
+		t.Fatalf("expected an invalid policy error, got: %v", err)
+	}
+}
+
+func TestPacketLogrElasticsearchInvalid(t *testing.T) {
+	_, err := New(WithElasticsearch(nil, WithElasticsearchIndex("logs-{service"), WithElasticsearchBatch(0, 1, 0), WithElasticsearchRetries(-1)))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"WithElasticsearch", "at least one url is required", "invalid index: unmatched {", "batch entries, bytes, and flush interval must be > 0", "retries must be >= 0"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, err)
+		}
+	}
+}
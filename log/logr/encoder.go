@@ -0,0 +1,45 @@
+package logr
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewEncoder builds the encoder named by c.Encoding, json, console, or logfmt, for cores that are set up outside of zap.Config.Build,
+// such as the ones of WithSink
+func NewEncoder(c zap.Config) (zapcore.Encoder, error) {
+	switch c.Encoding {
+	case "json":
+		return zapcore.NewJSONEncoder(c.EncoderConfig), nil
+	case "console":
+		return zapcore.NewConsoleEncoder(c.EncoderConfig), nil
+	case "logfmt":
+		return NewLogfmtEncoder(c.EncoderConfig), nil
+	}
+	return nil, errors.Errorf("unknown encoding: %q", c.Encoding)
+}
+
+// RFC3339Millis is RFC3339 with millisecond precision, which is what most log aggregators expect
+const RFC3339Millis = "2006-01-02T15:04:05.000Z07:00"
+
+// timeEncoders are the named formats accepted by WithTimeFormat
+var timeEncoders = map[string]zapcore.TimeEncoder{
+	"rfc3339":       zapcore.RFC3339TimeEncoder,
+	"rfc3339millis": zapcore.TimeEncoderOfLayout(RFC3339Millis),
+	"rfc3339nano":   zapcore.RFC3339NanoTimeEncoder,
+	"iso8601":       zapcore.ISO8601TimeEncoder,
+	"epoch":         zapcore.EpochTimeEncoder,
+	"epochmillis":   zapcore.EpochMillisTimeEncoder,
+	"epochnanos":    zapcore.EpochNanosTimeEncoder,
+}
+
+// timeEncoder returns the named time encoder, or one using format as the layout
+func timeEncoder(format string) zapcore.TimeEncoder {
+	if enc, ok := timeEncoders[strings.ToLower(format)]; ok {
+		return enc
+	}
+	return zapcore.TimeEncoderOfLayout(format)
+}
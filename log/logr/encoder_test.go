@@ -0,0 +1,132 @@
+package logr
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestPacketLogrDevelopmentMode(t *testing.T) {
+	for name, errToStderr := range map[string]bool{"default": false, "errors to stderr": true} {
+		t.Run(name, func(t *testing.T) {
+			capturedOutput := captureOutput(func() {
+				l, _, err := NewPacketLogr(WithDevelopmentMode(), WithEnableErrLogsToStderr(errToStderr))
+				if err != nil {
+					t.Fatal(err)
+				}
+				l.Info("console message", "hello", "world")
+			})
+			if strings.Contains(capturedOutput, `"msg":`) {
+				t.Fatalf("expected console output, got: %v", capturedOutput)
+			}
+			for _, want := range []string{"INFO", "console message", `"hello": "world"`, "encoder_test.go"} {
+				if !strings.Contains(capturedOutput, want) {
+					t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+				}
+			}
+		})
+	}
+}
+
+func TestPacketLogrEncoding(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		l, _, err := NewPacketLogr(WithDevelopmentMode(), WithEncoding("json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Info("json message")
+	})
+	if !strings.Contains(capturedOutput, `"M":"json message"`) {
+		t.Fatalf("expected json output, got: %v", capturedOutput)
+	}
+
+	for _, errToStderr := range []bool{false, true} {
+		if _, _, err := NewPacketLogr(WithEncoding("xml"), WithEnableErrLogsToStderr(errToStderr)); err == nil {
+			t.Fatal("expected an error for an unknown encoding")
+		}
+	}
+}
+
+func TestPacketLogrEncoderConfig(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		cfg := zap.NewProductionEncoderConfig()
+		cfg.MessageKey = "message"
+		cfg.LevelKey = "severity"
+		l, _, err := NewPacketLogr(
+			WithMessageKey("ignored"),
+			WithEncoderConfig(cfg),
+			WithTimeKey("@timestamp"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Info("custom schema")
+	})
+	for _, want := range []string{`"message":"custom schema"`, `"severity":"info"`, `"@timestamp":`} {
+		if !strings.Contains(capturedOutput, want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+		}
+	}
+	if strings.Contains(capturedOutput, `"ignored"`) {
+		t.Fatalf("expected WithEncoderConfig to replace earlier keys, got: %v", capturedOutput)
+	}
+}
+
+func TestPacketLogrEncoderKeys(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		l, _, err := NewPacketLogr(WithMessageKey("message"), WithLevelKey("lvl"), WithTimeKey(""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Info("custom keys")
+	})
+	for _, want := range []string{`"message":"custom keys"`, `"lvl":"info"`} {
+		if !strings.Contains(capturedOutput, want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+		}
+	}
+	if strings.Contains(capturedOutput, `"ts":`) {
+		t.Fatalf("expected time to be omitted, got: %v", capturedOutput)
+	}
+}
+
+func TestTimeEncoder(t *testing.T) {
+	ts := time.Date(2021, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	tests := map[string]string{
+		"rfc3339":       `"2021-01-02T03:04:05Z"`,
+		"RFC3339Millis": `"2021-01-02T03:04:05.006Z"`,
+		"rfc3339nano":   `"2021-01-02T03:04:05.006Z"`,
+		"epochmillis":   `1609556645006`,
+		"15:04:05":      `"03:04:05"`,
+	}
+	for format, want := range tests {
+		t.Run(format, func(t *testing.T) {
+			enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{TimeKey: "ts", EncodeTime: timeEncoder(format)})
+			buf, err := enc.EncodeEntry(zapcore.Entry{Time: ts}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); !strings.Contains(got, `"ts":`+want) {
+				t.Fatalf("expected to contain: %v, got: %v", want, got)
+			}
+		})
+	}
+}
+
+func TestPacketLogrTimeFormat(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		l, _, err := NewPacketLogr(WithTimeFormat("2006"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Info("time format")
+	})
+	want := fmt.Sprintf(`"ts":"%d"`, time.Now().Year())
+	if !strings.Contains(capturedOutput, want) {
+		t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+	}
+}
@@ -0,0 +1,116 @@
+package logr
+
+import (
+	"os"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+)
+
+// NewPacketLogrFromEnv is NewPacketLogr configured from environment variables.
+// Any opts passed in are applied after the environment so they always take precedence.
+//
+// The following environment variables are used when set:
+//
+//	LOG_LEVEL                                  see WithLogLevel
+//	LOG_OUTPUT_PATHS                           comma separated list, see WithOutputPaths
+//	LOG_SERVICE                                see WithServiceName
+//	LOG_ERRORS_TO_STDERR                       see WithEnableErrLogsToStderr
+//	ROLLBAR_TOKEN                              enables Rollbar using this token
+//	ROLLBAR_TOKEN_FILE                         enables Rollbar using the token in this file, such as a mounted secret
+//	ROLLBAR_DISABLE                            disables Rollbar even if ROLLBAR_TOKEN or ROLLBAR_TOKEN_FILE is set
+//	ROLLBAR_LEVEL                              see WithRollbarLevel
+//	BUGSNAG_API_KEY                            enables Bugsnag using this API key
+//	HONEYBADGER_API_KEY                        enables Honeybadger using this API key
+//	ENV, EQUINIX_ENV or PACKET_ENV             the environment, or Bugsnag release stage, of the error reporters
+//	VERSION, EQUINIX_VERSION or PACKET_VERSION the version of the error reporters, or the Honeybadger revision
+func NewPacketLogrFromEnv(opts ...LoggerOption) (logr.Logger, *zap.Logger, error) {
+	return NewPacketLogr(append(envOptions(), opts...)...)
+}
+
+// NewFromEnv is New configured from environment variables, see NewPacketLogrFromEnv for the variables used
+func NewFromEnv(opts ...LoggerOption) (*PacketLogr, error) {
+	return New(append(envOptions(), opts...)...)
+}
+
+func envOptions() []LoggerOption {
+	var opts []LoggerOption
+
+	if v := getEnv("LOG_LEVEL"); v != "" {
+		opts = append(opts, WithLogLevel(v))
+	}
+	if v := getEnv("LOG_OUTPUT_PATHS"); v != "" {
+		var paths []string
+		for _, path := range strings.Split(v, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				paths = append(paths, path)
+			}
+		}
+		opts = append(opts, WithOutputPaths(paths))
+	}
+	if v := getEnv("LOG_SERVICE"); v != "" {
+		opts = append(opts, WithServiceName(v))
+	}
+	if v := getEnv("LOG_ERRORS_TO_STDERR"); v != "" {
+		opts = append(opts, WithEnableErrLogsToStderr(parseBool(v)))
+	}
+
+	token, tokenFile := getEnv("ROLLBAR_TOKEN"), getEnv("ROLLBAR_TOKEN_FILE")
+	if (token != "" || tokenFile != "") && !parseBool(getEnv("ROLLBAR_DISABLE")) {
+		opts = append(opts, WithEnableRollbar(true), func(args *PacketLogr) {
+			args.rollbarConfig.Token = token
+			args.rollbarConfig.TokenFile = tokenFile
+		})
+		if v := getEnv("ENV", "EQUINIX_ENV", "PACKET_ENV"); v != "" {
+			opts = append(opts, func(args *PacketLogr) { args.rollbarConfig.Env = v })
+		}
+		if v := getEnv("VERSION", "EQUINIX_VERSION", "PACKET_VERSION"); v != "" {
+			opts = append(opts, func(args *PacketLogr) { args.rollbarConfig.Version = v })
+		}
+		if v := getEnv("ROLLBAR_LEVEL"); v != "" {
+			opts = append(opts, WithRollbarLevel(v))
+		}
+	}
+
+	if key := getEnv("BUGSNAG_API_KEY"); key != "" {
+		opts = append(opts, WithEnableBugsnag(true), func(args *PacketLogr) { args.bugsnagConfig.APIKey = key })
+		if v := getEnv("ENV", "EQUINIX_ENV", "PACKET_ENV"); v != "" {
+			opts = append(opts, func(args *PacketLogr) { args.bugsnagConfig.ReleaseStage = v })
+		}
+		if v := getEnv("VERSION", "EQUINIX_VERSION", "PACKET_VERSION"); v != "" {
+			opts = append(opts, func(args *PacketLogr) { args.bugsnagConfig.AppVersion = v })
+		}
+	}
+
+	if key := getEnv("HONEYBADGER_API_KEY"); key != "" {
+		opts = append(opts, WithEnableHoneybadger(true), func(args *PacketLogr) { args.honeybadgerConfig.APIKey = key })
+		if v := getEnv("ENV", "EQUINIX_ENV", "PACKET_ENV"); v != "" {
+			opts = append(opts, func(args *PacketLogr) { args.honeybadgerConfig.Env = v })
+		}
+		if v := getEnv("VERSION", "EQUINIX_VERSION", "PACKET_VERSION"); v != "" {
+			opts = append(opts, func(args *PacketLogr) { args.honeybadgerConfig.Revision = v })
+		}
+	}
+
+	return opts
+}
+
+// getEnv returns the value of the first of names that is set to a non-empty value
+func getEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// parseBool evaluates true if the value case-insensitive matches 1|t|true|y|yes
+func parseBool(v string) bool {
+	switch strings.ToLower(v) {
+	case "1", "t", "true", "y", "yes":
+		return true
+	}
+	return false
+}
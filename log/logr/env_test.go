@@ -0,0 +1,113 @@
+package logr
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+	prev, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+			return
+		}
+		os.Unsetenv(key)
+	})
+}
+
+func TestNewPacketLogrFromEnv(t *testing.T) {
+	setenv(t, "LOG_LEVEL", "debug")
+	setenv(t, "LOG_SERVICE", "envservice")
+	setenv(t, "LOG_OUTPUT_PATHS", "stdout, stdout")
+
+	capturedOutput := captureOutput(func() {
+		l, _, err := NewPacketLogrFromEnv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.V(1).Info("debug from env")
+	})
+	if !strings.Contains(capturedOutput, "debug from env") {
+		t.Fatalf("expected to contain: %v, got: %v", "debug from env", capturedOutput)
+	}
+	if !strings.Contains(capturedOutput, `"service":"envservice"`) {
+		t.Fatalf("expected to contain: %v, got: %v", `"service":"envservice"`, capturedOutput)
+	}
+}
+
+func TestNewPacketLogrFromEnvOverride(t *testing.T) {
+	setenv(t, "LOG_LEVEL", "debug")
+
+	l, _, err := NewPacketLogrFromEnv(WithLogLevel("info"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.V(1).Enabled() {
+		t.Fatal("expected WithLogLevel to override LOG_LEVEL")
+	}
+
+	pl, err := NewFromEnv(WithLogLevel("warn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pl.Level() != "warn" {
+		t.Fatalf("expected WithLogLevel to override LOG_LEVEL, got: %v", pl.Level())
+	}
+}
+
+func TestNewPacketLogrFromEnvBadLevel(t *testing.T) {
+	setenv(t, "LOG_LEVEL", "chatty")
+
+	if _, _, err := NewPacketLogrFromEnv(); err == nil {
+		t.Fatal("expected an error for an unknown LOG_LEVEL")
+	}
+}
+
+func TestEnvOptionsRollbar(t *testing.T) {
+	setenv(t, "ROLLBAR_TOKEN", "envtoken")
+	setenv(t, "ENV", "staging")
+	setenv(t, "PACKET_VERSION", "v3")
+	setenv(t, "ROLLBAR_LEVEL", "warn")
+
+	pl := &PacketLogr{rollbarConfig: RollbarConfig{Token: "123", Env: "production", Version: "1"}}
+	for _, opt := range envOptions() {
+		opt(pl)
+	}
+	if !pl.enableRollbar {
+		t.Fatal("expected ROLLBAR_TOKEN to enable rollbar")
+	}
+	if pl.rollbarReporter.LevelEnabler != zapcore.WarnLevel {
+		t.Fatalf("expected ROLLBAR_LEVEL to set the rollbar level, got: %v", pl.rollbarReporter.LevelEnabler)
+	}
+	want := RollbarConfig{Token: "envtoken", Env: "staging", Version: "v3"}
+	if pl.rollbarConfig != want {
+		t.Fatalf("expected rollbar config: %+v, got: %+v", want, pl.rollbarConfig)
+	}
+
+	setenv(t, "ROLLBAR_TOKEN", "")
+	setenv(t, "ROLLBAR_TOKEN_FILE", "/run/secrets/rollbar")
+	pl = &PacketLogr{}
+	for _, opt := range envOptions() {
+		opt(pl)
+	}
+	if !pl.enableRollbar || pl.rollbarConfig.TokenFile != "/run/secrets/rollbar" {
+		t.Fatalf("expected ROLLBAR_TOKEN_FILE to enable rollbar, got: %+v", pl.rollbarConfig)
+	}
+
+	setenv(t, "ROLLBAR_DISABLE", "true")
+	pl = &PacketLogr{}
+	for _, opt := range envOptions() {
+		opt(pl)
+	}
+	if pl.enableRollbar {
+		t.Fatal("expected ROLLBAR_DISABLE to keep rollbar disabled")
+	}
+}
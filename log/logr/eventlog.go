@@ -0,0 +1,42 @@
+package logr
+
+import (
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// eventLogID is the event ID every entry is written with
+const eventLogID = 1
+
+// WithWindowsEventLog also writes entries to the Windows Event Log from source, as information, warning, or error events depending on their level.
+// source has to be registered beforehand, for example by the installer with eventlog.InstallAsEventCreate from golang.org/x/sys/windows/svc/eventlog.
+// New fails on other platforms.
+func WithWindowsEventLog(source string) LoggerOption {
+	return func(args *PacketLogr) {
+		if source == "" {
+			args.errs = multierr.Append(args.errs, errors.New("WithWindowsEventLog: source must not be empty"))
+			return
+		}
+		args.newSinks = append(args.newSinks, newEventLogSink(source))
+	}
+}
+
+// eventType is how entries at level show up in the Event Log
+type eventType int
+
+const (
+	eventInfo eventType = iota
+	eventWarning
+	eventError
+)
+
+func eventTypeFor(level zapcore.Level) eventType {
+	switch {
+	case level >= zapcore.ErrorLevel:
+		return eventError
+	case level == zapcore.WarnLevel:
+		return eventWarning
+	}
+	return eventInfo
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package logr
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newEventLogSink(string) newSink {
+	return func(zap.Config, func(zapcore.WriteSyncer) zapcore.WriteSyncer) (zapcore.Core, io.Closer, error) {
+		return nil, nil, errors.New("the windows event log is only available on windows")
+	}
+}
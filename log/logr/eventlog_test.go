@@ -0,0 +1,27 @@
+package logr
+
+import (
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestEventTypeFor(t *testing.T) {
+	for level, want := range map[zapcore.Level]eventType{
+		zapcore.DebugLevel: eventInfo,
+		zapcore.InfoLevel:  eventInfo,
+		zapcore.WarnLevel:  eventWarning,
+		zapcore.ErrorLevel: eventError,
+		zapcore.FatalLevel: eventError,
+	} {
+		if got := eventTypeFor(level); got != want {
+			t.Fatalf("level %v: expected %v, got %v", level, want, got)
+		}
+	}
+}
+
+func TestPacketLogrWindowsEventLogInvalid(t *testing.T) {
+	if _, err := New(WithWindowsEventLog("")); err == nil {
+		t.Fatal("expected an error for an empty source")
+	}
+}
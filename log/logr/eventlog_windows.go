@@ -0,0 +1,68 @@
+package logr
+
+import (
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+func newEventLogSink(source string) newSink {
+	return func(c zap.Config, _ func(zapcore.WriteSyncer) zapcore.WriteSyncer) (zapcore.Core, io.Closer, error) {
+		l, err := eventlog.Open(source)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to open event log source %q", source)
+		}
+		encoder, err := NewEncoder(c)
+		if err != nil {
+			return nil, nil, multierr.Append(err, l.Close())
+		}
+		return &eventLogCore{LevelEnabler: c.Level, enc: encoder, log: l}, l, nil
+	}
+}
+
+// eventLogCore encodes entries and reports them as events
+type eventLogCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	log *eventlog.Log
+}
+
+func (c *eventLogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &eventLogCore{LevelEnabler: c.LevelEnabler, enc: c.enc.Clone(), log: c.log}
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+	}
+	return clone
+}
+
+func (c *eventLogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *eventLogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	msg := strings.TrimSuffix(buf.String(), "\n")
+	switch eventTypeFor(ent.Level) {
+	case eventError:
+		err = c.log.Error(eventLogID, msg)
+	case eventWarning:
+		err = c.log.Warning(eventLogID, msg)
+	default:
+		err = c.log.Info(eventLogID, msg)
+	}
+	return errors.Wrap(err, "failed to write to the event log")
+}
+
+func (c *eventLogCore) Sync() error { return nil }
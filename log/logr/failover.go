@@ -0,0 +1,274 @@
+package logr
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultHealthCheckTimeout  = 5 * time.Second
+)
+
+// FailoverOption configures WithSinkFailover
+type FailoverOption func(*failoverConfig)
+
+type failoverConfig struct {
+	interval time.Duration
+	timeout  time.Duration
+}
+
+// WithHealthCheck sets how often the primary sink is checked, 10s by default, and how long a check can take, 5s by default
+func WithHealthCheck(interval, timeout time.Duration) FailoverOption {
+	return func(c *failoverConfig) {
+		c.interval = interval
+		c.timeout = timeout
+	}
+}
+
+// SinkHealth is the state of a primary sink set up with WithSinkFailover
+type SinkHealth struct {
+	Sink    string
+	Healthy bool
+	// Since is when the sink last went up or down, or when the logger was built if it hasn't yet
+	Since time.Time
+	// Err is why the last health check failed, nil when it is healthy
+	Err error
+	// Failovers is how many times entries have been sent to the secondary sink instead
+	Failovers int
+}
+
+// healthChecker is a sink that can be health checked, which the remote sinks are
+type healthChecker interface {
+	sinkName() string
+	checkHealth(ctx context.Context) error
+}
+
+// WithSinkFailover sends entries to the sink added by primary, such as WithLogstash, and to the one added by secondary,
+// such as WithSinkConfig with a local file, while primary is down. The primary sink is health checked, by connecting to it
+// or making a request for the sinks that use HTTP, and the logger fails back to it once a check passes again.
+// The primary must be one of the sinks that take NetworkOptions, and a UDP sink always passes the check as there is no connection to make.
+// Transitions are logged as warnings, and the current state is returned by SinkHealth.
+// Entries already queued by the primary sink are still sent by it, or spooled, once it is reachable.
+func WithSinkFailover(primary, secondary LoggerOption, opts ...FailoverOption) LoggerOption {
+	return func(args *PacketLogr) {
+		c := failoverConfig{interval: defaultHealthCheckInterval, timeout: defaultHealthCheckTimeout}
+		for _, opt := range opts {
+			opt(&c)
+		}
+		var err error
+		if c.interval <= 0 || c.timeout <= 0 {
+			err = multierr.Append(err, errors.Errorf("health check interval and timeout must be > 0, got: %s and %s", c.interval, c.timeout))
+		}
+		newPrimary, perr := args.captureSink(primary)
+		newSecondary, serr := args.captureSink(secondary)
+		err = multierr.Combine(err, errors.WithMessage(perr, "primary"), errors.WithMessage(serr, "secondary"))
+		if err != nil {
+			args.errs = multierr.Append(args.errs, errors.WithMessage(err, "WithSinkFailover"))
+			return
+		}
+		args.newSinks = append(args.newSinks, func(zc zap.Config, wrap func(zapcore.WriteSyncer) zapcore.WriteSyncer) (zapcore.Core, io.Closer, error) {
+			primary, primaryCloser, err := newPrimary(zc, wrap)
+			if err != nil {
+				return nil, nil, errors.WithMessage(err, "primary")
+			}
+			secondary, secondaryCloser, err := newSecondary(zc, wrap)
+			if err != nil {
+				return nil, nil, multierr.Append(errors.WithMessage(err, "secondary"), closeSink(primaryCloser))
+			}
+			checker, ok := primaryCloser.(healthChecker)
+			if !ok {
+				err := errors.New("the primary sink can't be health checked, it must be one of the sinks that take NetworkOptions")
+				return nil, nil, multierr.Combine(err, closeSink(primaryCloser), closeSink(secondaryCloser))
+			}
+			s := &failoverSink{
+				config:    c,
+				checker:   checker,
+				primary:   primaryCloser,
+				secondary: secondaryCloser,
+				health:    SinkHealth{Sink: checker.sinkName(), Healthy: true, Since: time.Now()},
+				stop:      make(chan struct{}),
+				stopped:   make(chan struct{}),
+			}
+			return &failoverCore{primary: primary, secondary: secondary, s: s}, s, nil
+		})
+	}
+}
+
+// captureSink applies opt to get the one sink it adds, without adding it, its errors are returned instead of being collected.
+// The sink is built from p like the others, so that options such as WithBatchPolicy apply to it.
+func (p *PacketLogr) captureSink(opt LoggerOption) (newSink, error) {
+	if opt == nil {
+		return nil, errors.New("sink option must not be nil")
+	}
+	errs, n := p.errs, len(p.newSinks)
+	p.errs = nil
+	opt(p)
+	added := p.newSinks[n:]
+	p.newSinks = p.newSinks[:n:n]
+	err := p.errs
+	p.errs = errs
+	if err != nil {
+		return nil, err
+	}
+	if len(added) != 1 {
+		return nil, errors.Errorf("the option must add one sink, got: %d", len(added))
+	}
+	return added[0], nil
+}
+
+// failoverSink health checks the primary sink from a background goroutine, started by supervise once the logger is built
+type failoverSink struct {
+	config    failoverConfig
+	checker   healthChecker
+	primary   io.Closer
+	secondary io.Closer
+
+	mu      sync.RWMutex
+	health  SinkHealth
+	started bool
+
+	once    sync.Once
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func (s *failoverSink) healthy() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.health.Healthy
+}
+
+func (s *failoverSink) state() SinkHealth {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.health
+}
+
+// supervise checks the primary sink every interval until the sink is closed, logging transitions to log
+func (s *failoverSink) supervise(log *zap.Logger) {
+	s.mu.Lock()
+	s.started = true
+	s.mu.Unlock()
+	go func() {
+		defer close(s.stopped)
+		ticker := time.NewTicker(s.config.interval)
+		defer ticker.Stop()
+		for {
+			s.check(log)
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *failoverSink) check(log *zap.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.timeout)
+	err := s.checker.checkHealth(ctx)
+	cancel()
+
+	s.mu.Lock()
+	was := s.health
+	s.health.Err = err
+	if healthy := err == nil; healthy != was.Healthy {
+		s.health.Healthy, s.health.Since = healthy, time.Now()
+		if !healthy {
+			s.health.Failovers++
+		}
+	}
+	s.mu.Unlock()
+
+	switch {
+	case err != nil && was.Healthy:
+		log.Warn("sink is down, failing over to the secondary sink", zap.String("sink", was.Sink), zap.Error(err))
+	case err == nil && !was.Healthy:
+		log.Warn("sink is back up, failing back to it", zap.String("sink", was.Sink), zap.Duration("down_for", time.Since(was.Since)))
+	}
+}
+
+func (s *failoverSink) droppedEntries() uint64 {
+	return sinkDropped(s.primary) + sinkDropped(s.secondary)
+}
+
+func (s *failoverSink) spoolStats() (SpoolStats, bool) {
+	if sp, ok := s.primary.(spooler); ok {
+		return sp.spoolStats()
+	}
+	return SpoolStats{}, false
+}
+
+// Close stops the health checks and closes both sinks
+func (s *failoverSink) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	s.mu.RLock()
+	started := s.started
+	s.mu.RUnlock()
+	// supervise isn't started when building the logger fails
+	if started {
+		<-s.stopped
+	}
+	return multierr.Append(closeSink(s.primary), closeSink(s.secondary))
+}
+
+// failoverCore sends entries to the primary core, or the secondary one while the primary sink is down
+type failoverCore struct {
+	primary, secondary zapcore.Core
+	s                  *failoverSink
+}
+
+func (c *failoverCore) active() zapcore.Core {
+	if c.s.healthy() {
+		return c.primary
+	}
+	return c.secondary
+}
+
+func (c *failoverCore) Enabled(lvl zapcore.Level) bool {
+	return c.primary.Enabled(lvl) || c.secondary.Enabled(lvl)
+}
+
+func (c *failoverCore) With(fields []zapcore.Field) zapcore.Core {
+	return &failoverCore{primary: c.primary.With(fields), secondary: c.secondary.With(fields), s: c.s}
+}
+
+func (c *failoverCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return c.active().Check(ent, ce)
+}
+
+func (c *failoverCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.active().Write(ent, fields)
+}
+
+func (c *failoverCore) Sync() error {
+	return multierr.Append(c.primary.Sync(), c.secondary.Sync())
+}
+
+// SinkHealth returns the state of the primary sinks set up with WithSinkFailover
+func (p *PacketLogr) SinkHealth() []SinkHealth {
+	var health []SinkHealth
+	for _, closer := range p.sinkClosers {
+		if s, ok := closer.(*failoverSink); ok {
+			health = append(health, s.state())
+		}
+	}
+	return health
+}
+
+// superviseSinks starts the health checks of the sinks set up with WithSinkFailover, they are stopped when the sinks are closed
+func (p *PacketLogr) superviseSinks() {
+	for _, closer := range p.sinkClosers {
+		if s, ok := closer.(*failoverSink); ok {
+			s.supervise(p.zap)
+		}
+	}
+}
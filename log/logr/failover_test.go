@@ -0,0 +1,105 @@
+package logr
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func waitForHealth(t *testing.T, l *PacketLogr, healthy bool) SinkHealth {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if health := l.SinkHealth(); len(health) == 1 && health[0].Healthy == healthy {
+			return health[0]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for the sink to be healthy: %v, got: %+v", healthy, l.SinkHealth())
+	return SinkHealth{}
+}
+
+func TestPacketLogrSinkFailover(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	lines := make(chan string, 10)
+	go lineServer(ln, 0, lines)
+	local := filepath.Join(t.TempDir(), "local.log")
+
+	l, err := New(WithOutputPaths(nil), WithSinkFailover(
+		WithLogstash(addr),
+		WithSinkConfig("local", "", "", []string{local}),
+		WithHealthCheck(10*time.Millisecond, time.Second),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close(context.Background())
+
+	l.Info("while up")
+	if line := <-lines; !strings.Contains(line, `"msg":"while up"`) {
+		t.Fatalf("expected the entry in the primary sink, got: %v", line)
+	}
+
+	ln.Close()
+	health := waitForHealth(t, l, false)
+	if health.Sink != "logstash" || health.Failovers != 1 || health.Err == nil || !strings.Contains(health.Err.Error(), "connection refused") {
+		t.Fatalf("expected the logstash sink to be down, got: %+v", health)
+	}
+	l.Info("while down")
+
+	if ln, err = net.Listen("tcp", addr); err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go lineServer(ln, 0, lines)
+	waitForHealth(t, l, true)
+	l.Info("back up")
+
+	for _, want := range []string{`"msg":"sink is back up, failing back to it"`, `"msg":"back up"`} {
+		select {
+		case line := <-lines:
+			if !strings.Contains(line, want) {
+				t.Fatalf("expected: %v, got: %v", want, line)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %v", want)
+		}
+	}
+	b, err := os.ReadFile(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); !strings.Contains(got, `"msg":"sink is down, failing over to the secondary sink","service":"not/set","sink":"logstash"`) ||
+		!strings.Contains(got, `"msg":"while down"`) || strings.Contains(got, "while up") || strings.Contains(got, `"msg":"back up"`) {
+		t.Fatalf("expected only the entries logged while the sink was down, got: %v", got)
+	}
+}
+
+func TestPacketLogrSinkFailoverInvalid(t *testing.T) {
+	_, err := New(WithSinkFailover(WithLogstash(""), nil, WithHealthCheck(0, time.Second)))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		"WithSinkFailover: health check interval and timeout must be > 0",
+		"primary: WithLogstash",
+		"secondary: sink option must not be nil",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, err)
+		}
+	}
+
+	_, err = New(WithSinkFailover(WithSinkConfig("file", "", "", []string{os.DevNull}), WithLogstash("127.0.0.1:1")))
+	if err == nil || !strings.Contains(err.Error(), "the primary sink can't be health checked") {
+		t.Fatalf("expected the primary to need health checks, got: %v", err)
+	}
+}
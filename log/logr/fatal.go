@@ -0,0 +1,59 @@
+package logr
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// exitFlushTimeout bounds how long the error reporters are waited for before the process exits or panics
+const exitFlushTimeout = 5 * time.Second
+
+// Fatal logs msg with keysAndValues at the fatal level, waits for the error reporters to send it and flushes
+// buffered log entries, then exits with status 1. Use it instead of logging an error and calling os.Exit,
+// which loses what is still buffered.
+func (p *PacketLogr) Fatal(msg string, keysAndValues ...interface{}) {
+	l := p.zap.WithOptions(zap.AddCallerSkip(1))
+	l.Fatal(msg, handleFields(l, keysAndValues)...)
+}
+
+// DPanic logs msg with keysAndValues at the dpanic level, waits for the error reporters to send it and flushes
+// buffered log entries, then panics when the zap logger is in development, such as with WithZapOptions(zap.Development()).
+func (p *PacketLogr) DPanic(msg string, keysAndValues ...interface{}) {
+	l := p.zap.WithOptions(zap.AddCallerSkip(1))
+	l.DPanic(msg, handleFields(l, keysAndValues)...)
+}
+
+// flushOnExit tees a core that flushes p once entries at or above dpanic, the ones the logger may panic or exit after,
+// are written by the other cores.
+func flushOnExit(p *PacketLogr) zap.Option {
+	return zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(c, &flushCore{LevelEnabler: zapcore.DPanicLevel, p: p})
+	})
+}
+
+// flushCore flushes the error reporters and syncs the logger when an entry is written to it
+type flushCore struct {
+	zapcore.LevelEnabler
+	p *PacketLogr
+}
+
+func (c *flushCore) With([]zapcore.Field) zapcore.Core { return c }
+
+func (c *flushCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *flushCore) Write(zapcore.Entry, []zapcore.Field) error {
+	ctx, cancel := context.WithTimeout(context.Background(), exitFlushTimeout)
+	defer cancel()
+	return multierr.Append(c.p.FlushErrorReporters(ctx), c.p.Sync())
+}
+
+func (c *flushCore) Sync() error { return nil }
@@ -0,0 +1,72 @@
+package logr
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestPacketLogrFatal(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.log")
+	r := &testReporter{}
+	// exit the goroutine instead of the test binary
+	l, err := New(WithOutputPaths([]string{out}), WithErrorReporter(r), WithZapOptions(zap.OnFatal(zapcore.WriteThenGoexit)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Fatal("cannot start", "port", 8080)
+	}()
+	<-done
+
+	if len(r.entries) != 1 || r.entries[0].Level != zapcore.FatalLevel || r.flushed != 1 {
+		t.Fatalf("expected the fatal entry reported and flushed before exiting, got: %v", r.entries)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); !strings.Contains(got, `"msg":"cannot start"`) || !strings.Contains(got, `"port":8080`) ||
+		!strings.Contains(got, "fatal_test.go") {
+		t.Fatalf("expected the fatal entry with its caller, got: %v", got)
+	}
+}
+
+func TestPacketLogrDPanic(t *testing.T) {
+	r := &testReporter{}
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithErrorReporter(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.DPanic("unexpected state", "device", "d1")
+	if len(r.entries) != 1 || r.flushed != 1 {
+		t.Fatalf("expected the dpanic entry reported and flushed, got: %v", r.entries)
+	}
+	l.Error(nil, "not flushed")
+	if r.flushed != 1 {
+		t.Fatalf("expected error entries not to be flushed, got: %d flushes", r.flushed)
+	}
+}
+
+func TestPacketLogrDPanicDevelopment(t *testing.T) {
+	r := &testReporter{}
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithZapOptions(zap.Development()), WithErrorReporter(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if v := recover(); v != "unexpected state" {
+			t.Fatalf("expected a panic in development, got: %v", v)
+		}
+		if r.flushed != 1 {
+			t.Fatalf("expected the error reporters flushed before panicking, got: %d flushes", r.flushed)
+		}
+	}()
+	l.DPanic("unexpected state")
+}
@@ -0,0 +1,243 @@
+// Package fluentdsink sends the entries of a logr.PacketLogr to a fluentd or fluent-bit forward input:
+//
+//	l, err := logr.New(fluentdsink.WithSink("fluentd:24224", "app.logs", fluentdsink.WithAck(5*time.Second)))
+package fluentdsink
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"time"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/pkg/errors"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// Option configures WithSink
+type Option func(*fluentdConfig)
+
+type fluentdConfig struct {
+	addr       string
+	tag        string
+	ackTimeout time.Duration
+	network    []logr.NetworkOption
+}
+
+// WithAck asks fluentd to acknowledge every message, waiting up to timeout for the ack.
+// Messages that are not acknowledged are sent again after reconnecting, so they are delivered at least once.
+func WithAck(timeout time.Duration) Option {
+	return func(c *fluentdConfig) { c.ackTimeout = timeout }
+}
+
+// WithNetwork configures TLS and queueing, see logr.NetworkOption
+func WithNetwork(opts ...logr.NetworkOption) Option {
+	return func(c *fluentdConfig) { c.network = append(c.network, opts...) }
+}
+
+// WithSink also sends entries to a fluentd or fluent-bit forward input at addr with tag, using the forward protocol's message mode.
+// The record has the entry's fields along with the level, message, logger name, caller, and stack trace under the keys of the encoder config.
+func WithSink(addr, tag string, opts ...Option) logr.LoggerOption {
+	c := fluentdConfig{addr: addr, tag: tag}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	network := logr.NewNetworkConfig(c.network...)
+	err := network.Validate()
+	if addr == "" {
+		err = multierr.Append(err, errors.New("address must not be empty"))
+	}
+	if tag == "" {
+		err = multierr.Append(err, errors.New("tag must not be empty"))
+	}
+	if c.ackTimeout < 0 {
+		err = multierr.Append(err, errors.Errorf("ack timeout must be >= 0, got: %s", c.ackTimeout))
+	}
+	if err != nil {
+		return logr.WithOptionError(errors.WithMessage(err, "fluentdsink.WithSink"))
+	}
+	return logr.WithSink(func(sc logr.SinkContext) (zapcore.Core, io.Closer, error) {
+		s, err := logr.NewNetworkSink("fluentd", network, network.Dialer("tcp", c.addr), c.send)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &fluentdCore{LevelEnabler: sc.Config.Level, s: s, config: c, keys: sc.Config.EncoderConfig}, s, nil
+	})
+}
+
+// fluentdCore turns entries into forward protocol messages
+type fluentdCore struct {
+	zapcore.LevelEnabler
+	s      *logr.NetworkSink
+	config fluentdConfig
+	keys   zapcore.EncoderConfig
+	fields []zapcore.Field
+}
+
+func (c *fluentdCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(append([]zapcore.Field{}, c.fields...), fields...)
+	return &clone
+}
+
+func (c *fluentdCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *fluentdCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	msg, err := c.message(ent, fields)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode fluentd message")
+	}
+	return c.s.Send(msg)
+}
+
+func (c *fluentdCore) Sync() error { return nil }
+
+// message encodes [tag, time, record, option], option has the chunk ID fluentd acks when acks are on
+func (c *fluentdCore) message(ent zapcore.Entry, fields []zapcore.Field) ([]byte, error) {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	record := fluentdValue(enc.Fields).(map[string]interface{})
+	add := func(key string, value interface{}) {
+		if key != "" {
+			record[key] = value
+		}
+	}
+	add(c.keys.LevelKey, ent.Level.String())
+	add(c.keys.MessageKey, ent.Message)
+	if ent.LoggerName != "" {
+		add(c.keys.NameKey, ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		add(c.keys.CallerKey, ent.Caller.TrimmedPath())
+	}
+	if ent.Stack != "" {
+		add(c.keys.StacktraceKey, ent.Stack)
+	}
+
+	var buf bytes.Buffer
+	e := msgpack.NewEncoder(&buf)
+	e.SetSortMapKeys(true)
+	err := multierr.Combine(
+		e.EncodeArrayLen(4),
+		e.EncodeString(c.config.tag),
+		encodeEventTime(e, ent.Time),
+		e.Encode(record),
+	)
+	option := map[string]interface{}{}
+	if c.config.ackTimeout > 0 {
+		id := make([]byte, 16)
+		if _, randErr := rand.Read(id); randErr != nil {
+			return nil, randErr
+		}
+		option["chunk"] = base64.StdEncoding.EncodeToString(id)
+	}
+	err = multierr.Append(err, e.Encode(option))
+	return buf.Bytes(), err
+}
+
+// encodeEventTime writes t as the forward protocol's EventTime extension, which keeps nanoseconds
+func encodeEventTime(e *msgpack.Encoder, t time.Time) error {
+	if err := e.EncodeExtHeader(0, 8); err != nil {
+		return err
+	}
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, uint32(t.Unix()))
+	binary.BigEndian.PutUint32(b[4:], uint32(t.Nanosecond()))
+	_, err := e.Writer().Write(b)
+	return err
+}
+
+// fluentdValue converts v to the types fluentd understands, anything that isn't a basic type goes through JSON
+func fluentdValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, string, bool, float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = fluentdValue(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			a[i] = fluentdValue(e)
+		}
+		return a
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case time.Duration:
+		return v.String()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err.Error()
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return string(b)
+	}
+	return decoded
+}
+
+// fluentdChunk reads the chunk ID back out of the option of msg
+func fluentdChunk(msg []byte) (string, error) {
+	d := msgpack.NewDecoder(bytes.NewReader(msg))
+	if _, err := d.DecodeArrayLen(); err != nil {
+		return "", err
+	}
+	for i := 0; i < 3; i++ {
+		if err := d.Skip(); err != nil {
+			return "", err
+		}
+	}
+	option, err := d.DecodeMap()
+	if err != nil {
+		return "", err
+	}
+	chunk, _ := option["chunk"].(string)
+	return chunk, nil
+}
+
+// send writes msg and, when acks are on, waits for fluentd to ack its chunk ID
+func (c fluentdConfig) send(conn net.Conn, msg []byte) error {
+	if _, err := conn.Write(msg); err != nil {
+		return err
+	}
+	if c.ackTimeout == 0 {
+		return nil
+	}
+	chunk, err := fluentdChunk(msg)
+	if err != nil {
+		return err
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(c.ackTimeout)); err != nil {
+		return err
+	}
+	defer conn.SetReadDeadline(time.Time{})
+	resp, err := msgpack.NewDecoder(conn).DecodeMap()
+	if err != nil {
+		return errors.Wrap(err, "failed to read ack")
+	}
+	if ack, _ := resp["ack"].(string); ack != chunk {
+		return errors.Errorf("expected ack for chunk %q, got: %v", chunk, resp["ack"])
+	}
+	return nil
+}
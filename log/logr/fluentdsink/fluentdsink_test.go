@@ -0,0 +1,122 @@
+package fluentdsink
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type fluentdMessage struct {
+	tag    string
+	record map[string]interface{}
+	option map[string]interface{}
+}
+
+// fluentdServer reads forward protocol messages from every connection on ln, acking them if asked to
+func fluentdServer(t *testing.T, ln net.Listener, messages chan<- fluentdMessage) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			d := msgpack.NewDecoder(conn)
+			for {
+				var msg fluentdMessage
+				if _, err := d.DecodeArrayLen(); err != nil {
+					return
+				}
+				msg.tag, _ = d.DecodeString()
+				if extID, extLen, err := d.DecodeExtHeader(); err != nil || extID != 0 || extLen != 8 {
+					t.Errorf("expected an EventTime, got: %v %v %v", extID, extLen, err)
+					return
+				}
+				if _, err := d.Buffered().Read(make([]byte, 8)); err != nil {
+					return
+				}
+				msg.record, _ = d.DecodeMap()
+				msg.option, _ = d.DecodeMap()
+				if chunk, ok := msg.option["chunk"]; ok {
+					b, _ := msgpack.Marshal(map[string]interface{}{"ack": chunk})
+					_, _ = conn.Write(b)
+				}
+				messages <- msg
+			}
+		}(conn)
+	}
+}
+
+func TestWithSink(t *testing.T) {
+	tests := map[string]struct {
+		opts []Option
+		ack  bool
+	}{
+		"no ack": {},
+		"ack":    {opts: []Option{WithAck(time.Second)}, ack: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+			messages := make(chan fluentdMessage, 10)
+			go fluentdServer(t, ln, messages)
+
+			pl, err := logr.New(WithSink(ln.Addr().String(), "app.logs", tc.opts...), logr.WithOutputPaths([]string{os.DevNull}), logr.WithServiceName("fluent"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			pl.Named("db").Info("to fluentd", "took", time.Second, "req", map[string]interface{}{"id": 1})
+			pl.Info("second")
+			if err := pl.Close(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			msg := <-messages
+			if msg.tag != "app.logs" {
+				t.Fatalf("expected tag app.logs, got: %v", msg.tag)
+			}
+			for k, want := range map[string]interface{}{
+				"level":   "info",
+				"msg":     "to fluentd",
+				"logger":  "db",
+				"service": "fluent",
+				"took":    "1s",
+			} {
+				if msg.record[k] != want {
+					t.Fatalf("expected %v to be: %v, got: %v in %v", k, want, msg.record[k], msg.record)
+				}
+			}
+			if !strings.HasPrefix(msg.record["caller"].(string), "fluentdsink/fluentdsink_test.go:") {
+				t.Fatalf("expected the caller, got: %v", msg.record["caller"])
+			}
+			if _, ok := msg.option["chunk"]; ok != tc.ack {
+				t.Fatalf("expected chunk in option to be %v, got: %v", tc.ack, msg.option)
+			}
+			if msg := <-messages; msg.record["msg"] != "second" {
+				t.Fatalf("expected the second entry, got: %v", msg.record)
+			}
+		})
+	}
+}
+
+func TestWithSinkInvalid(t *testing.T) {
+	_, err := logr.New(WithSink("", "", WithAck(-1)))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"fluentdsink.WithSink", "address must not be empty", "tag must not be empty", "ack timeout must be >= 0"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, err)
+		}
+	}
+}
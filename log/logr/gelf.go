@@ -0,0 +1,269 @@
+package logr
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// gelfDefaultChunkSize keeps UDP chunks within a typical MTU
+	gelfDefaultChunkSize = 1420
+	// gelfMaxChunks is the most chunks a GELF message can be split into
+	gelfMaxChunks = 128
+	// gelfChunkHeaderSize is the magic bytes, message ID, sequence number, and sequence count
+	gelfChunkHeaderSize = 12
+)
+
+// gelfInvalidKeyChars are the characters GELF doesn't allow in additional field names
+var gelfInvalidKeyChars = regexp.MustCompile(`[^\w.\-]`)
+
+// GELFOption configures WithGELF
+type GELFOption func(*gelfConfig)
+
+type gelfConfig struct {
+	addr      string
+	tcp       bool
+	chunkSize int
+	network   netSinkConfig
+}
+
+// WithGELFTCP sends messages over TCP, null byte delimited, instead of UDP
+func WithGELFTCP() GELFOption {
+	return func(c *gelfConfig) { c.tcp = true }
+}
+
+// WithGELFTLS sends messages over TCP using TLS configured by config
+func WithGELFTLS(config *tls.Config) GELFOption {
+	return func(c *gelfConfig) {
+		c.tcp = true
+		WithSinkTLS(config)(&c.network)
+	}
+}
+
+// WithGELFNetwork configures queueing, see NetworkOption
+func WithGELFNetwork(opts ...NetworkOption) GELFOption {
+	return func(c *gelfConfig) {
+		for _, opt := range opts {
+			opt(&c.network)
+		}
+	}
+}
+
+// WithGELFChunkSize sets the size of the UDP chunks that messages bigger than it are split into, defaults to 1420 bytes
+func WithGELFChunkSize(size int) GELFOption {
+	return func(c *gelfConfig) { c.chunkSize = size }
+}
+
+// WithGELF also sends entries to Graylog at addr as GELF 1.1 messages, over UDP unless WithGELFTCP or WithGELFTLS is used.
+// The message is the short_message, any stack trace the full_message, and level is the syslog severity.
+// Fields become additional fields: nested objects are flattened to dotted names, arrays and bools are sent as JSON strings,
+// and id, which GELF reserves, is renamed to id_.
+func WithGELF(addr string, opts ...GELFOption) LoggerOption {
+	return func(args *PacketLogr) {
+		c := gelfConfig{addr: addr, chunkSize: gelfDefaultChunkSize, network: newNetSinkConfig(nil)}
+		for _, opt := range opts {
+			opt(&c)
+		}
+		err := c.network.validate()
+		if addr == "" {
+			err = multierr.Append(err, errors.New("address must not be empty"))
+		}
+		if !c.tcp && c.network.tlsConfig() != nil {
+			err = multierr.Append(err, errors.New("TLS and client certificates need TCP, see WithGELFTCP"))
+		}
+		if !c.tcp && c.chunkSize <= gelfChunkHeaderSize {
+			err = multierr.Append(err, errors.Errorf("chunk size must be > %d, got: %d", gelfChunkHeaderSize, c.chunkSize))
+		}
+		if err != nil {
+			args.errs = multierr.Append(args.errs, errors.WithMessage(err, "WithGELF"))
+			return
+		}
+		args.newSinks = append(args.newSinks, c.newSink)
+	}
+}
+
+func (c gelfConfig) newSink(zc zap.Config, _ func(zapcore.WriteSyncer) zapcore.WriteSyncer) (zapcore.Core, io.Closer, error) {
+	hostname, _ := os.Hostname()
+	network := "udp"
+	if c.tcp {
+		network = "tcp"
+	}
+	s, err := newNetSink("GELF", c.network, c.network.dialer(network, c.addr), c.send)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &gelfCore{LevelEnabler: zc.Level, s: s, config: c, host: hostname}, s, nil
+}
+
+// gelfCore turns entries into GELF messages
+type gelfCore struct {
+	zapcore.LevelEnabler
+	s      *netSink
+	config gelfConfig
+	host   string
+	fields []zapcore.Field
+}
+
+func (c *gelfCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(append([]zapcore.Field{}, c.fields...), fields...)
+	return &clone
+}
+
+func (c *gelfCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *gelfCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	b, err := json.Marshal(c.message(ent, fields))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode GELF message")
+	}
+	// check here as the sink would otherwise keep trying to send it
+	if !c.config.tcp {
+		if chunks := gelfChunkCount(len(b), c.config.chunkSize); chunks > gelfMaxChunks {
+			return errors.Errorf("GELF message of %d bytes needs %d chunks, the most allowed is %d", len(b), chunks, gelfMaxChunks)
+		}
+	}
+	return c.s.write(b)
+}
+
+func (c *gelfCore) Sync() error { return nil }
+
+func (c *gelfCore) message(ent zapcore.Entry, fields []zapcore.Field) map[string]interface{} {
+	msg := map[string]interface{}{
+		"version":       "1.1",
+		"host":          c.host,
+		"short_message": ent.Message,
+		"timestamp":     float64(ent.Time.UnixNano()) / 1e9,
+		"level":         syslogSeverity(ent.Level),
+	}
+	if ent.Stack != "" {
+		msg["full_message"] = ent.Stack
+	}
+	if ent.LoggerName != "" {
+		msg["_logger"] = ent.LoggerName
+	}
+	if ent.Caller.Defined {
+		msg["_caller"] = ent.Caller.TrimmedPath()
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	addGELFFields(msg, "", enc.Fields)
+	return msg
+}
+
+// addGELFFields adds fields to msg as additional fields, flattening nested objects into dotted names
+func addGELFFields(msg map[string]interface{}, prefix string, fields map[string]interface{}) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		key := gelfInvalidKeyChars.ReplaceAllString(prefix+k, "_")
+		if nested, ok := fields[k].(map[string]interface{}); ok {
+			addGELFFields(msg, key+".", nested)
+			continue
+		}
+		if key == "id" {
+			key = "id_"
+		}
+		msg["_"+key] = gelfValue(fields[k])
+	}
+}
+
+// gelfValue converts v to a string unless it is a number, the only other type GELF allows
+func gelfValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string, float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// send writes msg as UDP chunks, or null byte delimited over TCP
+func (c gelfConfig) send(conn net.Conn, msg []byte) error {
+	if c.tcp {
+		_, err := conn.Write(append(msg, 0))
+		return err
+	}
+	if len(msg) <= c.chunkSize {
+		_, err := conn.Write(msg)
+		return err
+	}
+	chunks, err := gelfChunks(msg, c.chunkSize)
+	if err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		if _, err := conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gelfChunkCount is how many chunks of size bytes a message of length bytes is split into
+func gelfChunkCount(length, size int) int {
+	dataSize := size - gelfChunkHeaderSize
+	return (length + dataSize - 1) / dataSize
+}
+
+// gelfChunks splits msg into chunks of at most size bytes, each starting with the chunk header
+func gelfChunks(msg []byte, size int) ([][]byte, error) {
+	dataSize := size - gelfChunkHeaderSize
+	count := gelfChunkCount(len(msg), size)
+	if count > gelfMaxChunks {
+		return nil, errors.Errorf("GELF message of %d bytes needs %d chunks, the most allowed is %d", len(msg), count, gelfMaxChunks)
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, errors.Wrap(err, "failed to generate GELF message ID")
+	}
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * dataSize
+		if end > len(msg) {
+			end = len(msg)
+		}
+		var chunk bytes.Buffer
+		chunk.Write([]byte{0x1e, 0x0f})
+		chunk.Write(id)
+		chunk.Write([]byte{byte(i), byte(count)})
+		chunk.Write(msg[i*dataSize : end])
+		chunks = append(chunks, chunk.Bytes())
+	}
+	return chunks, nil
+}
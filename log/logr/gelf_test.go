@@ -0,0 +1,132 @@
+package logr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPacketLogrGELFUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	pl, err := New(WithGELF(conn.LocalAddr().String()), WithOutputPaths([]string{os.DevNull}), WithServiceName("gelf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pl.Close(context.Background())
+	pl.Named("db").Error(nil, "gelf message", "id", 7, "ok", true, "req", map[string]interface{}{"path": "/x", "tags": []string{"a"}})
+
+	buf := make([]byte, 65536)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg map[string]interface{}
+	if err := json.Unmarshal(buf[:n], &msg); err != nil {
+		t.Fatal(err)
+	}
+	for k, want := range map[string]interface{}{
+		"version":       "1.1",
+		"short_message": "gelf message",
+		"level":         float64(3),
+		"_logger":       "db",
+		"_service":      "gelf",
+		"_component":    "db",
+		"_id_":          float64(7),
+		"_ok":           "true",
+		"_req.path":     "/x",
+		"_req.tags":     `["a"]`,
+	} {
+		if msg[k] != want {
+			t.Fatalf("expected %v to be: %v, got: %v in %v", k, want, msg[k], msg)
+		}
+	}
+	if !strings.HasPrefix(msg["_caller"].(string), "logr/gelf_test.go:") {
+		t.Fatalf("expected the caller, got: %v", msg["_caller"])
+	}
+}
+
+func TestGELFChunks(t *testing.T) {
+	msg := bytes.Repeat([]byte("x"), 100)
+	chunks, err := gelfChunks(msg, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 4 {
+		t.Fatalf("expected 4 chunks, got: %d", len(chunks))
+	}
+	var joined []byte
+	for i, chunk := range chunks {
+		if len(chunk) > 42 {
+			t.Fatalf("chunk %d is %d bytes", i, len(chunk))
+		}
+		if chunk[0] != 0x1e || chunk[1] != 0x0f || chunk[10] != byte(i) || chunk[11] != 4 {
+			t.Fatalf("unexpected chunk header: %v", chunk[:12])
+		}
+		if !bytes.Equal(chunk[2:10], chunks[0][2:10]) {
+			t.Fatal("expected every chunk to have the same message ID")
+		}
+		joined = append(joined, chunk[12:]...)
+	}
+	if !bytes.Equal(joined, msg) {
+		t.Fatalf("expected the chunks to add up to the message, got: %s", joined)
+	}
+	if _, err := gelfChunks(bytes.Repeat([]byte("x"), 200*30), 42); err == nil {
+		t.Fatal("expected an error for too many chunks")
+	}
+}
+
+func TestPacketLogrGELFTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	messages := make(chan string)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			msg, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			messages <- msg
+		}
+	}()
+
+	pl, err := New(WithGELF(ln.Addr().String(), WithGELFTCP()), WithOutputPaths([]string{os.DevNull}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pl.Close(context.Background())
+	pl.Info("over tcp")
+	if got := <-messages; !strings.Contains(got, `"short_message":"over tcp"`) || !strings.HasSuffix(got, "\x00") {
+		t.Fatalf("expected a null delimited message, got: %q", got)
+	}
+}
+
+func TestPacketLogrGELFInvalid(t *testing.T) {
+	_, err := New(WithGELF("", WithGELFChunkSize(10)))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"WithGELF: address must not be empty", "chunk size must be > 12"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, err)
+		}
+	}
+}
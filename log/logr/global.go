@@ -0,0 +1,34 @@
+package logr
+
+import (
+	"log"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// SetAsGlobal makes this logger the one used by zap.L and zap.S, redirects output from the standard library's log package to it at info level,
+// and makes it the fallback of FromContext, see SetAsFallback. klog is redirected with klogredirect.Redirect.
+// Libraries that log using any of those will then write structured lines through the same pipeline.
+// The returned func restores the previous globals.
+func (p *PacketLogr) SetAsGlobal() func() {
+	undoGlobals := zap.ReplaceGlobals(p.zap)
+	undoStdLog := zap.RedirectStdLog(p.zap)
+	undoFallback := p.SetAsFallback()
+	return func() {
+		undoFallback()
+		undoStdLog()
+		undoGlobals()
+	}
+}
+
+// StdLogger returns a *log.Logger that writes to this logger at level, for libraries that only accept a *log.Logger,
+// such as http.Server.ErrorLog. See WithLogLevel for the level names, trace is not supported.
+func (p *PacketLogr) StdLogger(level string) (*log.Logger, error) {
+	zLevel, err := parseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	l, err := zap.NewStdLogAt(p.zap, zLevel)
+	return l, errors.Wrap(err, "failed to create standard logger")
+}
@@ -0,0 +1,57 @@
+package logr
+
+import (
+	"log"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestPacketLogrSetAsGlobal(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		pl, err := New(WithServiceName("global"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		restore := pl.SetAsGlobal()
+		zap.L().Info("from zap global")
+		zap.S().Infow("from sugared global", "hello", "world")
+		log.Print("from stdlib log")
+		restore()
+		zap.L().Info("after restore")
+	})
+	for _, want := range []string{`"msg":"from zap global"`, `"msg":"from sugared global","service":"global","hello":"world"`, `"msg":"from stdlib log"`} {
+		if !strings.Contains(capturedOutput, want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+		}
+	}
+	if strings.Contains(capturedOutput, "after restore") {
+		t.Fatalf("expected globals to be restored, got: %v", capturedOutput)
+	}
+}
+
+func TestPacketLogrStdLogger(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		pl, err := New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		l, err := pl.StdLogger("warn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Printf("tls handshake error from %s", "10.0.0.1")
+
+		if _, err := pl.StdLogger("trace"); err == nil {
+			t.Fatal("expected an error for trace level")
+		}
+		if _, err := pl.StdLogger("bogus"); err == nil {
+			t.Fatal("expected an error for an unknown level")
+		}
+	})
+	want := `"level":"warn"`
+	if !strings.Contains(capturedOutput, want) || !strings.Contains(capturedOutput, `"msg":"tls handshake error from 10.0.0.1"`) {
+		t.Fatalf("expected a warn line, got: %v", capturedOutput)
+	}
+}
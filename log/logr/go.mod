@@ -1,12 +1,26 @@
 module github.com/packethost/pkg/log/logr
 
-go 1.15
+go 1.21
 
 require (
 	github.com/go-logr/logr v0.2.1
 	github.com/go-logr/zapr v0.2.0
-	github.com/jacobweinstock/rollzap v0.1.3
+	github.com/klauspost/compress v1.17.0
 	github.com/pkg/errors v0.9.1
 	github.com/rollbar/rollbar-go v1.2.0
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+	go.uber.org/multierr v1.6.0
 	go.uber.org/zap v1.16.0
+	golang.org/x/sys v0.18.0
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1
+)
+
+require (
+	github.com/stretchr/testify v1.8.4 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	go.uber.org/atomic v1.9.0 // indirect
+	golang.org/x/lint v0.0.0-20210508222113-6edffad5e616 // indirect
+	golang.org/x/tools v0.6.0 // indirect
+	gopkg.in/yaml.v2 v2.3.0 // indirect
+	honnef.co/go/tools v0.1.3 // indirect
 )
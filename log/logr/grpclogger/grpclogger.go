@@ -0,0 +1,52 @@
+// Package grpclogger writes the internal logs of gRPC with a logr.PacketLogr:
+//
+//	grpclog.SetLoggerV2(grpclogger.NewLoggerV2(l))
+package grpclogger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/packethost/pkg/log/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/grpclog"
+)
+
+// grpcLogger adapts a sugared zap logger to grpclog.LoggerV2
+type grpcLogger struct {
+	sugar *zap.SugaredLogger
+	core  zapcore.Core
+}
+
+// NewLoggerV2 returns a grpclog.LoggerV2 that writes gRPC's internal logs to p as entries named "grpc" at the matching levels.
+// V(l) reports whether zap level -l is enabled, the same mapping logr's V uses, so V(2) chatter only shows at trace.
+func NewLoggerV2(p *logr.PacketLogr) grpclog.LoggerV2 {
+	l := p.Zap().Named("grpc").WithOptions(zap.AddCallerSkip(1))
+	return &grpcLogger{sugar: l.Sugar(), core: l.Core()}
+}
+
+// sprintln formats like fmt.Sprintln without the trailing newline
+func sprintln(args []interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
+
+func (g *grpcLogger) Info(args ...interface{})                 { g.sugar.Info(args...) }
+func (g *grpcLogger) Infoln(args ...interface{})               { g.sugar.Info(sprintln(args)) }
+func (g *grpcLogger) Infof(format string, args ...interface{}) { g.sugar.Infof(format, args...) }
+
+func (g *grpcLogger) Warning(args ...interface{})                 { g.sugar.Warn(args...) }
+func (g *grpcLogger) Warningln(args ...interface{})               { g.sugar.Warn(sprintln(args)) }
+func (g *grpcLogger) Warningf(format string, args ...interface{}) { g.sugar.Warnf(format, args...) }
+
+func (g *grpcLogger) Error(args ...interface{})                 { g.sugar.Error(args...) }
+func (g *grpcLogger) Errorln(args ...interface{})               { g.sugar.Error(sprintln(args)) }
+func (g *grpcLogger) Errorf(format string, args ...interface{}) { g.sugar.Errorf(format, args...) }
+
+func (g *grpcLogger) Fatal(args ...interface{})                 { g.sugar.Fatal(args...) }
+func (g *grpcLogger) Fatalln(args ...interface{})               { g.sugar.Fatal(sprintln(args)) }
+func (g *grpcLogger) Fatalf(format string, args ...interface{}) { g.sugar.Fatalf(format, args...) }
+
+func (g *grpcLogger) V(l int) bool {
+	return g.core.Enabled(zapcore.Level(-l))
+}
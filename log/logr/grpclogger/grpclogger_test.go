@@ -0,0 +1,37 @@
+package grpclogger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+)
+
+func TestNewLoggerV2(t *testing.T) {
+	pl, logged := logtest.New(t, logr.WithLogLevel("debug"))
+	g := NewLoggerV2(pl)
+	g.Infoln("transport:", "loopyWriter exiting")
+	g.Warningf("resolver returned %d addresses", 0)
+	g.Error("connection ", "refused")
+	if !g.V(0) || !g.V(1) {
+		t.Fatal("expected V(0) and V(1) to be enabled at debug")
+	}
+	if g.V(2) {
+		t.Fatal("expected V(2) to be disabled at debug")
+	}
+	capturedOutput := logged()
+	for _, want := range []string{
+		`"level":"info","ts":`,
+		`"logger":"grpc","caller":"grpclogger/grpclogger_test.go:`,
+		`"msg":"transport: loopyWriter exiting"`,
+		`"level":"warn"`,
+		`"msg":"resolver returned 0 addresses"`,
+		`"level":"error"`,
+		`"msg":"connection refused"`,
+	} {
+		if !strings.Contains(capturedOutput, want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Package health serves the /livez and /readyz endpoints of Kubernetes probes from named checks:
+//
+//	h := health.New(logger)
+//	h.AddReadinessCheck("db", db.PingContext)
+//	http.Handle("/livez", h.LivenessHandler())
+//	http.Handle("/readyz", h.ReadinessHandler())
+//
+// The results of the checks are cached, so probes and load balancers checking often don't overload the dependencies,
+// and each check is given a timeout. A check starting or stopping to fail is logged.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	packetlogr "github.com/packethost/pkg/log/logr"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultTimeout  = 5 * time.Second
+	defaultCacheTTL = time.Second
+)
+
+// Check returns nil when what it checks is healthy, it should return once ctx is done
+type Check func(ctx context.Context) error
+
+// Option customizes New
+type Option func(*Health)
+
+// WithTimeout gives each check timeout to return instead of 5s, a check that hasn't returned by then fails
+func WithTimeout(timeout time.Duration) Option {
+	return func(h *Health) { h.timeout = timeout }
+}
+
+// WithCacheTTL reuses the result of a check for ttl instead of 1s, 0 runs the checks for every request
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(h *Health) { h.ttl = ttl }
+}
+
+// Health runs the liveness and readiness checks, see New
+type Health struct {
+	logger  logr.Logger
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu        sync.RWMutex
+	liveness  []*check
+	readiness []*check
+}
+
+// New returns a Health without checks, so both handlers report healthy until checks are added
+func New(p *packetlogr.PacketLogr, opts ...Option) *Health {
+	h := &Health{logger: p.Named("health"), timeout: defaultTimeout, ttl: defaultCacheTTL}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// AddLivenessCheck adds a check of whether the service is working at all, the service is restarted when it fails.
+// Liveness checks are also readiness checks.
+func (h *Health) AddLivenessCheck(name string, fn Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.liveness = append(h.liveness, &check{name: name, kind: "liveness", fn: fn})
+}
+
+// AddReadinessCheck adds a check of whether the service can serve requests, it is taken out of the load balancers while it fails,
+// such as for a database it depends on
+func (h *Health) AddReadinessCheck(name string, fn Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readiness = append(h.readiness, &check{name: name, kind: "readiness", fn: fn})
+}
+
+// LivenessHandler serves the results of the liveness checks, with a 503 when any fails
+func (h *Health) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.mu.RLock()
+		checks := h.liveness
+		h.mu.RUnlock()
+		h.serve(w, r, checks)
+	})
+}
+
+// ReadinessHandler serves the results of the liveness and readiness checks, with a 503 when any fails
+func (h *Health) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.mu.RLock()
+		checks := append(append([]*check{}, h.liveness...), h.readiness...)
+		h.mu.RUnlock()
+		h.serve(w, r, checks)
+	})
+}
+
+// Result is the result of a check, as served by the handlers
+type Result struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Response is what the handlers serve, the status is ok when every check is
+type Response struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+const (
+	statusOK     = "ok"
+	statusFailed = "failed"
+)
+
+func (h *Health) serve(w http.ResponseWriter, r *http.Request, checks []*check) {
+	resp := Response{Status: statusOK, Checks: make(map[string]Result, len(checks))}
+	results := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = c.run(r.Context(), h)
+		}(i, c)
+	}
+	wg.Wait()
+	for i, c := range checks {
+		result := Result{Status: statusOK}
+		if err := results[i]; err != nil {
+			resp.Status, result = statusFailed, Result{Status: statusFailed, Error: err.Error()}
+		}
+		resp.Checks[c.name] = result
+	}
+
+	status := http.StatusOK
+	if resp.Status != statusOK {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// check is a named Check with its cached result
+type check struct {
+	name string
+	kind string
+	fn   Check
+
+	mu        sync.Mutex
+	checked   bool
+	checkedAt time.Time
+	err       error
+}
+
+// run returns the cached result of the check, or runs it when the result is older than the ttl. Concurrent callers wait
+// for the same run.
+func (c *check) run(ctx context.Context, h *Health) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.checked && time.Since(c.checkedAt) < h.ttl {
+		return c.err
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	start := time.Now()
+	err := c.call(timeoutCtx)
+	// the request going away isn't a result of the check
+	if ctx.Err() != nil {
+		return err
+	}
+
+	kvs := []interface{}{"check", c.name, "kind", c.kind, "duration", time.Since(start)}
+	switch {
+	case err != nil && (!c.checked || c.err == nil):
+		packetlogr.Warn(h.logger, "health check failing", append(kvs, "error", err.Error())...)
+	case err == nil && c.checked && c.err != nil:
+		h.logger.Info("health check recovered", append(kvs, "previous_error", c.err.Error())...)
+	}
+	c.checked, c.checkedAt, c.err = true, time.Now(), err
+	return err
+}
+
+// call calls the check, it fails when it panics or hasn't returned once ctx is done
+func (c *check) call(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				done <- errors.Errorf("panic: %v", v)
+			}
+		}()
+		done <- c.fn(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "check timed out")
+	}
+}
@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/pkg/errors"
+)
+
+func TestHealth(t *testing.T) {
+	path := t.TempDir() + "/log"
+	p, err := logr.New(logr.WithOutputPaths([]string{path}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(p, WithTimeout(50*time.Millisecond), WithCacheTTL(time.Hour))
+
+	var dbUp atomic.Bool
+	var calls atomic.Int32
+	h.AddLivenessCheck("goroutines", func(context.Context) error { return nil })
+	h.AddReadinessCheck("db", func(context.Context) error {
+		calls.Add(1)
+		if !dbUp.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	h.AddReadinessCheck("slow", func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	get := func(handler http.Handler) (int, Response) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		var resp Response
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return w.Code, resp
+	}
+
+	if status, resp := get(h.LivenessHandler()); status != http.StatusOK || resp.Status != "ok" || len(resp.Checks) != 1 {
+		t.Fatalf("expected livez to be ok with only the liveness check, got: %d %v", status, resp)
+	}
+	status, resp := get(h.ReadinessHandler())
+	if status != http.StatusServiceUnavailable || resp.Status != "failed" || resp.Checks["db"].Error != "connection refused" ||
+		!strings.Contains(resp.Checks["slow"].Error, "timed out") || resp.Checks["goroutines"].Status != "ok" {
+		t.Fatalf("expected readyz to fail with the db and slow checks, got: %d %v", status, resp)
+	}
+	get(h.ReadinessHandler())
+	if calls.Load() != 1 {
+		t.Fatalf("expected the result of the check to be cached, got: %d calls", calls.Load())
+	}
+
+	h2 := New(p, WithCacheTTL(0))
+	h2.AddReadinessCheck("db", h.readiness[0].fn)
+	get(h2.ReadinessHandler())
+	dbUp.Store(true)
+	if status, _ := get(h2.ReadinessHandler()); status != http.StatusOK {
+		t.Fatalf("expected readyz to recover, got: %d", status)
+	}
+	_ = p.Close(context.Background())
+
+	log, _ := os.ReadFile(path)
+	for _, want := range []string{
+		`"msg":"health check failing","service":"not/set","component":"health","check":"db","kind":"readiness"`,
+		`"error":"connection refused"`,
+		`"msg":"health check recovered"`,
+	} {
+		if !strings.Contains(string(log), want) {
+			t.Fatalf("expected %s to be logged, got: %s", want, log)
+		}
+	}
+	if n := strings.Count(string(log), `"check":"db","kind":"readiness"`); n != 3 {
+		t.Fatalf("expected only the transitions to be logged, got: %s", log)
+	}
+}
@@ -0,0 +1,168 @@
+package logr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultHoneybadgerEndpoint     = "https://api.honeybadger.io"
+	defaultHoneybadgerFlushTimeout = 5 * time.Second
+)
+
+// HoneybadgerConfig is where and how error logs are reported to Honeybadger when WithEnableHoneybadger is set, see WithHoneybadgerConfig
+type HoneybadgerConfig struct {
+	// APIKey is the project's API key, when it is empty it is read from the HONEYBADGER_API_KEY environment variable
+	APIKey string
+	// Env is the environment notices are reported in, such as production or staging
+	Env string
+	// Revision is the version of the service notices are attributed to
+	Revision string
+	// ContextKeys renames fields in the notice's context, which holds all of the entry's fields, such as
+	// {"email": "user_email"} for the key Honeybadger shows the user's email from. user_id needs no renaming.
+	ContextKeys map[string]string
+	// Endpoint is the API endpoint, https://api.honeybadger.io when empty
+	Endpoint string
+	// FlushTimeout is how long Close waits for pending notices to be sent, 5s when 0
+	FlushTimeout time.Duration
+}
+
+// NewHoneybadgerConfig returns the config for reporting to Honeybadger with apiKey in env
+func NewHoneybadgerConfig(apiKey, env string) HoneybadgerConfig {
+	return HoneybadgerConfig{APIKey: apiKey, Env: env}
+}
+
+// WithEnableHoneybadger sends error logs to Honeybadger, along with or instead of the other error reporters.
+// Notices are sent from the background, Close waits for them to be sent.
+func WithEnableHoneybadger(enable bool) LoggerOption {
+	return func(args *PacketLogr) { args.enableHoneybadger = enable }
+}
+
+// WithHoneybadgerConfig customizes the Honeybadger details, see NewHoneybadgerConfig
+func WithHoneybadgerConfig(config HoneybadgerConfig) LoggerOption {
+	return func(args *PacketLogr) { args.honeybadgerConfig = config }
+}
+
+// apiKey is APIKey, or else HONEYBADGER_API_KEY
+func (c HoneybadgerConfig) apiKey() string {
+	if c.APIKey != "" {
+		return c.APIKey
+	}
+	return getEnv("HONEYBADGER_API_KEY")
+}
+
+func (c HoneybadgerConfig) validate() error {
+	var err error
+	if c.apiKey() == "" {
+		err = multierr.Append(err, errors.New("honeybadger is enabled but no API key is set, set the config's APIKey or HONEYBADGER_API_KEY"))
+	}
+	if c.FlushTimeout < 0 {
+		err = multierr.Append(err, errors.Errorf("honeybadger flush timeout must be >= 0, got: %s", c.FlushTimeout))
+	}
+	return err
+}
+
+// honeybadgerReporter builds the Honeybadger notices for the entries that are reported
+type honeybadgerReporter struct {
+	config   HoneybadgerConfig
+	service  string
+	apiKey   string
+	url      string
+	hostname string
+	*httpReporter
+}
+
+func (c HoneybadgerConfig) setupHoneybadger(service string, spool *diskSpool) *honeybadgerReporter {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = defaultHoneybadgerEndpoint
+	}
+	r := &honeybadgerReporter{config: c, service: service, apiKey: c.apiKey(), url: endpoint + "/v1/notices", httpReporter: newHTTPReporter("honeybadger", spool)}
+	r.hostname, _ = os.Hostname()
+	return r
+}
+
+// Report queues the notice for the entry to be sent
+func (r *honeybadgerReporter) Report(ent zapcore.Entry, fields []zapcore.Field) error {
+	fieldMap, err := reportFields(fields)
+	noticeContext := make(map[string]interface{}, len(fieldMap))
+	for k, v := range fieldMap {
+		if renamed, ok := r.config.ContextKeys[k]; ok {
+			k = renamed
+		}
+		noticeContext[k] = v
+	}
+	class, message := "log", ent.Message
+	if err != nil {
+		class, message = fmt.Sprintf("%T", errors.Cause(err)), ent.Message+": "+err.Error()
+	}
+	// the stack of where the error was made, or else where it was logged
+	var backtrace []map[string]string
+	for _, frame := range errorStack(err) {
+		backtrace = append(backtrace, map[string]string{
+			"number": strconv.Itoa(frame.Line),
+			"file":   frame.File,
+			"method": frame.Function,
+		})
+	}
+	if backtrace == nil && ent.Caller.Defined {
+		backtrace = append(backtrace, map[string]string{
+			"number": strconv.Itoa(ent.Caller.Line),
+			"file":   ent.Caller.TrimmedPath(),
+			"method": ent.Caller.Function,
+		})
+	}
+
+	body, jerr := json.Marshal(map[string]interface{}{
+		"notifier": map[string]string{"name": "packethost/pkg logr", "version": "1", "url": "https://github.com/packethost/pkg"},
+		"error": map[string]interface{}{
+			"class":     class,
+			"message":   message,
+			"backtrace": backtrace,
+			"tags":      []string{ent.Level.String()},
+		},
+		"request": map[string]interface{}{
+			"context":   noticeContext,
+			"component": ent.LoggerName,
+		},
+		"server": map[string]interface{}{
+			"environment_name": r.config.Env,
+			"hostname":         r.hostname,
+			"project_root":     r.service,
+			"revision":         r.config.Revision,
+			"time":             ent.Time.UTC().Format(time.RFC3339),
+		},
+	})
+	if jerr != nil {
+		return errors.Wrap(jerr, "failed to encode honeybadger notice")
+	}
+	req, rerr := http.NewRequest(http.MethodPost, r.url, bytes.NewReader(body))
+	if rerr != nil {
+		return errors.Wrap(rerr, "failed to create honeybadger request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-API-Key", r.apiKey)
+	r.send(req)
+	return nil
+}
+
+// Flush waits for the pending notices to be sent, giving up after the flush timeout or when ctx is done
+func (r *honeybadgerReporter) Flush(ctx context.Context) error {
+	ctx, cancel := reporterFlushContext(ctx, r.config.FlushTimeout, defaultHoneybadgerFlushTimeout)
+	defer cancel()
+	return r.httpReporter.flush(ctx)
+}
+
+// Close sends the pending notices and stops sending more
+func (r *honeybadgerReporter) Close() error { return r.close(context.Background()) }
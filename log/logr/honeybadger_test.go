@@ -0,0 +1,64 @@
+package logr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPacketLogrHoneybadger(t *testing.T) {
+	url, requests, bodies := reporterServer(t, http.StatusCreated)
+	config := NewHoneybadgerConfig("apikey", "staging")
+	config.Revision, config.Endpoint, config.ContextKeys = "0123abc", url, map[string]string{"email": "user_email"}
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithServiceName("github.com/packethost/pkg"),
+		WithEnableHoneybadger(true), WithHoneybadgerConfig(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.WithName("provisioner").WithValues("user_id", "u1", "email", "u@example.com").Error(errors.New("boom"), "provisioning failed")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-requests
+	if req.URL.Path != "/v1/notices" || req.Header.Get("X-API-Key") != "apikey" {
+		t.Fatalf("expected a notice with the API key, got: %v %v", req.URL, req.Header)
+	}
+	var notice struct {
+		Error struct {
+			Class     string              `json:"class"`
+			Message   string              `json:"message"`
+			Backtrace []map[string]string `json:"backtrace"`
+		} `json:"error"`
+		Request struct {
+			Context   map[string]interface{} `json:"context"`
+			Component string                 `json:"component"`
+		} `json:"request"`
+		Server map[string]string `json:"server"`
+	}
+	if err := json.Unmarshal(<-bodies, &notice); err != nil {
+		t.Fatal(err)
+	}
+	if notice.Error.Class != "*errors.errorString" || notice.Error.Message != "provisioning failed: boom" ||
+		len(notice.Error.Backtrace) != 1 || !strings.HasPrefix(notice.Error.Backtrace[0]["file"], "logr/honeybadger_test.go") {
+		t.Fatalf("expected the error, got: %+v", notice.Error)
+	}
+	if c := notice.Request.Context; c["user_id"] != "u1" || c["user_email"] != "u@example.com" || c["email"] != nil || notice.Request.Component != "provisioner" {
+		t.Fatalf("expected the fields as the context, got: %+v", notice.Request)
+	}
+	if s := notice.Server; s["environment_name"] != "staging" || s["revision"] != "0123abc" || s["project_root"] != "github.com/packethost/pkg" {
+		t.Fatalf("expected the server details, got: %v", s)
+	}
+}
+
+func TestPacketLogrHoneybadgerInvalid(t *testing.T) {
+	setenv(t, "HONEYBADGER_API_KEY", "")
+	_, err := New(WithEnableHoneybadger(true), WithHoneybadgerConfig(HoneybadgerConfig{FlushTimeout: -1}))
+	if err == nil || !strings.Contains(err.Error(), "honeybadger is enabled but no API key is set") || !strings.Contains(err.Error(), "honeybadger flush timeout must be >= 0") {
+		t.Fatalf("expected the config errors, got: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package logr
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// hookQueueSize is how many entries each hook has queued before the entries are dropped for it
+const hookQueueSize = 1000
+
+// hookRunner gives every hook a queue and a goroutine calling it with the entries queued, so the entry being written never
+// waits on the hooks, and a slow hook only holds up its own queue. Entries are dropped for a hook when its queue is full.
+type hookRunner struct {
+	timeout time.Duration
+	queues  []*queue[zapcore.Entry]
+	err     lastErr
+}
+
+func newHookRunner(fns []func(zapcore.Entry) error, timeout time.Duration) *hookRunner {
+	h := &hookRunner{timeout: timeout, queues: make([]*queue[zapcore.Entry], len(fns))}
+	for i, fn := range fns {
+		fn := fn
+		h.queues[i] = newQueue[zapcore.Entry](hookQueueSize)
+		h.queues[i].start(func(entries <-chan zapcore.Entry) {
+			for ent := range entries {
+				if err := fn(ent); err != nil {
+					h.err.set(err)
+				}
+			}
+		})
+	}
+	return h
+}
+
+// hooks queues each entry that is written for every hook, waiting at most timeout on a full queue before dropping the entry
+// for that hook. The dropped entries and the last error returned by a hook since the previous entry are reported by zap
+// to the error output, they never stop the entry being written. Once closed the entries aren't passed to the hooks.
+func hooks(h *hookRunner) zap.Option {
+	return zap.Hooks(func(ent zapcore.Entry) error {
+		var dropped int
+		for _, q := range h.queues {
+			if q.put(ent, h.timeout) == queueFull {
+				q.dropped.Add(1)
+				dropped++
+			}
+		}
+		err := h.err.take()
+		if dropped > 0 {
+			err = multierr.Append(err, errors.Errorf("%d of %d hook queues are full, dropped the entry for them", dropped, len(h.queues)))
+		}
+		return errors.Wrap(err, "entry hooks failed")
+	})
+}
+
+// dropped is how many entries haven't been passed to a hook, counted once for each hook
+func (h *hookRunner) dropped() uint64 {
+	var dropped uint64
+	for _, q := range h.queues {
+		dropped += q.dropped.Load()
+	}
+	return dropped
+}
+
+// close waits for the hooks to be called with the entries queued, giving up when ctx is done,
+// the entries written afterwards aren't passed to the hooks
+func (h *hookRunner) close(ctx context.Context) error {
+	for _, q := range h.queues {
+		q.close()
+	}
+	for _, q := range h.queues {
+		select {
+		case <-q.stopped:
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "gave up waiting for the entry hooks")
+		}
+	}
+	return errors.Wrap(h.err.take(), "entry hooks failed")
+}
+
+// DroppedHookEntries returns how many entries haven't been passed to a hook because its queue was full, see WithHook.
+// An entry dropped for more than one hook is counted once for each.
+func (p *PacketLogr) DroppedHookEntries() uint64 {
+	if p.hookRunner == nil {
+		return 0
+	}
+	return p.hookRunner.dropped()
+}
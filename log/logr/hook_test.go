@@ -0,0 +1,119 @@
+package logr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestPacketLogrHooks(t *testing.T) {
+	var infos, errs int32
+	capturedOutput := captureOutput(func() {
+		l, _, err := NewPacketLogr(
+			WithHook(func(ent zapcore.Entry) error {
+				atomic.AddInt32(&infos, 1)
+				return nil
+			}),
+			WithHook(func(ent zapcore.Entry) error {
+				if ent.Level >= zapcore.ErrorLevel {
+					atomic.AddInt32(&errs, 1)
+				}
+				return nil
+			}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Info("hooked message")
+		l.V(1).Info("filtered before the hooks")
+		l.Error(errors.New("boom"), "hooked error")
+		if err := l.(*PacketLogr).Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if got := atomic.LoadInt32(&infos); got != 2 {
+		t.Fatalf("expected the first hook to see 2 entries, got: %d", got)
+	}
+	if got := atomic.LoadInt32(&errs); got != 1 {
+		t.Fatalf("expected the second hook to see 1 error, got: %d", got)
+	}
+	if !strings.Contains(capturedOutput, `"msg":"hooked message"`) {
+		t.Fatalf("expected entries to be written, got: %v", capturedOutput)
+	}
+}
+
+func TestHooksDrops(t *testing.T) {
+	obs, logs := observer.New(zapcore.InfoLevel)
+	errorOutput := &bytes.Buffer{}
+	release := make(chan struct{})
+	var called atomic.Int32
+	h := newHookRunner([]func(zapcore.Entry) error{func(zapcore.Entry) error { <-release; called.Add(1); return nil }}, 0)
+	l := zap.New(obs, zap.ErrorOutput(zapcore.AddSync(errorOutput)), hooks(h))
+
+	start := time.Now()
+	// the blocked hook takes at most one entry, then its queue fills up
+	for i := 0; i < hookQueueSize+3; i++ {
+		l.Info("slow hook")
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Fatalf("expected the entries not to wait on the blocked hook, took: %s", took)
+	}
+	if logs.Len() != hookQueueSize+3 {
+		t.Fatalf("expected the entries to be written, got: %d", logs.Len())
+	}
+	if got := h.dropped(); got < 2 {
+		t.Fatalf("expected entries to be dropped, got: %d", got)
+	}
+	if want := "entry hooks failed: 1 of 1 hook queues are full, dropped the entry for them"; !strings.Contains(errorOutput.String(), want) {
+		t.Fatalf("expected error output to contain: %v, got: %v", want, errorOutput.String())
+	}
+
+	close(release)
+	if err := h.close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := int(called.Load()) + int(h.dropped()); got != hookQueueSize+3 {
+		t.Fatalf("expected every entry to be passed to the hook or dropped, got: %d", got)
+	}
+}
+
+func TestHooksErrors(t *testing.T) {
+	obs, _ := observer.New(zapcore.InfoLevel)
+	errorOutput := &bytes.Buffer{}
+	h := newHookRunner([]func(zapcore.Entry) error{func(zapcore.Entry) error { return errors.New("hook broke") }}, 0)
+	l := zap.New(obs, zap.ErrorOutput(zapcore.AddSync(errorOutput)), hooks(h))
+
+	// the error of an entry is reported with one of the entries that follow
+	for deadline := time.Now().Add(5 * time.Second); !strings.Contains(errorOutput.String(), "entry hooks failed: hook broke"); {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the hook error in the error output, got: %v", errorOutput.String())
+		}
+		l.Info("broken hook")
+		time.Sleep(time.Millisecond)
+	}
+	if err := h.close(context.Background()); err == nil || !strings.Contains(err.Error(), "hook broke") {
+		t.Fatalf("expected close to return the error of the last entry, got: %v", err)
+	}
+}
+
+func TestHooksCloseGivesUp(t *testing.T) {
+	obs, _ := observer.New(zapcore.InfoLevel)
+	release := make(chan struct{})
+	defer close(release)
+	h := newHookRunner([]func(zapcore.Entry) error{func(zapcore.Entry) error { <-release; return nil }}, 0)
+	l := zap.New(obs, hooks(h))
+	l.Info("blocked")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := h.close(ctx); err == nil || !strings.Contains(err.Error(), "gave up waiting for the entry hooks") {
+		t.Fatalf("expected close to give up, got: %v", err)
+	}
+}
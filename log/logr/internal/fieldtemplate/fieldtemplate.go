@@ -0,0 +1,95 @@
+// Package fieldtemplate fills in names, such as a NATS subject or an Elasticsearch index, from the entries of the sinks of the logr packages
+package fieldtemplate
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+)
+
+// Template is a name filled in from each entry.
+// Placeholders are in braces: {level} and {logger} are the entry's level and logger name, {date} is its UTC date as YYYY.MM.DD,
+// and any other name is the value of the string field with that key, from With or the entry. Missing values are "_".
+// The parsed template alternates literal text and placeholder names, so the names are the odd elements.
+type Template []string
+
+// Parse parses the template s
+func Parse(s string) (Template, error) {
+	if s == "" {
+		return nil, errors.New("template must not be empty")
+	}
+	var t Template
+	rest := s
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			start = len(rest)
+		}
+		if strings.IndexByte(rest[:start], '}') >= 0 {
+			return nil, errors.Errorf("unmatched } in %q", s)
+		}
+		if start == len(rest) {
+			return append(t, rest), nil
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, errors.Errorf("unmatched { in %q", s)
+		}
+		name := rest[start+1 : start+end]
+		if name == "" || strings.ContainsAny(name, "{ ") {
+			return nil, errors.Errorf("invalid placeholder %q in %q", name, s)
+		}
+		t = append(t, rest[:start], name)
+		rest = rest[start+end+1:]
+	}
+}
+
+// Values returns base with the string fields the placeholders refer to added, base is copied rather than changed
+func (t Template) Values(base map[string]string, fields []zapcore.Field) map[string]string {
+	values := base
+	copied := false
+	for i := 1; i < len(t); i += 2 {
+		for _, f := range fields {
+			if f.Key != t[i] || f.Type != zapcore.StringType {
+				continue
+			}
+			if !copied {
+				values = make(map[string]string, len(base)+1)
+				for k, v := range base {
+					values[k] = v
+				}
+				copied = true
+			}
+			values[f.Key] = f.String
+		}
+	}
+	return values
+}
+
+// Render fills in the placeholders from ent and values, each value is passed through clean
+func (t Template) Render(ent zapcore.Entry, values map[string]string, clean func(string) string) string {
+	var b strings.Builder
+	for i, part := range t {
+		if i%2 == 0 {
+			b.WriteString(part)
+			continue
+		}
+		var v string
+		switch part {
+		case "level":
+			v = ent.Level.String()
+		case "logger":
+			v = ent.LoggerName
+		case "date":
+			v = ent.Time.UTC().Format("2006.01.02")
+		default:
+			v = values[part]
+		}
+		if v == "" {
+			v = "_"
+		}
+		b.WriteString(clean(v))
+	}
+	return b.String()
+}
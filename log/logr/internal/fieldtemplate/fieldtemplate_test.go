@@ -0,0 +1,36 @@
+package fieldtemplate
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestTemplate(t *testing.T) {
+	tmpl, err := Parse("logs-{service}-{level}-{logger}-{date}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ent := zapcore.Entry{Level: zapcore.WarnLevel, LoggerName: "api", Time: time.Date(2020, 3, 4, 23, 0, 0, 0, time.FixedZone("", -3600))}
+	values := tmpl.Values(nil, []zapcore.Field{zap.String("service", "Billing"), zap.Int("level", 1), zap.String("other", "x")})
+	if got := tmpl.Render(ent, values, strings.ToLower); got != "logs-billing-warn-api-2020.03.05" {
+		t.Fatalf("expected the placeholders to be filled in, got: %v", got)
+	}
+	if got := tmpl.Render(zapcore.Entry{}, nil, strings.ToLower); got != "logs-_-info-_-0001.01.01" {
+		t.Fatalf("expected missing values to be _, got: %v", got)
+	}
+
+	base := map[string]string{"service": "billing"}
+	if tmpl.Values(base, []zapcore.Field{zap.String("service", "other")}); base["service"] != "billing" {
+		t.Fatalf("expected the base values not to change, got: %v", base)
+	}
+
+	for _, s := range []string{"", "logs-{service", "logs-service}", "logs-}{level}", "logs-{}"} {
+		if _, err := Parse(s); err == nil {
+			t.Fatalf("expected an error for %q", s)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Package logtest has the loggers the tests of the logr packages log with
+package logtest
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/packethost/pkg/log/logr"
+)
+
+// New returns a logger with opts writing to a file, and a func closing it and reading what was written to it
+func New(t *testing.T, opts ...logr.LoggerOption) (*logr.PacketLogr, func() string) {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), "out.log")
+	l, err := logr.New(append([]logr.LoggerOption{logr.WithOutputPaths([]string{out})}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, func() string {
+		t.Helper()
+		if err := l.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+}
+
+// NewEntries is New with a func returning the entries written, decoded
+func NewEntries(t *testing.T, opts ...logr.LoggerOption) (*logr.PacketLogr, func() []map[string]interface{}) {
+	t.Helper()
+	l, logged := New(t, opts...)
+	return l, func() []map[string]interface{} {
+		t.Helper()
+		var entries []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(logged()), "\n") {
+			if line == "" {
+				continue
+			}
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatal(err)
+			}
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+}
@@ -0,0 +1,113 @@
+package logr
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+)
+
+// RunIDKey is the field the ID of a job's run is logged as
+const RunIDKey = "run_id"
+
+// JobOption customizes NewJob
+type JobOption func(*Job)
+
+// WithSkipOverlappingRuns skips the runs that start while the previous one is still running, instead of running them
+// alongside it, they are still logged as overlapping
+func WithSkipOverlappingRuns() JobOption {
+	return func(j *Job) { j.skipOverlapping = true }
+}
+
+// Job logs the runs of a periodic job, such as one run by a cron scheduler or a ticker, see NewJob
+type Job struct {
+	p               *PacketLogr
+	name            string
+	fn              func(ctx context.Context) error
+	skipOverlapping bool
+
+	mu      sync.Mutex
+	running map[string]time.Time
+}
+
+// NewJob returns the Job named name running fn. Each run gets a new run_id, and the context fn is passed has a logger,
+// from FromContext, with the job and run_id. A "job started" entry is logged when a run starts, and "job finished" or
+// "job failed", with the duration, when it ends. Runs that panic are recovered from, and logged as errors with the stacktrace,
+// which reports them to the error reporters. A run that starts while the previous one is still running is logged
+// as a warning, as the job is taking longer than its schedule allows.
+func (p *PacketLogr) NewJob(name string, fn func(ctx context.Context) error, opts ...JobOption) *Job {
+	j := &Job{p: p, name: name, fn: fn, running: map[string]time.Time{}}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+// Run runs the job with a background context, it is the Run of the cron.Job interface of schedulers such as robfig/cron
+func (j *Job) Run() {
+	_ = j.RunContext(context.Background())
+}
+
+// RunContext runs the job with ctx, it returns the error of the run, or of its panic
+func (j *Job) RunContext(ctx context.Context) (err error) {
+	id := NewULID()
+	ctx = j.p.NewContextWithValues(ctx, "job", j.name, RunIDKey, id)
+	l := j.p.FromContext(ctx)
+
+	start := time.Now()
+	if !j.start(l, id, start) {
+		return nil
+	}
+	defer j.end(id)
+
+	l.Info("job started")
+	defer func() {
+		if v := recover(); v != nil {
+			if err, _ = v.(error); err == nil {
+				err = fmt.Errorf("%v", v)
+			}
+			err = errors.WithMessage(err, "panic")
+			l.Error(err, "job panicked", "duration", time.Since(start))
+		}
+	}()
+	if err = j.fn(ctx); err != nil {
+		l.Error(err, "job failed", "duration", time.Since(start))
+		return err
+	}
+	l.Info("job finished", "duration", time.Since(start))
+	return nil
+}
+
+// start records the run id, and warns when it overlaps the previous run, it is false when the run is skipped
+func (j *Job) start(l logr.Logger, id string, start time.Time) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(j.running) > 0 {
+		// the previous run is the latest of the ones still running
+		var prevID string
+		var prev time.Time
+		for runID, runStart := range j.running {
+			if runStart.After(prev) {
+				prevID, prev = runID, runStart
+			}
+		}
+		kvs := []interface{}{"previous_run_id", prevID, "previous_running_for", start.Sub(prev), "running", len(j.running)}
+		if j.skipOverlapping {
+			Warn(l, "job run skipped, the previous run is still running", kvs...)
+			return false
+		}
+		Warn(l, "job run overlaps the previous run, which is still running", kvs...)
+	}
+	j.running[id] = start
+	return true
+}
+
+// end records that the run id ended
+func (j *Job) end(id string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.running, id)
+}
@@ -0,0 +1,89 @@
+package logr
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestJob(t *testing.T) {
+	var runErr, panicErr error
+	out := captureOutput(func() {
+		l, err := New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.NewJob("sync-inventory", func(ctx context.Context) error {
+			l.FromContext(ctx).Info("syncing")
+			return nil
+		}).Run()
+		runErr = l.NewJob("sync-inventory", func(context.Context) error { return errors.New("inventory unavailable") }).RunContext(context.Background())
+		panicErr = l.NewJob("sync-inventory", func(context.Context) error { panic("boom") }).RunContext(context.Background())
+	})
+	if runErr == nil || runErr.Error() != "inventory unavailable" {
+		t.Fatalf("expected the error of the run, got: %v", runErr)
+	}
+	if panicErr == nil || panicErr.Error() != "panic: boom" {
+		t.Fatalf("expected the panic as the error of the run, got: %v", panicErr)
+	}
+	for _, want := range []string{
+		`"msg":"job started","service":"not/set","job":"sync-inventory","run_id":"`,
+		`"msg":"syncing","service":"not/set","job":"sync-inventory","run_id":"`,
+		`"msg":"job finished","service":"not/set","job":"sync-inventory","run_id":"`,
+		`"msg":"job failed","service":"not/set","job":"sync-inventory","run_id":"`,
+		`"error":"inventory unavailable"`,
+		`"msg":"job panicked"`,
+		`"error":"panic: boom"`,
+		`"stacktrace":"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %s to be logged, got: %v", want, out)
+		}
+	}
+}
+
+func TestJobOverlap(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		var opts []JobOption
+		if skip {
+			opts = append(opts, WithSkipOverlappingRuns())
+		}
+		var runs atomic.Int32
+		out := captureOutput(func() {
+			l, err := New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			started, release := make(chan struct{}), make(chan struct{})
+			job := l.NewJob("reap-devices", func(context.Context) error {
+				if runs.Add(1) == 1 {
+					close(started)
+					<-release
+				}
+				return nil
+			}, opts...)
+			done := make(chan struct{})
+			go func() {
+				job.Run()
+				close(done)
+			}()
+			<-started
+			job.Run()
+			close(release)
+			<-done
+		})
+
+		want, wantRuns := "job run overlaps the previous run, which is still running", int32(2)
+		if skip {
+			want, wantRuns = "job run skipped, the previous run is still running", 1
+		}
+		if !strings.Contains(out, `"level":"warn"`) || !strings.Contains(out, want) || !strings.Contains(out, `"previous_run_id":"`) {
+			t.Fatalf("expected %q to be logged as a warning, got: %v", want, out)
+		}
+		if runs.Load() != wantRuns {
+			t.Fatalf("expected %d runs, got: %d", wantRuns, runs.Load())
+		}
+	}
+}
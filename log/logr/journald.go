@@ -0,0 +1,199 @@
+package logr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// journaldSocket is where journald listens for the native protocol
+var journaldSocket = "/run/systemd/journal/socket"
+
+// journaldMaxKeyLength is the longest field name journald accepts
+const journaldMaxKeyLength = 64
+
+// WithJournald also sends entries to the systemd journal using its native protocol, so each field is a journal field that journalctl can filter on.
+// The message, level, logger name, and caller go in MESSAGE, PRIORITY, LOGGER, and CODE_FILE, CODE_LINE, and CODE_FUNC,
+// SYSLOG_IDENTIFIER is the name of the binary. Field names are upper cased with anything other than letters, digits, and underscores replaced by underscores,
+// so "request.id" can be matched with journalctl REQUEST_ID=abc. Values that aren't strings are JSON encoded.
+func WithJournald() LoggerOption {
+	return func(args *PacketLogr) { args.newSinks = append(args.newSinks, newJournaldSink) }
+}
+
+func newJournaldSink(c zap.Config, _ func(zapcore.WriteSyncer) zapcore.WriteSyncer) (zapcore.Core, io.Closer, error) {
+	w := &journaldWriter{socket: journaldSocket}
+	if err := w.connect(); err != nil {
+		return nil, nil, err
+	}
+	return &journaldCore{LevelEnabler: c.Level, w: w, identifier: filepath.Base(os.Args[0])}, w, nil
+}
+
+// journaldCore turns entries into journal fields
+type journaldCore struct {
+	zapcore.LevelEnabler
+	w          *journaldWriter
+	identifier string
+	fields     []zapcore.Field
+}
+
+func (c *journaldCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(append([]zapcore.Field{}, c.fields...), fields...)
+	return &clone
+}
+
+func (c *journaldCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *journaldCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var buf bytes.Buffer
+	appendJournalField(&buf, "MESSAGE", ent.Message)
+	appendJournalField(&buf, "PRIORITY", strconv.Itoa(syslogSeverity(ent.Level)))
+	appendJournalField(&buf, "SYSLOG_IDENTIFIER", c.identifier)
+	if ent.LoggerName != "" {
+		appendJournalField(&buf, "LOGGER", ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		appendJournalField(&buf, "CODE_FILE", ent.Caller.File)
+		appendJournalField(&buf, "CODE_LINE", strconv.Itoa(ent.Caller.Line))
+		if ent.Caller.Function != "" {
+			appendJournalField(&buf, "CODE_FUNC", ent.Caller.Function)
+		}
+	}
+	if ent.Stack != "" {
+		appendJournalField(&buf, "STACKTRACE", ent.Stack)
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	keys := make([]string, 0, len(enc.Fields))
+	for k := range enc.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		appendJournalField(&buf, journalKey(k), journalValue(enc.Fields[k]))
+	}
+	return c.w.write(buf.Bytes())
+}
+
+func (c *journaldCore) Sync() error { return nil }
+
+// journalKey makes key a valid journal field name
+func journalKey(key string) string {
+	b := make([]byte, 0, len(key))
+	for i := 0; i < len(key); i++ {
+		switch ch := key[i]; {
+		case ch >= 'a' && ch <= 'z':
+			b = append(b, ch-'a'+'A')
+		case ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+			b = append(b, ch)
+		default:
+			b = append(b, '_')
+		}
+	}
+	// names must start with a letter, leading underscores are reserved for journald's own fields
+	name := strings.TrimLeft(string(b), "_")
+	if name == "" || name[0] <= '9' {
+		name = "F" + name
+	}
+	if len(name) > journaldMaxKeyLength {
+		name = name[:journaldMaxKeyLength]
+	}
+	return name
+}
+
+func journalValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// appendJournalField writes a field using the native protocol, values with newlines are written with their length instead of KEY=value
+func appendJournalField(buf *bytes.Buffer, key, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(key)
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteString(key)
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journaldWriter sends each entry as a datagram, entries too big for a datagram are passed to journald in a file
+type journaldWriter struct {
+	socket string
+
+	mu   sync.Mutex
+	conn *net.UnixConn
+}
+
+func (w *journaldWriter) connect() error {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: w.socket, Net: "unixgram"})
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to journald")
+	}
+	w.conn = conn
+	return nil
+}
+
+func (w *journaldWriter) write(data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return err
+		}
+	}
+	_, err := w.conn.Write(data)
+	if err != nil && isMessageTooLong(err) {
+		err = sendJournalFile(w.conn, data)
+	}
+	return errors.Wrap(err, "failed to write to journald")
+}
+
+func (w *journaldWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
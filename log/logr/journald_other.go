@@ -0,0 +1,18 @@
+//go:build !linux
+
+package logr
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// isMessageTooLong is only needed on linux, where journald runs
+func isMessageTooLong(error) bool {
+	return false
+}
+
+func sendJournalFile(*net.UnixConn, []byte) error {
+	return errors.New("entries too big for a datagram are only supported on linux")
+}
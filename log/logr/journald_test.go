@@ -0,0 +1,73 @@
+package logr
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPacketLogrJournald(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	defer func(s string) { journaldSocket = s }(journaldSocket)
+	journaldSocket = socket
+
+	pl, err := New(WithJournald(), WithOutputPaths([]string{os.DevNull}), WithServiceName("journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pl.Close(context.Background())
+	pl.Named("db").Info("journald message", "request.id", "abc", "count", 3, "multi", "line one\nline two")
+
+	buf := make([]byte, 65536)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(buf[:n])
+	for _, want := range []string{
+		"MESSAGE=journald message\n",
+		"PRIORITY=6\n",
+		"SYSLOG_IDENTIFIER=" + filepath.Base(os.Args[0]) + "\n",
+		"LOGGER=db\n",
+		"CODE_FILE=",
+		"COMPONENT=db\n",
+		"SERVICE=journal\n",
+		"REQUEST_ID=abc\n",
+		"COUNT=3\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected to contain: %q, got: %q", want, got)
+		}
+	}
+	var multi bytes.Buffer
+	multi.WriteString("MULTI\n")
+	_ = binary.Write(&multi, binary.LittleEndian, uint64(len("line one\nline two")))
+	multi.WriteString("line one\nline two\n")
+	if !strings.Contains(got, multi.String()) {
+		t.Fatalf("expected the multi line value to be length prefixed, got: %q", got)
+	}
+}
+
+func TestJournalKey(t *testing.T) {
+	for key, want := range map[string]string{
+		"request.id":            "REQUEST_ID",
+		"_private":              "PRIVATE",
+		"1st":                   "F1ST",
+		"":                      "F",
+		strings.Repeat("a", 70): strings.Repeat("A", 64),
+	} {
+		if got := journalKey(key); got != want {
+			t.Fatalf("key %q: expected %q, got %q", key, want, got)
+		}
+	}
+}
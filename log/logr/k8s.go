@@ -0,0 +1,58 @@
+package logr
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// WithK8sConventions logs the way Kubernetes components do with their json format, following the Kubernetes
+// structured logging guidelines, so operator logs read the same as the rest of a cluster's and work with its tooling.
+// The timestamp is ts in epoch milliseconds, the message msg, and the logger name logger. There is no level,
+// entries below warn have their logr V level as v instead, and errors are logged as err with only their message,
+// without errorVerbose or a stacktrace. The error reporters and hooks see the entries as they were logged.
+func WithK8sConventions() LoggerOption {
+	return func(args *PacketLogr) {
+		args.encoderConfigOpts = append(args.encoderConfigOpts, func(c *zapcore.EncoderConfig) {
+			c.TimeKey = "ts"
+			c.EncodeTime = zapcore.EpochMillisTimeEncoder
+			c.LevelKey = ""
+			c.MessageKey = "msg"
+			c.NameKey = "logger"
+			c.CallerKey = "caller"
+			c.StacktraceKey = ""
+		})
+		args.zapOptions = append(args.zapOptions, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return &rewriteCore{Core: core, message: func(msg string) string { return msg }, fields: k8sFields, extra: k8sVerbosity}
+		}))
+	}
+}
+
+// k8sFields logs errors as strings, the one logr.Logger.Error is given as err
+func k8sFields(fields []zapcore.Field) []zapcore.Field {
+	var rewritten []zapcore.Field
+	for i, f := range fields {
+		if f.Type != zapcore.ErrorType {
+			continue
+		}
+		if rewritten == nil {
+			rewritten = append([]zapcore.Field(nil), fields...)
+		}
+		key := f.Key
+		if key == "error" {
+			key = "err"
+		}
+		rewritten[i] = zap.String(key, f.Interface.(error).Error())
+	}
+	if rewritten == nil {
+		return fields
+	}
+	return rewritten
+}
+
+// k8sVerbosity is the v field of the entries below warn, V(n) is the zap level -n
+func k8sVerbosity(ent zapcore.Entry) []zapcore.Field {
+	if ent.Level >= zapcore.WarnLevel {
+		return nil
+	}
+	return []zapcore.Field{zap.Int("v", -int(ent.Level))}
+}
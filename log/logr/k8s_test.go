@@ -0,0 +1,51 @@
+package logr
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestK8sConventions(t *testing.T) {
+	out := captureOutput(func() {
+		l, err := New(WithK8sConventions(), WithMaxV(2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := l.WithName("controller")
+		c.V(2).Info("reconciling", "pod", "default/web-0")
+		c.Error(errors.New("pod not found"), "reconcile failed", "cause", errors.New("deleted"))
+	})
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 entries, got: %v", out)
+	}
+	var info, failed map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &info); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &failed); err != nil {
+		t.Fatal(err)
+	}
+
+	if info["msg"] != "reconciling" || info["v"] != float64(2) || info["logger"] != "controller" || info["pod"] != "default/web-0" {
+		t.Fatalf("expected the msg, v, and logger keys, got: %v", info)
+	}
+	if _, ok := info["ts"].(float64); !ok {
+		t.Fatalf("expected ts to be epoch milliseconds, got: %v", info)
+	}
+	if _, ok := info["level"]; ok {
+		t.Fatalf("expected no level, got: %v", info)
+	}
+
+	if failed["err"] != "pod not found" || failed["cause"] != "deleted" {
+		t.Fatalf("expected the errors as strings, got: %v", failed)
+	}
+	for _, key := range []string{"v", "error", "errorVerbose", "stacktrace"} {
+		if _, ok := failed[key]; ok {
+			t.Fatalf("expected no %s, got: %v", key, failed)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Package kafkasink publishes the entries of a logr.PacketLogr to a Kafka topic, keyed by service:
+//
+//	l, err := logr.New(kafkasink.WithSink([]string{"kafka:9092"}, "logs"))
+package kafkasink
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"time"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Option configures WithSink
+type Option func(*kafkaConfig)
+
+type kafkaConfig struct {
+	brokers       []string
+	topic         string
+	batchSize     int
+	batchBytes    int64
+	batchTimeout  time.Duration
+	tls           *tls.Config
+	fallbackPaths []string
+}
+
+// WithBatch sets how many entries are sent to a partition at once and how long to wait for a batch to fill up,
+// the ones set with logr.WithBatchPolicy, or else the kafka-go defaults of 100 entries and 1s, are used when 0.
+func WithBatch(size int, timeout time.Duration) Option {
+	return func(c *kafkaConfig) {
+		c.batchSize = size
+		c.batchTimeout = timeout
+	}
+}
+
+// WithTLS connects to the brokers using TLS configured by config
+func WithTLS(config *tls.Config) Option {
+	return func(c *kafkaConfig) { c.tls = config }
+}
+
+// WithFallback sets where entries that fail to be delivered are written instead, stderr by default
+func WithFallback(paths ...string) Option {
+	return func(c *kafkaConfig) { c.fallbackPaths = paths }
+}
+
+// WithSink also publishes entries to topic, JSON encoded whatever the encoding of the output paths is.
+// Entries are keyed by service name so that each service's entries stay in order on one partition.
+// They are sent asynchronously in batches, see WithBatch, and any that fail to be delivered are written to the fallback, see WithFallback.
+func WithSink(brokers []string, topic string, opts ...Option) logr.LoggerOption {
+	c := kafkaConfig{brokers: brokers, topic: topic, fallbackPaths: []string{"stderr"}}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	var err error
+	if len(brokers) == 0 {
+		err = multierr.Append(err, errors.New("at least one broker is required"))
+	}
+	if topic == "" {
+		err = multierr.Append(err, errors.New("topic must not be empty"))
+	}
+	if c.batchSize < 0 || c.batchTimeout < 0 {
+		err = multierr.Append(err, errors.Errorf("batch size and timeout must be >= 0, got: %d and %s", c.batchSize, c.batchTimeout))
+	}
+	if len(c.fallbackPaths) == 0 {
+		err = multierr.Append(err, errors.New("at least one fallback path is required"))
+	}
+	if err != nil {
+		return logr.WithOptionError(errors.WithMessage(err, "kafkasink.WithSink"))
+	}
+	return logr.WithSink(func(sc logr.SinkContext) (zapcore.Core, io.Closer, error) {
+		c := c
+		if policy := sc.BatchPolicy; policy != nil && c.batchSize == 0 && c.batchTimeout == 0 {
+			c.batchSize, c.batchBytes, c.batchTimeout = policy.MaxEntries, int64(policy.MaxBytes), policy.MaxInterval
+		}
+		return c.open(sc.Config)
+	})
+}
+
+// kafkaWriter is the part of kafka.Writer the sink uses
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+func (c kafkaConfig) open(zc zap.Config) (zapcore.Core, io.Closer, error) {
+	zc.Encoding = "json"
+	encoder, err := logr.NewEncoder(zc)
+	if err != nil {
+		return nil, nil, err
+	}
+	fallback, closeFallback, err := zap.Open(c.fallbackPaths...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to open kafka fallback paths")
+	}
+	w := &kafka.Writer{
+		Addr:         kafka.TCP(c.brokers...),
+		Topic:        c.topic,
+		Balancer:     &kafka.Hash{},
+		BatchSize:    c.batchSize,
+		BatchBytes:   c.batchBytes,
+		BatchTimeout: c.batchTimeout,
+		Async:        true,
+		Completion:   kafkaFallback(fallback),
+	}
+	if c.tls != nil {
+		w.Transport = &kafka.Transport{TLS: c.tls}
+	}
+	closer := closerFunc(func() error {
+		err := w.Close()
+		closeFallback()
+		return errors.Wrap(err, "failed to close kafka writer")
+	})
+	return &kafkaCore{LevelEnabler: zc.Level, enc: encoder, w: w}, closer, nil
+}
+
+// kafkaFallback writes messages that failed to be delivered to fallback
+func kafkaFallback(fallback zapcore.WriteSyncer) func([]kafka.Message, error) {
+	return func(msgs []kafka.Message, err error) {
+		if err == nil {
+			return
+		}
+		for _, msg := range msgs {
+			_, _ = fallback.Write(append(msg.Value, '\n'))
+		}
+	}
+}
+
+// kafkaCore encodes entries and publishes them keyed by the service field
+type kafkaCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	w   kafkaWriter
+	key []byte
+}
+
+func (c *kafkaCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &kafkaCore{LevelEnabler: c.LevelEnabler, enc: c.enc.Clone(), w: c.w, key: c.key}
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+		if f.Key == "service" && f.Type == zapcore.StringType {
+			clone.key = []byte(f.String)
+		}
+	}
+	return clone
+}
+
+func (c *kafkaCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *kafkaCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	// the writer holds on to the value until it is sent so it can't share the pooled buffer
+	value := make([]byte, buf.Len())
+	copy(value, buf.Bytes())
+	if n := len(value); n > 0 && value[n-1] == '\n' {
+		value = value[:n-1]
+	}
+	return errors.Wrap(c.w.WriteMessages(context.Background(), kafka.Message{Key: c.key, Value: value}), "failed to write to kafka")
+}
+
+func (c *kafkaCore) Sync() error { return nil }
+
+// closerFunc lets a func be used as the sink's io.Closer
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
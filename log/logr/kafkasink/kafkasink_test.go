@@ -0,0 +1,73 @@
+package kafkasink
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type fakeKafkaWriter struct {
+	msgs []kafka.Message
+}
+
+func (w *fakeKafkaWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	w.msgs = append(w.msgs, msgs...)
+	return nil
+}
+
+func (w *fakeKafkaWriter) Close() error { return nil }
+
+func TestKafkaCore(t *testing.T) {
+	encoder, err := logr.NewEncoder(zap.NewProductionConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &fakeKafkaWriter{}
+	l := zap.New(&kafkaCore{LevelEnabler: zapcore.InfoLevel, enc: encoder, w: w}).With(zap.String("service", "billing"))
+	l.Debug("filtered")
+	l.Info("to kafka", zap.Int("n", 1))
+
+	if len(w.msgs) != 1 {
+		t.Fatalf("expected 1 message, got: %v", w.msgs)
+	}
+	if got := string(w.msgs[0].Key); got != "billing" {
+		t.Fatalf("expected the service name as key, got: %v", got)
+	}
+	if got := string(w.msgs[0].Value); !strings.HasSuffix(got, `"msg":"to kafka","service":"billing","n":1}`) {
+		t.Fatalf("expected a JSON entry without a newline, got: %q", got)
+	}
+}
+
+func TestKafkaFallback(t *testing.T) {
+	var buf bytes.Buffer
+	fallback := kafkaFallback(zapcore.AddSync(&buf))
+	msgs := []kafka.Message{{Value: []byte(`{"msg":"one"}`)}, {Value: []byte(`{"msg":"two"}`)}}
+
+	fallback(msgs, nil)
+	if buf.Len() != 0 {
+		t.Fatalf("expected delivered messages not to be written, got: %v", buf.String())
+	}
+	fallback(msgs, errors.New("broker down"))
+	if got := buf.String(); got != "{\"msg\":\"one\"}\n{\"msg\":\"two\"}\n" {
+		t.Fatalf("expected failed messages in the fallback, got: %q", got)
+	}
+}
+
+func TestWithSinkInvalid(t *testing.T) {
+	_, err := logr.New(WithSink(nil, "", WithBatch(-1, 0), WithFallback()))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"kafkasink.WithSink", "at least one broker is required", "topic must not be empty", "batch size and timeout must be >= 0", "at least one fallback path is required"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, err)
+		}
+	}
+}
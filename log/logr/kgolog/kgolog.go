@@ -0,0 +1,147 @@
+// Package kgolog logs the produce and consume events, rebalances, and errors of franz-go Kafka clients with a logr.PacketLogr,
+// with the fields topic, partition, offset, and consumer_group:
+//
+//	cl, err := kgo.NewClient(
+//		kgo.ConsumerGroup("provisioner"),
+//		kgo.WithHooks(kgolog.NewHooks(l)),
+//		kgolog.OnPartitionsAssigned(l, nil),
+//		kgolog.OnPartitionsRevoked(l, nil),
+//		kgolog.OnPartitionsLost(l, nil),
+//	)
+package kgolog
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// debug is the verbosity records are logged at, as there is one entry per record
+const debug = 1
+
+// Hooks logs, for kgo.WithHooks, the records produced and consumed at debug, and the produce, group, and broker connection errors.
+// The records produced are logged with the logger of their context, from logr.FromContext, so the ones produced while handling
+// a request have its fields. Each client needs its own Hooks, for its consumer group.
+type Hooks struct {
+	p     *logr.PacketLogr
+	group atomic.Value
+}
+
+var (
+	_ kgo.HookNewClient               = (*Hooks)(nil)
+	_ kgo.HookProduceRecordUnbuffered = (*Hooks)(nil)
+	_ kgo.HookFetchRecordUnbuffered   = (*Hooks)(nil)
+	_ kgo.HookGroupManageError        = (*Hooks)(nil)
+	_ kgo.HookBrokerConnect           = (*Hooks)(nil)
+)
+
+// NewHooks returns the Hooks logging with p
+func NewHooks(p *logr.PacketLogr) *Hooks {
+	h := &Hooks{p: p}
+	h.group.Store("")
+	return h
+}
+
+// OnNewClient keeps the consumer group of cl for the entries
+func (h *Hooks) OnNewClient(cl *kgo.Client) {
+	group, _ := cl.OptValue(kgo.ConsumerGroup).(string)
+	h.group.Store(group)
+}
+
+// OnProduceRecordUnbuffered logs that r was produced, or that producing it failed with err
+func (h *Hooks) OnProduceRecordUnbuffered(r *kgo.Record, err error) {
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	l := h.p.FromContext(ctx)
+	if err != nil {
+		l.Error(err, "failed to produce record", "topic", r.Topic, "partition", r.Partition)
+		return
+	}
+	l.V(debug).Info("produced record", "topic", r.Topic, "partition", r.Partition, "offset", r.Offset)
+}
+
+// OnFetchRecordUnbuffered logs that r was consumed, when it was returned from polling
+func (h *Hooks) OnFetchRecordUnbuffered(r *kgo.Record, polled bool) {
+	if !polled {
+		return
+	}
+	h.p.V(debug).Info("consumed record", h.fields("topic", r.Topic, "partition", r.Partition, "offset", r.Offset)...)
+}
+
+// OnGroupManageError logs the error that ended a group session, the client joins the group again after it
+func (h *Hooks) OnGroupManageError(err error) {
+	h.p.Error(err, "consumer group session failed", h.fields()...)
+}
+
+// OnBrokerConnect logs the failures to connect to a broker
+func (h *Hooks) OnBrokerConnect(meta kgo.BrokerMetadata, dialDur time.Duration, _ net.Conn, err error) {
+	if err == nil {
+		return
+	}
+	h.p.Info("failed to connect to broker", "broker", net.JoinHostPort(meta.Host, strconv.Itoa(int(meta.Port))), "duration", dialDur, "error", err.Error())
+}
+
+// fields are kvs with the consumer group, when the client has one
+func (h *Hooks) fields(kvs ...interface{}) []interface{} {
+	if group := h.group.Load().(string); group != "" {
+		kvs = append(kvs, "consumer_group", group)
+	}
+	return kvs
+}
+
+// OnPartitionsAssigned logs the partitions assigned to the client in a rebalance, and then calls next when it isn't nil
+func OnPartitionsAssigned(p *logr.PacketLogr, next func(context.Context, *kgo.Client, map[string][]int32)) kgo.GroupOpt {
+	return kgo.OnPartitionsAssigned(func(ctx context.Context, cl *kgo.Client, assigned map[string][]int32) {
+		logRebalance(p, "partitions assigned", cl, assigned)
+		if next != nil {
+			next(ctx, cl, assigned)
+		}
+	})
+}
+
+// OnPartitionsRevoked logs the partitions revoked from the client in a rebalance, and then calls next. When next is nil the
+// uncommitted offsets are committed, unless autocommitting is disabled, as kgo does when OnPartitionsRevoked isn't set.
+func OnPartitionsRevoked(p *logr.PacketLogr, next func(context.Context, *kgo.Client, map[string][]int32)) kgo.GroupOpt {
+	return kgo.OnPartitionsRevoked(func(ctx context.Context, cl *kgo.Client, revoked map[string][]int32) {
+		logRebalance(p, "partitions revoked", cl, revoked)
+		if next != nil {
+			next(ctx, cl, revoked)
+			return
+		}
+		if disabled, _ := cl.OptValue(kgo.DisableAutoCommit).(bool); !disabled {
+			if err := cl.CommitUncommittedOffsets(ctx); err != nil {
+				p.Error(err, "failed to commit offsets of revoked partitions", groupField(cl)...)
+			}
+		}
+	})
+}
+
+// OnPartitionsLost logs the partitions lost by the client, on fatal group errors, and then calls next when it isn't nil
+func OnPartitionsLost(p *logr.PacketLogr, next func(context.Context, *kgo.Client, map[string][]int32)) kgo.GroupOpt {
+	return kgo.OnPartitionsLost(func(ctx context.Context, cl *kgo.Client, lost map[string][]int32) {
+		logRebalance(p, "partitions lost", cl, lost)
+		if next != nil {
+			next(ctx, cl, lost)
+		}
+	})
+}
+
+// logRebalance logs the partitions of a rebalance, when there are some, as kgo calls OnPartitionsRevoked at the end of every session
+func logRebalance(p *logr.PacketLogr, msg string, cl *kgo.Client, partitions map[string][]int32) {
+	if len(partitions) == 0 {
+		return
+	}
+	p.Info(msg, append(groupField(cl), "partitions", partitions)...)
+}
+
+func groupField(cl *kgo.Client) []interface{} {
+	group, _ := cl.OptValue(kgo.ConsumerGroup).(string)
+	return []interface{}{"consumer_group", group}
+}
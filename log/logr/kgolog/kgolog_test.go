@@ -0,0 +1,47 @@
+package kgolog
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestHooks(t *testing.T) {
+	l, logged := logtest.New(t, logr.WithLogLevel("debug"))
+	hooks := NewHooks(l)
+	cl, err := kgo.NewClient(kgo.SeedBrokers("127.0.0.1:1"), kgo.ConsumerGroup("provisioner"), kgo.WithHooks(hooks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	ctx := l.NewContextWithValues(context.Background(), "request_id", "r1")
+	hooks.OnProduceRecordUnbuffered(&kgo.Record{Context: ctx, Topic: "events", Partition: 2, Offset: 42}, nil)
+	hooks.OnProduceRecordUnbuffered(&kgo.Record{Topic: "events", Partition: 1}, errors.New("record too large"))
+	hooks.OnFetchRecordUnbuffered(&kgo.Record{Topic: "events", Partition: 3, Offset: 7}, true)
+	hooks.OnFetchRecordUnbuffered(&kgo.Record{Topic: "events", Partition: 3, Offset: 8}, false)
+	hooks.OnGroupManageError(errors.New("rebalance in progress"))
+	logRebalance(l, "partitions assigned", cl, map[string][]int32{"events": {0, 1}})
+	logRebalance(l, "partitions revoked", cl, map[string][]int32{})
+
+	got := logged()
+	for _, want := range []string{
+		`"msg":"produced record","service":"not/set","request_id":"r1","topic":"events","partition":2,"offset":42`,
+		`"msg":"failed to produce record","service":"not/set","topic":"events","partition":1,"error":"record too large"`,
+		`"msg":"consumed record","service":"not/set","topic":"events","partition":3,"offset":7,"consumer_group":"provisioner"`,
+		`"msg":"consumer group session failed","service":"not/set","consumer_group":"provisioner","error":"rebalance in progress"`,
+		`"msg":"partitions assigned","service":"not/set","consumer_group":"provisioner","partitions":{"events":[0,1]}`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %s to be logged, got: %v", want, got)
+		}
+	}
+	if strings.Contains(got, `"offset":8`) || strings.Contains(got, "partitions revoked") {
+		t.Fatalf("expected unpolled records and empty rebalances not to be logged, got: %v", got)
+	}
+}
@@ -0,0 +1,24 @@
+module github.com/packethost/pkg/log/logr/klogredirect
+
+go 1.21
+
+require (
+	github.com/go-logr/logr v0.2.1
+	github.com/go-logr/zapr v0.2.0
+	github.com/packethost/pkg/log/logr v0.1.0
+	go.uber.org/zap v1.16.0
+	k8s.io/klog/v2 v2.4.0
+)
+
+require (
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/rollbar/rollbar-go v1.2.0 // indirect
+	go.uber.org/atomic v1.9.0 // indirect
+	go.uber.org/multierr v1.6.0 // indirect
+	golang.org/x/mod v0.8.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/packethost/pkg/log/logr => ../
@@ -0,0 +1,83 @@
+// Package klogredirect sends what is logged through klog, such as by client-go in Kubernetes controllers, to a logr.PacketLogr:
+//
+//	defer klogredirect.Redirect(l)()
+package klogredirect
+
+import (
+	"flag"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
+	packetlogr "github.com/packethost/pkg/log/logr"
+	"go.uber.org/zap"
+	"k8s.io/klog/v2"
+)
+
+// klogLogger trims the newline klog appends to every message before passing it on
+type klogLogger struct {
+	logr.Logger
+}
+
+func (k klogLogger) Info(msg string, keysAndValues ...interface{}) {
+	k.Logger.Info(strings.TrimSuffix(msg, "\n"), keysAndValues...)
+}
+
+func (k klogLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	k.Logger.Error(err, strings.TrimSuffix(msg, "\n"), keysAndValues...)
+}
+
+func (k klogLogger) V(level int) logr.Logger {
+	return klogLogger{k.Logger.V(level)}
+}
+
+func (k klogLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return klogLogger{k.Logger.WithValues(keysAndValues...)}
+}
+
+func (k klogLogger) WithName(name string) logr.Logger {
+	return klogLogger{k.Logger.WithName(name)}
+}
+
+// klogRedirect is the logger klog was last given by Redirect, klog has no way to get the logger it has
+var klogRedirect struct {
+	sync.Mutex
+	logger logr.Logger
+}
+
+// Redirect sends everything logged through klog to p, named "klog".
+// klog's own headers are turned off and its -v verbosity is opened all the way up,
+// so klog.V(n) entries are filtered by p's level, as V(n) is, including changes made by SetLevel.
+// The caller is not recorded as it would always point into klog.
+// The returned func restores klog's previous flags and logger, which is the one of the previous Redirect, or klog's own output.
+// Loggers given to klog.SetLogger directly aren't restored as klog can't return them.
+func Redirect(p *packetlogr.PacketLogr) func() {
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	flags := map[string]string{
+		"v":            strconv.Itoa(packetlogr.MaxV),
+		"skip_headers": "true",
+	}
+	previous := make(map[string]string, len(flags))
+	for name, value := range flags {
+		previous[name] = fs.Lookup(name).Value.String()
+		_ = fs.Set(name, value)
+	}
+	logger := klogLogger{zapr.NewLogger(p.Zap().Named("klog").WithOptions(zap.WithCaller(false)))}
+	klogRedirect.Lock()
+	previousLogger := klogRedirect.logger
+	klogRedirect.logger = logger
+	klog.SetLogger(logger)
+	klogRedirect.Unlock()
+	return func() {
+		klogRedirect.Lock()
+		klogRedirect.logger = previousLogger
+		klog.SetLogger(previousLogger)
+		klogRedirect.Unlock()
+		for name, value := range previous {
+			_ = fs.Set(name, value)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package klogredirect
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+	"k8s.io/klog/v2"
+)
+
+func TestRedirect(t *testing.T) {
+	pl, logged := logtest.New(t, logr.WithLogLevel("debug"), logr.WithServiceName("controller"))
+	restore := Redirect(pl)
+	klog.Info("from klog")
+	klog.V(1).Infof("verbose %d", 1)
+	klog.V(2).Info("too verbose")
+	klog.ErrorS(errors.New("boom"), "reconcile failed", "name", "widget")
+	if err := pl.SetLevel("trace"); err != nil {
+		t.Fatal(err)
+	}
+	klog.V(2).Info("now verbose enough")
+	restore()
+	capturedOutput := logged()
+	for _, want := range []string{
+		`"logger":"klog","msg":"from klog","service":"controller"}`,
+		`"msg":"verbose 1"`,
+		`"level":"error"`,
+		`"msg":"reconcile failed","service":"controller","name":"widget","error":"boom"`,
+		`"msg":"now verbose enough"`,
+	} {
+		if !strings.Contains(capturedOutput, want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+		}
+	}
+	for _, unwanted := range []string{"too verbose", `"caller"`} {
+		if strings.Contains(capturedOutput, unwanted) {
+			t.Fatalf("expected not to contain: %v, got: %v", unwanted, capturedOutput)
+		}
+	}
+}
+
+func TestRedirectRestore(t *testing.T) {
+	dir := t.TempDir()
+	first, err := logr.New(logr.WithOutputPaths([]string{dir + "/first"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := logr.New(logr.WithOutputPaths([]string{dir + "/second"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	restoreFirst := Redirect(first)
+	defer restoreFirst()
+	restoreSecond := Redirect(second)
+	klog.Info("to the second")
+	restoreSecond()
+	klog.Info("to the first")
+	for name, want := range map[string]string{"first": "to the first", "second": "to the second"} {
+		b, err := os.ReadFile(dir + "/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(b); strings.Count(got, `"msg"`) != 1 || !strings.Contains(got, want) {
+			t.Fatalf("expected the %s logger to only have: %v, got: %v", name, want, got)
+		}
+	}
+}
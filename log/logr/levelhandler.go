@@ -0,0 +1,105 @@
+package logr
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LevelHandlerOption for setting optional values on the LevelHandler
+type LevelHandlerOption func(*levelHandler)
+
+// WithLevelHandlerAuth sets a func that must return nil for a request to be served.
+// The error returned is sent back to the caller along with a 401 status code.
+func WithLevelHandlerAuth(auth func(*http.Request) error) LevelHandlerOption {
+	return func(args *levelHandler) { args.auth = auth }
+}
+
+// WithLevelHandlerBearerToken only allows requests that send the token in an "Authorization: Bearer" header
+func WithLevelHandlerBearerToken(token string) LevelHandlerOption {
+	return WithLevelHandlerAuth(BearerTokenAuth(token))
+}
+
+// BearerTokenAuth returns an auth func, as WithLevelHandlerAuth takes, that only allows the requests sending the token
+// in an "Authorization: Bearer" header. An empty token allows no request.
+func BearerTokenAuth(token string) func(*http.Request) error {
+	return func(r *http.Request) error {
+		if token == "" {
+			return errors.New("no bearer token is configured")
+		}
+		const prefix = "bearer "
+		header := r.Header.Get("Authorization")
+		if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+			return errors.New("missing bearer token")
+		}
+		if subtle.ConstantTimeCompare([]byte(header[len(prefix):]), []byte(token)) != 1 {
+			return errors.New("invalid bearer token")
+		}
+		return nil
+	}
+}
+
+type levelHandler struct {
+	p    *PacketLogr
+	auth func(*http.Request) error
+}
+
+// levelBody is the request and response body of the LevelHandler
+type levelBody struct {
+	Level *string `json:"level"`
+}
+
+// LevelHandler returns an http.Handler that reports the current log level on GET and changes it on PUT,
+// the request and response bodies are JSON of the form {"level":"debug"}. It takes the levels WithLogLevel does,
+// such as trace and v3. Services will usually mount it at /debug/loglevel.
+func (p *PacketLogr) LevelHandler(opts ...LevelHandlerOption) http.Handler {
+	h := &levelHandler{p: p}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.auth != nil {
+		if err := h.auth(r); err != nil {
+			writeLevelError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req levelBody
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeLevelError(w, http.StatusBadRequest, fmt.Sprintf("request body must be like {\"level\":\"debug\"}: %v", err))
+			return
+		}
+		if req.Level == nil {
+			writeLevelError(w, http.StatusBadRequest, "must specify a logging level")
+			return
+		}
+		if err := h.p.SetLevel(*req.Level); err != nil {
+			writeLevelError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	default:
+		writeLevelError(w, http.StatusMethodNotAllowed, "only GET and PUT are supported")
+		return
+	}
+	level := h.p.Level()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(levelBody{Level: &level})
+}
+
+func writeLevelError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{msg})
+}
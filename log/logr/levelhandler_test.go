@@ -0,0 +1,107 @@
+package logr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLevelHandler(t *testing.T) {
+	l, _, err := NewPacketLogr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl := l.(*PacketLogr)
+	h := pl.LevelHandler()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/loglevel", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status: %v, got: %v", http.StatusOK, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"level":"info"`) {
+		t.Fatalf("expected to contain: %v, got: %v", `"level":"info"`, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/debug/loglevel", strings.NewReader(`{"level":"debug"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status: %v, got: %v", http.StatusOK, w.Code)
+	}
+	if pl.Level() != "debug" {
+		t.Fatalf("expected level to be: debug, got: %v", pl.Level())
+	}
+
+	for _, level := range []string{"trace", "v3"} {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/debug/loglevel", strings.NewReader(`{"level":"`+level+`"}`)))
+		if w.Code != http.StatusOK || pl.Level() != level {
+			t.Fatalf("expected level to be set to: %v, got: %v %v", level, w.Code, pl.Level())
+		}
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/loglevel", nil))
+		if want := `{"level":"` + level + `"}`; strings.TrimSpace(w.Body.String()) != want {
+			t.Fatalf("expected: %v, got: %v", want, w.Body.String())
+		}
+	}
+
+	for body, status := range map[string]int{`{"level":"loud"}`: http.StatusBadRequest, `{}`: http.StatusBadRequest, `level=info`: http.StatusBadRequest} {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/debug/loglevel", strings.NewReader(body)))
+		if w.Code != status || !strings.Contains(w.Body.String(), `"error"`) {
+			t.Fatalf("expected status: %v for %v, got: %v %v", status, body, w.Code, w.Body.String())
+		}
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/loglevel", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status: %v, got: %v", http.StatusMethodNotAllowed, w.Code)
+	}
+	if pl.Level() != "v3" {
+		t.Fatalf("expected the level to be unchanged, got: %v", pl.Level())
+	}
+}
+
+func TestLevelHandlerBearerToken(t *testing.T) {
+	l, _, err := NewPacketLogr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl := l.(*PacketLogr)
+	h := pl.LevelHandler(WithLevelHandlerBearerToken("s3cret"))
+
+	tests := map[string]struct {
+		header string
+		status int
+	}{
+		"no header":   {status: http.StatusUnauthorized},
+		"wrong token": {header: "Bearer nope", status: http.StatusUnauthorized},
+		"wrong type":  {header: "Basic s3cret", status: http.StatusUnauthorized},
+		"valid token": {header: "Bearer s3cret", status: http.StatusOK},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/debug/loglevel", strings.NewReader(`{"level":"warn"}`))
+			if tc.header != "" {
+				r.Header.Set("Authorization", tc.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tc.status {
+				t.Fatalf("expected status: %v, got: %v", tc.status, w.Code)
+			}
+		})
+	}
+	if pl.Level() != "warn" {
+		t.Fatalf("expected level to be: warn, got: %v", pl.Level())
+	}
+}
+
+func TestBearerTokenAuthEmptyToken(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/debug/loglevel", nil)
+	r.Header.Set("Authorization", "Bearer ")
+	if err := BearerTokenAuth("")(r); err == nil {
+		t.Fatal("expected an empty token to allow no request")
+	}
+}
@@ -0,0 +1,101 @@
+package logr
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LogCounter counts what a logger writes, see WithLogCounter. The metrics package has one counting with Prometheus.
+type LogCounter interface {
+	// CountEntry counts an entry written at level, see WithLogLevel for the names, by component, the logger name
+	CountEntry(level, component string)
+	// CountBytes counts n bytes written to the outputs and sinks
+	CountBytes(n int)
+}
+
+// WithLogCounter counts what is logged with c: each entry by level and component, and the bytes written to the outputs and sinks.
+// Only the entries that are written are counted, not the ones filtered out by level or sampling.
+// The entries dropped are counted by Dropped and DroppedSinkEntries.
+func WithLogCounter(c LogCounter) LoggerOption {
+	return func(args *PacketLogr) {
+		if c == nil {
+			args.errs = multierr.Append(args.errs, errors.New("WithLogCounter: counter must not be nil"))
+			return
+		}
+		args.logCounter = c
+	}
+}
+
+// DroppedSinkEntries returns how many entries the sinks have dropped because their queue was full
+func (p *PacketLogr) DroppedSinkEntries() uint64 {
+	var dropped uint64
+	for _, closer := range p.sinkClosers {
+		dropped += sinkDropped(closer)
+	}
+	return dropped
+}
+
+// countEntries wraps the core so the entries it writes are counted
+func countEntries(c LogCounter) zap.Option {
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &countingCore{Core: core, counter: c}
+	})
+}
+
+// countBytes wraps ws so the bytes written to it are counted
+func countBytes(ws zapcore.WriteSyncer, c LogCounter) zapcore.WriteSyncer {
+	return countingWriteSyncer{WriteSyncer: ws, counter: c}
+}
+
+// countingCore counts the entries the wrapped core takes, it calls the wrapped core's Check so the entries
+// filtered out by the level checks and sampling underneath aren't counted
+type countingCore struct {
+	zapcore.Core
+	counter LogCounter
+}
+
+func (c *countingCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	return &clone
+}
+
+func (c *countingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	inner := c.Core.Check(ent, nil)
+	if inner == nil {
+		return ce
+	}
+	c.counter.CountEntry(levelName(ent.Level), ent.LoggerName)
+	w := &rewriteWriter{inner: inner, fields: keepFields}
+	ce = ce.AddCore(ent, w)
+	w.outer = ce
+	return ce
+}
+
+type countingWriteSyncer struct {
+	zapcore.WriteSyncer
+	counter LogCounter
+}
+
+func (w countingWriteSyncer) Write(p []byte) (int, error) {
+	n, err := w.WriteSyncer.Write(p)
+	w.counter.CountBytes(n)
+	return n, err
+}
+
+// dropCounter is a sink that counts the entries it drops
+type dropCounter interface {
+	droppedEntries() uint64
+}
+
+// sinkDropped is how many entries the sink closer belongs to has dropped
+func sinkDropped(closer io.Closer) uint64 {
+	if d, ok := closer.(dropCounter); ok {
+		return d.droppedEntries()
+	}
+	return 0
+}
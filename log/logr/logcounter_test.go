@@ -0,0 +1,65 @@
+package logr
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testCounter is a LogCounter that records what it counts
+type testCounter struct {
+	mu      sync.Mutex
+	entries map[string]int
+	bytes   int
+}
+
+func (c *testCounter) CountEntry(level, component string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]int{}
+	}
+	c.entries[component+"/"+level]++
+}
+
+func (c *testCounter) CountBytes(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bytes += n
+}
+
+func TestWithLogCounter(t *testing.T) {
+	c := &testCounter{}
+	var pl *PacketLogr
+	out := captureOutput(func() {
+		var err error
+		pl, err = New(WithLogCounter(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pl.Info("started")
+		pl.V(1).Info("filtered out")
+		db := pl.Named("db")
+		db.Info("connected")
+		db.Error(nil, "query failed")
+		_ = pl.Sync()
+	})
+
+	if want := map[string]int{"/info": 1, "db/info": 1, "db/error": 1}; !reflect.DeepEqual(c.entries, want) {
+		t.Fatalf("expected the written entries to be counted, got: %v", c.entries)
+	}
+	if c.bytes != len(out) {
+		t.Fatalf("expected %d bytes to be counted, got: %v", len(out), c.bytes)
+	}
+	if got := pl.DroppedSinkEntries(); got != 0 {
+		t.Fatalf("expected no dropped entries, got: %v", got)
+	}
+}
+
+func TestWithLogCounterNil(t *testing.T) {
+	_, err := New(WithLogCounter(nil))
+	if err == nil || !strings.Contains(err.Error(), "WithLogCounter") {
+		t.Fatalf("expected an error for a nil counter, got: %v", err)
+	}
+}
@@ -0,0 +1,318 @@
+package logr
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var bufferPool = buffer.NewPool()
+
+func init() {
+	// an error here means someone else registered "logfmt" first, in which case theirs is used by zap.Config.Build
+	_ = zap.RegisterEncoder("logfmt", func(c zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return NewLogfmtEncoder(c), nil
+	})
+}
+
+// logfmtEncoder writes entries as key=value pairs separated by spaces.
+// Nested objects and namespaces are flattened into dotted keys and arrays are written as quoted JSON.
+type logfmtEncoder struct {
+	*zapcore.EncoderConfig
+	buf        *buffer.Buffer
+	namespaces []string
+}
+
+// NewLogfmtEncoder creates a zapcore.Encoder that writes logfmt, it is also available to WithEncoding as "logfmt"
+func NewLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{EncoderConfig: &cfg, buf: bufferPool.Get()}
+}
+
+func (enc *logfmtEncoder) Clone() zapcore.Encoder {
+	clone := enc.clone()
+	_, _ = clone.buf.Write(enc.buf.Bytes())
+	return clone
+}
+
+func (enc *logfmtEncoder) clone() *logfmtEncoder {
+	return &logfmtEncoder{
+		EncoderConfig: enc.EncoderConfig,
+		buf:           bufferPool.Get(),
+		namespaces:    append([]string(nil), enc.namespaces...),
+	}
+}
+
+func (enc *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.clone()
+	// entry metadata is never namespaced
+	final.namespaces = nil
+
+	if final.TimeKey != "" {
+		final.AddTime(final.TimeKey, ent.Time)
+	}
+	if final.LevelKey != "" {
+		final.addKey(final.LevelKey)
+		cur := final.buf.Len()
+		if final.EncodeLevel != nil {
+			final.EncodeLevel(ent.Level, final)
+		}
+		if cur == final.buf.Len() {
+			final.AppendString(ent.Level.String())
+		}
+	}
+	if ent.LoggerName != "" && final.NameKey != "" {
+		final.addKey(final.NameKey)
+		cur := final.buf.Len()
+		if final.EncodeName != nil {
+			final.EncodeName(ent.LoggerName, final)
+		}
+		if cur == final.buf.Len() {
+			final.AppendString(ent.LoggerName)
+		}
+	}
+	if ent.Caller.Defined {
+		if final.CallerKey != "" {
+			final.addKey(final.CallerKey)
+			cur := final.buf.Len()
+			if final.EncodeCaller != nil {
+				final.EncodeCaller(ent.Caller, final)
+			}
+			if cur == final.buf.Len() {
+				final.AppendString(ent.Caller.String())
+			}
+		}
+		if final.FunctionKey != "" {
+			final.AddString(final.FunctionKey, ent.Caller.Function)
+		}
+	}
+	if final.MessageKey != "" {
+		final.AddString(final.MessageKey, ent.Message)
+	}
+
+	if enc.buf.Len() > 0 {
+		if final.buf.Len() > 0 {
+			final.buf.AppendByte(' ')
+		}
+		_, _ = final.buf.Write(enc.buf.Bytes())
+	}
+	final.namespaces = append(final.namespaces, enc.namespaces...)
+	for i := range fields {
+		fields[i].AddTo(final)
+	}
+
+	if ent.Stack != "" && final.StacktraceKey != "" {
+		final.namespaces = nil
+		final.AddString(final.StacktraceKey, ent.Stack)
+	}
+
+	if final.LineEnding != "" {
+		final.buf.AppendString(final.LineEnding)
+	} else {
+		final.buf.AppendString(zapcore.DefaultLineEnding)
+	}
+	return final.buf, nil
+}
+
+func (enc *logfmtEncoder) addKey(key string) {
+	if enc.buf.Len() > 0 {
+		enc.buf.AppendByte(' ')
+	}
+	for _, ns := range enc.namespaces {
+		appendLogfmtKey(enc.buf, ns)
+		enc.buf.AppendByte('.')
+	}
+	appendLogfmtKey(enc.buf, key)
+	enc.buf.AppendByte('=')
+}
+
+func (enc *logfmtEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	m := zapcore.NewMapObjectEncoder()
+	if err := m.AddArray(key, arr); err != nil {
+		return err
+	}
+	enc.AddReflected(key, m.Fields[key])
+	return nil
+}
+
+func (enc *logfmtEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	depth := len(enc.namespaces)
+	enc.namespaces = append(enc.namespaces, key)
+	err := obj.MarshalLogObject(enc)
+	enc.namespaces = enc.namespaces[:depth]
+	return err
+}
+
+func (enc *logfmtEncoder) AddBinary(key string, val []byte) {
+	enc.AddString(key, base64.StdEncoding.EncodeToString(val))
+}
+
+func (enc *logfmtEncoder) AddByteString(key string, val []byte) {
+	enc.addKey(key)
+	enc.AppendByteString(val)
+}
+
+func (enc *logfmtEncoder) AddBool(key string, val bool) {
+	enc.addKey(key)
+	enc.AppendBool(val)
+}
+
+func (enc *logfmtEncoder) AddComplex128(key string, val complex128) {
+	enc.addKey(key)
+	enc.AppendComplex128(val)
+}
+
+func (enc *logfmtEncoder) AddComplex64(key string, val complex64) {
+	enc.AddComplex128(key, complex128(val))
+}
+
+func (enc *logfmtEncoder) AddDuration(key string, val time.Duration) {
+	enc.addKey(key)
+	cur := enc.buf.Len()
+	if enc.EncodeDuration != nil {
+		enc.EncodeDuration(val, enc)
+	}
+	if cur == enc.buf.Len() {
+		enc.AppendInt64(int64(val))
+	}
+}
+
+func (enc *logfmtEncoder) AddFloat64(key string, val float64) {
+	enc.addKey(key)
+	enc.AppendFloat64(val)
+}
+
+func (enc *logfmtEncoder) AddFloat32(key string, val float32) {
+	enc.addKey(key)
+	enc.AppendFloat32(val)
+}
+
+func (enc *logfmtEncoder) AddInt(key string, val int)     { enc.AddInt64(key, int64(val)) }
+func (enc *logfmtEncoder) AddInt32(key string, val int32) { enc.AddInt64(key, int64(val)) }
+func (enc *logfmtEncoder) AddInt16(key string, val int16) { enc.AddInt64(key, int64(val)) }
+func (enc *logfmtEncoder) AddInt8(key string, val int8)   { enc.AddInt64(key, int64(val)) }
+
+func (enc *logfmtEncoder) AddInt64(key string, val int64) {
+	enc.addKey(key)
+	enc.AppendInt64(val)
+}
+
+func (enc *logfmtEncoder) AddString(key, val string) {
+	enc.addKey(key)
+	enc.AppendString(val)
+}
+
+func (enc *logfmtEncoder) AddTime(key string, val time.Time) {
+	enc.addKey(key)
+	cur := enc.buf.Len()
+	if enc.EncodeTime != nil {
+		enc.EncodeTime(val, enc)
+	}
+	if cur == enc.buf.Len() {
+		enc.AppendInt64(val.UnixNano())
+	}
+}
+
+func (enc *logfmtEncoder) AddUint(key string, val uint)       { enc.AddUint64(key, uint64(val)) }
+func (enc *logfmtEncoder) AddUint32(key string, val uint32)   { enc.AddUint64(key, uint64(val)) }
+func (enc *logfmtEncoder) AddUint16(key string, val uint16)   { enc.AddUint64(key, uint64(val)) }
+func (enc *logfmtEncoder) AddUint8(key string, val uint8)     { enc.AddUint64(key, uint64(val)) }
+func (enc *logfmtEncoder) AddUintptr(key string, val uintptr) { enc.AddUint64(key, uint64(val)) }
+
+func (enc *logfmtEncoder) AddUint64(key string, val uint64) {
+	enc.addKey(key)
+	enc.AppendUint64(val)
+}
+
+func (enc *logfmtEncoder) AddReflected(key string, val interface{}) error {
+	b, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	enc.addKey(key)
+	enc.AppendByteString(b)
+	return nil
+}
+
+func (enc *logfmtEncoder) OpenNamespace(key string) {
+	enc.namespaces = append(enc.namespaces, key)
+}
+
+// The Append methods implement zapcore.PrimitiveArrayEncoder so the EncoderConfig's
+// level, time, duration, caller, and name encoders can write values directly.
+
+func (enc *logfmtEncoder) AppendBool(val bool) { enc.buf.AppendBool(val) }
+
+func (enc *logfmtEncoder) AppendByteString(val []byte) { appendLogfmtValue(enc.buf, string(val)) }
+
+func (enc *logfmtEncoder) AppendComplex128(val complex128) {
+	enc.buf.AppendString(strconv.FormatComplex(val, 'g', -1, 128))
+}
+
+func (enc *logfmtEncoder) AppendComplex64(val complex64) { enc.AppendComplex128(complex128(val)) }
+
+func (enc *logfmtEncoder) AppendFloat64(val float64) { enc.appendFloat(val, 64) }
+func (enc *logfmtEncoder) AppendFloat32(val float32) { enc.appendFloat(float64(val), 32) }
+
+func (enc *logfmtEncoder) appendFloat(val float64, bitSize int) {
+	switch {
+	case math.IsNaN(val):
+		enc.buf.AppendString("NaN")
+	case math.IsInf(val, 1):
+		enc.buf.AppendString("+Inf")
+	case math.IsInf(val, -1):
+		enc.buf.AppendString("-Inf")
+	default:
+		enc.buf.AppendFloat(val, bitSize)
+	}
+}
+
+func (enc *logfmtEncoder) AppendInt(val int)     { enc.AppendInt64(int64(val)) }
+func (enc *logfmtEncoder) AppendInt32(val int32) { enc.AppendInt64(int64(val)) }
+func (enc *logfmtEncoder) AppendInt16(val int16) { enc.AppendInt64(int64(val)) }
+func (enc *logfmtEncoder) AppendInt8(val int8)   { enc.AppendInt64(int64(val)) }
+func (enc *logfmtEncoder) AppendInt64(val int64) { enc.buf.AppendInt(val) }
+
+func (enc *logfmtEncoder) AppendString(val string) { appendLogfmtValue(enc.buf, val) }
+
+func (enc *logfmtEncoder) AppendUint(val uint)       { enc.AppendUint64(uint64(val)) }
+func (enc *logfmtEncoder) AppendUint32(val uint32)   { enc.AppendUint64(uint64(val)) }
+func (enc *logfmtEncoder) AppendUint16(val uint16)   { enc.AppendUint64(uint64(val)) }
+func (enc *logfmtEncoder) AppendUint8(val uint8)     { enc.AppendUint64(uint64(val)) }
+func (enc *logfmtEncoder) AppendUintptr(val uintptr) { enc.AppendUint64(uint64(val)) }
+func (enc *logfmtEncoder) AppendUint64(val uint64)   { enc.buf.AppendUint(val) }
+
+// appendLogfmtKey writes key replacing any characters that are not allowed in a logfmt key with an underscore
+func appendLogfmtKey(buf *buffer.Buffer, key string) {
+	if key == "" {
+		buf.AppendByte('_')
+		return
+	}
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			buf.AppendByte('_')
+			continue
+		}
+		buf.AppendString(string(r))
+	}
+}
+
+// appendLogfmtValue writes val as is if possible, otherwise as a quoted string
+func appendLogfmtValue(buf *buffer.Buffer, val string) {
+	if val != "" && strings.IndexFunc(val, needsQuote) < 0 {
+		buf.AppendString(val)
+		return
+	}
+	buf.AppendString(strconv.Quote(val))
+}
+
+func needsQuote(r rune) bool {
+	return r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || !strconv.IsPrint(r)
+}
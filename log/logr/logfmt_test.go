@@ -0,0 +1,99 @@
+package logr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type logfmtUser struct {
+	name string
+	id   int
+}
+
+func (u logfmtUser) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", u.name)
+	enc.AddInt("id", u.id)
+	return nil
+}
+
+func TestLogfmtEncoder(t *testing.T) {
+	cfg := zap.NewProductionEncoderConfig()
+	cfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	enc := NewLogfmtEncoder(cfg)
+	enc.AddString("service", "myservice")
+
+	ent := zapcore.Entry{
+		Level:      zapcore.InfoLevel,
+		Time:       time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+		LoggerName: "db",
+		Message:    "hello world",
+	}
+	fields := []zapcore.Field{
+		zap.String("empty", ""),
+		zap.String("quoted", `say "hi"`),
+		zap.Int("count", 3),
+		zap.Bool("ok", true),
+		zap.Duration("took", time.Second),
+		zap.Error(errors.New("boom")),
+		zap.Object("user", logfmtUser{name: "jane doe", id: 7}),
+		zap.Strings("tags", []string{"a", "b"}),
+		zap.String("bad key=", "v"),
+		zap.Namespace("ns"),
+		zap.String("inner", "x"),
+	}
+	buf, err := enc.EncodeEntry(ent, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	want := `ts=2021-01-02T03:04:05.000Z level=info logger=db msg="hello world" service=myservice empty="" quoted="say \"hi\"" count=3 ok=true took=1 error=boom user.name="jane doe" user.id=7 tags="[\"a\",\"b\"]" bad_key_=v ns.inner=x` + "\n"
+	if got != want {
+		t.Fatalf("expected:\n%v\ngot:\n%v", want, got)
+	}
+}
+
+func TestLogfmtEncoderClone(t *testing.T) {
+	enc := NewLogfmtEncoder(zapcore.EncoderConfig{MessageKey: "msg"})
+	enc.AddString("a", "1")
+	clone := enc.Clone()
+	clone.AddString("b", "2")
+
+	buf, err := enc.EncodeEntry(zapcore.Entry{Message: "m"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "msg=m a=1\n"; got != want {
+		t.Fatalf("expected: %q, got: %q", want, got)
+	}
+	buf, err = clone.EncodeEntry(zapcore.Entry{Message: "m"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "msg=m a=1 b=2\n"; got != want {
+		t.Fatalf("expected: %q, got: %q", want, got)
+	}
+}
+
+func TestPacketLogrLogfmt(t *testing.T) {
+	for name, errToStderr := range map[string]bool{"default": false, "errors to stderr": true} {
+		t.Run(name, func(t *testing.T) {
+			capturedOutput := captureOutput(func() {
+				l, _, err := NewPacketLogr(WithEncoding("logfmt"), WithServiceName("myservice"), WithEnableErrLogsToStderr(errToStderr))
+				if err != nil {
+					t.Fatal(err)
+				}
+				l.Info("logfmt message", "hello", "world")
+			})
+			for _, want := range []string{"level=info", `msg="logfmt message"`, "service=myservice", "hello=world"} {
+				if !strings.Contains(capturedOutput, want) {
+					t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package logr
+
+import (
+	"io"
+	"net"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// WithLogstash also sends entries as newline delimited JSON over TCP to addr, for a Logstash tcp input using the json_lines codec.
+// Entries are always JSON encoded whatever the encoding of the output paths is, see NetworkOption for TLS and queueing.
+func WithLogstash(addr string, opts ...NetworkOption) LoggerOption {
+	return func(args *PacketLogr) {
+		c := newNetSinkConfig(opts)
+		err := c.validate()
+		if addr == "" {
+			err = multierr.Append(err, errors.New("address must not be empty"))
+		}
+		if err != nil {
+			args.errs = multierr.Append(args.errs, errors.WithMessage(err, "WithLogstash"))
+			return
+		}
+		args.newSinks = append(args.newSinks, func(zc zap.Config, _ func(zapcore.WriteSyncer) zapcore.WriteSyncer) (zapcore.Core, io.Closer, error) {
+			zc.Encoding = "json"
+			encoder, err := NewEncoder(zc)
+			if err != nil {
+				return nil, nil, err
+			}
+			s, err := newNetSink("logstash", c, c.dialer("tcp", addr), func(conn net.Conn, msg []byte) error {
+				_, err := conn.Write(msg)
+				return err
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			return zapcore.NewCore(encoder, s, zc.Level), s, nil
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package logr
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPacketLogrLogstash(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 10)
+	go lineServer(ln, 0, lines)
+
+	pl, err := New(WithLogstash(ln.Addr().String()), WithOutputPaths([]string{os.DevNull}), WithEncoding("console"), WithServiceName("stash"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl.Info("to logstash", "n", 1)
+	if err := pl.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-lines:
+		if !strings.HasPrefix(got, "{") || !strings.Contains(got, `"msg":"to logstash","service":"stash","n":1}`) {
+			t.Fatalf("expected a JSON line, got: %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the entry")
+	}
+}
+
+func TestPacketLogrLogstashInvalid(t *testing.T) {
+	_, err := New(WithLogstash("", WithNetworkQueue(0, -1)))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"WithLogstash", "address must not be empty", "queue size must be > 0", "queue timeout must be >= 0"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package logr
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Masked wraps obj, a struct or pointer to a struct, so that its fields are logged according to their `log` struct tags.
+//
+//	type User struct {
+//		ID       int     `json:"id"`
+//		Email    string  `log:",mask"`
+//		Password string  `log:"-"`
+//		Address  Address `log:"address"`
+//	}
+//	logger.Info("signed up", "user", logr.Masked(user))
+//
+// The tag's name sets the key, defaulting to the json tag's name and then the field name, followed by options:
+//
+//	omit  the field is not logged, same as a tag of "-"
+//	mask  the key is logged with a value of "[REDACTED]"
+//
+// Nested structs, including those in slices and maps, are masked using their own tags.
+// Values that are not structs are logged under a "value" key. A pointer back to one of the values it is in is logged as "[CYCLE]".
+func Masked(obj interface{}) zapcore.ObjectMarshaler {
+	return maskedObject{v: reflect.ValueOf(obj)}
+}
+
+// cycleValue is logged instead of a pointer back to one of the values it is in
+const cycleValue = "[CYCLE]"
+
+type maskedObject struct {
+	v reflect.Value
+	// seen are the pointers followed to get to v, it is nil for the object returned by Masked
+	seen seenPointers
+}
+
+func (m maskedObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	seen := m.seen
+	if seen == nil {
+		seen = seenPointers{}
+	}
+	v, ptrs, _ := seen.follow(m.v)
+	if !v.IsValid() || v.Kind() != reflect.Struct {
+		return addMasked(enc, "value", m.v, seen)
+	}
+	defer seen.visit(ptrs)()
+	return marshalMaskedStruct(enc, v, seen)
+}
+
+func marshalMaskedStruct(enc zapcore.ObjectEncoder, v reflect.Value, seen seenPointers) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		name, omit, mask := parseLogTag(f)
+		if omit {
+			continue
+		}
+		fv := v.Field(i)
+		// embedded structs are flattened like encoding/json does
+		if f.Anonymous && name == "" {
+			if ev, ptrs, ok := seen.follow(fv); ok && ev.IsValid() && ev.Kind() == reflect.Struct {
+				leave := seen.visit(ptrs)
+				err := marshalMaskedStruct(enc, ev, seen)
+				leave()
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if mask {
+			enc.AddString(name, redactedValue)
+			continue
+		}
+		if err := addMasked(enc, name, fv, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseLogTag returns the key and options from the field's log tag, falling back to the json tag for the key
+func parseLogTag(f reflect.StructField) (name string, omit, mask bool) {
+	tag := f.Tag.Get("log")
+	if tag == "-" {
+		return "", true, false
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	for _, opt := range parts[1:] {
+		switch opt {
+		case "omit":
+			omit = true
+		case "mask":
+			mask = true
+		}
+	}
+	if name == "" {
+		if jsonName := strings.Split(f.Tag.Get("json"), ",")[0]; jsonName != "-" {
+			name = jsonName
+		}
+	}
+	return name, omit, mask
+}
+
+func addMasked(enc zapcore.ObjectEncoder, key string, v reflect.Value, seen seenPointers) error {
+	if !v.IsValid() {
+		return enc.AddReflected(key, nil)
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		enc.AddTime(key, t)
+		return nil
+	}
+	ev, ptrs, ok := seen.follow(v)
+	if !ok {
+		enc.AddString(key, cycleValue)
+		return nil
+	}
+	if !ev.IsValid() {
+		return enc.AddReflected(key, nil)
+	}
+	switch ev.Kind() {
+	case reflect.Struct:
+		defer seen.visit(ptrs)()
+		return enc.AddObject(key, maskedObject{v: ev, seen: seen})
+	case reflect.Slice, reflect.Array:
+		if containsStructs(ev, seen) {
+			defer seen.visit(ptrs)()
+			return enc.AddArray(key, maskedArray{v: ev, seen: seen})
+		}
+	case reflect.Map:
+		if containsStructs(ev, seen) {
+			defer seen.visit(append(ptrs, seenPointer{ev.Pointer(), ev.Type()}))()
+			return enc.AddObject(key, maskedMap{v: ev, seen: seen})
+		}
+	}
+	return enc.AddReflected(key, v.Interface())
+}
+
+type maskedArray struct {
+	v    reflect.Value
+	seen seenPointers
+}
+
+func (m maskedArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for i := 0; i < m.v.Len(); i++ {
+		ev, ptrs, ok := m.seen.follow(m.v.Index(i))
+		if !ok {
+			enc.AppendString(cycleValue)
+			continue
+		}
+		if !ev.IsValid() || ev.Kind() != reflect.Struct {
+			if err := enc.AppendReflected(m.v.Index(i).Interface()); err != nil {
+				return err
+			}
+			continue
+		}
+		leave := m.seen.visit(ptrs)
+		err := enc.AppendObject(maskedObject{v: ev, seen: m.seen})
+		leave()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type maskedMap struct {
+	v    reflect.Value
+	seen seenPointers
+}
+
+func (m maskedMap) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	iter := m.v.MapRange()
+	for iter.Next() {
+		if err := addMasked(enc, fmt.Sprint(iter.Key().Interface()), iter.Value(), m.seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seenPointer is a pointer followed, with its type as a struct and its first field have the same address
+type seenPointer struct {
+	p uintptr
+	t reflect.Type
+}
+
+// seenPointers are the pointers followed to get to the value being masked, a pointer to one of them is a cycle
+type seenPointers map[seenPointer]bool
+
+// follow follows pointers and interfaces, returning the zero Value for nil, along with the pointers followed.
+// ok is false when one of them was already seen.
+func (s seenPointers) follow(v reflect.Value) (_ reflect.Value, ptrs []seenPointer, ok bool) {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}, ptrs, true
+		}
+		if v.Kind() == reflect.Ptr {
+			p := seenPointer{v.Pointer(), v.Type()}
+			if s[p] {
+				return reflect.Value{}, nil, false
+			}
+			ptrs = append(ptrs, p)
+		}
+		v = v.Elem()
+	}
+	if v.IsValid() && v.Kind() == reflect.Map && !v.IsNil() && s[seenPointer{v.Pointer(), v.Type()}] {
+		return reflect.Value{}, nil, false
+	}
+	return v, ptrs, true
+}
+
+// visit marks ptrs as seen until the func returned is called, once the value they point to is masked
+func (s seenPointers) visit(ptrs []seenPointer) func() {
+	for _, p := range ptrs {
+		s[p] = true
+	}
+	return func() {
+		for _, p := range ptrs {
+			delete(s, p)
+		}
+	}
+}
+
+// containsStructs reports whether the elements of v, a slice, array, or map, are structs or pointers to structs.
+// When they are interfaces, such as in a []interface{} or map[string]interface{}, it reports whether any of them holds one.
+func containsStructs(v reflect.Value, seen seenPointers) bool {
+	if t := v.Type().Elem(); t.Kind() != reflect.Interface {
+		return isStructType(t)
+	}
+	holdsStruct := func(e reflect.Value) bool {
+		ev, _, ok := seen.follow(e)
+		return !ok || (ev.IsValid() && isStructType(ev.Type()))
+	}
+	if v.Kind() == reflect.Map {
+		iter := v.MapRange()
+		for iter.Next() {
+			if holdsStruct(iter.Value()) {
+				return true
+			}
+		}
+		return false
+	}
+	for i := 0; i < v.Len(); i++ {
+		if holdsStruct(v.Index(i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStructType reports whether values of type t are structs or pointers to structs
+func isStructType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{})
+}
@@ -0,0 +1,139 @@
+package logr
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type maskedAddress struct {
+	Street string `log:",mask"`
+	City   string `json:"city"`
+}
+
+type maskedAudit struct {
+	CreatedBy string `json:"created_by"`
+}
+
+type maskedUser struct {
+	maskedAudit
+	ID        int                      `json:"id"`
+	Email     string                   `log:"email,mask"`
+	Password  string                   `log:"-"`
+	SSN       string                   `log:",omit"`
+	Ignored   string                   `json:"-"`
+	Addresses []maskedAddress          `log:"addresses"`
+	Primary   *maskedAddress           `log:"primary"`
+	Others    map[string]maskedAddress `log:"others"`
+	Tags      []string                 `log:"tags"`
+	Joined    time.Time                `log:"joined"`
+	Manager   *maskedUser              `log:"manager"`
+	internal  string
+}
+
+func TestMasked(t *testing.T) {
+	user := maskedUser{
+		maskedAudit: maskedAudit{CreatedBy: "admin"},
+		ID:          7,
+		Email:       "jane@example.com",
+		Password:    "hunter2",
+		SSN:         "123-45-6789",
+		Ignored:     "visible",
+		Addresses:   []maskedAddress{{Street: "1 Main St", City: "NYC"}},
+		Primary:     &maskedAddress{Street: "2 Main St", City: "SFO"},
+		Others:      map[string]maskedAddress{"work": {Street: "3 Main St", City: "AMS"}},
+		Tags:        []string{"a"},
+		Joined:      time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+		internal:    "hidden",
+	}
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{EncodeTime: zapcore.RFC3339TimeEncoder})
+	buf, err := enc.EncodeEntry(zapcore.Entry{}, []zapcore.Field{zap.Object("user", Masked(&user))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	want := `{"user":{"created_by":"admin","id":7,"email":"[REDACTED]","Ignored":"visible",` +
+		`"addresses":[{"Street":"[REDACTED]","city":"NYC"}],"primary":{"Street":"[REDACTED]","city":"SFO"},` +
+		`"others":{"work":{"Street":"[REDACTED]","city":"AMS"}},"tags":["a"],"joined":"2021-01-02T03:04:05Z","manager":null}}` + "\n"
+	if got != want {
+		t.Fatalf("expected:\n%v\ngot:\n%v", want, got)
+	}
+}
+
+func TestMaskedInterfaces(t *testing.T) {
+	v := struct {
+		Items []interface{}          `log:"items"`
+		Extra map[string]interface{} `log:"extra"`
+		Plain []interface{}          `log:"plain"`
+	}{
+		Items: []interface{}{"a", maskedAddress{Street: "1 Main St", City: "NYC"}, &maskedAddress{Street: "2 Main St", City: "SFO"}, nil},
+		Extra: map[string]interface{}{"home": maskedAddress{Street: "3 Main St", City: "AMS"}},
+		Plain: []interface{}{"b", 1},
+	}
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{})
+	buf, err := enc.EncodeEntry(zapcore.Entry{}, []zapcore.Field{zap.Object("v", Masked(v))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	want := `{"v":{"items":["a",{"Street":"[REDACTED]","city":"NYC"},{"Street":"[REDACTED]","city":"SFO"},null],` +
+		`"extra":{"home":{"Street":"[REDACTED]","city":"AMS"}},"plain":["b",1]}}` + "\n"
+	if got != want {
+		t.Fatalf("expected:\n%v\ngot:\n%v", want, got)
+	}
+}
+
+func TestMaskedCycle(t *testing.T) {
+	jane := &maskedUser{ID: 1}
+	john := &maskedUser{ID: 2, Manager: jane}
+	jane.Manager = john
+	primary := &maskedAddress{City: "NYC"}
+	jane.Primary, john.Primary = primary, primary
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{})
+	buf, err := enc.EncodeEntry(zapcore.Entry{}, []zapcore.Field{zap.Object("user", Masked(jane))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{`"manager":{"created_by":"","id":2,`, `"manager":"[CYCLE]"`, `"primary":{"Street":"[REDACTED]","city":"NYC"}`} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, got)
+		}
+	}
+	// the pointers shared by values that aren't in one another aren't cycles
+	if n := strings.Count(got, `"city":"NYC"`); n != 2 {
+		t.Fatalf("expected the shared address twice, got: %v", got)
+	}
+}
+
+func TestMaskedNonStruct(t *testing.T) {
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{})
+	buf, err := enc.EncodeEntry(zapcore.Entry{}, []zapcore.Field{zap.Object("n", Masked(42))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), `{"n":{"value":42}}`+"\n"; got != want {
+		t.Fatalf("expected: %v, got: %v", want, got)
+	}
+}
+
+func TestPacketLogrMasked(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		l, _, err := NewPacketLogr()
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Info("signed up", "user", Masked(maskedUser{ID: 1, Email: "jane@example.com", Password: "hunter2"}))
+	})
+	for _, secret := range []string{"jane@example.com", "hunter2"} {
+		if strings.Contains(capturedOutput, secret) {
+			t.Fatalf("expected %v to be masked, got: %v", secret, capturedOutput)
+		}
+	}
+	if !strings.Contains(capturedOutput, `"user":{"created_by":"","id":1,"email":"[REDACTED]"`) {
+		t.Fatalf("expected masked user, got: %v", capturedOutput)
+	}
+}
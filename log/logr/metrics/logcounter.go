@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"github.com/packethost/pkg/log/logr"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LogCounter counts what a logger writes with Prometheus counters, it is the logr.LogCounter of logr.WithLogCounter:
+//
+//	counter := metrics.NewLogCounter()
+//	logger, err := logr.New(logr.WithLogCounter(counter))
+//	err = m.Register(counter.Collector(logger))
+type LogCounter struct {
+	lines *prometheus.CounterVec
+	bytes prometheus.Counter
+}
+
+// NewLogCounter returns a counter of log_lines_total by level and component, the logger name, and log_bytes_total written to the outputs and sinks
+func NewLogCounter() *LogCounter {
+	return &LogCounter{
+		lines: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "log_lines_total",
+			Help: "Number of log entries written, by level and component.",
+		}, []string{"level", "component"}),
+		bytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "log_bytes_total",
+			Help: "Number of bytes of log entries written to the outputs and sinks.",
+		}),
+	}
+}
+
+func (c *LogCounter) CountEntry(level, component string) {
+	c.lines.WithLabelValues(level, component).Inc()
+}
+
+func (c *LogCounter) CountBytes(n int) {
+	c.bytes.Add(float64(n))
+}
+
+// Collector returns the collector of the counters, along with log_dropped_total, the entries p dropped because the async buffer
+// or a sink's queue was full. p is the logger counted with c.
+func (c *LogCounter) Collector(p *logr.PacketLogr) prometheus.Collector {
+	return &logCollector{
+		c:       c,
+		dropped: prometheus.NewDesc("log_dropped_total", "Number of log entries dropped because the async buffer or a sink's queue was full.", nil, nil),
+		p:       p,
+	}
+}
+
+// logCollector is the prometheus.Collector of a LogCounter
+type logCollector struct {
+	c       *LogCounter
+	dropped *prometheus.Desc
+	p       *logr.PacketLogr
+}
+
+func (l *logCollector) Describe(ch chan<- *prometheus.Desc) {
+	l.c.lines.Describe(ch)
+	l.c.bytes.Describe(ch)
+	ch <- l.dropped
+}
+
+func (l *logCollector) Collect(ch chan<- prometheus.Metric) {
+	l.c.lines.Collect(ch)
+	l.c.bytes.Collect(ch)
+	ch <- prometheus.MustNewConstMetric(l.dropped, prometheus.CounterValue, float64(l.p.Dropped()+l.p.DroppedSinkEntries()))
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLogCounter(t *testing.T) {
+	counter := NewLogCounter()
+	pl, logged := logtest.New(t, logr.WithLogCounter(counter))
+	pl.Info("started")
+	pl.V(1).Info("filtered out")
+	db := pl.Named("db")
+	db.Info("connected")
+	db.Error(nil, "query failed")
+	out := logged()
+
+	want := `
+# HELP log_dropped_total Number of log entries dropped because the async buffer or a sink's queue was full.
+# TYPE log_dropped_total counter
+log_dropped_total 0
+# HELP log_lines_total Number of log entries written, by level and component.
+# TYPE log_lines_total counter
+log_lines_total{component="",level="info"} 1
+log_lines_total{component="db",level="error"} 1
+log_lines_total{component="db",level="info"} 1
+`
+	collector := counter.Collector(pl)
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), "log_lines_total", "log_dropped_total"); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(counter.bytes); got != float64(len(out)) {
+		t.Fatalf("expected %d bytes to be counted, got: %v", len(out), got)
+	}
+	if err := prometheus.NewRegistry().Register(collector); err != nil {
+		t.Fatalf("expected the collector to register, got: %v", err)
+	}
+}
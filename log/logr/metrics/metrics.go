@@ -0,0 +1,197 @@
+// Package metrics is the opinionated Prometheus setup, the same way logr.New is for logging. New builds a registry
+// with the process and Go runtime collectors, whose metrics all have the service's name as the service label,
+// and Handler serves it:
+//
+//	m, err := metrics.New(metrics.WithServiceName("billing"), metrics.WithLogger(logger))
+//	requests, err := m.NewCounter("http_requests_total", "Number of HTTP requests handled.", "method", "code")
+//	http.Handle("/metrics", m.Handler())
+//
+// The New helpers enforce the Prometheus naming conventions, see https://prometheus.io/docs/practices/naming/.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/multierr"
+)
+
+// ServiceLabel is the label the service's name is set as on every metric, see WithServiceName
+const ServiceLabel = "service"
+
+// Option customizes New
+type Option func(*Metrics)
+
+// WithServiceName sets the service label of every metric to name
+func WithServiceName(name string) Option {
+	return func(m *Metrics) {
+		if name == "" {
+			m.errs = multierr.Append(m.errs, errors.New("WithServiceName: name must not be empty"))
+			return
+		}
+		m.labels[ServiceLabel] = name
+	}
+}
+
+// WithConstLabels adds labels with the same value on every metric, such as the region or environment
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(m *Metrics) {
+		for name, value := range labels {
+			if err := validLabel(name); err != nil {
+				m.errs = multierr.Append(m.errs, errors.WithMessage(err, "WithConstLabels"))
+				continue
+			}
+			m.labels[name] = value
+		}
+	}
+}
+
+// WithNamespace prefixes the names of the metrics made with the New helpers with namespace and an underscore
+func WithNamespace(namespace string) Option {
+	return func(m *Metrics) {
+		if !nameRE.MatchString(namespace) {
+			m.errs = multierr.Append(m.errs, errors.Errorf("WithNamespace: namespace must be snake_case, got: %q", namespace))
+			return
+		}
+		m.namespace = namespace
+	}
+}
+
+// WithoutRuntimeCollectors leaves out the process and Go runtime collectors, such as for tests
+func WithoutRuntimeCollectors() Option {
+	return func(m *Metrics) { m.runtime = false }
+}
+
+// WithLogger logs the errors of Handler, such as a collector failing, with p
+func WithLogger(p *logr.PacketLogr) Option {
+	return func(m *Metrics) { m.logger = p }
+}
+
+// Metrics is a Prometheus registry whose metrics all have the service's labels, see New
+type Metrics struct {
+	registry   *prometheus.Registry
+	registerer prometheus.Registerer
+	labels     prometheus.Labels
+	namespace  string
+	runtime    bool
+	logger     *logr.PacketLogr
+	errs       error
+}
+
+// New builds a registry with the process and Go runtime collectors, the metrics registered with it get the service's labels
+func New(opts ...Option) (*Metrics, error) {
+	m := &Metrics{registry: prometheus.NewRegistry(), labels: prometheus.Labels{}, runtime: true}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.errs != nil {
+		return nil, m.errs
+	}
+	m.registerer = prometheus.WrapRegistererWith(m.labels, m.registry)
+	if m.runtime {
+		if err := multierr.Combine(
+			m.registerer.Register(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{})),
+			m.registerer.Register(prometheus.NewGoCollector()),
+		); err != nil {
+			return nil, errors.Wrap(err, "failed to register the runtime collectors")
+		}
+	}
+	return m, nil
+}
+
+// Registry returns the registry, for gathering its metrics, registering collectors with it skips the service's labels
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Registerer returns the registerer adding the service's labels, for libraries that register their own collectors
+func (m *Metrics) Registerer() prometheus.Registerer {
+	return m.registerer
+}
+
+// Register registers c with the service's labels
+func (m *Metrics) Register(c prometheus.Collector) error {
+	return m.registerer.Register(c)
+}
+
+// Handler serves the metrics in the Prometheus exposition format, along with promhttp's metrics of the handler itself
+func (m *Metrics) Handler() http.Handler {
+	opts := promhttp.HandlerOpts{Registry: m.registerer}
+	if m.logger != nil {
+		opts.ErrorLog = errorLog{m.logger}
+	}
+	return promhttp.InstrumentMetricHandler(m.registerer, promhttp.HandlerFor(m.registry, opts))
+}
+
+// errorLog logs the errors of promhttp's handler
+type errorLog struct {
+	p *logr.PacketLogr
+}
+
+func (l errorLog) Println(v ...interface{}) {
+	l.p.Named("metrics").Error(errors.New(strings.TrimSpace(fmt.Sprintln(v...))), "failed to serve metrics")
+}
+
+// NewCounter registers a counter with the labels, its name must be snake_case and end in _total
+func (m *Metrics) NewCounter(name, help string, labels ...string) (*prometheus.CounterVec, error) {
+	if err := validName(name, help, labels); err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(name, "_total") {
+		return nil, errors.Errorf("counter %q must end in _total", name)
+	}
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: m.namespace, Name: name, Help: help}, labels)
+	return register(m, c)
+}
+
+// NewGauge registers a gauge with the labels, its name must be snake_case and not end in _total
+func (m *Metrics) NewGauge(name, help string, labels ...string) (*prometheus.GaugeVec, error) {
+	if err := validName(name, help, labels); err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(name, "_total") {
+		return nil, errors.Errorf("gauge %q must not end in _total, it is for counters", name)
+	}
+	g := prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: m.namespace, Name: name, Help: help}, labels)
+	return register(m, g)
+}
+
+// NewHistogram registers a histogram with the labels, its name must be snake_case and end in a base unit, such as _seconds or _bytes.
+// The buckets are prometheus.DefBuckets when nil, which suits durations in seconds.
+func (m *Metrics) NewHistogram(name, help string, buckets []float64, labels ...string) (*prometheus.HistogramVec, error) {
+	if err := validName(name, help, labels); err != nil {
+		return nil, err
+	}
+	if !hasUnit(name) {
+		return nil, errors.Errorf("histogram %q must end in a base unit, one of %s", name, joinUnits())
+	}
+	for _, label := range labels {
+		if label == "le" {
+			return nil, errors.Errorf("histogram %q must not have the label le, it is the bucket's", name)
+		}
+	}
+	h := prometheus.NewHistogramVec(prometheus.HistogramOpts{Namespace: m.namespace, Name: name, Help: help, Buckets: buckets}, labels)
+	return register(m, h)
+}
+
+// register registers c, when an identical collector was registered before it is returned instead so the New helpers
+// can be called again for the same metric, such as by each instance of a client
+func register[C prometheus.Collector](m *Metrics, c C) (C, error) {
+	err := m.registerer.Register(c)
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(C); ok {
+			return existing, nil
+		}
+	}
+	if err != nil {
+		var zero C
+		return zero, err
+	}
+	return c, nil
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMetrics(t *testing.T) {
+	m, err := New(WithServiceName("billing"), WithConstLabels(prometheus.Labels{"region": "da"}), WithNamespace("api"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests, err := m.NewCounter("http_requests_total", "Number of HTTP requests handled.", "code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests.WithLabelValues("200").Inc()
+	again, err := m.NewCounter("http_requests_total", "Number of HTTP requests handled.", "code")
+	if err != nil || again != requests {
+		t.Fatalf("expected the counter registered before, got: %v", err)
+	}
+	latency, err := m.NewHistogram("http_request_duration_seconds", "How long HTTP requests take.", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latency.WithLabelValues().Observe(0.2)
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	for _, want := range []string{
+		`api_http_requests_total{code="200",region="da",service="billing"} 1`,
+		`api_http_request_duration_seconds_count{region="da",service="billing"} 1`,
+		`go_goroutines{region="da",service="billing"}`,
+		`process_start_time_seconds{region="da",service="billing"}`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Fatalf("expected %s to be served, got: %s", want, body)
+		}
+	}
+}
+
+func TestMetricsNaming(t *testing.T) {
+	m, err := New(WithoutRuntimeCollectors())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, err := range map[string]error{
+		"counter without _total":      errOf(m.NewCounter("http_requests", "help")),
+		"camelCase counter":           errOf(m.NewCounter("httpRequests_total", "help")),
+		"counter without help":        errOf(m.NewCounter("http_requests_total", "")),
+		"camelCase label":             errOf(m.NewCounter("http_requests_total", "help", "statusCode")),
+		"service label":               errOf(m.NewCounter("http_requests_total", "help", "service")),
+		"gauge ending in _total":      errOf(m.NewGauge("queue_depth_total", "help")),
+		"gauge ending in _count":      errOf(m.NewGauge("queue_count", "help")),
+		"histogram without unit":      errOf(m.NewHistogram("http_request_duration", "help", nil)),
+		"histogram in milliseconds":   errOf(m.NewHistogram("http_request_duration_milliseconds", "help", nil)),
+		"histogram with the le label": errOf(m.NewHistogram("response_size_bytes", "help", nil, "le")),
+	} {
+		if err == nil {
+			t.Fatalf("expected an error for a %s", name)
+		}
+	}
+
+	if _, err := New(WithServiceName(""), WithNamespace("Api"), WithConstLabels(prometheus.Labels{"service": "x"})); err == nil || strings.Count(err.Error(), ";") != 2 {
+		t.Fatalf("expected the errors of every option, got: %v", err)
+	}
+}
+
+func errOf(_ interface{}, err error) error {
+	return err
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// nameRE is snake_case, the names of metrics and labels
+var nameRE = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// units are the base units histograms must end in, see https://prometheus.io/docs/practices/naming/#base-units
+var units = []string{"seconds", "bytes", "ratio", "meters", "grams", "joules", "volts", "amperes", "celsius"}
+
+// reservedSuffixes are added by Prometheus to the series of histograms and summaries
+var reservedSuffixes = []string{"_bucket", "_count", "_sum"}
+
+// validName checks the name, help, and labels of a metric made with the New helpers
+func validName(name, help string, labels []string) error {
+	if !nameRE.MatchString(name) {
+		return errors.Errorf("metric %q must be snake_case", name)
+	}
+	for _, suffix := range reservedSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return errors.Errorf("metric %q must not end in %s, it is added by Prometheus", name, suffix)
+		}
+	}
+	if help == "" {
+		return errors.Errorf("metric %q must have help", name)
+	}
+	for _, label := range labels {
+		if err := validLabel(label); err != nil {
+			return errors.WithMessagef(err, "metric %q", name)
+		}
+	}
+	return nil
+}
+
+// validLabel checks the name of a label, the service label is set by WithServiceName
+func validLabel(name string) error {
+	if !nameRE.MatchString(name) {
+		return errors.Errorf("label %q must be snake_case", name)
+	}
+	if name == ServiceLabel {
+		return errors.Errorf("label %q is set by WithServiceName", name)
+	}
+	return nil
+}
+
+// hasUnit is whether name ends in a base unit
+func hasUnit(name string) bool {
+	for _, unit := range units {
+		if strings.HasSuffix(name, "_"+unit) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinUnits() string {
+	return strings.Join(units, ", ")
+}
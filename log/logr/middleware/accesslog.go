@@ -0,0 +1,284 @@
+package middleware
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+)
+
+// The verbosities, as logr's V takes them, of the level names WithRouteLevel takes
+var routeVerbosity = map[string]int{"info": 0, "debug": 1, "trace": 2, "off": routeOff}
+
+// routeOff is the verbosity of the routes that aren't logged
+const routeOff = -1
+
+// idSegment matches the path segments NormalizePath replaces: numbers, UUIDs, and long hex strings such as hashes
+var idSegment = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+// Option customizes AccessLog, the gRPC interceptors, and NewLoggingTransport
+type Option func(*config)
+
+type config struct {
+	normalize      func(*http.Request) string
+	routes         map[string]int
+	trustedProxies int
+	requestIDOpts  []logr.RequestIDOption
+	// payloadMethods and redactedPaths are for the gRPC interceptors, see WithPayloads
+	payloadMethods []string
+	redactedPaths  map[string]bool
+	// the rest are for NewLoggingTransport
+	redactedParams  map[string]bool
+	loggedHeaders   []string
+	redactedHeaders map[string]bool
+	retries         int
+	// errs are the errors of the options, returned by the constructors
+	errs error
+}
+
+func newConfig(opts []Option) (config, error) {
+	c := config{
+		normalize:       func(r *http.Request) string { return NormalizePath(r.URL.Path) },
+		routes:          map[string]int{},
+		redactedPaths:   map[string]bool{},
+		redactedParams:  map[string]bool{},
+		redactedHeaders: map[string]bool{},
+	}
+	for _, name := range defaultRedactedParams {
+		c.redactedParams[name] = true
+	}
+	for _, name := range defaultRedactedHeaders {
+		c.redactedHeaders[http.CanonicalHeaderKey(name)] = true
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c, c.errs
+}
+
+// WithPathNormalizer logs the path normalize returns for a request instead of the one NormalizePath returns,
+// such as the route pattern a router matched
+func WithPathNormalizer(normalize func(r *http.Request) string) Option {
+	return func(c *config) { c.normalize = normalize }
+}
+
+// WithRouteLevel logs the requests whose normalized path is route at level, one of info, debug, or trace, or not at all
+// with off, such as WithRouteLevel("/healthz", "off"). A route ending in * is a prefix.
+func WithRouteLevel(route, level string) Option {
+	return withRouteLevel("WithRouteLevel", route, level)
+}
+
+// withRouteLevel is WithRouteLevel, with the errors attributed to the option name
+func withRouteLevel(name, route, level string) Option {
+	return func(c *config) {
+		v, ok := routeVerbosity[level]
+		if !ok {
+			c.errs = multierr.Append(c.errs, errors.Errorf("%s: level must be one of info, debug, trace, or off, got: %q", name, level))
+			return
+		}
+		c.routes[route] = v
+	}
+}
+
+// WithForwardedFor logs the address X-Forwarded-For has for the client as the remote IP, for servers behind trustedProxies
+// proxies each appending the address it was called from. It is the right-most address the proxies didn't append themselves,
+// the trustedProxies-th from the right, as the addresses to the left of it are whatever the client sent.
+func WithForwardedFor(trustedProxies int) Option {
+	return func(c *config) {
+		if trustedProxies < 1 {
+			c.errs = multierr.Append(c.errs, errors.Errorf("WithForwardedFor: there must be at least 1 trusted proxy, got: %d", trustedProxies))
+			return
+		}
+		c.trustedProxies = trustedProxies
+	}
+}
+
+// WithRequestIDOptions customizes how requests without a request ID get one, see logr.RequestIDMiddleware,
+// and the header NewLoggingTransport sets it on, such as with logr.WithRequestIDHeader
+func WithRequestIDOptions(opts ...logr.RequestIDOption) Option {
+	return func(c *config) { c.requestIDOpts = append(c.requestIDOpts, opts...) }
+}
+
+// AccessLog logs one entry per request handled by next once it is done, with its method, path, status, bytes, duration,
+// remote_ip, user_agent, and request_id, using the logger logr.FromContext returns for the request, which next can log with too.
+// Requests whose handler panics are logged with a 500, unless it had responded already, and the panic carries on.
+// Requests that don't have a request ID yet, from logr.RequestIDMiddleware, are given one by it. The path is normalized with
+// NormalizePath, so that the paths of a route are the same, and entries are logged at info unless WithRouteLevel says otherwise.
+// It returns the errors of opts, such as a WithRouteLevel level that isn't valid.
+func AccessLog(p *logr.PacketLogr, next http.Handler, opts ...Option) (http.Handler, error) {
+	a, err := NewAccessLogger(p, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return a.Handler(next), nil
+}
+
+// AccessLogger logs requests like AccessLog, for routers whose handlers aren't http.Handlers, such as gin and echo,
+// or that take the middleware apart from the handler, such as chi, see Handler
+type AccessLogger struct {
+	p *logr.PacketLogr
+	c config
+}
+
+// NewAccessLogger returns an AccessLogger logging with p, or the errors of opts
+func NewAccessLogger(p *logr.PacketLogr, opts ...Option) (*AccessLogger, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &AccessLogger{p: p, c: c}, nil
+}
+
+// Handler is AccessLog with a's options, for routers taking middleware as a func(http.Handler) http.Handler, such as chi
+func (a *AccessLogger) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r = a.Start(w, r)
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			v := recover()
+			if v != nil && !rw.wroteHeader {
+				rw.status = http.StatusInternalServerError
+			}
+			a.Log(r, "", rw.status, rw.bytes, time.Since(start))
+			if v != nil {
+				panic(v)
+			}
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// Start gives r a request ID, and sets it on w, unless it already has one, see logr.RequestIDMiddleware.
+// The returned request is the one to handle and to pass to Log.
+func (a *AccessLogger) Start(w http.ResponseWriter, r *http.Request) *http.Request {
+	if logr.RequestIDFromContext(r.Context()) != "" {
+		return r
+	}
+	a.p.RequestIDMiddleware(http.HandlerFunc(func(_ http.ResponseWriter, withID *http.Request) { r = withID }), a.c.requestIDOpts...).ServeHTTP(w, r)
+	return r
+}
+
+// Log logs the entry of r, which was handled in duration with a response of status and bytes. route is the path that is logged,
+// such as the route template a router matched, when it is empty the path of r is normalized instead.
+func (a *AccessLogger) Log(r *http.Request, route string, status, bytes int, duration time.Duration) {
+	path := route
+	if path == "" {
+		path = a.c.normalize(r)
+	}
+	v, ok := a.c.verbosity(path)
+	if !ok {
+		return
+	}
+	a.p.FromContext(r.Context()).V(v).Info("request",
+		"method", r.Method,
+		"path", path,
+		"status", status,
+		"bytes", bytes,
+		"duration", duration,
+		"remote_ip", a.c.remoteIP(r),
+		"user_agent", r.UserAgent(),
+	)
+}
+
+// verbosity is the verbosity requests to path are logged at, it is false when they aren't logged
+func (c config) verbosity(path string) (int, bool) {
+	v, ok := c.routes[path]
+	if !ok {
+		// the longest prefix wins
+		longest := -1
+		for route, rv := range c.routes {
+			if prefix := strings.TrimSuffix(route, "*"); prefix != route && strings.HasPrefix(path, prefix) && len(prefix) > longest {
+				v, longest = rv, len(prefix)
+			}
+		}
+	}
+	return v, v != routeOff
+}
+
+func (c config) remoteIP(r *http.Request) string {
+	if c.trustedProxies > 0 {
+		// the header can be sent more than once, the addresses of all of them are in order
+		var hops []string
+		for _, fwd := range r.Header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(fwd, ",") {
+				if hop = strings.TrimSpace(hop); hop != "" {
+					hops = append(hops, hop)
+				}
+			}
+		}
+		if len(hops) > 0 {
+			// with fewer addresses than proxies, all of them were appended by the proxies and the left-most is the closest to the client
+			i := len(hops) - c.trustedProxies
+			if i < 0 {
+				i = 0
+			}
+			return hops[i]
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// NormalizePath replaces the segments of path that look like IDs, numbers, UUIDs, and long hex strings, with {id},
+// so that /devices/7c0f2d1e-1e5a-4b2c-9a56-3c1b8f2d9e10/ports/2 is /devices/{id}/ports/{id}
+func NormalizePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if idSegment.MatchString(s) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// responseWriter records the status and the number of bytes of the response
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Flush flushes the response when the wrapped writer supports it, for streaming handlers
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hijacks the connection when the wrapped writer supports it, for websockets
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController
+func (w *responseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+)
+
+// accessLog is AccessLog failing t on the errors of opts
+func accessLog(t *testing.T, l *logr.PacketLogr, next http.Handler, opts ...Option) http.Handler {
+	t.Helper()
+	h, err := AccessLog(l, next, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestAccessLog(t *testing.T) {
+	l, entries := logtest.NewEntries(t)
+	h := accessLog(t, l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.FromContext(r.Context()).Info("handling")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/devices/7c0f2d1e-1e5a-4b2c-9a56-3c1b8f2d9e10/ports/2", nil)
+	req.Header.Set("User-Agent", "test")
+	req.Header.Set(logr.RequestIDHeader, "r1")
+	req.RemoteAddr = "10.0.0.1:4321"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	got := entries()
+	if len(got) != 2 || got[0]["msg"] != "handling" || got[0]["request_id"] != "r1" {
+		t.Fatalf("expected the handler to log with the request's logger, got: %v", got)
+	}
+	entry := got[1]
+	want := map[string]interface{}{
+		"msg": "request", "method": "POST", "path": "/devices/{id}/ports/{id}", "status": float64(201), "bytes": float64(5),
+		"remote_ip": "10.0.0.1", "user_agent": "test", "request_id": "r1", "level": "info",
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Fatalf("expected %s to be %v, got: %v", k, v, entry)
+		}
+	}
+	if _, ok := entry["duration"]; !ok {
+		t.Fatalf("expected the duration, got: %v", entry)
+	}
+	if rec.Header().Get(logr.RequestIDHeader) != "r1" {
+		t.Fatalf("expected the request ID on the response, got: %v", rec.Header())
+	}
+}
+
+func TestAccessLogRequestIDMiddleware(t *testing.T) {
+	l, entries := logtest.NewEntries(t)
+	var id string
+	h := l.RequestIDMiddleware(accessLog(t, l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = logr.RequestIDFromContext(r.Context())
+	})))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	got := entries()
+	if len(got) != 1 || id == "" || got[0]["request_id"] != id {
+		t.Fatalf("expected the request ID of the outer middleware to be kept, got: %v and %v", id, got)
+	}
+}
+
+func TestAccessLogRouteLevel(t *testing.T) {
+	l, entries := logtest.NewEntries(t, logr.WithLogLevel("debug"))
+	h := accessLog(t, l, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+		WithRouteLevel("/healthz", "off"), WithRouteLevel("/metrics*", "debug"), WithRouteLevel("/metrics/internal*", "trace"),
+		WithForwardedFor(2))
+	for _, path := range []string{"/healthz", "/metrics/go", "/metrics/internal/x", "/devices"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Forwarded-For", "203.0.113.7, 192.0.2.1, 10.0.0.1")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	got := entries()
+	if len(got) != 2 || got[0]["path"] != "/metrics/go" || got[0]["level"] != "debug" || got[1]["path"] != "/devices" {
+		t.Fatalf("expected only the routes at or above the log level, got: %v", got)
+	}
+	if got[1]["remote_ip"] != "192.0.2.1" {
+		t.Fatalf("expected the forwarded address, got: %v", got[1])
+	}
+}
+
+func TestAccessLogPanic(t *testing.T) {
+	l, entries := logtest.NewEntries(t)
+	h := accessLog(t, l, http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic("boom") }))
+	func() {
+		defer func() {
+			if v := recover(); v != "boom" {
+				t.Fatalf("expected the panic to carry on, got: %v", v)
+			}
+		}()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/devices", nil))
+	}()
+	if got := entries(); len(got) != 1 || got[0]["status"] != float64(500) {
+		t.Fatalf("expected the request to be logged with a 500, got: %v", got)
+	}
+}
+
+func TestAccessLogInvalidOptions(t *testing.T) {
+	l, _ := logtest.NewEntries(t)
+	_, err := AccessLog(l, http.NotFoundHandler(), WithRouteLevel("/healthz", "warn"), WithForwardedFor(0))
+	for _, want := range []string{
+		`WithRouteLevel: level must be one of info, debug, trace, or off, got: "warn"`,
+		"WithForwardedFor: there must be at least 1 trusted proxy, got: 0",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, err)
+		}
+	}
+	if _, err := NewAccessLogger(l, WithRouteLevel("/healthz", "warn")); err == nil {
+		t.Fatal("expected NewAccessLogger to return the error of the option")
+	}
+}
+
+func TestAccessLogPathNormalizer(t *testing.T) {
+	l, entries := logtest.NewEntries(t)
+	h := accessLog(t, l, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}), WithPathNormalizer(func(*http.Request) string { return "/devices/:id" }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/devices/1", nil))
+	if got := entries(); len(got) != 1 || got[0]["path"] != "/devices/:id" {
+		t.Fatalf("expected the normalized path, got: %v", got)
+	}
+}
+
+func TestNormalizePath(t *testing.T) {
+	for path, want := range map[string]string{
+		"/":                             "/",
+		"/devices/42":                   "/devices/{id}",
+		"/images/0123456789abcdef01234": "/images/{id}",
+		"/v1/devices":                   "/v1/devices",
+	} {
+		if got := NormalizePath(path); got != want {
+			t.Fatalf("expected %s to be %s, got: %s", path, want, got)
+		}
+	}
+}
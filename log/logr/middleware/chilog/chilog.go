@@ -0,0 +1,40 @@
+// Package chilog adapts the access log and panic recovery of the middleware package to chi routers,
+// logging the route patterns chi matched, such as /devices/{id}, as the paths.
+package chilog
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/middleware"
+)
+
+// AccessLog returns chi middleware logging requests like middleware.AccessLog, with the route pattern chi matched as the path.
+// It is for Router.Use, the route context of chi isn't on the request yet when the router is wrapped instead.
+// Requests that didn't match a route have their path normalized with middleware.NormalizePath. It returns the errors of opts.
+func AccessLog(p *logr.PacketLogr, opts ...middleware.Option) (func(http.Handler) http.Handler, error) {
+	a, err := middleware.NewAccessLogger(p, append([]middleware.Option{middleware.WithPathNormalizer(routePattern)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return a.Handler, nil
+}
+
+// Recoverer returns chi middleware recovering from panics like middleware.Recover, use it after AccessLog so the panics
+// are logged with the request's fields and the access log has the 500
+func Recoverer(p *logr.PacketLogr) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return middleware.Recover(p, next)
+	}
+}
+
+// routePattern is the route pattern chi matched for r, such as /devices/{id}/ports/{port}
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return middleware.NormalizePath(r.URL.Path)
+}
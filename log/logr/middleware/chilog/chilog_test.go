@@ -0,0 +1,42 @@
+package chilog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+)
+
+func TestAccessLog(t *testing.T) {
+	l, logged := logtest.New(t)
+	accessLog, err := AccessLog(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := chi.NewRouter()
+	r.Use(accessLog, Recoverer(l))
+	r.Get("/devices/{id}", func(w http.ResponseWriter, r *http.Request) {
+		l.FromContext(r.Context()).Info("handling")
+	})
+	r.Get("/panics", func(http.ResponseWriter, *http.Request) { panic("boom") })
+	for _, path := range []string{"/devices/abc", "/panics"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(logr.RequestIDHeader, "r1")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	got := logged()
+	for _, want := range []string{
+		`"msg":"handling","service":"not/set","request_id":"r1"`,
+		`"method":"GET","path":"/devices/{id}","status":200`,
+		`"msg":"recovered from panic"`,
+		`"path":"/panics","status":500`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %s to be logged, got: %v", want, got)
+		}
+	}
+}
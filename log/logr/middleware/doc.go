@@ -0,0 +1,3 @@
+// Package middleware logs the requests handled by HTTP servers, the outbound calls of HTTP clients, and the RPCs of gRPC servers and clients,
+// with a logr.PacketLogr, one structured entry per request. The chilog, ginlog, and echolog packages adapt it to the chi, gin, and echo routers.
+package middleware
@@ -0,0 +1,58 @@
+// Package echolog adapts the access log and panic recovery of the middleware package to echo servers,
+// logging the route paths echo matched, such as /devices/:id, as the paths.
+package echolog
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/middleware"
+)
+
+// AccessLog returns echo middleware logging requests like middleware.AccessLog, with the route path echo matched as the path.
+// The logger of c.Request().Context(), from logr.FromContext, has the request's fields. The errors of handlers are handled
+// with c.Error, so the access log has their status, and are returned like echo's own logger middleware does.
+// It returns the errors of opts.
+func AccessLog(p *logr.PacketLogr, opts ...middleware.Option) (echo.MiddlewareFunc, error) {
+	a, err := middleware.NewAccessLogger(p, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			c.SetRequest(a.Start(c.Response(), c.Request()))
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+			a.Log(c.Request(), c.Path(), c.Response().Status, int(c.Response().Size), time.Since(start))
+			return err
+		}
+	}, nil
+}
+
+// Recover returns echo middleware recovering from panics, logging them with middleware.LogPanic and handling them as a 500
+// with c.Error, instead of echo's Recover. Use it after AccessLog so the panics are logged with the request's fields and the
+// access log has the 500.
+func Recover(p *logr.PacketLogr) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				middleware.LogPanic(p, c.Request(), v)
+				c.Error(echo.NewHTTPError(http.StatusInternalServerError).SetInternal(fmt.Errorf("panic: %v", v)))
+			}()
+			return next(c)
+		}
+	}
+}
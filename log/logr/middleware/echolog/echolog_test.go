@@ -0,0 +1,49 @@
+package echolog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+)
+
+func TestAccessLog(t *testing.T) {
+	l, logged := logtest.New(t)
+	accessLog, err := AccessLog(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	e.Use(accessLog, Recover(l))
+	e.GET("/devices/:id", func(c echo.Context) error {
+		l.FromContext(c.Request().Context()).Info("handling")
+		return c.String(http.StatusCreated, "hello")
+	})
+	e.GET("/forbidden", func(echo.Context) error { return echo.ErrForbidden })
+	e.GET("/panics", func(echo.Context) error { panic("boom") })
+	for _, path := range []string{"/devices/abc", "/forbidden", "/panics"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(logr.RequestIDHeader, "r1")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if path == "/panics" && rec.Code != http.StatusInternalServerError {
+			t.Fatalf("expected a 500, got: %d", rec.Code)
+		}
+	}
+	got := logged()
+	for _, want := range []string{
+		`"msg":"handling","service":"not/set","request_id":"r1"`,
+		`"method":"GET","path":"/devices/:id","status":201,"bytes":5`,
+		`"path":"/forbidden","status":403`,
+		`"msg":"recovered from panic"`,
+		`"path":"/panics","status":500`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %s to be logged, got: %v", want, got)
+		}
+	}
+}
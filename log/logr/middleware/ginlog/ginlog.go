@@ -0,0 +1,57 @@
+// Package ginlog adapts the access log and panic recovery of the middleware package to gin engines,
+// logging the route templates gin matched, such as /devices/:id, as the paths.
+package ginlog
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/middleware"
+)
+
+// AccessLog returns gin middleware logging requests like middleware.AccessLog, with the route template gin matched as the path.
+// The logger of c.Request.Context(), from logr.FromContext, has the request's fields. Requests that didn't match a route
+// have their path normalized with middleware.NormalizePath. It returns the errors of opts.
+func AccessLog(p *logr.PacketLogr, opts ...middleware.Option) (gin.HandlerFunc, error) {
+	a, err := middleware.NewAccessLogger(p, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Request = a.Start(c.Writer, c.Request)
+		c.Next()
+		// the size is -1 when nothing was written
+		bytes := c.Writer.Size()
+		if bytes < 0 {
+			bytes = 0
+		}
+		a.Log(c.Request, c.FullPath(), c.Writer.Status(), bytes, time.Since(start))
+	}, nil
+}
+
+// Recovery returns gin middleware recovering from panics, logging them with middleware.LogPanic and aborting with 500
+// when nothing was written yet, instead of gin.Recovery. Use it after AccessLog so the panics are logged with the request's
+// fields and the access log has the 500.
+func Recovery(p *logr.PacketLogr) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			middleware.LogPanic(p, c.Request, v)
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			c.AbortWithStatus(http.StatusInternalServerError)
+		}()
+		c.Next()
+	}
+}
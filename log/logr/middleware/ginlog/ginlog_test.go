@@ -0,0 +1,45 @@
+package ginlog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+)
+
+func TestAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	l, logged := logtest.New(t)
+	accessLog, err := AccessLog(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(accessLog, Recovery(l))
+	r.GET("/devices/:id", func(c *gin.Context) {
+		l.FromContext(c.Request.Context()).Info("handling")
+		c.String(http.StatusCreated, "hello")
+	})
+	r.GET("/panics", func(*gin.Context) { panic("boom") })
+	for _, path := range []string{"/devices/abc", "/panics", "/devices/1/missing"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(logr.RequestIDHeader, "r1")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	got := logged()
+	for _, want := range []string{
+		`"msg":"handling","service":"not/set","request_id":"r1"`,
+		`"method":"GET","path":"/devices/:id","status":201,"bytes":5`,
+		`"msg":"recovered from panic"`,
+		`"path":"/panics","status":500,"bytes":0`,
+		`"path":"/devices/{id}/missing","status":404`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %s to be logged, got: %v", want, got)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+module github.com/packethost/pkg/log/logr/middleware
+
+go 1.21
+
+require (
+	github.com/go-chi/chi/v5 v5.0.12
+	github.com/go-logr/logr v0.2.1
+	github.com/packethost/pkg/log/logr v0.1.0
+	github.com/packethost/pkg/log/logr/otellog v0.1.0
+	github.com/pkg/errors v0.9.1
+	go.uber.org/multierr v1.6.0
+	google.golang.org/grpc v1.55.0
+	google.golang.org/protobuf v1.31.0
+)
+
+require (
+	github.com/go-logr/zapr v0.2.0 // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/rollbar/rollbar-go v1.2.0 // indirect
+	go.opentelemetry.io/otel v1.0.1 // indirect
+	go.opentelemetry.io/otel/trace v1.0.1 // indirect
+	go.uber.org/atomic v1.9.0 // indirect
+	go.uber.org/zap v1.16.0 // indirect
+	golang.org/x/net v0.19.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20230530153820-e85fd2cbaebc // indirect
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
+)
+
+replace github.com/packethost/pkg/log/logr => ../
+
+replace github.com/packethost/pkg/log/logr/otellog => ../otellog/
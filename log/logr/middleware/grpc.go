@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/go-logr/logr"
+	packetlogr "github.com/packethost/pkg/log/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// WithMethodLevel logs the RPCs of method, the full method such as /packet.api.Devices/Get, at level, one of info, debug,
+// or trace, or not at all with off. A method ending in * is a prefix, such as /grpc.health.v1.Health/*.
+// The rpc started entries are logged at the next level down, debug for info.
+func WithMethodLevel(method, level string) Option {
+	return withRouteLevel("WithMethodLevel", method, level)
+}
+
+// UnaryServerInterceptor logs each RPC when it starts, at debug by default, and when it finishes, with its method, code, duration,
+// and peer. RPCs that fail with a code that is the server's fault, Unknown, Internal, or DataLoss, are logged as errors.
+// The handler's context has a logger, from logr.FromContext, with the method and the request ID, which RPCs without one yet
+// are given by UnaryServerRequestIDInterceptor. It returns the errors of opts, such as a WithMethodLevel level that isn't valid.
+func UnaryServerInterceptor(p *packetlogr.PacketLogr, opts ...Option) (grpc.UnaryServerInterceptor, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	requestID := UnaryServerRequestIDInterceptor(p, c.requestIDOpts...)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		logged := func(ctx context.Context, req interface{}) (interface{}, error) {
+			ctx = p.NewContextWithValues(ctx, "method", info.FullMethod)
+			l := p.FromContext(ctx)
+			finish := c.rpcStarted(l, info.FullMethod, peerAddr(ctx))
+			logPayload := c.payloadLogger(l, info.FullMethod)
+			if logPayload != nil {
+				logPayload("received", req)
+			}
+			resp, err := handler(ctx, req)
+			if logPayload != nil && err == nil {
+				logPayload("sent", resp)
+			}
+			finish(err)
+			return resp, err
+		}
+		if packetlogr.RequestIDFromContext(ctx) != "" {
+			return logged(ctx, req)
+		}
+		return requestID(ctx, req, info, logged)
+	}, nil
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming RPCs, they finish when the handler returns
+func StreamServerInterceptor(p *packetlogr.PacketLogr, opts ...Option) (grpc.StreamServerInterceptor, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	requestID := StreamServerRequestIDInterceptor(p, c.requestIDOpts...)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		logged := func(srv interface{}, ss grpc.ServerStream) error {
+			ctx := p.NewContextWithValues(ss.Context(), "method", info.FullMethod)
+			l := p.FromContext(ctx)
+			finish := c.rpcStarted(l, info.FullMethod, peerAddr(ctx))
+			err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx, logPayload: c.payloadLogger(l, info.FullMethod)})
+			finish(err)
+			return err
+		}
+		if packetlogr.RequestIDFromContext(ss.Context()) != "" {
+			return logged(srv, ss)
+		}
+		return requestID(srv, ss, info, logged)
+	}, nil
+}
+
+// UnaryClientInterceptor logs each RPC made like UnaryServerInterceptor does, with the target of the connection as the peer.
+// Use it along with UnaryClientRequestIDInterceptor to pass the request ID on.
+func UnaryClientInterceptor(p *packetlogr.PacketLogr, opts ...Option) (grpc.UnaryClientInterceptor, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		l := p.FromContext(ctx).WithValues("method", method)
+		finish := c.rpcStarted(l, method, cc.Target())
+		logPayload := c.payloadLogger(l, method)
+		if logPayload != nil {
+			logPayload("sent", req)
+		}
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+		if logPayload != nil && err == nil {
+			logPayload("received", reply)
+		}
+		finish(err)
+		return err
+	}, nil
+}
+
+// StreamClientInterceptor is UnaryClientInterceptor for streaming RPCs, they finish once the stream is done being received from
+func StreamClientInterceptor(p *packetlogr.PacketLogr, opts ...Option) (grpc.StreamClientInterceptor, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		l := p.FromContext(ctx).WithValues("method", method)
+		finish := c.rpcStarted(l, method, cc.Target())
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			finish(err)
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, finish: finish, logPayload: c.payloadLogger(l, method)}, nil
+	}, nil
+}
+
+// rpcStarted logs that the RPC to method started, it returns the func logging that it finished
+func (c config) rpcStarted(l logr.Logger, method, peer string) func(error) {
+	v, ok := c.verbosity(method)
+	if !ok {
+		return func(error) {}
+	}
+	start := time.Now()
+	l.V(v+1).Info("rpc started", "peer", peer)
+	return func(err error) {
+		code := status.Code(err)
+		kvs := []interface{}{"code", code.String(), "duration", time.Since(start), "peer", peer}
+		switch code {
+		case codes.OK:
+		case codes.Unknown, codes.Internal, codes.DataLoss:
+			l.Error(err, "rpc finished", kvs...)
+			return
+		default:
+			kvs = append(kvs, "error", err.Error())
+		}
+		l.V(v).Info("rpc finished", kvs...)
+	}
+}
+
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// serverStream is a grpc.ServerStream whose context has the logger, and that logs its messages when logPayload isn't nil
+type serverStream struct {
+	grpc.ServerStream
+	ctx        context.Context
+	logPayload func(direction string, m interface{})
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if s.logPayload != nil && err == nil {
+		s.logPayload("sent", m)
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if s.logPayload != nil && err == nil {
+		s.logPayload("received", m)
+	}
+	return err
+}
+
+// clientStream logs that the RPC finished once receiving from it fails, io.EOF being a stream that finished normally,
+// and logs its messages when logPayload isn't nil
+type clientStream struct {
+	grpc.ClientStream
+	finish     func(error)
+	done       bool
+	logPayload func(direction string, m interface{})
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if s.logPayload != nil && err == nil {
+		s.logPayload("sent", m)
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if s.logPayload != nil && err == nil {
+		s.logPayload("received", m)
+	}
+	if err != nil && !s.done {
+		s.done = true
+		if err == io.EOF {
+			s.finish(nil)
+		} else {
+			s.finish(err)
+		}
+	}
+	return err
+}
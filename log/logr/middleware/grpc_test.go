@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+)
+
+// healthClient serves the health service with the interceptors over an in-memory connection
+func healthClient(t *testing.T, l *logr.PacketLogr, opts ...Option) healthpb.HealthClient {
+	t.Helper()
+	unaryServer, err := UnaryServerInterceptor(l, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamServer, err := StreamServerInterceptor(l, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unaryClient, err := UnaryClientInterceptor(l, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamClient, err := StreamClientInterceptor(l, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.UnaryInterceptor(unaryServer), grpc.StreamInterceptor(streamServer))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithUnaryInterceptor(unaryClient), grpc.WithStreamInterceptor(streamClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+// finished returns the rpc finished entries of the server, or else of the client
+func finished(entries []map[string]interface{}, peer string) []map[string]interface{} {
+	var got []map[string]interface{}
+	for _, e := range entries {
+		if e["msg"] == "rpc finished" && (e["peer"] == "bufnet") == (peer == "bufnet") {
+			got = append(got, e)
+		}
+	}
+	return got
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	l, entries := logtest.NewEntries(t, logr.WithLogLevel("debug"))
+	client := healthClient(t, l)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "r1")
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"}); err == nil {
+		t.Fatal("expected the check of an unknown service to fail")
+	}
+
+	all := entries()
+	server := finished(all, "server")
+	if len(server) != 2 {
+		t.Fatalf("expected the server to log both RPCs, got: %v", all)
+	}
+	if e := server[0]; e["method"] != "/grpc.health.v1.Health/Check" || e["code"] != "OK" || e["request_id"] != "r1" || e["level"] != "info" {
+		t.Fatalf("expected the successful RPC with the request ID, got: %v", e)
+	}
+	if e := server[1]; e["code"] != "NotFound" || e["error"] == nil || e["request_id"] == nil || e["request_id"] == "r1" {
+		t.Fatalf("expected the failed RPC with a new request ID, got: %v", e)
+	}
+	if client := finished(all, "bufnet"); len(client) != 2 || client[1]["code"] != "NotFound" {
+		t.Fatalf("expected the client to log both RPCs, got: %v", all)
+	}
+	var started int
+	for _, e := range all {
+		if e["msg"] == "rpc started" && e["level"] == "debug" {
+			started++
+		}
+	}
+	if started != 4 {
+		t.Fatalf("expected the RPCs to be logged when they start at debug, got: %v", all)
+	}
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	l, entries := logtest.NewEntries(t)
+	client := healthClient(t, l)
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := stream.Recv(); err == nil {
+		t.Fatal("expected the cancelled stream to fail")
+	}
+
+	all := entries()
+	if client := finished(all, "bufnet"); len(client) != 1 || client[0]["method"] != "/grpc.health.v1.Health/Watch" || client[0]["code"] != "Canceled" {
+		t.Fatalf("expected the client to log the stream when it finished, got: %v", all)
+	}
+}
+
+func TestInterceptorsMethodLevel(t *testing.T) {
+	l, entries := logtest.NewEntries(t)
+	client := healthClient(t, l, WithMethodLevel("/grpc.health.v1.Health/*", "off"))
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if all := entries(); len(all) != 0 {
+		t.Fatalf("expected the suppressed method not to be logged, got: %v", all)
+	}
+}
+
+func TestInterceptorsInvalidOptions(t *testing.T) {
+	l, _ := logtest.NewEntries(t)
+	opt := WithMethodLevel("/grpc.health.v1.Health/*", "warn")
+	want := `WithMethodLevel: level must be one of info, debug, trace, or off, got: "warn"`
+	_, unaryServer := UnaryServerInterceptor(l, opt)
+	_, streamServer := StreamServerInterceptor(l, opt)
+	_, unaryClient := UnaryClientInterceptor(l, opt)
+	_, streamClient := StreamClientInterceptor(l, opt)
+	for name, err := range map[string]error{"unary server": unaryServer, "stream server": streamServer, "unary client": unaryClient, "stream client": streamClient} {
+		if err == nil || err.Error() != want {
+			t.Fatalf("expected the %s interceptor to return the error of the option, got: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+
+	packetlogr "github.com/packethost/pkg/log/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// UnaryServerRequestIDInterceptor gives each RPC a request ID like logr.RequestIDMiddleware does: the one in its x-request-id metadata
+// when it has a valid one, or else a new one. The ID is sent back in the response header and set on the RPC's context,
+// whose logger logr.FromContext returns logs it as request_id. The client interceptors pass it on to the RPCs made with that context,
+// along with the ID set by logr.RequestIDMiddleware, so a chain of calls across services shares one ID.
+func UnaryServerRequestIDInterceptor(p *packetlogr.PacketLogr, opts ...packetlogr.RequestIDOption) grpc.UnaryServerInterceptor {
+	s := packetlogr.NewRequestIDSource(opts...)
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(grpcRequestID(ctx, p, s), req)
+	}
+}
+
+// StreamServerRequestIDInterceptor is UnaryServerRequestIDInterceptor for streaming RPCs
+func StreamServerRequestIDInterceptor(p *packetlogr.PacketLogr, opts ...packetlogr.RequestIDOption) grpc.StreamServerInterceptor {
+	s := packetlogr.NewRequestIDSource(opts...)
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &requestIDServerStream{ServerStream: ss, ctx: grpcRequestID(ss.Context(), p, s)})
+	}
+}
+
+// grpcRequestID reads the request ID of an incoming RPC and sends it back
+func grpcRequestID(ctx context.Context, p *packetlogr.PacketLogr, s packetlogr.RequestIDSource) context.Context {
+	var incoming string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(s.Header()); len(values) > 0 {
+			incoming = values[0]
+		}
+	}
+	id := s.ID(incoming)
+	// it can only fail once the header was sent, which it can't be before the handler is called
+	_ = grpc.SetHeader(ctx, metadata.Pairs(s.Header(), id))
+	return p.NewContextWithValues(packetlogr.ContextWithRequestID(ctx, id), packetlogr.RequestIDKey, id)
+}
+
+// requestIDServerStream is a grpc.ServerStream whose context has the request ID
+type requestIDServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *requestIDServerStream) Context() context.Context { return s.ctx }
+
+// UnaryClientRequestIDInterceptor sends the request ID of the context, see logr.RequestIDFromContext, in the x-request-id metadata of RPCs
+func UnaryClientRequestIDInterceptor(opts ...packetlogr.RequestIDOption) grpc.UnaryClientInterceptor {
+	s := packetlogr.NewRequestIDSource(opts...)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		return invoker(outgoingRequestID(ctx, s), method, req, reply, cc, callOpts...)
+	}
+}
+
+// StreamClientRequestIDInterceptor is UnaryClientRequestIDInterceptor for streaming RPCs
+func StreamClientRequestIDInterceptor(opts ...packetlogr.RequestIDOption) grpc.StreamClientInterceptor {
+	s := packetlogr.NewRequestIDSource(opts...)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoingRequestID(ctx, s), desc, cc, method, callOpts...)
+	}
+}
+
+// outgoingRequestID adds the request ID of ctx to its outgoing metadata
+func outgoingRequestID(ctx context.Context, s packetlogr.RequestIDSource) context.Context {
+	if id := packetlogr.RequestIDFromContext(ctx); id != "" {
+		return metadata.AppendToOutgoingContext(ctx, s.Header(), id)
+	}
+	return ctx
+}
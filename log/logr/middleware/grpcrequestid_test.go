@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+)
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context { return s.ctx }
+
+func TestUnaryServerRequestIDInterceptor(t *testing.T) {
+	var ids []string
+	pl, logged := logtest.New(t, logr.WithServiceName("rpc"))
+	interceptor := UnaryServerRequestIDInterceptor(pl, logr.WithRequestIDHeader("x-correlation-id"))
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		ids = append(ids, logr.RequestIDFromContext(ctx))
+		pl.FromContext(ctx).Info("handled")
+		return nil, nil
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-correlation-id", "from-caller"))
+	_, _ = interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	_, _ = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	capturedOutput := logged()
+	if ids[0] != "from-caller" || ids[1] == "" || ids[1] == "from-caller" {
+		t.Fatalf("expected the incoming ID to be kept and a new one otherwise, got: %v", ids)
+	}
+	if want := `"msg":"handled","service":"rpc","request_id":"from-caller"`; !strings.Contains(capturedOutput, want) {
+		t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+	}
+}
+
+func TestStreamServerRequestIDInterceptor(t *testing.T) {
+	pl, _ := logtest.New(t)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(logr.RequestIDHeader, "from-caller"))
+	var id string
+	err := StreamServerRequestIDInterceptor(pl)(nil, &testServerStream{ctx: ctx}, &grpc.StreamServerInfo{}, func(_ interface{}, ss grpc.ServerStream) error {
+		id = logr.RequestIDFromContext(ss.Context())
+		return nil
+	})
+	if err != nil || id != "from-caller" {
+		t.Fatalf("expected the incoming ID on the stream's context, got: %v, %v", id, err)
+	}
+}
+
+func TestClientRequestIDInterceptors(t *testing.T) {
+	ctx := logr.ContextWithRequestID(context.Background(), "r1")
+	var sent []string
+	invoker := func(ctx context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		sent = append(sent, md.Get("x-request-id")...)
+		return nil
+	}
+	_ = UnaryClientRequestIDInterceptor()(ctx, "/svc/Method", nil, nil, nil, invoker)
+	_ = UnaryClientRequestIDInterceptor()(context.Background(), "/svc/Method", nil, nil, nil, invoker)
+	streamer := func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		sent = append(sent, md.Get("x-request-id")...)
+		return nil, nil
+	}
+	_, _ = StreamClientRequestIDInterceptor()(ctx, &grpc.StreamDesc{}, nil, "/svc/Stream", streamer)
+	if len(sent) != 2 || sent[0] != "r1" || sent[1] != "r1" {
+		t.Fatalf("expected the request ID to be sent only when the context has one, got: %v", sent)
+	}
+}
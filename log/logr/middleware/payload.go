@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// redactedValue replaces redacted string fields of payloads, the other redacted fields are cleared
+const redactedValue = "[REDACTED]"
+
+// WithPayloads logs the messages the RPCs of methods send and receive, as rpc message entries one level down from the RPC's,
+// debug by default, with the message as JSON. A method ending in * is a prefix. Fields marked with the debug_redact
+// protobuf field option are redacted, and so are the ones set with WithPayloadRedaction. Messages can hold anything the
+// service handles, so only log them while debugging, such as in a development environment.
+func WithPayloads(methods ...string) Option {
+	return func(c *config) { c.payloadMethods = append(c.payloadMethods, methods...) }
+}
+
+// WithPayloadRedaction redacts the fields at paths from the messages logged with WithPayloads. Paths are the protobuf field names
+// from the message, separated by dots, such as password or credentials.token, and cover all the elements of repeated and map fields.
+func WithPayloadRedaction(paths ...string) Option {
+	return func(c *config) {
+		for _, path := range paths {
+			c.redactedPaths[path] = true
+		}
+	}
+}
+
+// logsPayloads is whether the messages of method are logged
+func (c config) logsPayloads(method string) bool {
+	for _, m := range c.payloadMethods {
+		if m == method || (strings.HasSuffix(m, "*") && strings.HasPrefix(method, strings.TrimSuffix(m, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// payloadLogger returns the func logging the messages of method with l, or nil when they aren't logged
+func (c config) payloadLogger(l logr.Logger, method string) func(direction string, m interface{}) {
+	v, ok := c.verbosity(method)
+	if !ok || !c.logsPayloads(method) {
+		return nil
+	}
+	return func(direction string, m interface{}) {
+		if l := l.V(v + 1); l.Enabled() {
+			l.Info("rpc message", "direction", direction, "message", c.payload(m))
+		}
+	}
+}
+
+// payload is m as JSON with the redacted fields redacted
+func (c config) payload(m interface{}) interface{} {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return fmt.Sprintf("%T", m)
+	}
+	msg = proto.Clone(msg)
+	redact(msg.ProtoReflect(), "", c.redactedPaths)
+	b, err := protojson.Marshal(msg)
+	if err != nil {
+		return fmt.Sprintf("failed to encode %T: %v", m, err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return string(b)
+	}
+	return fields
+}
+
+// redact redacts the fields of m, and of the messages in it, that are marked with debug_redact or whose path is in paths
+func redact(m protoreflect.Message, prefix string, paths map[string]bool) {
+	if m.Descriptor().FullName() == anyName {
+		redactAny(m, prefix, paths)
+		return
+	}
+	type field struct {
+		fd protoreflect.FieldDescriptor
+		v  protoreflect.Value
+	}
+	// the fields are changed after ranging over them, which mustn't change the message
+	var fields []field
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		fields = append(fields, field{fd, v})
+		return true
+	})
+	for _, f := range fields {
+		path := prefix + string(f.fd.Name())
+		if opts, ok := f.fd.Options().(*descriptorpb.FieldOptions); paths[path] || (ok && opts.GetDebugRedact()) {
+			if f.fd.Kind() == protoreflect.StringKind && f.fd.Cardinality() != protoreflect.Repeated {
+				m.Set(f.fd, protoreflect.ValueOfString(redactedValue))
+			} else {
+				m.Clear(f.fd)
+			}
+			continue
+		}
+		switch {
+		case f.fd.IsMap():
+			if f.fd.MapValue().Message() != nil {
+				f.v.Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+					redact(v.Message(), path+".", paths)
+					return true
+				})
+			}
+		case f.fd.Message() == nil:
+		case f.fd.IsList():
+			for i, list := 0, f.v.List(); i < list.Len(); i++ {
+				redact(list.Get(i).Message(), path+".", paths)
+			}
+		default:
+			redact(f.v.Message(), path+".", paths)
+		}
+	}
+}
+
+// anyName is the name of google.protobuf.Any, whose message is only known by its type URL
+const anyName protoreflect.FullName = "google.protobuf.Any"
+
+// redactAny redacts the message packed in the Any m, resolved from its type URL with the global registry. The message's fields
+// have the paths of the Any's, as they are in its JSON. Messages that can't be resolved or unpacked are dropped, as what they
+// hold can't be redacted.
+func redactAny(m protoreflect.Message, prefix string, paths map[string]bool) {
+	typeURL, value := m.Descriptor().Fields().ByName("type_url"), m.Descriptor().Fields().ByName("value")
+	if url := m.Get(typeURL).String(); url != "" {
+		if mt, err := protoregistry.GlobalTypes.FindMessageByURL(url); err == nil {
+			packed := mt.New()
+			if err := proto.Unmarshal(m.Get(value).Bytes(), packed.Interface()); err == nil {
+				redact(packed, prefix, paths)
+				if b, err := proto.Marshal(packed.Interface()); err == nil {
+					m.Set(value, protoreflect.ValueOfBytes(b))
+					return
+				}
+			}
+		}
+	}
+	m.Clear(typeURL)
+	m.Clear(value)
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+)
+
+func TestPayloads(t *testing.T) {
+	l, entries := logtest.NewEntries(t, logr.WithLogLevel("debug"))
+	client := healthClient(t, l, WithPayloads("/grpc.health.v1.Health/*"), WithPayloadRedaction("service"))
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "billing"}); err == nil {
+		t.Fatal("expected the check of an unknown service to fail")
+	}
+
+	var messages []string
+	for _, e := range entries() {
+		if e["msg"] != "rpc message" {
+			continue
+		}
+		if e["level"] != "debug" {
+			t.Fatalf("expected the messages at debug, got: %v", e)
+		}
+		message, _ := e["message"].(map[string]interface{})
+		desc := e["direction"].(string)
+		if service, ok := message["service"]; ok {
+			desc += " " + service.(string)
+		}
+		if status, ok := message["status"]; ok {
+			desc += " " + status.(string)
+		}
+		messages = append(messages, desc)
+	}
+	want := []string{"sent", "received", "sent SERVING", "received SERVING", "sent [REDACTED]", "received [REDACTED]"}
+	if !reflect.DeepEqual(messages, want) {
+		t.Fatalf("expected the messages of both RPCs with the service redacted, got: %v", messages)
+	}
+}
+
+// secretMessage makes a message whose token field has debug_redact, and with a repeated field of messages like it
+func secretMessage(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("secret.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Secret"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("name"), JsonName: proto.String("name"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				{Name: proto.String("token"), JsonName: proto.String("token"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Options: &descriptorpb.FieldOptions{DebugRedact: proto.Bool(true)}},
+				{Name: proto.String("children"), JsonName: proto.String("children"), Number: proto.Int32(3), Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+					TypeName: proto.String(".test.Secret")},
+			},
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd.Messages().Get(0)
+}
+
+func TestPayloadRedaction(t *testing.T) {
+	md := secretMessage(t)
+	newSecret := func(name, token string) *dynamicpb.Message {
+		m := dynamicpb.NewMessage(md)
+		m.Set(md.Fields().ByName("name"), protoreflect.ValueOfString(name))
+		m.Set(md.Fields().ByName("token"), protoreflect.ValueOfString(token))
+		return m
+	}
+	m := newSecret("parent", "t1")
+	children := m.Mutable(md.Fields().ByName("children")).List()
+	children.Append(protoreflect.ValueOfMessage(newSecret("child", "t2")))
+
+	c, err := newConfig([]Option{WithPayloadRedaction("children.name")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := c.payload(m)
+	want := map[string]interface{}{
+		"name": "parent", "token": "[REDACTED]",
+		"children": []interface{}{map[string]interface{}{"name": "[REDACTED]", "token": "[REDACTED]"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected the debug_redact fields and the paths to be redacted, got: %v", got)
+	}
+	if token := m.Get(md.Fields().ByName("token")).String(); token != "t1" {
+		t.Fatalf("expected the message itself to be left alone, got: %v", token)
+	}
+}
+
+func TestPayloadRedactionAny(t *testing.T) {
+	packed, err := anypb.New(&healthpb.HealthCheckRequest{Service: "billing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newConfig([]Option{WithPayloadRedaction("service")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"@type": "type.googleapis.com/grpc.health.v1.HealthCheckRequest", "service": "[REDACTED]"}
+	if got := c.payload(packed); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected the packed message to be redacted, got: %v", got)
+	}
+
+	// the secret message isn't in the global registry
+	secret := dynamicpb.NewMessage(secretMessage(t))
+	secret.Set(secret.Descriptor().Fields().ByName("token"), protoreflect.ValueOfString("t1"))
+	unknown, err := anypb.New(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.payload(unknown); !reflect.DeepEqual(got, map[string]interface{}{}) {
+		t.Fatalf("expected the message that can't be resolved to be dropped, got: %v", got)
+	}
+}
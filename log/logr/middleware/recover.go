@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/pkg/errors"
+)
+
+// Recover recovers from the panics of next, logs them with LogPanic, and responds 500 when next hadn't responded yet.
+// http.ErrAbortHandler is panicked again, as it is how handlers abort a response on purpose.
+func Recover(p *logr.PacketLogr, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			LogPanic(p, r, v)
+			if !rw.wroteHeader {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// LogPanic logs v, recovered from a panic while handling r, as an error with the stack trace using the logger of r,
+// so it has the request's fields and is sent to the error reporters. It is for the recovery hooks of routers.
+func LogPanic(p *logr.PacketLogr, r *http.Request, v interface{}) {
+	err, ok := v.(error)
+	if !ok {
+		err = fmt.Errorf("%v", v)
+	}
+	p.FromContext(r.Context()).Error(errors.WithMessage(err, "panic"), "recovered from panic", "method", r.Method, "path", r.URL.Path)
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+)
+
+func TestRecover(t *testing.T) {
+	l, entries := logtest.NewEntries(t)
+	h := accessLog(t, l, Recover(l, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	})))
+	req := httptest.NewRequest(http.MethodGet, "/devices/1", nil)
+	req.Header.Set("X-Request-ID", "r1")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected a 500, got: %d", rec.Code)
+	}
+	got := entries()
+	if len(got) != 2 || got[0]["msg"] != "recovered from panic" || got[0]["request_id"] != "r1" || got[0]["error"] != "panic: boom" {
+		t.Fatalf("expected the panic to be logged with the request's logger, got: %v", got)
+	}
+	if stack, _ := got[0]["stacktrace"].(string); !strings.Contains(stack, "TestRecover") {
+		t.Fatalf("expected the stack trace of the panic, got: %v", got[0])
+	}
+	if got[1]["msg"] != "request" || got[1]["status"] != float64(500) {
+		t.Fatalf("expected the access log to have the 500, got: %v", got[1])
+	}
+}
+
+func TestRecoverErrAbortHandler(t *testing.T) {
+	l, _ := logtest.NewEntries(t)
+	h := Recover(l, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Fatalf("expected http.ErrAbortHandler to be panicked again, got: %v", v)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/otellog"
+)
+
+// The query parameters and headers NewLoggingTransport always redacts, as they usually hold credentials
+var (
+	defaultRedactedParams  = []string{"access_token", "api_key", "apikey", "key", "password", "secret", "sig", "signature", "token", "X-Amz-Credential", "X-Amz-Security-Token", "X-Amz-Signature"}
+	defaultRedactedHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "Set-Cookie", "X-Api-Key", "X-Auth-Token"}
+)
+
+// retryBackoff is how long NewLoggingTransport waits before the first retry, it doubles for each retry after it
+var retryBackoff = 100 * time.Millisecond
+
+// redacted replaces the values of the headers that are redacted, redactedParam the ones of query parameters as it needs no escaping
+const (
+	redacted      = "[REDACTED]"
+	redactedParam = "REDACTED"
+)
+
+// WithRedactedQueryParams redacts the values of the query parameters names, besides the usual ones such as access_token and
+// signature, in the URLs NewLoggingTransport logs. The password of the URLs is always redacted.
+func WithRedactedQueryParams(names ...string) Option {
+	return func(c *config) {
+		for _, name := range names {
+			c.redactedParams[name] = true
+		}
+	}
+}
+
+// WithLoggedHeaders logs the request and response headers names, as request_headers and response_headers,
+// for NewLoggingTransport. The usual credentials, such as Authorization, are redacted.
+func WithLoggedHeaders(names ...string) Option {
+	return func(c *config) { c.loggedHeaders = append(c.loggedHeaders, names...) }
+}
+
+// WithRedactedHeaders redacts the values of the headers names, besides the usual ones such as Authorization, when WithLoggedHeaders logs them
+func WithRedactedHeaders(names ...string) Option {
+	return func(c *config) {
+		for _, name := range names {
+			c.redactedHeaders[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+}
+
+// WithRetries retries the requests of NewLoggingTransport up to n times when they fail with a network error, or with 429, 502,
+// 503, or 504, backing off exponentially from 100ms. Only idempotent requests, by method or with an Idempotency-Key header,
+// whose body can be sent again are retried.
+func WithRetries(n int) Option {
+	return func(c *config) { c.retries = n }
+}
+
+// NewLoggingTransport returns a transport making requests with base, http.DefaultTransport when it is nil, that logs each of them
+// once it has a response, with its method, redacted url, status, duration, and retries, and the error when it failed. It logs
+// with logr.FromContext of the request's context, so the calls a handler makes have its request's fields, and sets the request ID
+// and the W3C traceparent of the context on the requests, when they don't have them already, so the services called log them too.
+// It returns the errors of opts.
+func NewLoggingTransport(base http.RoundTripper, opts ...Option) (http.RoundTripper, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &loggingTransport{base: base, c: c, requestIDHeader: logr.NewRequestIDSource(c.requestIDOpts...).Header()}, nil
+}
+
+type loggingTransport struct {
+	base http.RoundTripper
+	c    config
+	// requestIDHeader is the header the request ID is set on, see WithRequestIDOptions
+	requestIDHeader string
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	req = req.Clone(ctx)
+	if req.Header.Get(t.requestIDHeader) == "" {
+		logr.SetRequestIDHeader(ctx, req.Header, t.requestIDHeader)
+	}
+	if req.Header.Get("traceparent") == "" {
+		otellog.InjectTraceParent(ctx, req.Header)
+	}
+
+	start := time.Now()
+	resp, retries, err := t.roundTrip(req)
+	kvs := []interface{}{"method", req.Method, "url", t.c.redactURL(req.URL), "duration", time.Since(start), "retries", retries}
+	if resp != nil {
+		kvs = append(kvs, "status", resp.StatusCode)
+	}
+	if err != nil {
+		kvs = append(kvs, "error", err.Error())
+	}
+	if len(t.c.loggedHeaders) > 0 {
+		kvs = append(kvs, "request_headers", t.c.headers(req.Header))
+		if resp != nil {
+			kvs = append(kvs, "response_headers", t.c.headers(resp.Header))
+		}
+	}
+	logr.FromContext(ctx).Info("outbound request", kvs...)
+	return resp, err
+}
+
+// roundTrip makes req, retrying it as WithRetries says, and returns the last response and how many times it was retried
+func (t *loggingTransport) roundTrip(req *http.Request) (*http.Response, int, error) {
+	backoff := retryBackoff
+	for retries := 0; ; retries++ {
+		resp, err := t.base.RoundTrip(req)
+		if retries == t.c.retries || !retryable(req, resp, err) {
+			return resp, retries, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, retries, err
+			}
+			req.Body = body
+		}
+		if err := sleep(req.Context(), backoff); err != nil {
+			return nil, retries, err
+		}
+		backoff *= 2
+	}
+}
+
+// retryable is whether req, which failed with resp or err, can be sent again
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		if req.Header.Get("Idempotency-Key") == "" {
+			return false
+		}
+	}
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// redactURL is u without its password and with the values of its redacted query parameters replaced
+func (c config) redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Redacted()
+	}
+	redactedURL := *u
+	query := u.Query()
+	for name := range query {
+		if c.redactedParams[name] {
+			query[name] = []string{redactedParam}
+		}
+	}
+	redactedURL.RawQuery = query.Encode()
+	return redactedURL.Redacted()
+}
+
+// headers is the logged headers of h, the ones being redacted replaced
+func (c config) headers(h http.Header) map[string]string {
+	logged := map[string]string{}
+	for _, name := range c.loggedHeaders {
+		name = http.CanonicalHeaderKey(name)
+		v := h.Get(name)
+		if v == "" {
+			continue
+		}
+		if c.redactedHeaders[name] {
+			v = redacted
+		}
+		logged[name] = v
+	}
+	return logged
+}
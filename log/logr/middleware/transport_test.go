@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+)
+
+func setRetryBackoff(t *testing.T, d time.Duration) {
+	prev := retryBackoff
+	retryBackoff = d
+	t.Cleanup(func() { retryBackoff = prev })
+}
+
+// newTransport is NewLoggingTransport failing t on the errors of opts
+func newTransport(t *testing.T, base http.RoundTripper, opts ...Option) http.RoundTripper {
+	t.Helper()
+	rt, err := NewLoggingTransport(base, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rt
+}
+
+func TestNewLoggingTransport(t *testing.T) {
+	setRetryBackoff(t, time.Millisecond)
+	var calls atomic.Int32
+	var requestID string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = r.Header.Get(logr.RequestIDHeader)
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("Content-Type", "text/plain")
+	}))
+	defer srv.Close()
+
+	l, entries := logtest.NewEntries(t)
+	client := &http.Client{Transport: newTransport(t, nil, WithRetries(2), WithRedactedQueryParams("account"),
+		WithLoggedHeaders("Authorization", "Content-Type", "Set-Cookie"))}
+	ctx := l.NewContextWithValues(logr.ContextWithRequestID(context.Background(), "r1"), logr.RequestIDKey, "r1")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/devices?account=acme&token=secret&page=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls.Load() != 2 {
+		t.Fatalf("expected the 503 to be retried, got: %d after %d calls", resp.StatusCode, calls.Load())
+	}
+	if requestID != "r1" {
+		t.Fatalf("expected the request ID to be sent, got: %q", requestID)
+	}
+
+	got := entries()
+	if len(got) != 1 {
+		t.Fatalf("expected one entry, got: %v", got)
+	}
+	entry := got[0]
+	wantURL := srv.URL + "/v1/devices?account=REDACTED&page=2&token=REDACTED"
+	if entry["msg"] != "outbound request" || entry["url"] != wantURL || entry["status"] != float64(200) || entry["retries"] != float64(1) ||
+		entry["request_id"] != "r1" || entry["method"] != "GET" {
+		t.Fatalf("expected the call to be logged, redacted, got: %v", entry)
+	}
+	reqHeaders, _ := entry["request_headers"].(map[string]interface{})
+	respHeaders, _ := entry["response_headers"].(map[string]interface{})
+	if reqHeaders["Authorization"] != "[REDACTED]" || respHeaders["Set-Cookie"] != "[REDACTED]" || respHeaders["Content-Type"] != "text/plain" {
+		t.Fatalf("expected the logged headers, redacted, got: %v and %v", reqHeaders, respHeaders)
+	}
+}
+
+func TestNewLoggingTransportError(t *testing.T) {
+	l, entries := logtest.NewEntries(t)
+	defer l.SetAsFallback()()
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	srv.Close()
+
+	var posts atomic.Int32
+	client := &http.Client{Transport: newTransport(t, roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		posts.Add(1)
+		return http.DefaultTransport.RoundTrip(r)
+	}), WithRetries(2))}
+	resp, err := client.Post("http://user:pass@"+strings.TrimPrefix(srv.URL, "http://"), "text/plain", strings.NewReader("body"))
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("expected the request to fail")
+	}
+	if posts.Load() != 1 {
+		t.Fatalf("expected the POST not to be retried, got: %d calls", posts.Load())
+	}
+	got := entries()
+	if len(got) != 1 || got[0]["error"] == nil || got[0]["retries"] != float64(0) || strings.Contains(got[0]["url"].(string), "pass") {
+		t.Fatalf("expected the failure to be logged, by the fallback, got: %v", got)
+	}
+}
+
+func TestNewLoggingTransportRequestIDHeader(t *testing.T) {
+	l, _ := logtest.NewEntries(t)
+	var header http.Header
+	client := &http.Client{Transport: newTransport(t, roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		header = r.Header
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	}), WithRequestIDOptions(logr.WithRequestIDHeader("X-Correlation-ID")))}
+	ctx := l.NewContextWithValues(logr.ContextWithRequestID(context.Background(), "r1"), logr.RequestIDKey, "r1")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if header.Get("X-Correlation-ID") != "r1" || header.Get(logr.RequestIDHeader) != "" {
+		t.Fatalf("expected the request ID on the configured header, got: %v", header)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
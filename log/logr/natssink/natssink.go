@@ -0,0 +1,235 @@
+// Package natssink publishes the entries of a logr.PacketLogr to NATS, or to a JetStream stream:
+//
+//	l, err := logr.New(natssink.WithSink("nats://nats:4222", "logs.{service}.{level}"))
+package natssink
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/fieldtemplate"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// natsReconnectBufSize is how many bytes of messages are held while reconnecting to NATS
+	natsReconnectBufSize = 8 * 1024 * 1024
+	// natsMaxPending is how many JetStream messages can be waiting for an ack
+	natsMaxPending = 1000
+	// natsCloseTimeout is how long Close waits for queued messages to be sent
+	natsCloseTimeout = 5 * time.Second
+)
+
+// natsSubjectReplacer replaces the characters that can't be in a subject token
+var natsSubjectReplacer = strings.NewReplacer(".", "_", " ", "_", "\t", "_", "*", "_", ">", "_")
+
+// Option configures WithSink
+type Option func(*config)
+
+type config struct {
+	url             string
+	subject         fieldtemplate.Template
+	jetStream       bool
+	maxPending      int
+	reconnectBuffer int
+	options         []nats.Option
+}
+
+// WithJetStream publishes to JetStream so that entries are persisted by a stream on the subject,
+// with up to maxPending entries waiting to be acked by the server, 1000 when 0, after which logging waits briefly and then drops the entry.
+func WithJetStream(maxPending int) Option {
+	return func(c *config) {
+		c.jetStream = true
+		c.maxPending = maxPending
+	}
+}
+
+// WithReconnectBuffer sets how many bytes of entries are held in memory while reconnecting, 8MiB by default,
+// entries logged once it is full are dropped.
+func WithReconnectBuffer(size int) Option {
+	return func(c *config) { c.reconnectBuffer = size }
+}
+
+// WithOptions sets options for the connection, such as credentials or TLS
+func WithOptions(opts ...nats.Option) Option {
+	return func(c *config) { c.options = append(c.options, opts...) }
+}
+
+// WithSink also publishes entries, JSON encoded whatever the encoding of the output paths is, to the NATS server at url.
+// subject can have placeholders that are filled in from each entry, such as logs.{service}.{level}: {level} and {logger} are
+// the entry's level and logger name, {date} is its UTC date as YYYY.MM.DD, and any other name in braces is the value of the string
+// field with that key, from With or the entry. Missing values, and characters that can't be in a subject token, are replaced with "_".
+// The connection is reconnected whenever it is lost, including when the server is down at startup.
+func WithSink(url, subject string, opts ...Option) logr.LoggerOption {
+	c := config{url: url, maxPending: natsMaxPending, reconnectBuffer: natsReconnectBufSize}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	var err error
+	if url == "" {
+		err = multierr.Append(err, errors.New("url must not be empty"))
+	}
+	subj, subjErr := fieldtemplate.Parse(subject)
+	err = multierr.Append(err, errors.WithMessage(subjErr, "invalid subject"))
+	c.subject = subj
+	if c.maxPending < 0 {
+		err = multierr.Append(err, errors.Errorf("max pending must be >= 0, got: %d", c.maxPending))
+	}
+	if c.maxPending == 0 {
+		c.maxPending = natsMaxPending
+	}
+	if c.reconnectBuffer < 1 {
+		err = multierr.Append(err, errors.Errorf("reconnect buffer must be > 0, got: %d", c.reconnectBuffer))
+	}
+	if err != nil {
+		return logr.WithOptionError(errors.WithMessage(err, "natssink.WithSink"))
+	}
+	return logr.WithSink(c.open)
+}
+
+// natsPublisher is how the sink publishes, so that core NATS and JetStream look the same
+type natsPublisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// jetStreamPublisher publishes to JetStream without waiting for the ack, failures are reported to the error handler
+type jetStreamPublisher struct {
+	js nats.JetStreamContext
+}
+
+func (p jetStreamPublisher) Publish(subject string, data []byte) error {
+	_, err := p.js.PublishAsync(subject, data)
+	return err
+}
+
+func (c config) open(sc logr.SinkContext) (zapcore.Core, io.Closer, error) {
+	zc := sc.Config
+	zc.Encoding = "json"
+	encoder, err := logr.NewEncoder(zc)
+	if err != nil {
+		return nil, nil, err
+	}
+	asyncErrs := &lastErr{}
+	opts := append([]nats.Option{
+		nats.Name("logr"),
+		nats.MaxReconnects(-1),
+		nats.RetryOnFailedConnect(true),
+		nats.ReconnectBufSize(c.reconnectBuffer),
+		// the reconnect handling is nats.go's, these only make sure errors aren't lost
+		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) { asyncErrs.set(err) }),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				asyncErrs.set(errors.Wrap(err, "disconnected"))
+			}
+		}),
+	}, c.options...)
+	nc, err := nats.Connect(c.url, opts...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to connect to NATS")
+	}
+
+	var pub natsPublisher = nc
+	closer := closerFunc(func() error {
+		err := nc.FlushTimeout(natsCloseTimeout)
+		nc.Close()
+		return multierr.Append(errors.Wrap(err, "failed to flush NATS sink"), asyncErrs.take())
+	})
+	if c.jetStream {
+		js, err := nc.JetStream(
+			nats.PublishAsyncMaxPending(c.maxPending),
+			nats.PublishAsyncErrHandler(func(_ nats.JetStream, _ *nats.Msg, err error) { asyncErrs.set(err) }),
+		)
+		if err != nil {
+			nc.Close()
+			return nil, nil, errors.Wrap(err, "failed to get JetStream context")
+		}
+		pub = jetStreamPublisher{js: js}
+		closer = closerFunc(func() error {
+			var err error
+			select {
+			case <-js.PublishAsyncComplete():
+			case <-time.After(natsCloseTimeout):
+				err = errors.Errorf("NATS sink closed with %d messages not acked", js.PublishAsyncPending())
+			}
+			nc.Close()
+			return multierr.Append(err, asyncErrs.take())
+		})
+	}
+	return &natsCore{LevelEnabler: zc.Level, enc: encoder, pub: pub, err: asyncErrs, subject: c.subject}, closer, nil
+}
+
+// natsCore encodes entries and publishes them to the subject rendered from them
+type natsCore struct {
+	zapcore.LevelEnabler
+	enc     zapcore.Encoder
+	pub     natsPublisher
+	err     *lastErr
+	subject fieldtemplate.Template
+	values  map[string]string
+}
+
+func (c *natsCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	clone.values = c.subject.Values(c.values, fields)
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+	}
+	return &clone
+}
+
+func (c *natsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *natsCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	// publishes hold on to the data until it is sent so it can't share the pooled buffer
+	data := make([]byte, buf.Len())
+	copy(data, buf.Bytes())
+	if n := len(data); n > 0 && data[n-1] == '\n' {
+		data = data[:n-1]
+	}
+	err = c.pub.Publish(c.subject.Render(ent, c.subject.Values(c.values, fields), natsSubjectReplacer.Replace), data)
+	return errors.Wrap(multierr.Append(c.err.take(), err), "failed to publish to NATS")
+}
+
+func (c *natsCore) Sync() error { return nil }
+
+// closerFunc lets a func be used as the sink's io.Closer
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+// lastErr holds the last error reported from the background by the connection until the next write returns it
+type lastErr struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (e *lastErr) set(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.err = err
+}
+
+func (e *lastErr) take() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	err := e.err
+	e.err = nil
+	return err
+}
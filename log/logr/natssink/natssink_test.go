@@ -0,0 +1,135 @@
+package natssink
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/fieldtemplate"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type natsMsg struct {
+	subject, data string
+}
+
+type fakeNATSPublisher struct {
+	msgs []natsMsg
+}
+
+func (p *fakeNATSPublisher) Publish(subject string, data []byte) error {
+	p.msgs = append(p.msgs, natsMsg{subject, string(data)})
+	return nil
+}
+
+// natsServer speaks enough of the NATS protocol for a client to connect and publish, sending what is published to msgs
+func natsServer(ln net.Listener, msgs chan<- natsMsg) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			if _, err := conn.Write([]byte("INFO {\"server_id\":\"test\",\"version\":\"2.10.0\",\"max_payload\":1048576}\r\n")); err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				switch fields := strings.Fields(line); fields[0] {
+				case "PING":
+					_, _ = conn.Write([]byte("PONG\r\n"))
+				case "PUB":
+					data, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					msgs <- natsMsg{fields[1], strings.TrimSuffix(data, "\r\n")}
+				}
+			}
+		}(conn)
+	}
+}
+
+func TestNATSCore(t *testing.T) {
+	encoder, err := logr.NewEncoder(zap.NewProductionConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := fieldtemplate.Parse("logs.{service}.{component}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &fakeNATSPublisher{}
+	base := zap.New(&natsCore{LevelEnabler: zapcore.InfoLevel, enc: encoder, pub: pub, err: &lastErr{}, subject: subject})
+	l := base.With(zap.String("service", "billing"))
+	l.Debug("filtered")
+	l.Info("one", zap.String("component", "in.voices"))
+	l.Info("two")
+	base.Info("three")
+
+	if len(pub.msgs) != 3 {
+		t.Fatalf("expected 3 messages, got: %v", pub.msgs)
+	}
+	for i, want := range []string{"logs.billing.in_voices", "logs.billing._", "logs._._"} {
+		if pub.msgs[i].subject != want {
+			t.Fatalf("expected subject: %v, got: %v", want, pub.msgs[i].subject)
+		}
+	}
+	if got := pub.msgs[0].data; !strings.HasSuffix(got, `"msg":"one","service":"billing","component":"in.voices"}`) {
+		t.Fatalf("expected a JSON entry without a newline, got: %q", got)
+	}
+}
+
+func TestWithSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	msgs := make(chan natsMsg, 10)
+	go natsServer(ln, msgs)
+
+	l, err := logr.New(logr.WithServiceName("billing"), logr.WithOutputPaths([]string{os.DevNull}), WithSink("nats://"+ln.Addr().String(), "logs.{service}.{level}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("to nats")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-msgs:
+		if msg.subject != "logs.billing.info" {
+			t.Fatalf("expected the subject to be rendered, got: %v", msg.subject)
+		}
+		if !strings.Contains(msg.data, `"msg":"to nats"`) {
+			t.Fatalf("expected the entry, got: %v", msg.data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the message")
+	}
+}
+
+func TestWithSinkInvalid(t *testing.T) {
+	_, err := logr.New(WithSink("", "logs.{", WithJetStream(-1), WithReconnectBuffer(0)))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"natssink.WithSink", "url must not be empty", "invalid subject: unmatched {", "max pending must be >= 0", "reconnect buffer must be > 0"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, err)
+		}
+	}
+}
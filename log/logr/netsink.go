@@ -0,0 +1,405 @@
+package logr
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+)
+
+const (
+	// netSinkQueueSize is how many messages a network sink holds while it is sending or reconnecting
+	netSinkQueueSize = 1000
+	// netSinkQueueTimeout is how long logging waits on a full network sink queue before dropping the message
+	netSinkQueueTimeout = 100 * time.Millisecond
+	// netSinkDialTimeout is how long connecting to the remote end can take
+	netSinkDialTimeout = 10 * time.Second
+	// netSinkWriteTimeout is how long sending a message can take before the connection is considered failed
+	netSinkWriteTimeout = 10 * time.Second
+	netSinkMinBackoff   = 100 * time.Millisecond
+	netSinkMaxBackoff   = 30 * time.Second
+)
+
+// netSinkCloseTimeout is how long Close waits for a network sink to send what is queued, and then for it to stop
+var netSinkCloseTimeout = 5 * time.Second
+
+// NetworkOption configures how a sink that sends to a remote address, such as WithLogstash, connects, authenticates, and queues
+type NetworkOption func(*netSinkConfig)
+
+type netSinkConfig struct {
+	tls          *tls.Config
+	auth         SinkAuth
+	cert         *fileCert
+	token        *fileToken
+	queueSize    int
+	queueTimeout time.Duration
+	spoolDir     string
+	spoolBytes   int64
+	compression  Compression
+	// http is set for the sinks that use HTTP, which are the only ones that support token auth
+	http bool
+}
+
+func newNetSinkConfig(opts []NetworkOption) netSinkConfig {
+	c := netSinkConfig{queueSize: netSinkQueueSize, queueTimeout: netSinkQueueTimeout}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// newHTTPSinkConfig is newNetSinkConfig for the sinks that use HTTP
+func newHTTPSinkConfig(opts []NetworkOption) netSinkConfig {
+	c := netSinkConfig{queueSize: netSinkQueueSize, queueTimeout: netSinkQueueTimeout, http: true}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+func (c netSinkConfig) validate() error {
+	var err error
+	if c.queueSize < 1 {
+		err = multierr.Append(err, errors.Errorf("queue size must be > 0, got: %d", c.queueSize))
+	}
+	if c.queueTimeout < 0 {
+		err = multierr.Append(err, errors.Errorf("queue timeout must be >= 0, got: %s", c.queueTimeout))
+	}
+	return multierr.Combine(err, c.validateAuth(), c.validateSpool(), c.validateCompression())
+}
+
+// NetworkConfig is the NetworkOptions of a sink of another package applied, see NewNetworkConfig
+type NetworkConfig struct {
+	c netSinkConfig
+}
+
+// NewNetworkConfig applies opts for a sink that connects to a remote address with the dial func of Dialer
+func NewNetworkConfig(opts ...NetworkOption) NetworkConfig {
+	return NetworkConfig{c: newNetSinkConfig(opts)}
+}
+
+// NewHTTPNetworkConfig is NewNetworkConfig for a sink that uses HTTP, which is the only kind that supports token auth and compression
+func NewHTTPNetworkConfig(opts ...NetworkOption) NetworkConfig {
+	return NetworkConfig{c: newHTTPSinkConfig(opts)}
+}
+
+// Validate checks the options for mistakes, all of the problems found are returned together
+func (c NetworkConfig) Validate() error { return c.c.validate() }
+
+// Dialer returns a dial func for network and addr that uses TLS when it is configured, giving up after 10s.
+// The TLS config is read on every dial, so reconnecting picks up rotated client certificates.
+func (c NetworkConfig) Dialer(network, addr string) func() (net.Conn, error) {
+	return c.c.dialer(network, addr)
+}
+
+// TLSConfig is the TLS config set with WithSinkTLS or WithSinkAuth, it is nil without TLS.
+// It should be used for every connection, it reloads the client certificate set with WithSinkAuth when it changes.
+func (c NetworkConfig) TLSConfig() *tls.Config { return c.c.tlsConfig() }
+
+// HasToken is whether a bearer token is set with WithSinkAuth
+func (c NetworkConfig) HasToken() bool { return c.c.auth.Token != "" || c.c.token != nil }
+
+// BearerToken is the token set with WithSinkAuth, it is read again from its file when it changes
+func (c NetworkConfig) BearerToken() (string, error) { return c.c.bearerToken() }
+
+// Compression is the compression set with WithSinkCompression, its Algorithm is empty when there is none
+func (c NetworkConfig) Compression() Compression { return c.c.compression }
+
+// NewRequest creates a request with body compressed as set with WithSinkCompression, setting its Content-Encoding header
+func (c NetworkConfig) NewRequest(ctx context.Context, method, url string, body []byte) (*http.Request, error) {
+	return c.c.newRequest(ctx, method, url, body)
+}
+
+// WithNetworkQueue sets how many messages are held while sending or reconnecting, 1000 by default,
+// and how long logging waits when the queue is full before dropping the message, 100ms by default.
+func WithNetworkQueue(size int, timeout time.Duration) NetworkOption {
+	return func(c *netSinkConfig) {
+		c.queueSize = size
+		c.queueTimeout = timeout
+	}
+}
+
+// netSink is the base of the sinks that send to a remote address.
+// Messages are queued and sent from a background goroutine, which reconnects with exponential backoff when the connection fails
+// and sends the message again.
+// With WithSinkSpool messages that don't fit in the queue are spooled to disk and sent once the queue is empty.
+type netSink struct {
+	name    string
+	dial    func() (net.Conn, error)
+	send    func(conn net.Conn, msg []byte) error
+	timeout time.Duration
+	queue   *queue[[]byte]
+	spool   *diskSpool
+	abort   chan struct{}
+	err     lastErr
+
+	// connMu guards conn, the connection being sent on, so Close can close it under a blocked write when it gives up
+	connMu sync.Mutex
+	conn   net.Conn
+}
+
+// NetworkSink is the base of the sinks of other packages that send to a remote address, like the built-in ones do, see NewNetworkSink
+type NetworkSink struct {
+	*netSink
+}
+
+// NewNetworkSink starts sending the messages given to Send to the address dial connects to, such as the one of NetworkConfig.Dialer,
+// queued and spooled as configured by c. send writes a single message to the connection, name is the sink in errors and SpoolStats.
+func NewNetworkSink(name string, c NetworkConfig, dial func() (net.Conn, error), send func(conn net.Conn, msg []byte) error) (*NetworkSink, error) {
+	s, err := newNetSink(name, c.c, dial, send)
+	if err != nil {
+		return nil, err
+	}
+	return &NetworkSink{netSink: s}, nil
+}
+
+// Send queues msg, which the sink keeps, waiting up to the queue timeout when the queue is full, after which it is spooled or dropped.
+// It also returns the last error the sink had sending messages.
+func (s *NetworkSink) Send(msg []byte) error {
+	return s.write(msg)
+}
+
+// newNetSink starts sending to the address dial connects to, send writes a single message to the connection
+func newNetSink(name string, c netSinkConfig, dial func() (net.Conn, error), send func(net.Conn, []byte) error) (*netSink, error) {
+	spool, err := c.openSpool()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "%s sink", name)
+	}
+	s := &netSink{
+		name:    name,
+		dial:    dial,
+		send:    send,
+		timeout: c.queueTimeout,
+		queue:   newQueue[[]byte](c.queueSize),
+		spool:   spool,
+		abort:   make(chan struct{}),
+	}
+	s.queue.start(s.run)
+	return s, nil
+}
+
+// dialer returns a dial func for network and addr that uses TLS when it is configured, giving up after netSinkDialTimeout.
+// The TLS config is read on every dial, so reconnecting picks up rotated client certificates.
+func (c netSinkConfig) dialer(network, addr string) func() (net.Conn, error) {
+	return func() (net.Conn, error) {
+		dialer := &net.Dialer{Timeout: netSinkDialTimeout}
+		if config := c.tlsConfig(); config != nil {
+			return tls.DialWithDialer(dialer, network, addr, config)
+		}
+		return dialer.Dial(network, addr)
+	}
+}
+
+// write queues msg, waiting up to the queue timeout when the queue is full, after which msg is spooled or dropped
+func (s *netSink) write(msg []byte) error {
+	result := s.queue.put(msg, s.timeout)
+	if result == queueClosed {
+		return errors.Errorf("%s sink is closed", s.name)
+	}
+	err := s.err.take()
+	if result == queued {
+		return err
+	}
+	if s.spool != nil {
+		return multierr.Append(err, errors.WithMessagef(s.spool.push(msg), "%s sink queue is full", s.name))
+	}
+	s.queue.dropped.Add(1)
+	return multierr.Append(err, errors.Errorf("%s sink queue is full, dropped the message", s.name))
+}
+
+// Write queues a copy of p so the sink can be used as a zapcore.WriteSyncer
+func (s *netSink) Write(p []byte) (int, error) {
+	msg := make([]byte, len(p))
+	copy(msg, p)
+	return len(p), s.write(msg)
+}
+
+// Sync does nothing, messages are sent as soon as the connection allows
+func (s *netSink) Sync() error { return nil }
+
+func (s *netSink) setErr(err error) {
+	s.err.set(errors.Wrapf(err, "%s sink", s.name))
+}
+
+// setConn sets the connection being sent on, it is closed straight away when the sink has been aborted
+func (s *netSink) setConn(conn net.Conn) net.Conn {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	select {
+	case <-s.abort:
+		if conn != nil {
+			_ = conn.Close()
+		}
+		return nil
+	default:
+	}
+	s.conn = conn
+	return conn
+}
+
+// closeConn closes the connection being sent on, if any
+func (s *netSink) closeConn() {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+}
+
+func (s *netSink) run(queue <-chan []byte) {
+	defer s.closeConn()
+	var conn net.Conn
+	backoff := netSinkMinBackoff
+	// deliver sends msg, trying again until it is sent, it returns false when the sink is aborted first
+	deliver := func(msg []byte) bool {
+		for {
+			var err error
+			if conn == nil {
+				if conn, err = s.dial(); err == nil {
+					if conn = s.setConn(conn); conn == nil {
+						return false
+					}
+				}
+			}
+			if err == nil {
+				if err = conn.SetWriteDeadline(time.Now().Add(netSinkWriteTimeout)); err == nil {
+					err = s.send(conn, msg)
+				}
+				if err != nil {
+					s.closeConn()
+					conn = nil
+				}
+			}
+			if err == nil {
+				backoff = netSinkMinBackoff
+				return true
+			}
+			s.setErr(err)
+			select {
+			case <-s.abort:
+				return false
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > netSinkMaxBackoff {
+				backoff = netSinkMaxBackoff
+			}
+		}
+	}
+	for {
+		if s.spool != nil && len(queue) == 0 && s.spool.pending() {
+			r, err := s.spool.pop()
+			if err == nil && r != nil {
+				for msg, ok := r.next(); ok; msg, ok = r.next() {
+					// the spool file is only removed once all of it is sent, so it is replayed again if the sink is aborted
+					if !deliver(msg) {
+						_ = r.close()
+						return
+					}
+				}
+				if err := r.done(); err != nil {
+					s.setErr(err)
+				}
+				continue
+			}
+			if err != nil {
+				s.setErr(err)
+			}
+		}
+		msg, ok := <-queue
+		if !ok {
+			return
+		}
+		if !deliver(msg) {
+			s.spill(msg)
+			return
+		}
+	}
+}
+
+// spill spools msg, which couldn't be sent before the sink was aborted, it is lost when there is no spool
+func (s *netSink) spill(msg []byte) {
+	if s.spool != nil {
+		if err := s.spool.push(msg); err != nil {
+			s.setErr(err)
+		}
+	}
+}
+
+func (s *netSink) sinkName() string { return s.name }
+
+// checkHealth connects to the remote end, separately from the connection used for sending
+func (s *netSink) checkHealth(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		conn, err := s.dial()
+		if err == nil {
+			err = conn.Close()
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return errors.WithMessagef(err, "%s sink", s.name)
+	case <-ctx.Done():
+		return errors.Wrapf(ctx.Err(), "%s sink", s.name)
+	}
+}
+
+func (s *netSink) droppedEntries() uint64 {
+	return s.queue.dropped.Load()
+}
+
+func (s *netSink) spoolStats() (SpoolStats, bool) {
+	if s.spool == nil {
+		return SpoolStats{}, false
+	}
+	stats := s.spool.stats(s.name)
+	stats.Dropped += s.queue.dropped.Load()
+	return stats, true
+}
+
+// Close waits up to netSinkCloseTimeout for queued messages to be sent and closes the connection,
+// what is still queued after that is spooled when there is a spool. The connection is closed under a message being sent
+// when it gives up, and it waits up to netSinkCloseTimeout again for the sink to stop.
+func (s *netSink) Close() error {
+	if !s.queue.close() {
+		return nil
+	}
+
+	timer := time.NewTimer(netSinkCloseTimeout)
+	defer timer.Stop()
+	select {
+	case <-s.queue.stopped:
+		return multierr.Append(s.err.take(), s.closeSpool())
+	case <-timer.C:
+		close(s.abort)
+		s.closeConn()
+		timer.Reset(netSinkCloseTimeout)
+		select {
+		case <-s.queue.stopped:
+		case <-timer.C:
+			return multierr.Append(s.err.take(), errors.Errorf("%s sink didn't stop within %s", s.name, netSinkCloseTimeout))
+		}
+		if s.spool == nil {
+			return multierr.Append(s.err.take(), errors.Errorf("%s sink closed with %d messages not sent", s.name, len(s.queue.items)))
+		}
+		n := len(s.queue.items)
+		for msg := range s.queue.items {
+			s.spill(msg)
+		}
+		return multierr.Combine(s.err.take(), s.closeSpool(), errors.Errorf("%s sink closed with %d messages spooled", s.name, n))
+	}
+}
+
+func (s *netSink) closeSpool() error {
+	if s.spool == nil {
+		return nil
+	}
+	return s.spool.close()
+}
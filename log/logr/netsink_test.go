@@ -0,0 +1,144 @@
+package logr
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lineServer accepts connections on ln and sends every line it reads to lines, closing each connection after max lines when max > 0
+func lineServer(ln net.Listener, max int, lines chan<- string) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for i := 0; max <= 0 || i < max; i++ {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				lines <- line
+			}
+		}(conn)
+	}
+}
+
+func TestNetSinkReconnects(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 10)
+	go lineServer(ln, 1, lines)
+
+	c := newNetSinkConfig(nil)
+	s, err := newNetSink("test", c, c.dialer("tcp", ln.Addr().String()), func(conn net.Conn, msg []byte) error {
+		_ = conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+		// a read tells us when the server has hung up, so the message is sent again on a new connection
+		if _, err := conn.Read(make([]byte, 1)); err != nil && !isTimeout(err) {
+			return err
+		}
+		_, err := conn.Write(msg)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, msg := range []string{"one\n", "two\n", "three\n"} {
+		if err := s.write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range []string{"one\n", "two\n", "three\n"} {
+		select {
+		case got := <-lines:
+			if got != want {
+				t.Fatalf("expected: %q, got: %q", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+func TestNetSinkDropsWhenFull(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	c := newNetSinkConfig([]NetworkOption{WithNetworkQueue(1, 50*time.Millisecond)})
+	s, err := newNetSink("test", c, func() (net.Conn, error) {
+		<-block
+		return nil, net.ErrClosed
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var errs []error
+	for i := 0; i < 5; i++ {
+		if err := s.write([]byte("msg")); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	// one message is being sent and one is queued
+	if got := s.queue.dropped.Load(); got != 3 {
+		t.Fatalf("expected 3 dropped messages, got: %d", got)
+	}
+	if len(errs) != 3 || !strings.Contains(errs[0].Error(), "test sink queue is full") {
+		t.Fatalf("expected queue full errors, got: %v", errs)
+	}
+}
+
+func TestNetSinkCloseAbortsBlockedWrite(t *testing.T) {
+	defer func(timeout time.Duration) { netSinkCloseTimeout = timeout }(netSinkCloseTimeout)
+	netSinkCloseTimeout = 50 * time.Millisecond
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// the server never reads, so the writes block once the socket buffers are full
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	c := newNetSinkConfig(nil)
+	s, err := newNetSink("test", c, c.dialer("tcp", ln.Addr().String()), func(conn net.Conn, msg []byte) error {
+		_, err := conn.Write(msg)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := s.write(make([]byte, 16<<20)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now()
+	err = s.Close()
+	if took := time.Since(start); took > time.Second {
+		t.Fatalf("expected Close to give up on the blocked write, took: %s", took)
+	}
+	if err == nil || !strings.Contains(err.Error(), "test sink closed with") {
+		t.Fatalf("expected the messages not sent to be reported, got: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+// Package obs sets up the logger, metrics, and tracing of a service together so they agree with each other: they have
+// the same service name and resource attributes, entries logged with the loggers of logr.FromContext have the trace_id
+// and span_id of the context's span, the volume of logs is a metric, and Shutdown flushes all of them in order:
+//
+//	o, err := obs.New(obs.WithServiceName("billing"), obs.WithResourceAttrs(map[string]string{"deployment.environment": "prod"}))
+//	defer o.Shutdown(context.Background())
+//	http.Handle("/metrics", o.Metrics.Handler())
+package obs
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/metrics"
+	"github.com/packethost/pkg/log/logr/otellog"
+	"github.com/packethost/pkg/log/logr/tracing"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/multierr"
+)
+
+// Option customizes New
+type Option func(*config)
+
+type config struct {
+	serviceName    string
+	attrs          map[string]string
+	loggerOpts     []logr.LoggerOption
+	metricsOpts    []metrics.Option
+	tracingOpts    []tracing.Option
+	disableTracing bool
+}
+
+// WithServiceName sets the service name of the logger, the service label of the metrics, and the service.name of the spans
+func WithServiceName(name string) Option {
+	return func(c *config) { c.serviceName = name }
+}
+
+// WithResourceAttrs adds attributes describing the service, such as the deployment.environment or service.version,
+// they are logged as fields, set as constant labels on the metrics, with dots replaced by underscores, and added to the spans' resource
+func WithResourceAttrs(attrs map[string]string) Option {
+	return func(c *config) {
+		for k, v := range attrs {
+			c.attrs[k] = v
+		}
+	}
+}
+
+// WithLoggerOptions customizes the logger, they are applied after the ones New sets up so they take precedence
+func WithLoggerOptions(opts ...logr.LoggerOption) Option {
+	return func(c *config) { c.loggerOpts = append(c.loggerOpts, opts...) }
+}
+
+// WithMetricsOptions customizes the metrics, they are applied after the ones New sets up so they take precedence
+func WithMetricsOptions(opts ...metrics.Option) Option {
+	return func(c *config) { c.metricsOpts = append(c.metricsOpts, opts...) }
+}
+
+// WithTracingOptions customizes the tracing, such as with tracing.WithOTLPEndpoint,
+// they are applied after the ones New sets up so they take precedence
+func WithTracingOptions(opts ...tracing.Option) Option {
+	return func(c *config) { c.tracingOpts = append(c.tracingOpts, opts...) }
+}
+
+// WithoutTracing leaves out the TracerProvider, for services whose spans are recorded by something else
+func WithoutTracing() Option {
+	return func(c *config) { c.disableTracing = true }
+}
+
+// Obs is the logger, metrics, and tracing of a service, see New
+type Obs struct {
+	Logger  *logr.PacketLogr
+	Metrics *metrics.Metrics
+	// TracerProvider is nil with WithoutTracing
+	TracerProvider *sdktrace.TracerProvider
+
+	shutdownTracing func(context.Context) error
+}
+
+// New sets up the logger, from logr.NewFromEnv, the metrics, and the tracing, whose TracerProvider is the global one.
+// The logger counts what it logs with the metrics, see metrics.LogCounter, and logs the errors of the metrics and tracing.
+func New(opts ...Option) (*Obs, error) {
+	c := config{attrs: map[string]string{}}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.serviceName == "" {
+		return nil, errors.New("a service name is required, see WithServiceName")
+	}
+	keys := make([]string, 0, len(c.attrs))
+	for k := range c.attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]interface{}, 0, 2*len(keys))
+	labels := make(prometheus.Labels, len(keys))
+	attrs := make([]attribute.KeyValue, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, k, c.attrs[k])
+		labels[strings.ReplaceAll(k, ".", "_")] = c.attrs[k]
+		attrs = append(attrs, attribute.String(k, c.attrs[k]))
+	}
+
+	counter := metrics.NewLogCounter()
+	logger, err := logr.NewFromEnv(append([]logr.LoggerOption{
+		logr.WithServiceName(c.serviceName),
+		logr.WithKeysAndValues(kvs),
+		logr.WithLogCounter(counter),
+		otellog.WithTraceFields(),
+	}, c.loggerOpts...)...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to set up the logger")
+	}
+	o := &Obs{Logger: logger, shutdownTracing: func(context.Context) error { return nil }}
+
+	o.Metrics, err = metrics.New(append([]metrics.Option{
+		metrics.WithServiceName(c.serviceName),
+		metrics.WithConstLabels(labels),
+		metrics.WithLogger(logger),
+	}, c.metricsOpts...)...)
+	if err != nil {
+		return nil, multierr.Append(errors.WithMessage(err, "failed to set up the metrics"), logger.Close(context.Background()))
+	}
+	if err := o.Metrics.Register(counter.Collector(logger)); err != nil {
+		return nil, multierr.Append(errors.Wrap(err, "failed to register the log metrics"), logger.Close(context.Background()))
+	}
+
+	if !c.disableTracing {
+		o.TracerProvider, o.shutdownTracing, err = tracing.New(append([]tracing.Option{
+			tracing.WithServiceName(c.serviceName),
+			tracing.WithResourceAttrs(attrs...),
+			tracing.WithGlobal(),
+			tracing.WithLogger(logger),
+		}, c.tracingOpts...)...)
+		if err != nil {
+			return nil, multierr.Append(errors.WithMessage(err, "failed to set up tracing"), logger.Close(context.Background()))
+		}
+	}
+	return o, nil
+}
+
+// Shutdown flushes and shuts down the tracing, and then the logger, which logs the errors of the tracing while it is shut down,
+// giving up when ctx is done. It is meant to be deferred in main.
+func (o *Obs) Shutdown(ctx context.Context) error {
+	err := o.shutdownTracing(ctx)
+	if err != nil {
+		o.Logger.Error(err, "failed to shut down tracing")
+	}
+	return multierr.Append(err, o.Logger.Close(ctx))
+}
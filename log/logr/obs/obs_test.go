@@ -0,0 +1,79 @@
+package obs
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/metrics"
+	"github.com/packethost/pkg/log/logr/tracing"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// exporter keeps the spans it exports, unlike tracetest.InMemoryExporter it keeps them once it is shut down
+type exporter struct {
+	mu    sync.Mutex
+	spans []sdktrace.ReadOnlySpan
+}
+
+func (e *exporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *exporter) Shutdown(context.Context) error { return nil }
+
+func TestNew(t *testing.T) {
+	path := t.TempDir() + "/log"
+	exp := &exporter{}
+	o, err := New(
+		WithServiceName("billing"),
+		WithResourceAttrs(map[string]string{"deployment.environment": "test"}),
+		WithLoggerOptions(logr.WithOutputPaths([]string{path})),
+		WithMetricsOptions(metrics.WithoutRuntimeCollectors()),
+		WithTracingOptions(tracing.WithExporter(exp)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, span := otel.Tracer("test").Start(context.Background(), "charge")
+	o.Logger.FromContext(ctx).Info("charging")
+	span.End()
+
+	w := httptest.NewRecorder()
+	o.Metrics.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	if want := `log_lines_total{component="",deployment_environment="test",level="info",service="billing"} 1`; !strings.Contains(string(body), want) {
+		t.Fatalf("expected %s to be served, got: %s", want, body)
+	}
+
+	if err := o.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(exp.spans) != 1 {
+		t.Fatalf("expected Shutdown to flush the span, got: %v", exp.spans)
+	}
+	if got := exp.spans[0].Resource().String(); !strings.Contains(got, "deployment.environment=test") || !strings.Contains(got, "service.name=billing") {
+		t.Fatalf("expected the resource attributes, got: %v", got)
+	}
+	log, _ := os.ReadFile(path)
+	for _, want := range []string{`"service":"billing"`, `"deployment.environment":"test"`, `"trace_id":"` + span.SpanContext().TraceID().String() + `"`} {
+		if !strings.Contains(string(log), want) {
+			t.Fatalf("expected %s to be logged, got: %s", want, log)
+		}
+	}
+}
+
+func TestNewWithoutServiceName(t *testing.T) {
+	if _, err := New(WithoutTracing()); err == nil {
+		t.Fatal("expected an error without a service name")
+	}
+}
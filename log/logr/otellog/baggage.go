@@ -0,0 +1,44 @@
+package otellog
+
+import (
+	"context"
+	"sort"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// WithBaggageFields adds the members of the OpenTelemetry baggage of a context to the loggers of logr.PacketLogr.FromContext, as fields.
+// fields maps the baggage keys to log, such as tenant or region, to the field names they are logged as, the baggage key when empty.
+// Members that aren't in the baggage are left out.
+func WithBaggageFields(fields map[string]string) logr.LoggerOption {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if key == "" {
+			return logr.WithOptionError(errors.New("otellog.WithBaggageFields: baggage key must not be empty"))
+		}
+		keys = append(keys, key)
+	}
+	// sorted so the fields are always in the same order
+	sort.Strings(keys)
+	return logr.WithContextFields(func(ctx context.Context) []interface{} {
+		b := baggage.FromContext(ctx)
+		if b.Len() == 0 {
+			return nil
+		}
+		var kvs []interface{}
+		for _, key := range keys {
+			m := b.Member(key)
+			if m.Key() == "" {
+				continue
+			}
+			name := fields[key]
+			if name == "" {
+				name = key
+			}
+			kvs = append(kvs, name, m.Value())
+		}
+		return kvs
+	})
+}
@@ -0,0 +1,35 @@
+package otellog
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+func TestWithBaggageFields(t *testing.T) {
+	b, err := baggage.Parse("tenant=acme,region=da11,secret=hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := baggage.ContextWithBaggage(context.Background(), b)
+	pl, logged := logtest.New(t, logr.WithServiceName("ctx"), WithBaggageFields(map[string]string{"tenant": "tenant_id", "region": "", "customer": ""}))
+	pl.FromContext(ctx).Info("with baggage")
+	pl.FromContext(context.Background()).Info("without baggage")
+	capturedOutput := logged()
+	for _, want := range []string{`"msg":"with baggage","service":"ctx","region":"da11","tenant_id":"acme"}`, `"msg":"without baggage","service":"ctx"}`} {
+		if !strings.Contains(capturedOutput, want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+		}
+	}
+}
+
+func TestWithBaggageFieldsEmptyKey(t *testing.T) {
+	_, err := logr.New(WithBaggageFields(map[string]string{"": "tenant"}))
+	if err == nil || !strings.Contains(err.Error(), "otellog.WithBaggageFields") {
+		t.Fatalf("expected an error for an empty key, got: %v", err)
+	}
+}
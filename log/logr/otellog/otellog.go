@@ -0,0 +1,61 @@
+// Package otellog logs the OpenTelemetry span and baggage of a context with the loggers of logr.PacketLogr.FromContext,
+// and passes the W3C traceparent of requests on:
+//
+//	l, err := logr.New(otellog.WithTraceFields(), otellog.WithBaggageFields(map[string]string{"tenant": "tenant_id"}))
+package otellog
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/packethost/pkg/log/logr"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The fields the OpenTelemetry trace and span IDs are logged as, the names Grafana and the OpenTelemetry log data model use
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+	// TraceStateKey is the field the vendor specific trace state is logged as, see TraceParentFields
+	TraceStateKey = "trace_state"
+)
+
+// WithTraceFields adds the trace_id and span_id of the OpenTelemetry span of a context to the loggers of logr.PacketLogr.FromContext,
+// so logs can be correlated with traces
+func WithTraceFields() logr.LoggerOption {
+	return logr.WithContextFields(TraceFields)
+}
+
+// TraceFields returns the IDs of the OpenTelemetry span ctx carries as keys and values, they are empty when there is none
+func TraceFields(ctx context.Context) []interface{} {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return []interface{}{TraceIDKey, sc.TraceID().String(), SpanIDKey, sc.SpanID().String()}
+}
+
+// ExtractTraceParent returns a copy of ctx carrying the remote span of the W3C traceparent and tracestate headers of an incoming
+// request, when there is a valid one, so that WithTraceFields adds its trace_id and span_id, and InjectTraceParent passes it on,
+// without the service being traced itself. span_id is then the caller's span.
+func ExtractTraceParent(ctx context.Context, header http.Header) context.Context {
+	return propagation.TraceContext{}.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// InjectTraceParent sets the W3C traceparent and tracestate headers of an outgoing request from the span ctx carries,
+// whether it was started by OpenTelemetry or extracted with ExtractTraceParent. It does nothing when ctx has no span.
+func InjectTraceParent(ctx context.Context, header http.Header) {
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// TraceParentFields returns the trace_id and span_id of the W3C traceparent header, and the tracestate header as trace_state,
+// as keys and values, such as for logr.Logger.WithValues. They are empty when there is no valid traceparent.
+func TraceParentFields(header http.Header) []interface{} {
+	sc := trace.SpanContextFromContext(ExtractTraceParent(context.Background(), header))
+	kvs := TraceFields(trace.ContextWithSpanContext(context.Background(), sc))
+	if state := sc.TraceState().String(); len(kvs) > 0 && state != "" {
+		kvs = append(kvs, TraceStateKey, state)
+	}
+	return kvs
+}
@@ -0,0 +1,70 @@
+package otellog
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWithTraceFields(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+	pl, logged := logtest.New(t, logr.WithServiceName("ctx"), WithTraceFields())
+	pl.FromContext(ctx).Info("traced")
+	pl.FromContext(context.Background()).Info("not traced")
+	capturedOutput := logged()
+	for _, want := range []string{`"msg":"traced","service":"ctx","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7"}`, `"msg":"not traced","service":"ctx"}`} {
+		if !strings.Contains(capturedOutput, want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+		}
+	}
+}
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestTraceParentFields(t *testing.T) {
+	header := http.Header{}
+	header.Set("traceparent", testTraceParent)
+	header.Set("tracestate", "vendor=value")
+	want := []interface{}{TraceIDKey, "4bf92f3577b34da6a3ce929d0e0e4736", SpanIDKey, "00f067aa0ba902b7", TraceStateKey, "vendor=value"}
+	if got := TraceParentFields(header); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected the trace fields, got: %v", got)
+	}
+
+	for _, invalid := range []string{"", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "garbage"} {
+		header.Set("traceparent", invalid)
+		if got := TraceParentFields(header); got != nil {
+			t.Fatalf("expected no fields for %q, got: %v", invalid, got)
+		}
+	}
+}
+
+func TestExtractInjectTraceParent(t *testing.T) {
+	in := http.Header{}
+	in.Set("traceparent", testTraceParent)
+	ctx := ExtractTraceParent(context.Background(), in)
+
+	out := http.Header{}
+	InjectTraceParent(ctx, out)
+	if got := out.Get("traceparent"); got != testTraceParent {
+		t.Fatalf("expected the traceparent to be passed on, got: %v", got)
+	}
+	pl, logged := logtest.New(t, logr.WithServiceName("ctx"), WithTraceFields())
+	pl.FromContext(ctx).Info("extracted")
+	if capturedOutput, want := logged(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7"`; !strings.Contains(capturedOutput, want) {
+		t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+	}
+
+	empty := http.Header{}
+	InjectTraceParent(context.Background(), empty)
+	if len(empty) != 0 {
+		t.Fatalf("expected no headers without a span, got: %v", empty)
+	}
+}
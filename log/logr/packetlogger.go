@@ -1,33 +1,81 @@
 package logr
 
 import (
+	"context"
+	"io"
 	"os"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
 	"github.com/pkg/errors"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-// WithLogLevel sets the log level
+// WithLogLevel sets the log level, one of trace, debug, info, warn, error, dpanic, panic, or fatal,
+// or v followed by a logr V level, such as v4, see WithMaxV
 func WithLogLevel(level string) LoggerOption {
 	return func(args *PacketLogr) { args.logLevel = level }
 }
 
-// WithOutputPaths adds output paths
+// WithMaxV sets the log level to logr's V level v, so V(n) entries are only logged when n <= v, as Kubernetes' -v flag does.
+// V(0) is info, V(1) debug and V(2) trace, higher ones are more verbose still. It replaces WithLogLevel.
+func WithMaxV(v int) LoggerOption {
+	return func(args *PacketLogr) {
+		if v < 0 || v > MaxV {
+			args.errs = multierr.Append(args.errs, errors.Errorf("WithMaxV: v must be between 0 and %d, got: %d", MaxV, v))
+			return
+		}
+		args.logLevel = "v" + strconv.Itoa(v)
+	}
+}
+
+// WithOutputPaths adds output paths, they can only be nil when WithSinkConfig is used
 func WithOutputPaths(paths []string) LoggerOption {
 	return func(args *PacketLogr) { args.outputPaths = paths }
 }
 
+// WithErrorOutputPaths sets where zap writes its own internal errors, such as failing to write to an output path.
+// Defaults to stderr.
+func WithErrorOutputPaths(paths []string) LoggerOption {
+	return func(args *PacketLogr) { args.errorOutputPaths = paths }
+}
+
 // WithServiceName adds a service name a logged field
 func WithServiceName(name string) LoggerOption {
 	return func(args *PacketLogr) { args.serviceName = name }
 }
 
-// WithKeysAndValues adds extra key/value fields
+// WithKeysAndValues adds extra key/value fields, kvs must be an even number of alternating string keys and values
 func WithKeysAndValues(kvs []interface{}) LoggerOption {
-	return func(args *PacketLogr) { args.keysAndValues = append(args.keysAndValues, kvs...) }
+	return func(args *PacketLogr) {
+		if err := validateKeysAndValues(kvs); err != nil {
+			args.errs = multierr.Append(args.errs, errors.WithMessage(err, "WithKeysAndValues"))
+			return
+		}
+		args.keysAndValues = append(args.keysAndValues, kvs...)
+	}
+}
+
+// WithFields adds extra fields, they are added sorted by key
+func WithFields(fields map[string]interface{}) LoggerOption {
+	return func(args *PacketLogr) {
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			args.keysAndValues = append(args.keysAndValues, k, fields[k])
+		}
+	}
 }
 
 // WithEnableErrLogsToStderr sends .Error logs to stderr
@@ -35,33 +83,249 @@ func WithEnableErrLogsToStderr(enable bool) LoggerOption {
 	return func(args *PacketLogr) { args.enableErrLogsToStderr = enable }
 }
 
-// WithEnableRollbar sends error logs to Rollbar service
+// WithEnableRollbar sends error logs to Rollbar service, see WithRollbarLevel to send more or fewer.
+// The stack of an error made with github.com/pkg/errors is the one of where it was made, not where it was logged.
 func WithEnableRollbar(enable bool) LoggerOption {
 	return func(args *PacketLogr) { args.enableRollbar = enable }
 }
 
-// WithRollbarConfig customizes the Rollbar details
-func WithRollbarConfig(config rollbarConfig) LoggerOption {
+// WithRollbarConfig customizes the Rollbar details, see NewRollbarConfig.
+// The environment, version, and code version that are empty are detected, see WithDeployInfo,
+// and otherwise it reports to the production environment as version 1.
+func WithRollbarConfig(config RollbarConfig) LoggerOption {
 	return func(args *PacketLogr) { args.rollbarConfig = config }
 }
 
+// WithSignalLevelToggle flips the log level between info and debug every time sig is received, syscall.SIGUSR1 is a good choice
+func WithSignalLevelToggle(sig os.Signal) LoggerOption {
+	return func(args *PacketLogr) { args.toggleSignal = sig }
+}
+
+// WithComponentLevels sets per component log levels, keyed on the name given to logr.Logger.WithName.
+// Nested names such as "db.pool" fall back to the closest parent, "db", and then to the global log level.
+func WithComponentLevels(levels map[string]string) LoggerOption {
+	return func(args *PacketLogr) { args.componentLevels = levels }
+}
+
+// WithEncoding sets the log encoding, one of json (the default), console, or logfmt
+func WithEncoding(encoding string) LoggerOption {
+	return func(args *PacketLogr) { args.encoding = encoding }
+}
+
+// WithDevelopmentMode switches to human-readable console output with colorized levels, caller info, and ISO8601 timestamps.
+// An encoding set with WithEncoding takes precedence over the console encoding.
+func WithDevelopmentMode() LoggerOption {
+	return func(args *PacketLogr) { args.developmentMode = true }
+}
+
+// WithEncoderConfig replaces zap's production encoder config, and the one set by WithDevelopmentMode.
+// Options that set individual keys are applied in order so they can be used to tweak the config before or after.
+func WithEncoderConfig(config zapcore.EncoderConfig) LoggerOption {
+	return func(args *PacketLogr) {
+		args.encoderConfigOpts = append(args.encoderConfigOpts, func(c *zapcore.EncoderConfig) { *c = config })
+	}
+}
+
+// WithTimeKey sets the key used for the timestamp, an empty key omits it
+func WithTimeKey(key string) LoggerOption {
+	return func(args *PacketLogr) {
+		args.encoderConfigOpts = append(args.encoderConfigOpts, func(c *zapcore.EncoderConfig) { c.TimeKey = key })
+	}
+}
+
+// WithLevelKey sets the key used for the log level, an empty key omits it
+func WithLevelKey(key string) LoggerOption {
+	return func(args *PacketLogr) {
+		args.encoderConfigOpts = append(args.encoderConfigOpts, func(c *zapcore.EncoderConfig) { c.LevelKey = key })
+	}
+}
+
+// WithMessageKey sets the key used for the log message, an empty key omits it
+func WithMessageKey(key string) LoggerOption {
+	return func(args *PacketLogr) {
+		args.encoderConfigOpts = append(args.encoderConfigOpts, func(c *zapcore.EncoderConfig) { c.MessageKey = key })
+	}
+}
+
+// WithTimeEncoder sets the func used to encode timestamps
+func WithTimeEncoder(encoder zapcore.TimeEncoder) LoggerOption {
+	return func(args *PacketLogr) {
+		args.encoderConfigOpts = append(args.encoderConfigOpts, func(c *zapcore.EncoderConfig) { c.EncodeTime = encoder })
+	}
+}
+
+// WithTimeFormat sets the timestamp format, one of rfc3339, rfc3339millis, rfc3339nano, iso8601, epoch, epochmillis, or epochnanos.
+// Any other value is used as a time.Format layout.
+func WithTimeFormat(format string) LoggerOption {
+	return WithTimeEncoder(timeEncoder(format))
+}
+
+// WithCaller enables or disables annotating logs with the file:line of the caller, enabled by default
+func WithCaller(enable bool) LoggerOption {
+	return func(args *PacketLogr) { args.disableCaller = !enable }
+}
+
+// WithCallerSkip increases the number of frames skipped when annotating logs with the caller.
+// When building wrappers around the logger, supplying this option prevents the wrapper from always being reported as the caller.
+func WithCallerSkip(skip int) LoggerOption {
+	return func(args *PacketLogr) { args.callerSkip = skip }
+}
+
+// WithSampling caps the log volume per second, after the first initial entries with the same level and message
+// only every thereafter entry is logged. The default is 100 and 100.
+func WithSampling(initial, thereafter int) LoggerOption {
+	return func(args *PacketLogr) {
+		args.enableSampling = true
+		args.samplingConfig = zap.SamplingConfig{Initial: initial, Thereafter: thereafter}
+	}
+}
+
+// WithEnableSampling enables or disables sampling, enabled by default
+func WithEnableSampling(enable bool) LoggerOption {
+	return func(args *PacketLogr) { args.enableSampling = enable }
+}
+
+// WithDeduplication suppresses entries with the same level, logger name, and message that are logged within window of the first one.
+// A summary with the number of suppressed entries is logged once the window has passed, or when the logger is synced.
+// Rollbar is deduplicated too so tight error loops don't use up its quota, see WithErrorReportDeduplication to only deduplicate the error reporters.
+func WithDeduplication(window time.Duration) LoggerOption {
+	return func(args *PacketLogr) { args.dedupeWindow = window }
+}
+
+// WithAsyncBuffer queues up to size entries in memory and writes them to the outputs from a background goroutine,
+// batching what arrives within flushInterval, so slow disks or sockets don't hold up the goroutines doing the logging.
+// Logging blocks once the queue is full. Use Flush, Sync, or Close to make sure queued entries are written.
+func WithAsyncBuffer(size int, flushInterval time.Duration) LoggerOption {
+	return func(args *PacketLogr) {
+		args.enableAsync = true
+		args.asyncSize = size
+		args.asyncFlushInterval = flushInterval
+	}
+}
+
+// WithDropWhenFull makes logging drop entries instead of blocking when the WithAsyncBuffer queue is full, for services where latency matters more than every line.
+// Dropped returns the number of dropped entries, and the number dropped is written to the error output, see WithErrorOutputPaths,
+// every summaryInterval in which any were, 0 turns this off.
+func WithDropWhenFull(summaryInterval time.Duration) LoggerOption {
+	return func(args *PacketLogr) {
+		args.dropWhenFull = true
+		args.dropSummaryInterval = summaryInterval
+	}
+}
+
+// WithHook calls hook with every entry that is written, after sampling, component levels, and deduplication have had their say.
+// It can be used more than once, each hook is called from its own goroutine with the entries queued for it, so the entries being
+// written don't wait on the hooks and a slow one doesn't hold up the others. The entries are dropped for a hook when up to 1000
+// are already queued for it, see WithHookTimeout and DroppedHookEntries. Close waits for the hooks to be called with what is queued.
+func WithHook(hook func(zapcore.Entry) error) LoggerOption {
+	return func(args *PacketLogr) { args.hooks = append(args.hooks, hook) }
+}
+
+// WithHookTimeout sets how long an entry waits on a full hook queue before it is dropped for that hook, defaults to 0, never waiting.
+// Hook errors and dropped entries are written to the error output.
+func WithHookTimeout(timeout time.Duration) LoggerOption {
+	return func(args *PacketLogr) { args.hookTimeout = timeout }
+}
+
+// WithZapOptions adds zap options that are passed to zap.Config.Build after the ones set up by PacketLogr
+func WithZapOptions(opts ...zap.Option) LoggerOption {
+	return func(args *PacketLogr) { args.zapOptions = append(args.zapOptions, opts...) }
+}
+
+// WithCore wraps the core built by PacketLogr, use zapcore.NewTee(core, yours) in wrap to also send entries to your own core.
+// A teed core filters entries using its own LevelEnabler, not the PacketLogr log level.
+func WithCore(wrap func(zapcore.Core) zapcore.Core) LoggerOption {
+	return WithZapOptions(zap.WrapCore(wrap))
+}
+
 // PacketLogr is a wrapper around zap.SugaredLogger
 type PacketLogr struct {
 	logr.Logger
 	logLevel              string
 	outputPaths           []string
+	errorOutputPaths      []string
 	serviceName           string
 	keysAndValues         []interface{}
 	enableErrLogsToStderr bool
 	enableRollbar         bool
-	rollbarConfig         rollbarConfig
+	rollbarConfig         RollbarConfig
+	rollbarReporter       rollbarReporter
+	enableBugsnag         bool
+	bugsnagConfig         BugsnagConfig
+	enableHoneybadger     bool
+	honeybadgerConfig     HoneybadgerConfig
+	enableDatadog         bool
+	datadogConfig         DatadogConfig
+	deployInfo            DeployInfo
+	reporters             []ErrorReporter
+	newReporters          []newReporter
+	reporterQueues        []*httpReporter
+	level                 zap.AtomicLevel
+	toggleSignal          os.Signal
+	componentLevels       map[string]string
+	encoding              string
+	developmentMode       bool
+	encoderConfigOpts     []func(*zapcore.EncoderConfig)
+	disableCaller         bool
+	callerSkip            int
+	enableSampling        bool
+	samplingConfig        zap.SamplingConfig
+	dedupeWindow          time.Duration
+	reportDedupeWindow    time.Duration
+	reporterSpoolDir      string
+	reporterSpoolBytes    int64
+	hooks                 []func(zapcore.Entry) error
+	contextFields         []func(context.Context) []interface{}
+	enableAsync           bool
+	asyncSize             int
+	asyncFlushInterval    time.Duration
+	asyncWriters          []*asyncWriter
+	logCounter            LogCounter
+	dropWhenFull          bool
+	dropSummaryInterval   time.Duration
+	stopDropReports       func()
+	stopSpoolReports      func()
+	stopDedupeFlushes     func()
+	newSinks              []newSink
+	sinkConfigs           []string
+	batchPolicy           *batchConfig
+	sinkClosers           []io.Closer
+	hookTimeout           time.Duration
+	hookRunner            *hookRunner
+	alertRules            []*alertRule
+	alerts                *alerter
+	zapOptions            []zap.Option
+	redactedKeys          []string
+	scrubPatterns         []*regexp.Regexp
+	zap                   *zap.Logger
+	stopSignalToggle      func()
+
+	// errs are collected from options that can fail and returned by validate
+	errs error
 }
 
 // LoggerOption for setting optional values
 type LoggerOption func(*PacketLogr)
 
-// NewPacketLogr is the opionated packet logger setup
+// NewPacketLogr is the opionated packet logger setup, it is a thin wrapper around New.
+// When it fails, the loggers returned along with the error discard every entry, so they can still be used.
 func NewPacketLogr(opts ...LoggerOption) (logr.Logger, *zap.Logger, error) {
+	pl, err := New(opts...)
+	if err != nil {
+		pl = nopPacketLogr()
+		return pl, pl.zap, err
+	}
+	return pl, pl.zap, nil
+}
+
+// nopPacketLogr is a PacketLogr discarding every entry
+func nopPacketLogr() *PacketLogr {
+	nop := zap.NewNop()
+	return &PacketLogr{Logger: warnLogger{Logger: zapr.NewLogger(nop), zap: nop}, zap: nop, level: zap.NewAtomicLevel()}
+}
+
+// New is the opionated packet logger setup
+func New(opts ...LoggerOption) (*PacketLogr, error) {
 	// defaults
 	const (
 		defaultLogLevel    = "info"
@@ -71,53 +335,298 @@ func NewPacketLogr(opts ...LoggerOption) (logr.Logger, *zap.Logger, error) {
 		defaultOutputPaths   = []string{"stdout"}
 		defaultKeysAndValues = []interface{}{}
 		zapConfig            = zap.NewProductionConfig()
-		zLevel               = zap.InfoLevel
 		defaultZapOpts       = []zap.Option{}
-		rollbarOptions       zap.Option
-		defaultRollbarConfig = rollbarConfig{
-			token:   "123",
-			env:     "production",
-			version: "1",
-		}
 	)
 
 	pl := &PacketLogr{
-		Logger:        nil,
-		logLevel:      defaultLogLevel,
-		outputPaths:   defaultOutputPaths,
-		serviceName:   defaultServiceName,
-		keysAndValues: defaultKeysAndValues,
-		enableRollbar: false,
-		rollbarConfig: defaultRollbarConfig,
+		Logger:          nil,
+		logLevel:        defaultLogLevel,
+		outputPaths:     defaultOutputPaths,
+		serviceName:     defaultServiceName,
+		keysAndValues:   defaultKeysAndValues,
+		enableRollbar:   false,
+		rollbarReporter: rollbarReporter{LevelEnabler: zapcore.ErrorLevel},
+		enableSampling:  true,
+		samplingConfig:  *zapConfig.Sampling,
 	}
+	// sampling is set up by sampler instead so that it also wraps the cores set up by the options
+	zapConfig.Sampling = nil
 
 	for _, opt := range opts {
 		opt(pl)
 	}
+	if err := pl.validate(); err != nil {
+		return nil, err
+	}
 
-	switch pl.logLevel {
-	case "debug":
-		zLevel = zap.DebugLevel
+	zLevel, err := parseLevel(pl.logLevel)
+	if err != nil {
+		return nil, err
 	}
-	zapConfig.Level = zap.NewAtomicLevelAt(zLevel)
+	pl.level = zap.NewAtomicLevelAt(zLevel)
+	zapConfig.Level = pl.level
 	zapConfig.OutputPaths = sliceDedupe(pl.outputPaths)
+	if pl.errorOutputPaths != nil {
+		zapConfig.ErrorOutputPaths = sliceDedupe(pl.errorOutputPaths)
+	}
+	zapConfig.EncoderConfig.EncodeLevel = encodeLevel(zapcore.LowercaseLevelEncoder, strings.ToLower)
+	if pl.developmentMode {
+		zapConfig.Encoding = "console"
+		zapConfig.EncoderConfig = zap.NewDevelopmentEncoderConfig()
+		zapConfig.EncoderConfig.EncodeLevel = encodeLevel(zapcore.CapitalColorLevelEncoder, strings.ToUpper)
+	}
+	if pl.encoding != "" {
+		zapConfig.Encoding = pl.encoding
+	}
+	for _, opt := range pl.encoderConfigOpts {
+		opt(&zapConfig.EncoderConfig)
+	}
+	zapConfig.DisableCaller = pl.disableCaller
+	if pl.callerSkip != 0 {
+		defaultZapOpts = append(defaultZapOpts, zap.AddCallerSkip(pl.callerSkip))
+	}
 
+	var componentFilter zap.Option
+	if len(pl.componentLevels) > 0 {
+		componentFilter, err = componentLevels(pl.level, pl.componentLevels)
+		if err != nil {
+			return nil, err
+		}
+		// the component filter does all of the level checks so the cores underneath must let everything through
+		zapConfig.Level = zap.NewAtomicLevelAt(minLevel)
+	}
+
+	wrapOutput := func(ws zapcore.WriteSyncer) zapcore.WriteSyncer { return ws }
+	if pl.enableAsync {
+		wrapOutput = func(ws zapcore.WriteSyncer) zapcore.WriteSyncer {
+			w := newAsyncWriter(ws, pl.asyncSize, pl.asyncFlushInterval, pl.dropWhenFull)
+			pl.asyncWriters = append(pl.asyncWriters, w)
+			return w
+		}
+	}
+	if pl.logCounter != nil {
+		wrapAsync := wrapOutput
+		wrapOutput = func(ws zapcore.WriteSyncer) zapcore.WriteSyncer { return wrapAsync(countBytes(ws, pl.logCounter)) }
+	}
 	if pl.enableErrLogsToStderr {
-		defaultZapOpts = append(defaultZapOpts, errLogsToStderr(zapConfig))
+		splitLogger, err := errLogsToStderr(zapConfig, wrapOutput)
+		if err != nil {
+			return nil, pl.closeOnError(err)
+		}
+		defaultZapOpts = append(defaultZapOpts, splitLogger)
+	} else if pl.enableAsync || pl.logCounter != nil {
+		// the output is built here instead of by zap.Config.Build so it is wrapped
+		output, err := asyncOutput(zapConfig, wrapOutput)
+		if err != nil {
+			return nil, pl.closeOnError(err)
+		}
+		defaultZapOpts = append(defaultZapOpts, output)
+	}
+	if len(pl.newSinks) > 0 {
+		sinks, err := pl.sinks(zapConfig, wrapOutput)
+		if err != nil {
+			return nil, pl.closeOnError(errors.Wrap(err, "failed to set up sinks"))
+		}
+		defaultZapOpts = append(defaultZapOpts, sinks)
+	}
+	if pl.enableSampling {
+		defaultZapOpts = append(defaultZapOpts, sampler(pl.samplingConfig))
+	}
+	// the component filter wraps the sampler so filtered out entries don't count against the sample
+	if componentFilter != nil {
+		defaultZapOpts = append(defaultZapOpts, componentFilter)
+	}
+	// the counter wraps the component filter and sampler so only the entries that are written are counted
+	if pl.logCounter != nil {
+		defaultZapOpts = append(defaultZapOpts, countEntries(pl.logCounter))
 	}
 
+	defaultZapOpts = append(defaultZapOpts, pl.zapOptions...)
+
 	zapLogger, err := zapConfig.Build(defaultZapOpts...)
 	if err != nil {
-		return pl, zapLogger, errors.Wrap(err, "failed to build logger config")
+		return nil, pl.closeOnError(errors.Wrap(err, "failed to build logger config"))
+	}
+	// the built-in reporters come before the ones added with WithErrorReporter
+	var reporters []ErrorReporter
+	buildInfo, _ := debug.ReadBuildInfo()
+	deploy := pl.deployInfo.detect(buildInfo)
+	pl.rollbarConfig = pl.rollbarConfig.withDeploy(deploy)
+	pl.bugsnagConfig = pl.bugsnagConfig.withDeploy(deploy)
+	pl.honeybadgerConfig = pl.honeybadgerConfig.withDeploy(deploy)
+	pl.datadogConfig = pl.datadogConfig.withDeploy(deploy)
+	spools := map[string]*diskSpool{}
+	for name, enabled := range map[string]bool{"rollbar": pl.enableRollbar, "bugsnag": pl.enableBugsnag, "honeybadger": pl.enableHoneybadger, "datadog": pl.enableDatadog} {
+		if !enabled {
+			continue
+		}
+		spool, err := pl.openReporterSpool(name)
+		if err != nil {
+			for _, s := range spools {
+				err = multierr.Append(err, s.close())
+			}
+			return nil, pl.closeOnError(err)
+		}
+		spools[name] = spool
 	}
 	if pl.enableRollbar {
-		rollbarOptions = pl.rollbarConfig.setupRollbar(pl.serviceName, zapLogger)
-		zapLogger = zapLogger.WithOptions(rollbarOptions)
+		pl.rollbarConfig.setupRollbar(pl.serviceName, zapLogger, &pl.rollbarReporter, spools["rollbar"])
+		reporters = append(reporters, &pl.rollbarReporter)
+	}
+	if pl.enableBugsnag {
+		reporters = append(reporters, pl.bugsnagConfig.setupBugsnag(spools["bugsnag"]))
+	}
+	if pl.enableHoneybadger {
+		reporters = append(reporters, pl.honeybadgerConfig.setupHoneybadger(pl.serviceName, spools["honeybadger"]))
+	}
+	if pl.enableDatadog {
+		reporters = append(reporters, pl.datadogConfig.setupDatadog(pl.serviceName, spools["datadog"]))
+	}
+	for _, nr := range pl.newReporters {
+		if !nr.enabled {
+			continue
+		}
+		r, err := pl.openReporter(nr, deploy)
+		if err != nil {
+			// the reporters opened so far are closed along with the ones of WithErrorReporter
+			pl.reporters = append(reporters, pl.reporters...)
+			return nil, pl.closeOnError(err)
+		}
+		reporters = append(reporters, r)
+	}
+	pl.reporters = append(reporters, pl.reporters...)
+	if pl.reportDedupeWindow > 0 {
+		for i, r := range pl.reporters {
+			pl.reporters[i] = newDedupeReporter(r, pl.reportDedupeWindow, time.Now)
+		}
+	}
+	if len(pl.reporters) > 0 {
+		zapLogger = zapLogger.WithOptions(reportTo(pl.reporters, pl.level))
+	}
+	if pl.dedupeWindow > 0 {
+		zapLogger = zapLogger.WithOptions(pl.deduplicate(pl.dedupeWindow))
+	}
+	if len(pl.hooks) > 0 {
+		pl.hookRunner = newHookRunner(pl.hooks, pl.hookTimeout)
+		zapLogger = zapLogger.WithOptions(hooks(pl.hookRunner))
+	}
+	if len(pl.alertRules) > 0 {
+		pl.alerts = newAlerter(pl.alertRules)
+		zapLogger = zapLogger.WithOptions(alertOn(pl.alerts))
+	}
+	// the flush comes after the entry is written to all the other cores
+	zapLogger = zapLogger.WithOptions(flushOnExit(pl))
+	// redaction wraps everything else so nothing sees the redacted values
+	if len(pl.redactedKeys) > 0 || len(pl.scrubPatterns) > 0 {
+		r := newRedactor(pl.redactedKeys, pl.scrubPatterns)
+		var message func(string) string
+		if len(pl.scrubPatterns) > 0 {
+			message = r.scrub
+		}
+		zapLogger = zapLogger.WithOptions(rewrite(message, r.fields))
 	}
 	keysAndValues := append(pl.keysAndValues, "service", pl.serviceName)
 	zapLogger = zapLogger.With(handleFields(zapLogger, keysAndValues)...)
-	pl.Logger = zapr.NewLogger(zapLogger)
-	return pl, zapLogger, err
+	pl.zap = zapLogger
+	if pl.dropWhenFull && pl.dropSummaryInterval > 0 {
+		errOut, _, err := zap.Open(zapConfig.ErrorOutputPaths...)
+		if err != nil {
+			return nil, pl.closeOnError(errors.Wrap(err, "failed to open the error output paths"))
+		}
+		pl.reportDrops(pl.dropSummaryInterval, errOut)
+	}
+	// started once New can no longer fail, so a failed New leaves nothing running
+	if pl.toggleSignal != nil {
+		pl.toggleLevelOnSignal(zapLogger)
+	}
+	pl.reportSpools()
+	pl.superviseSinks()
+	pl.Logger = warnLogger{Logger: zapr.NewLogger(zapLogger), zap: zapLogger}
+	return pl, nil
+}
+
+// Logr returns the logr.Logger
+func (p *PacketLogr) Logr() logr.Logger {
+	return p.Logger
+}
+
+// Zap returns the underlying zap.Logger
+func (p *PacketLogr) Zap() *zap.Logger {
+	return p.zap
+}
+
+// Named returns a logger for a component of the service, the component is set as the logger name and as a "component" field
+// so it can be filtered on consistently. The name is also what WithComponentLevels matches on.
+func (p *PacketLogr) Named(component string) logr.Logger {
+	return p.Logger.WithName(component).WithValues("component", component)
+}
+
+// AtomicLevel returns the level shared by every logger derived from this PacketLogr
+func (p *PacketLogr) AtomicLevel() zap.AtomicLevel {
+	return p.level
+}
+
+// Level returns the name of the current log level
+func (p *PacketLogr) Level() string {
+	return levelName(p.level.Level())
+}
+
+// SetLevel changes the log level at runtime, see WithLogLevel for accepted values
+func (p *PacketLogr) SetLevel(level string) error {
+	zLevel, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	p.level.SetLevel(zLevel)
+	return nil
+}
+
+// traceLevel is one step more verbose than debug, which corresponds to logr's V(2)
+const traceLevel = zapcore.DebugLevel - 1
+
+// MaxV is the most verbose V level, see WithMaxV
+const MaxV = -int(minLevel)
+
+// parseLevel converts a named log level, or a V level such as v3, into its zap equivalent
+func parseLevel(level string) (zapcore.Level, error) {
+	if strings.EqualFold(level, "trace") {
+		return traceLevel, nil
+	}
+	if len(level) > 1 && (level[0] == 'v' || level[0] == 'V') {
+		v, err := strconv.Atoi(level[1:])
+		if err != nil || v < 0 || v > MaxV {
+			return 0, errors.Errorf("failed to parse log level: V level must be between 0 and %d, got: %q", MaxV, level)
+		}
+		return zapcore.Level(-v), nil
+	}
+	var zLevel zapcore.Level
+	if err := zLevel.UnmarshalText([]byte(level)); err != nil {
+		return zLevel, errors.Wrap(err, "failed to parse log level")
+	}
+	return zLevel, nil
+}
+
+// levelName is the inverse of parseLevel, the levels more verbose than trace are named by their V level
+func levelName(level zapcore.Level) string {
+	if level == traceLevel {
+		return "trace"
+	}
+	if level < traceLevel {
+		return "v" + strconv.Itoa(-int(level))
+	}
+	return level.String()
+}
+
+// encodeLevel encodes the levels more verbose than debug by their names, such as trace and v3, with the case of
+// enc, which would encode them as Level(-2) and Level(-3)
+func encodeLevel(enc zapcore.LevelEncoder, toCase func(string) string) zapcore.LevelEncoder {
+	return func(level zapcore.Level, pae zapcore.PrimitiveArrayEncoder) {
+		if level < zapcore.DebugLevel {
+			pae.AppendString(toCase(levelName(level)))
+			return
+		}
+		enc(level, pae)
+	}
 }
 
 func sliceDedupe(elements []string) []string {
@@ -134,16 +643,19 @@ func sliceDedupe(elements []string) []string {
 	return result
 }
 
-func errLogsToStderr(c zap.Config) zap.Option {
+func errLogsToStderr(c zap.Config, wrap func(zapcore.WriteSyncer) zapcore.WriteSyncer) (zap.Option, error) {
 	errorLogs := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-		return lvl >= zapcore.ErrorLevel
+		return lvl >= zapcore.ErrorLevel && c.Level.Enabled(lvl)
 	})
 	nonErrorLogs := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-		return !errorLogs(lvl)
+		return lvl < zapcore.ErrorLevel && c.Level.Enabled(lvl)
 	})
-	console := zapcore.Lock(os.Stdout)
-	consoleErrors := zapcore.Lock(os.Stderr)
-	encoder := zapcore.NewJSONEncoder(c.EncoderConfig)
+	console := wrap(zapcore.Lock(os.Stdout))
+	consoleErrors := wrap(zapcore.Lock(os.Stderr))
+	encoder, err := NewEncoder(c)
+	if err != nil {
+		return nil, err
+	}
 
 	core := zapcore.NewTee(
 		zapcore.NewCore(encoder, console, nonErrorLogs),
@@ -153,7 +665,38 @@ func errLogsToStderr(c zap.Config) zap.Option {
 		return core
 
 	})
-	return splitLogger
+	return splitLogger, nil
+}
+
+// sampler wraps the core with a per-second sampler, the same as zap.Config.Build would
+func sampler(c zap.SamplingConfig) zap.Option {
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		var opts []zapcore.SamplerOption
+		if c.Hook != nil {
+			opts = append(opts, zapcore.SamplerHook(c.Hook))
+		}
+		return &samplerCore{
+			Core:      zapcore.NewSamplerWithOptions(core, time.Second, c.Initial, c.Thereafter, opts...),
+			unsampled: core,
+		}
+	})
+}
+
+// samplerCore sends entries more verbose than debug around the sampler, which only keeps counters for debug and above
+type samplerCore struct {
+	zapcore.Core
+	unsampled zapcore.Core
+}
+
+func (c *samplerCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplerCore{Core: c.Core.With(fields), unsampled: c.unsampled.With(fields)}
+}
+
+func (c *samplerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level < zapcore.DebugLevel {
+		return c.unsampled.Check(ent, ce)
+	}
+	return c.Core.Check(ent, ce)
 }
 
 // handleFields converts a bunch of arbitrary key-value pairs into Zap fields.  It takes
@@ -2,13 +2,21 @@ package logr
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
 	"testing"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestPacketLogr(t *testing.T) {
@@ -33,11 +41,7 @@ func TestPacketLogrRollbarEnabled(t *testing.T) {
 			WithLogLevel("debug"),
 			WithEnableRollbar(true),
 			WithServiceName("github.com/packethost/pkg"),
-			WithRollbarConfig(rollbarConfig{
-				token:   "badtoken",
-				env:     "production",
-				version: "v2",
-			}),
+			WithRollbarConfig(NewRollbarConfig("badtoken", "production", "v2")),
 		)
 		if err != nil {
 			t.Fatal(err)
@@ -110,3 +114,333 @@ func captureOutput(f func()) string {
 	writer.Close()
 	return <-out
 }
+
+func TestPacketLogrLogLevels(t *testing.T) {
+	tests := map[string]struct {
+		level     string
+		debugLogs bool
+		wantErr   bool
+	}{
+		"trace":   {level: "trace", debugLogs: true},
+		"debug":   {level: "debug", debugLogs: true},
+		"info":    {level: "info"},
+		"warn":    {level: "warn"},
+		"error":   {level: "error"},
+		"dpanic":  {level: "dpanic"},
+		"panic":   {level: "panic"},
+		"fatal":   {level: "fatal"},
+		"upper":   {level: "DEBUG", debugLogs: true},
+		"unknown": {level: "verbose", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			l, _, err := NewPacketLogr(WithLogLevel(tc.level))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error for level: %v", tc.level)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := l.V(1).Enabled(); got != tc.debugLogs {
+				t.Fatalf("expected V(1) enabled to be: %v, got: %v", tc.debugLogs, got)
+			}
+		})
+	}
+}
+
+func TestPacketLogrTraceLevel(t *testing.T) {
+	l, _, err := NewPacketLogr(WithLogLevel("trace"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !l.V(2).Enabled() {
+		t.Fatal("expected V(2) to be enabled at trace level")
+	}
+	l, _, err = NewPacketLogr(WithLogLevel("debug"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.V(2).Enabled() {
+		t.Fatal("expected V(2) to be disabled at debug level")
+	}
+}
+
+func TestPacketLogrTraceLevelEncoding(t *testing.T) {
+	for name, tc := range map[string]struct {
+		opts []LoggerOption
+		want string
+	}{
+		"json":        {want: `"level":"trace"`},
+		"development": {opts: []LoggerOption{WithDevelopmentMode()}, want: "\tTRACE\t"},
+	} {
+		out := captureOutput(func() {
+			l, _, err := NewPacketLogr(append(tc.opts, WithLogLevel("trace"))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l.V(2).Info("traced")
+		})
+		if !strings.Contains(out, tc.want) {
+			t.Fatalf("expected the %s trace level to be %s, got: %v", name, tc.want, out)
+		}
+	}
+}
+
+func TestPacketLogrMaxV(t *testing.T) {
+	var pl *PacketLogr
+	out := captureOutput(func() {
+		l, _, err := NewPacketLogr(WithMaxV(4))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pl = l.(*PacketLogr)
+		l.V(4).Info("verbose")
+		l.V(5).Info("too verbose")
+	})
+	if !strings.Contains(out, `"level":"v4","ts":`) || !strings.Contains(out, `"msg":"verbose"`) || strings.Contains(out, "too verbose") {
+		t.Fatalf("expected only V(4) to be logged, got: %v", out)
+	}
+	if pl.Level() != "v4" {
+		t.Fatalf("expected level to be: v4, got: %v", pl.Level())
+	}
+	if err := pl.SetLevel("v1"); err != nil {
+		t.Fatal(err)
+	}
+	if pl.Level() != "debug" || pl.V(2).Enabled() {
+		t.Fatalf("expected SetLevel(v1) to be debug, got: %v", pl.Level())
+	}
+
+	for _, opt := range []LoggerOption{WithMaxV(-1), WithLogLevel("v-1"), WithLogLevel("vx")} {
+		if _, _, err := NewPacketLogr(opt); err == nil {
+			t.Fatal("expected an error for an invalid V level")
+		}
+	}
+}
+
+func TestPacketLogrSetLevel(t *testing.T) {
+	l, _, err := NewPacketLogr(WithEnableErrLogsToStderr(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl := l.(*PacketLogr)
+	if pl.Level() != "info" {
+		t.Fatalf("expected level to be: info, got: %v", pl.Level())
+	}
+	if l.V(1).Enabled() {
+		t.Fatal("expected V(1) to be disabled at info level")
+	}
+
+	if err := pl.SetLevel("debug"); err != nil {
+		t.Fatal(err)
+	}
+	if !l.V(1).Enabled() {
+		t.Fatal("expected V(1) to be enabled after SetLevel(debug)")
+	}
+	if !pl.AtomicLevel().Enabled(zapcore.DebugLevel) {
+		t.Fatal("expected AtomicLevel to reflect SetLevel(debug)")
+	}
+
+	if err := pl.SetLevel("trace"); err != nil {
+		t.Fatal(err)
+	}
+	if pl.Level() != "trace" {
+		t.Fatalf("expected level to be: trace, got: %v", pl.Level())
+	}
+
+	if err := pl.SetLevel("loud"); err == nil {
+		t.Fatal("expected an error for an unknown level")
+	}
+	if pl.Level() != "trace" {
+		t.Fatalf("expected level to be unchanged, got: %v", pl.Level())
+	}
+}
+
+// logViaWrapper returns the line number of its call to Info
+func logViaWrapper(l logr.Logger, msg string) int {
+	_, _, line, _ := runtime.Caller(0)
+	l.Info(msg)
+	return line + 1
+}
+
+func TestPacketLogrCaller(t *testing.T) {
+	tests := map[string]struct {
+		opts       []LoggerOption
+		wantCaller bool
+	}{
+		"default":        {},
+		"caller enabled": {opts: []LoggerOption{WithCaller(true)}},
+		"caller skip":    {opts: []LoggerOption{WithCallerSkip(1)}, wantCaller: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var wrapperLine, callerLine int
+			capturedOutput := captureOutput(func() {
+				l, _, err := NewPacketLogr(tc.opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, _, callerLine, _ = runtime.Caller(0)
+				wrapperLine = logViaWrapper(l, "caller message")
+			})
+			want := fmt.Sprintf(`"caller":"logr/packetlogger_test.go:%d"`, wrapperLine)
+			if tc.wantCaller {
+				want = fmt.Sprintf(`"caller":"logr/packetlogger_test.go:%d"`, callerLine+1)
+			}
+			if !strings.Contains(capturedOutput, want) {
+				t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+			}
+		})
+	}
+
+	capturedOutput := captureOutput(func() {
+		l, _, err := NewPacketLogr(WithCaller(false))
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Info("no caller message")
+	})
+	if strings.Contains(capturedOutput, `"caller":`) {
+		t.Fatalf("expected caller to be omitted, got: %v", capturedOutput)
+	}
+}
+
+func TestPacketLogrZapOptions(t *testing.T) {
+	var hooked int
+	capturedOutput := captureOutput(func() {
+		l, _, err := NewPacketLogr(
+			WithZapOptions(zap.Fields(zap.String("region", "ewr1"))),
+			WithZapOptions(zap.Hooks(func(zapcore.Entry) error {
+				hooked++
+				return nil
+			})),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Info("zap options message")
+	})
+	if !strings.Contains(capturedOutput, `"region":"ewr1"`) {
+		t.Fatalf("expected to contain: %v, got: %v", `"region":"ewr1"`, capturedOutput)
+	}
+	if hooked != 1 {
+		t.Fatalf("expected hook to be called once, got: %d", hooked)
+	}
+}
+
+func TestPacketLogrCore(t *testing.T) {
+	obs, logs := observer.New(zapcore.InfoLevel)
+	capturedOutput := captureOutput(func() {
+		l, _, err := NewPacketLogr(
+			WithLogLevel("debug"),
+			WithServiceName("myservice"),
+			WithCore(func(core zapcore.Core) zapcore.Core {
+				return zapcore.NewTee(core, obs)
+			}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Info("teed message")
+		l.V(1).Info("debug message")
+	})
+	for _, want := range []string{"teed message", "debug message"} {
+		if !strings.Contains(capturedOutput, want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+		}
+	}
+	if logs.Len() != 1 || logs.All()[0].Message != "teed message" {
+		t.Fatalf("expected only the teed message, got: %v", logs.All())
+	}
+	if got := logs.All()[0].ContextMap()["service"]; got != "myservice" {
+		t.Fatalf("expected service field, got: %v", got)
+	}
+}
+
+func TestPacketLogrErrorOutputPaths(t *testing.T) {
+	errorLog := filepath.Join(t.TempDir(), "zap-errors.log")
+	if _, _, err := NewPacketLogr(WithErrorOutputPaths([]string{errorLog})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(errorLog); err != nil {
+		t.Fatalf("expected error output path to be opened: %v", err)
+	}
+
+	badPath := filepath.Join(t.TempDir(), "missing", "zap-errors.log")
+	if _, _, err := NewPacketLogr(WithErrorOutputPaths([]string{badPath})); err == nil {
+		t.Fatal("expected an error for an error output path that can't be opened")
+	}
+}
+
+func TestPacketLogrErrorLogger(t *testing.T) {
+	l, z, err := NewPacketLogr(WithLogLevel("nope"))
+	if err == nil {
+		t.Fatal("expected an error for an invalid level")
+	}
+	if l == nil || z == nil {
+		t.Fatalf("expected loggers discarding the entries, got: %v, %v", l, z)
+	}
+	l.WithName("x").WithValues("k", "v").V(1).Info("discarded")
+	Warn(l, "discarded")
+	z.Info("discarded")
+	if err := l.(*PacketLogr).Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		pl, err := New(WithServiceName("myservice"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pl.Logr().Info("logr message")
+		pl.Zap().Info("zap message")
+		if err := pl.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	})
+	for _, want := range []string{"logr message", "zap message", `"service":"myservice"`} {
+		if !strings.Contains(capturedOutput, want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+		}
+	}
+
+	if pl, err := New(WithLogLevel("nope")); err == nil || pl != nil {
+		t.Fatalf("expected a nil logger and an error, got: %v, %v", pl, err)
+	}
+}
+
+func TestPacketLogrNamed(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		pl, err := New(WithComponentLevels(map[string]string{"db": "debug"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pl.Named("db").V(1).Info("named message")
+	})
+	for _, want := range []string{`"logger":"db"`, `"component":"db"`, "named message"} {
+		if !strings.Contains(capturedOutput, want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+		}
+	}
+}
+
+func TestPacketLogrFields(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		l, _, err := NewPacketLogr(
+			WithFields(map[string]interface{}{"region": "ewr1", "canary": true}),
+			WithKeysAndValues([]interface{}{"hello", "world"}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Info("fields message")
+	})
+	want := `"canary":true,"region":"ewr1","hello":"world"`
+	if !strings.Contains(capturedOutput, want) {
+		t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+	}
+}
@@ -0,0 +1,104 @@
+package logr
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// noTimeout makes put wait for as long as the queue is full
+const noTimeout time.Duration = -1
+
+// putResult is what became of an item given to put
+type putResult int
+
+const (
+	queued putResult = iota
+	// queueFull is an item that didn't fit in the queue in time, it is up to the caller to spool it or count it as dropped
+	queueFull
+	queueClosed
+)
+
+// queue is the bounded queue the background writers, sinks, error reporters, hooks, and alerts are built on.
+// Items are taken off it in order by one goroutine, which stops once the queue is closed and it has taken the rest.
+// Errors of the goroutine are kept by its owner until the next call that can return them, such as the next write.
+type queue[T any] struct {
+	items   chan T
+	dropped atomic.Uint64
+	// stopped is closed once run returns
+	stopped chan struct{}
+
+	// mu guards closed, puts hold the read lock so items can't be closed under them
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newQueue[T any](size int) *queue[T] {
+	return &queue[T]{items: make(chan T, size), stopped: make(chan struct{})}
+}
+
+// start takes the items off the queue with run in the background, run must return once items is closed and drained
+func (q *queue[T]) start(run func(items <-chan T)) {
+	go func() {
+		defer close(q.stopped)
+		run(q.items)
+	}()
+}
+
+// put queues item, waiting up to wait when the queue is full, not at all when it is 0, and for as long as it takes with noTimeout
+func (q *queue[T]) put(item T, wait time.Duration) putResult {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return queueClosed
+	}
+	select {
+	case q.items <- item:
+		return queued
+	default:
+	}
+	if wait == 0 {
+		return queueFull
+	}
+	// a nil channel never fires, so noTimeout waits for room
+	var timeout <-chan time.Time
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case q.items <- item:
+		return queued
+	case <-timeout:
+		return queueFull
+	}
+}
+
+// putContext queues item, waiting while the queue is full until ctx is done
+func (q *queue[T]) putContext(ctx context.Context, item T) putResult {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return queueClosed
+	}
+	select {
+	case q.items <- item:
+		return queued
+	case <-ctx.Done():
+		return queueFull
+	}
+}
+
+// close closes the queue so the goroutine stops once it has taken what is left, it returns false when it was already closed
+func (q *queue[T]) close() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+	q.closed = true
+	close(q.items)
+	return true
+}
@@ -0,0 +1,46 @@
+package logr
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQueuePut(t *testing.T) {
+	q := newQueue[int](1)
+	if got := q.put(1, 0); got != queued {
+		t.Fatalf("expected the item to be queued, got: %v", got)
+	}
+	if got := q.put(2, 0); got != queueFull {
+		t.Fatalf("expected the full queue not to wait, got: %v", got)
+	}
+	start := time.Now()
+	if got := q.put(2, 10*time.Millisecond); got != queueFull || time.Since(start) < 10*time.Millisecond {
+		t.Fatalf("expected the full queue to wait before giving up, got: %v after %s", got, time.Since(start))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got := q.putContext(ctx, 2); got != queueFull {
+		t.Fatalf("expected the full queue to give up once ctx is done, got: %v", got)
+	}
+
+	var taken []int
+	q.start(func(items <-chan int) {
+		for item := range items {
+			taken = append(taken, item)
+		}
+	})
+	if got := q.put(2, noTimeout); got != queued {
+		t.Fatalf("expected the item to be queued once there is room, got: %v", got)
+	}
+	if !q.close() || q.close() {
+		t.Fatal("expected only the first close to close the queue")
+	}
+	<-q.stopped
+	if len(taken) != 2 || taken[0] != 1 || taken[1] != 2 {
+		t.Fatalf("expected the items to be taken in order, got: %v", taken)
+	}
+	if got := q.put(3, noTimeout); got != queueClosed {
+		t.Fatalf("expected the closed queue to refuse the item, got: %v", got)
+	}
+}
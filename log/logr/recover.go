@@ -0,0 +1,72 @@
+package logr
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RecoverOption customizes RecoverAndReport
+type RecoverOption func(*recoverOptions)
+
+type recoverOptions struct {
+	repanic bool
+}
+
+// WithRepanic panics again with the recovered value once it is logged and reported,
+// so the process still crashes but the panic isn't lost
+func WithRepanic() RecoverOption {
+	return func(o *recoverOptions) { o.repanic = true }
+}
+
+// RecoverAndReport recovers from a panic, logs it at the error level with its stacktrace, which reports it to the error reporters
+// of logger such as Rollbar and Bugsnag, and also to reporter when it isn't nil, then waits for them to be sent.
+// It does nothing when there is no panic. It must be deferred directly, such as at the top of main or of a goroutine:
+//
+//	defer logr.RecoverAndReport(logger, nil)
+//
+// reporter is meant for one that isn't set up on logger, it is reported to at any level.
+func RecoverAndReport(logger *PacketLogr, reporter ErrorReporter, opts ...RecoverOption) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	var o recoverOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	err, ok := v.(error)
+	if !ok {
+		err = fmt.Errorf("%v", v)
+	}
+	err = errors.WithMessage(err, "panic")
+	stack := string(debug.Stack())
+	fields := []zap.Field{zap.Error(err)}
+	if ce := logger.zap.Check(zapcore.ErrorLevel, "recovered from panic"); ce != nil {
+		ce.Stack = stack
+		ce.Write(fields...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exitFlushTimeout)
+	defer cancel()
+	flushErr := logger.FlushErrorReporters(ctx)
+	if reporter != nil {
+		ent := zapcore.Entry{Level: zapcore.ErrorLevel, Time: time.Now(), Message: "recovered from panic", Stack: stack}
+		flushErr = multierr.Combine(flushErr, reporter.Report(ent, fields), reporter.Flush(ctx))
+	}
+	if flushErr != nil {
+		logger.zap.Warn("failed to report the recovered panic", zap.Error(flushErr))
+	}
+	_ = logger.Sync()
+
+	if o.repanic {
+		panic(v)
+	}
+}
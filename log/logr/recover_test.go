@@ -0,0 +1,71 @@
+package logr
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecoverAndReport(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.log")
+	logged := &testReporter{}
+	extra := &testReporter{}
+	l, err := New(WithOutputPaths([]string{out}), WithErrorReporter(logged))
+	if err != nil {
+		t.Fatal(err)
+	}
+	func() {
+		defer RecoverAndReport(l, extra)
+		panic("boom")
+	}()
+
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+	if !strings.Contains(got, `"msg":"recovered from panic"`) || !strings.Contains(got, `"error":"panic: boom"`) ||
+		!strings.Contains(got, "TestRecoverAndReport") {
+		t.Fatalf("expected the panic logged with its stacktrace, got: %v", got)
+	}
+	for _, r := range []*testReporter{logged, extra} {
+		if len(r.entries) != 1 || r.entries[0].Stack == "" || r.flushed != 1 {
+			t.Fatalf("expected the panic reported with its stacktrace and flushed, got: %v", r.entries)
+		}
+	}
+}
+
+func TestRecoverAndReportRepanic(t *testing.T) {
+	r := &testReporter{}
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithErrorReporter(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	boom := errors.New("boom")
+	defer func() {
+		if v := recover(); v != boom {
+			t.Fatalf("expected the original panic, got: %v", v)
+		}
+		if len(r.entries) != 1 {
+			t.Fatalf("expected the panic reported before panicking again, got: %v", r.entries)
+		}
+	}()
+	defer RecoverAndReport(l, nil, WithRepanic())
+	panic(boom)
+}
+
+func TestRecoverAndReportNoPanic(t *testing.T) {
+	r := &testReporter{}
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithErrorReporter(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	func() {
+		defer RecoverAndReport(l, nil)
+	}()
+	if len(r.entries) != 0 || r.flushed != 0 {
+		t.Fatalf("expected nothing reported without a panic, got: %v", r.entries)
+	}
+}
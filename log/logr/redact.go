@@ -0,0 +1,387 @@
+package logr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redactedValue replaces the values of redacted keys and the text matched by scrub patterns
+const redactedValue = "[REDACTED]"
+
+// Patterns for commonly leaked values that can be passed to WithScrubPatterns
+var (
+	// BearerTokenPattern matches bearer tokens such as those sent in Authorization headers
+	BearerTokenPattern = regexp.MustCompile(`(?i)\bbearer\s+[a-z0-9\-._~+/]+=*`)
+	// CreditCardPattern matches 13 to 19 digit card numbers, optionally separated by spaces or dashes
+	CreditCardPattern = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	// IPv4Pattern matches dotted decimal IPv4 addresses
+	IPv4Pattern = regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`)
+)
+
+// WithRedactedKeys replaces the values of fields with any of the keys, compared case-insensitively, with "[REDACTED]".
+// Keys are redacted no matter how deeply they are nested in objects, maps, and structs, and before entries reach any core, including Rollbar.
+func WithRedactedKeys(keys ...string) LoggerOption {
+	return func(args *PacketLogr) { args.redactedKeys = append(args.redactedKeys, keys...) }
+}
+
+// WithScrubPatterns replaces any text matching the patterns with "[REDACTED]" in messages and string values,
+// including error messages and strings nested in objects, before entries reach any core, including Rollbar.
+func WithScrubPatterns(patterns ...*regexp.Regexp) LoggerOption {
+	return func(args *PacketLogr) { args.scrubPatterns = append(args.scrubPatterns, patterns...) }
+}
+
+// redactor replaces the values of its keys and scrubs its patterns out of strings
+type redactor struct {
+	keys     map[string]struct{}
+	patterns []*regexp.Regexp
+}
+
+func newRedactor(keys []string, patterns []*regexp.Regexp) *redactor {
+	r := &redactor{keys: make(map[string]struct{}, len(keys)), patterns: patterns}
+	for _, key := range keys {
+		r.keys[strings.ToLower(key)] = struct{}{}
+	}
+	return r
+}
+
+func (r *redactor) match(key string) bool {
+	_, ok := r.keys[strings.ToLower(key)]
+	return ok
+}
+
+// scrub replaces the text matching any of the patterns
+func (r *redactor) scrub(s string) string {
+	for _, p := range r.patterns {
+		s = p.ReplaceAllString(s, redactedValue)
+	}
+	return s
+}
+
+// fields returns fields with any matching keys redacted, fields is only copied if something needs to change
+func (r *redactor) fields(fields []zapcore.Field) []zapcore.Field {
+	out := fields
+	for i := range fields {
+		f, changed := r.field(fields[i])
+		if !changed {
+			continue
+		}
+		if &out[0] == &fields[0] {
+			out = append([]zapcore.Field(nil), fields...)
+		}
+		out[i] = f
+	}
+	return out
+}
+
+func (r *redactor) field(f zapcore.Field) (zapcore.Field, bool) {
+	if f.Type != zapcore.NamespaceType && r.match(f.Key) {
+		return zap.String(f.Key, redactedValue), true
+	}
+	switch f.Type {
+	case zapcore.ObjectMarshalerType:
+		return zap.Object(f.Key, redactedObject{obj: f.Interface.(zapcore.ObjectMarshaler), r: r}), true
+	case zapcore.ArrayMarshalerType:
+		return zap.Array(f.Key, redactedArray{arr: f.Interface.(zapcore.ArrayMarshaler), r: r}), true
+	case zapcore.ReflectType:
+		return zap.Reflect(f.Key, r.value(f.Interface)), true
+	}
+	if len(r.patterns) == 0 {
+		return f, false
+	}
+	switch f.Type {
+	case zapcore.StringType:
+		return zap.String(f.Key, r.scrub(f.String)), true
+	case zapcore.ByteStringType:
+		return zap.ByteString(f.Key, []byte(r.scrub(string(f.Interface.([]byte))))), true
+	case zapcore.StringerType:
+		return zap.String(f.Key, r.scrub(f.Interface.(fmt.Stringer).String())), true
+	case zapcore.ErrorType:
+		return zap.NamedError(f.Key, r.scrubError(f.Interface.(error))), true
+	}
+	return f, false
+}
+
+// value redacts reflected values by round tripping them through JSON, which is what zap's encoders do with them anyway
+func (r *redactor) value(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		// leave it for the encoder to report the error
+		return v
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return v
+	}
+	return r.walk(generic)
+}
+
+func (r *redactor) walk(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if r.match(k) {
+				v[k] = redactedValue
+				continue
+			}
+			v[k] = r.walk(val)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = r.walk(v[i])
+		}
+	case string:
+		return r.scrub(v)
+	}
+	return v
+}
+
+// scrubError returns err with its message and verbose output scrubbed. It keeps the stack recorded by pkg/errors and the errors
+// combined by multierr, so the reporters still find where err was made and the encoder still logs the causes.
+func (r *redactor) scrubError(err error) error {
+	e := scrubbedError{err: err, r: r}
+	switch err.(type) {
+	case errorGroup:
+		return scrubbedErrors{e}
+	case stackTracer:
+		return scrubbedStackError{e}
+	}
+	return e
+}
+
+// scrubbedError scrubs the message and the verbose, %+v, output of err
+type scrubbedError struct {
+	err error
+	r   *redactor
+}
+
+func (e scrubbedError) Error() string {
+	return e.r.scrub(e.err.Error())
+}
+
+// Unwrap returns the error err wraps, scrubbed too, so that the reporters that walk the chain of an error, such as Sentry's,
+// find the stacks in it without getting the messages of the wrapped errors unscrubbed
+func (e scrubbedError) Unwrap() error {
+	wrapped := unwrapError(e.err)
+	if wrapped == nil {
+		return nil
+	}
+	return e.r.scrubError(wrapped)
+}
+
+// Is reports whether target is in the chain of err, which the scrubbed errors of Unwrap aren't
+func (e scrubbedError) Is(target error) bool {
+	return errors.Is(e.err, target)
+}
+
+func (e scrubbedError) Format(s fmt.State, verb rune) {
+	format := "%v"
+	if s.Flag('+') {
+		format = "%+v"
+	}
+	fmt.Fprint(s, e.r.scrub(fmt.Sprintf(format, e.err)))
+}
+
+// scrubbedStackError is a scrubbedError of an error with a stack recorded by pkg/errors
+type scrubbedStackError struct {
+	scrubbedError
+}
+
+func (e scrubbedStackError) StackTrace() errors.StackTrace {
+	return e.err.(stackTracer).StackTrace()
+}
+
+// errorGroup is implemented by the errors combined by multierr, zap logs each of them under errorCauses
+type errorGroup interface {
+	Errors() []error
+}
+
+// scrubbedErrors is a scrubbedError of an errorGroup, each of the errors is scrubbed too
+type scrubbedErrors struct {
+	scrubbedError
+}
+
+func (e scrubbedErrors) Errors() []error {
+	errs := e.err.(errorGroup).Errors()
+	scrubbed := make([]error, len(errs))
+	for i, err := range errs {
+		scrubbed[i] = e.r.scrubError(err)
+	}
+	return scrubbed
+}
+
+// redactedObject redacts any matching keys added by obj
+type redactedObject struct {
+	obj zapcore.ObjectMarshaler
+	r   *redactor
+}
+
+func (o redactedObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return o.obj.MarshalLogObject(redactingObjectEncoder{ObjectEncoder: enc, r: o.r})
+}
+
+// redactedArray redacts any matching keys in the objects appended by arr
+type redactedArray struct {
+	arr zapcore.ArrayMarshaler
+	r   *redactor
+}
+
+func (a redactedArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	return a.arr.MarshalLogArray(redactingArrayEncoder{ArrayEncoder: enc, r: a.r})
+}
+
+type redactingObjectEncoder struct {
+	zapcore.ObjectEncoder
+	r *redactor
+}
+
+// add calls fn to add the value unless key needs to be redacted
+func (e redactingObjectEncoder) add(key string, fn func()) {
+	if e.r.match(key) {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return
+	}
+	fn()
+}
+
+func (e redactingObjectEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	if e.r.match(key) {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return nil
+	}
+	return e.ObjectEncoder.AddArray(key, redactedArray{arr: arr, r: e.r})
+}
+
+func (e redactingObjectEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	if e.r.match(key) {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return nil
+	}
+	return e.ObjectEncoder.AddObject(key, redactedObject{obj: obj, r: e.r})
+}
+
+func (e redactingObjectEncoder) AddReflected(key string, v interface{}) error {
+	if e.r.match(key) {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return nil
+	}
+	return e.ObjectEncoder.AddReflected(key, e.r.value(v))
+}
+
+func (e redactingObjectEncoder) AddBinary(k string, v []byte) {
+	e.add(k, func() { e.ObjectEncoder.AddBinary(k, v) })
+}
+
+func (e redactingObjectEncoder) AddByteString(k string, v []byte) {
+	e.add(k, func() { e.ObjectEncoder.AddByteString(k, []byte(e.r.scrub(string(v)))) })
+}
+
+func (e redactingObjectEncoder) AddBool(k string, v bool) {
+	e.add(k, func() { e.ObjectEncoder.AddBool(k, v) })
+}
+
+func (e redactingObjectEncoder) AddComplex128(k string, v complex128) {
+	e.add(k, func() { e.ObjectEncoder.AddComplex128(k, v) })
+}
+
+func (e redactingObjectEncoder) AddComplex64(k string, v complex64) {
+	e.add(k, func() { e.ObjectEncoder.AddComplex64(k, v) })
+}
+
+func (e redactingObjectEncoder) AddDuration(k string, v time.Duration) {
+	e.add(k, func() { e.ObjectEncoder.AddDuration(k, v) })
+}
+
+func (e redactingObjectEncoder) AddFloat64(k string, v float64) {
+	e.add(k, func() { e.ObjectEncoder.AddFloat64(k, v) })
+}
+
+func (e redactingObjectEncoder) AddFloat32(k string, v float32) {
+	e.add(k, func() { e.ObjectEncoder.AddFloat32(k, v) })
+}
+
+func (e redactingObjectEncoder) AddInt(k string, v int) {
+	e.add(k, func() { e.ObjectEncoder.AddInt(k, v) })
+}
+
+func (e redactingObjectEncoder) AddInt64(k string, v int64) {
+	e.add(k, func() { e.ObjectEncoder.AddInt64(k, v) })
+}
+
+func (e redactingObjectEncoder) AddInt32(k string, v int32) {
+	e.add(k, func() { e.ObjectEncoder.AddInt32(k, v) })
+}
+
+func (e redactingObjectEncoder) AddInt16(k string, v int16) {
+	e.add(k, func() { e.ObjectEncoder.AddInt16(k, v) })
+}
+
+func (e redactingObjectEncoder) AddInt8(k string, v int8) {
+	e.add(k, func() { e.ObjectEncoder.AddInt8(k, v) })
+}
+
+func (e redactingObjectEncoder) AddString(k, v string) {
+	e.add(k, func() { e.ObjectEncoder.AddString(k, e.r.scrub(v)) })
+}
+
+func (e redactingObjectEncoder) AddTime(k string, v time.Time) {
+	e.add(k, func() { e.ObjectEncoder.AddTime(k, v) })
+}
+
+func (e redactingObjectEncoder) AddUint(k string, v uint) {
+	e.add(k, func() { e.ObjectEncoder.AddUint(k, v) })
+}
+
+func (e redactingObjectEncoder) AddUint64(k string, v uint64) {
+	e.add(k, func() { e.ObjectEncoder.AddUint64(k, v) })
+}
+
+func (e redactingObjectEncoder) AddUint32(k string, v uint32) {
+	e.add(k, func() { e.ObjectEncoder.AddUint32(k, v) })
+}
+
+func (e redactingObjectEncoder) AddUint16(k string, v uint16) {
+	e.add(k, func() { e.ObjectEncoder.AddUint16(k, v) })
+}
+
+func (e redactingObjectEncoder) AddUint8(k string, v uint8) {
+	e.add(k, func() { e.ObjectEncoder.AddUint8(k, v) })
+}
+
+func (e redactingObjectEncoder) AddUintptr(k string, v uintptr) {
+	e.add(k, func() { e.ObjectEncoder.AddUintptr(k, v) })
+}
+
+type redactingArrayEncoder struct {
+	zapcore.ArrayEncoder
+	r *redactor
+}
+
+func (e redactingArrayEncoder) AppendArray(arr zapcore.ArrayMarshaler) error {
+	return e.ArrayEncoder.AppendArray(redactedArray{arr: arr, r: e.r})
+}
+
+func (e redactingArrayEncoder) AppendObject(obj zapcore.ObjectMarshaler) error {
+	return e.ArrayEncoder.AppendObject(redactedObject{obj: obj, r: e.r})
+}
+
+func (e redactingArrayEncoder) AppendReflected(v interface{}) error {
+	return e.ArrayEncoder.AppendReflected(e.r.value(v))
+}
+
+func (e redactingArrayEncoder) AppendByteString(v []byte) {
+	e.ArrayEncoder.AppendByteString([]byte(e.r.scrub(string(v))))
+}
+
+func (e redactingArrayEncoder) AppendString(v string) {
+	e.ArrayEncoder.AppendString(e.r.scrub(v))
+}
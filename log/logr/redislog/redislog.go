@@ -0,0 +1,168 @@
+// Package redislog logs the commands of go-redis clients with the logger of their context, from logr.FromContext,
+// so the commands sent while handling a request have its fields:
+//
+//	rdb := redis.NewClient(&redis.Options{Addr: addr})
+//	rdb.AddHook(redislog.NewHook())
+package redislog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultSlowThreshold is how long a command takes before it is logged as slow, see WithSlowThreshold
+const defaultSlowThreshold = 100 * time.Millisecond
+
+// debug is the verbosity commands are logged at, the slow ones are logged at warn
+const debug = 1
+
+// idSegment matches the segments of keys KeyPattern replaces: numbers, UUIDs, and long hex strings such as hashes
+var idSegment = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+// keyless are the commands that have no key, their first arg isn't logged
+var keyless = map[string]bool{
+	"auth": true, "client": true, "cluster": true, "command": true, "config": true, "dbsize": true, "discard": true, "echo": true,
+	"exec": true, "flushall": true, "flushdb": true, "hello": true, "info": true, "multi": true, "ping": true, "psubscribe": true,
+	"publish": true, "punsubscribe": true, "quit": true, "script": true, "select": true, "subscribe": true, "time": true, "unsubscribe": true,
+}
+
+// Option customizes NewHook
+type Option func(*Hook)
+
+// WithSlowThreshold logs the commands taking longer than d as slow commands, at warn, instead of the ones taking longer than 100ms.
+// 0 turns it off.
+func WithSlowThreshold(d time.Duration) Option {
+	return func(h *Hook) { h.slow = d }
+}
+
+// Hook logs the commands and pipelines of a client once they are done at debug, with the command, the pattern of its key,
+// see KeyPattern, and the duration. The values of commands are never logged. The commands slower than the threshold
+// of WithSlowThreshold are logged at warn, the ones that failed are logged as errors, besides redis.Nil, which is a missing key,
+// and the ones canceled by their context, which are logged with the error.
+type Hook struct {
+	slow time.Duration
+}
+
+var _ redis.Hook = (*Hook)(nil)
+
+// NewHook returns a Hook, for the AddHook method of the go-redis clients
+func NewHook(opts ...Option) *Hook {
+	h := &Hook{slow: defaultSlowThreshold}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// DialHook logs the connections that couldn't be made
+func (h *Hook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		start := time.Now()
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			logr.FromContext(ctx).Info("failed to connect to redis", "addr", addr, "duration", time.Since(start), "error", err.Error())
+		}
+		return conn, err
+	}
+}
+
+// ProcessHook logs each command
+func (h *Hook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.log(ctx, "redis command", time.Since(start), cmd.Err(), "command", command(cmd))
+		return err
+	}
+}
+
+// ProcessPipelineHook logs each pipeline, and transaction, as one entry with its commands, the error is the first one of them
+func (h *Hook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		commands := make([]string, len(cmds))
+		cmdErr := err
+		for i, cmd := range cmds {
+			commands[i] = command(cmd)
+			if cmdErr == nil {
+				cmdErr = cmd.Err()
+			}
+		}
+		h.log(ctx, "redis pipeline", time.Since(start), cmdErr, "commands", commands)
+		return err
+	}
+}
+
+func (h *Hook) log(ctx context.Context, msg string, duration time.Duration, err error, kvs ...interface{}) {
+	l := logr.FromContext(ctx)
+	kvs = append(kvs, "duration", duration)
+	switch {
+	case err == nil, errors.Is(err, redis.Nil):
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		kvs = append(kvs, "error", err.Error())
+	default:
+		l.Error(err, msg, kvs...)
+		return
+	}
+	if h.slow > 0 && duration > h.slow {
+		logr.Warn(l, "slow "+msg, kvs...)
+		return
+	}
+	l.V(debug).Info(msg, kvs...)
+}
+
+// command is the full name of cmd, and the pattern of its key when it has one, such as "get session:{id}"
+func command(cmd redis.Cmder) string {
+	name := cmd.FullName()
+	pos := keyPos(cmd)
+	args := cmd.Args()
+	if pos < 0 || pos >= len(args) {
+		return name
+	}
+	key, ok := args[pos].(string)
+	if !ok {
+		return name
+	}
+	return name + " " + KeyPattern(key)
+}
+
+// keyPos is the index of the key in the args of cmd, it is -1 when it has none
+func keyPos(cmd redis.Cmder) int {
+	name := cmd.Name()
+	switch name {
+	case "eval", "evalsha", "eval_ro", "evalsha_ro", "fcall", "fcall_ro":
+		// eval script numkeys key
+		if args := cmd.Args(); len(args) > 3 && fmt.Sprint(args[2]) != "0" {
+			return 3
+		}
+		return -1
+	case "memory", "object", "xinfo":
+		// memory usage key
+		return 2
+	}
+	if keyless[name] {
+		return -1
+	}
+	return 1
+}
+
+// KeyPattern replaces the segments of key, separated by colons, that look like IDs, numbers, UUIDs, and long hex strings, with {id},
+// so that session:7c0f2d1e-1e5a-4b2c-9a56-3c1b8f2d9e10 is session:{id}
+func KeyPattern(key string) string {
+	segments := strings.Split(key, ":")
+	for i, s := range segments {
+		if idSegment.MatchString(s) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, ":")
+}
@@ -0,0 +1,77 @@
+package redislog
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestHook(t *testing.T) {
+	l, logged := logtest.New(t, logr.WithLogLevel("debug"))
+	ctx := l.NewContextWithValues(context.Background(), "request_id", "r1")
+	process := NewHook(WithSlowThreshold(10 * time.Millisecond)).ProcessHook(func(_ context.Context, cmd redis.Cmder) error {
+		switch cmd.Name() {
+		case "get":
+			cmd.SetErr(redis.Nil)
+		case "hset":
+			cmd.SetErr(errors.New("WRONGTYPE Operation against a key holding the wrong kind of value"))
+		case "keys":
+			time.Sleep(20 * time.Millisecond)
+		}
+		return cmd.Err()
+	})
+	for _, cmd := range []redis.Cmder{
+		redis.NewStatusCmd(ctx, "set", "session:7c0f2d1e-1e5a-4b2c-9a56-3c1b8f2d9e10", "secret-token"),
+		redis.NewStringCmd(ctx, "get", "device:42:state"),
+		redis.NewIntCmd(ctx, "hset", "user:1", "password", "hunter2"),
+		redis.NewStringSliceCmd(ctx, "keys", "session:*"),
+		redis.NewCmd(ctx, "eval", "return 1", 1, "lock:42"),
+		redis.NewStatusCmd(ctx, "ping"),
+	} {
+		_ = process(ctx, cmd)
+	}
+	pipeline := NewHook().ProcessPipelineHook(func(context.Context, []redis.Cmder) error { return nil })
+	_ = pipeline(ctx, []redis.Cmder{redis.NewIntCmd(ctx, "incr", "counter:5"), redis.NewBoolCmd(ctx, "expire", "counter:5", 60)})
+
+	got := logged()
+	for _, want := range []string{
+		`"level":"debug"`,
+		`"msg":"redis command","service":"not/set","request_id":"r1","command":"set session:{id}"`,
+		`"command":"get device:{id}:state"`,
+		`"level":"error"`,
+		`"msg":"redis command","service":"not/set","request_id":"r1","command":"hset user:{id}","duration":`,
+		`"error":"WRONGTYPE Operation against a key holding the wrong kind of value"`,
+		`"level":"warn"`,
+		`"msg":"slow redis command","service":"not/set","request_id":"r1","command":"keys session:*"`,
+		`"command":"eval lock:{id}"`,
+		`"command":"ping"`,
+		`"msg":"redis pipeline","service":"not/set","request_id":"r1","commands":["incr counter:{id}","expire counter:{id}"]`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %s to be logged, got: %v", want, got)
+		}
+	}
+	for _, value := range []string{"secret-token", "hunter2", "redis: nil"} {
+		if strings.Contains(got, value) {
+			t.Fatalf("expected %s not to be logged, got: %v", value, got)
+		}
+	}
+}
+
+func TestKeyPattern(t *testing.T) {
+	for key, want := range map[string]string{
+		"session:7c0f2d1e-1e5a-4b2c-9a56-3c1b8f2d9e10": "session:{id}",
+		"device:42:state":    "device:{id}:state",
+		"queue:provisioning": "queue:provisioning",
+	} {
+		if got := KeyPattern(key); got != want {
+			t.Fatalf("expected %s to be %s, got: %s", key, want, got)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package logr
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// reportOccurrencesKey is the field holding how many occurrences of an error an event stands for, see WithErrorReportDeduplication
+const reportOccurrencesKey = "occurrences"
+
+// WithErrorReportDeduplication reports only the first of the identical errors reported within window of it to the error reporters,
+// such as Rollbar and Bugsnag, so that a storm of them makes one event instead of thousands. Errors are identical when they have
+// the same level, logger name, message, and error. The next one reported after the window has the occurrences field with how many
+// there were since the first one, including itself, and the latest of those still waiting for the window to pass are reported with it
+// when the reporters are flushed, such as by Close. Entries that panic or exit are always reported.
+// Unlike WithDeduplication the log outputs still get every entry.
+func WithErrorReportDeduplication(window time.Duration) LoggerOption {
+	return func(args *PacketLogr) { args.reportDedupeWindow = window }
+}
+
+// dedupeReporter drops the errors that repeat within a window of the first before passing them on to an error reporter
+type dedupeReporter struct {
+	ErrorReporter
+	window time.Duration
+	now    func() time.Time
+
+	mu   sync.Mutex
+	seen map[reportDedupeKey]*reportDedupeEntry
+}
+
+type reportDedupeKey struct {
+	level zapcore.Level
+	name  string
+	msg   string
+	err   string
+}
+
+type reportDedupeEntry struct {
+	first      time.Time
+	suppressed int
+	// ent and fields are the latest suppressed occurrence, reported with the count when the entry is flushed
+	ent    zapcore.Entry
+	fields []zapcore.Field
+}
+
+func newDedupeReporter(r ErrorReporter, window time.Duration, now func() time.Time) *dedupeReporter {
+	return &dedupeReporter{ErrorReporter: r, window: window, now: now, seen: map[reportDedupeKey]*reportDedupeEntry{}}
+}
+
+func (r *dedupeReporter) Report(ent zapcore.Entry, fields []zapcore.Field) error {
+	// entries that panic or exit are never dropped
+	if ent.Level >= zapcore.DPanicLevel {
+		return r.ErrorReporter.Report(ent, fields)
+	}
+
+	key := reportDedupeKey{level: ent.Level, name: ent.LoggerName, msg: ent.Message}
+	for _, f := range fields {
+		if err, ok := f.Interface.(error); ok && f.Type == zapcore.ErrorType {
+			key.err = err.Error()
+		}
+	}
+	now := r.now()
+
+	r.mu.Lock()
+	prev, ok := r.seen[key]
+	if ok && now.Sub(prev.first) < r.window {
+		prev.suppressed++
+		prev.ent, prev.fields = ent, fields
+		r.mu.Unlock()
+		return nil
+	}
+	var suppressed int
+	if ok {
+		suppressed = prev.suppressed
+	}
+	r.seen[key] = &reportDedupeEntry{first: now}
+	var expired []reportDedupeEntry
+	if len(r.seen) > maxDedupeKeys {
+		expired = r.sweep(now)
+	}
+	r.mu.Unlock()
+
+	var err error
+	for _, e := range expired {
+		err = multierr.Append(err, r.reportSuppressed(e))
+	}
+	if suppressed > 0 {
+		fields = append(fields[:len(fields):len(fields)], zap.Int(reportOccurrencesKey, suppressed+1))
+	}
+	return multierr.Append(err, r.ErrorReporter.Report(ent, fields))
+}
+
+// Enabled is the level of the wrapped reporter, error when it doesn't have one
+func (r *dedupeReporter) Enabled(lvl zapcore.Level) bool {
+	if enabler, ok := r.ErrorReporter.(zapcore.LevelEnabler); ok {
+		return enabler.Enabled(lvl)
+	}
+	return zapcore.ErrorLevel.Enabled(lvl)
+}
+
+// Flush reports the latest of the suppressed errors with their count before flushing the wrapped reporter
+func (r *dedupeReporter) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	var suppressed []reportDedupeEntry
+	for _, prev := range r.seen {
+		if prev.suppressed > 0 {
+			suppressed = append(suppressed, *prev)
+			prev.suppressed = 0
+		}
+	}
+	r.mu.Unlock()
+
+	var err error
+	for _, e := range suppressed {
+		err = multierr.Append(err, r.reportSuppressed(e))
+	}
+	return multierr.Append(err, r.ErrorReporter.Flush(ctx))
+}
+
+func (r *dedupeReporter) close(ctx context.Context) error {
+	return closeReporter(ctx, r.ErrorReporter)
+}
+
+// sweep forgets about the errors whose window has passed and returns those with suppressed occurrences, r.mu must be held
+func (r *dedupeReporter) sweep(now time.Time) []reportDedupeEntry {
+	var expired []reportDedupeEntry
+	for key, prev := range r.seen {
+		if now.Sub(prev.first) < r.window {
+			continue
+		}
+		if prev.suppressed > 0 {
+			expired = append(expired, *prev)
+		}
+		delete(r.seen, key)
+	}
+	return expired
+}
+
+func (r *dedupeReporter) reportSuppressed(e reportDedupeEntry) error {
+	fields := append(e.fields[:len(e.fields):len(e.fields)], zap.Int(reportOccurrencesKey, e.suppressed))
+	return r.ErrorReporter.Report(e.ent, fields)
+}
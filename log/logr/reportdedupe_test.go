@@ -0,0 +1,95 @@
+package logr
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestDedupeReporter(t *testing.T) {
+	now := time.Unix(0, 0)
+	inner := &testReporter{}
+	r := newDedupeReporter(inner, time.Minute, func() time.Time { return now })
+	report := func(msg string, err error) {
+		if rerr := r.Report(zapcore.Entry{Level: zapcore.ErrorLevel, Message: msg}, []zapcore.Field{zap.Error(err)}); rerr != nil {
+			t.Fatal(rerr)
+		}
+	}
+	occurrences := func(i int) interface{} {
+		fields, _ := reportFields(inner.fields[i])
+		return fields[reportOccurrencesKey]
+	}
+
+	for i := 0; i < 100; i++ {
+		report("provisioning failed", errors.New("timeout"))
+	}
+	report("provisioning failed", errors.New("no capacity"))
+	if len(inner.entries) != 2 {
+		t.Fatalf("expected one event for each error, got: %v", inner.entries)
+	}
+
+	now = now.Add(time.Minute)
+	report("provisioning failed", errors.New("timeout"))
+	if len(inner.entries) != 3 || occurrences(2) != int64(100) {
+		t.Fatalf("expected the occurrences since the first one once the window passed, got: %v", occurrences(2))
+	}
+
+	report("provisioning failed", errors.New("timeout"))
+	report("provisioning failed", errors.New("timeout"))
+	if err := r.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.entries) != 4 || occurrences(3) != int64(2) || inner.flushed != 1 {
+		t.Fatalf("expected the suppressed occurrences reported when flushed, got: %v", inner.entries)
+	}
+	if err := r.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.entries) != 4 {
+		t.Fatalf("expected the suppressed occurrences to be reported once, got: %v", inner.entries)
+	}
+}
+
+func TestDedupeReporterFatal(t *testing.T) {
+	inner := &testReporter{}
+	r := newDedupeReporter(inner, time.Minute, time.Now)
+	for i := 0; i < 3; i++ {
+		if err := r.Report(zapcore.Entry{Level: zapcore.DPanicLevel, Message: "unexpected state"}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(inner.entries) != 3 {
+		t.Fatalf("expected entries that panic or exit to always be reported, got: %v", inner.entries)
+	}
+}
+
+func TestPacketLogrErrorReportDeduplication(t *testing.T) {
+	inner := &testReporter{}
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithErrorReporter(leveledTestReporter{inner, zapcore.WarnLevel}),
+		WithErrorReportDeduplication(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		l.Zap().Warn("slow response")
+	}
+	l.Zap().Info("not reported")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.entries) != 2 || inner.closed != 1 {
+		t.Fatalf("expected the first occurrence and the suppressed count when closed, got: %v", inner.entries)
+	}
+	if fields, _ := reportFields(inner.fields[1]); fields[reportOccurrencesKey] != int64(4) {
+		t.Fatalf("expected the count of suppressed occurrences, got: %v", fields)
+	}
+
+	if _, err := New(WithErrorReportDeduplication(-time.Second)); err == nil {
+		t.Fatal("expected an error for a negative window")
+	}
+}
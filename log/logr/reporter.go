@@ -0,0 +1,463 @@
+package logr
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// reporterQueueSize is how many events an error reporter holds while they are sent, more are dropped
+	reporterQueueSize = 1000
+	// reporterRequestTimeout is how long sending an event to an error reporter can take
+	reporterRequestTimeout = 10 * time.Second
+)
+
+// ErrorReporter is where the entries at or above its level are reported to, such as Rollbar or an in-house error tracker,
+// see WithErrorReporter. The built-in reporters, such as WithEnableRollbar and WithEnableBugsnag, implement it too.
+type ErrorReporter interface {
+	// Report is called for each entry at or above the reporter's level, with its fields including the ones added
+	// with WithValues. The returned error is written to the error output.
+	Report(entry zapcore.Entry, fields []zapcore.Field) error
+	// Flush waits for the reported entries to be sent, giving up when ctx is done
+	Flush(ctx context.Context) error
+	// Close releases the reporter, Close calls it after flushing
+	Close() error
+}
+
+// WithErrorReporter reports entries to r, along with the other error reporters. Entries at or above error are reported,
+// unless r implements zapcore.LevelEnabler, and entries below the log level aren't reported either.
+// Close flushes and closes r.
+func WithErrorReporter(r ErrorReporter) LoggerOption {
+	return func(args *PacketLogr) {
+		if r == nil {
+			args.errs = multierr.Append(args.errs, errors.New("WithErrorReporter: reporter must not be nil"))
+			return
+		}
+		args.reporters = append(args.reporters, r)
+	}
+}
+
+// ReporterContext is what the error reporters of other packages are set up with once the logger is built, see WithNewErrorReporter
+type ReporterContext struct {
+	// ServiceName is the name set with WithServiceName
+	ServiceName string
+	// Deploy is the deploy environment and release set with WithDeployInfo, with the fields that are empty detected
+	Deploy DeployInfo
+	// Queue sends the reporter's requests in the background, spooling them while its endpoint is unreachable.
+	// It is only set with WithErrorReporterSpool, Close closes it after closing the reporter.
+	Queue *ReporterQueue
+}
+
+// ReporterQueue sends the requests of an error reporter from a background goroutine, in order. The ones that can't be sent
+// because the endpoint is unreachable are spooled, see WithErrorReporterSpool, and SpoolStats includes them.
+type ReporterQueue struct {
+	r *httpReporter
+}
+
+// Send queues req to be sent, dropping it when the queue is full. Its body is read with GetBody to spool it, as set by http.NewRequest.
+func (q *ReporterQueue) Send(req *http.Request) { q.r.send(req) }
+
+// Flush waits for the queued requests to be sent, giving up when ctx is done, and returns the delivery errors since the last one
+func (q *ReporterQueue) Flush(ctx context.Context) error { return q.r.flush(ctx) }
+
+// Err returns the delivery errors since the last Flush or Err, including how many requests were dropped
+func (q *ReporterQueue) Err() error { return q.r.takeErr() }
+
+// newReporter is an error reporter of another package, added with WithNewErrorReporter or WithErrorReporterEnabled
+type newReporter struct {
+	name string
+	// open is the setup of WithNewErrorReporter or WithErrorReporterSetup, defaultOpen the one of WithErrorReporterEnabled
+	open        func(ReporterContext) (ErrorReporter, error)
+	defaultOpen func(ReporterContext) (ErrorReporter, error)
+	enabled     bool
+}
+
+// setup is how the reporter is set up, open unless only WithErrorReporterEnabled was given
+func (nr newReporter) setup() func(ReporterContext) (ErrorReporter, error) {
+	if nr.open != nil {
+		return nr.open
+	}
+	return nr.defaultOpen
+}
+
+// WithNewErrorReporter reports entries to the error reporter open sets up once the logger is built, along with the other error reporters.
+// Its events are spooled to the name subdirectory of the dir of WithErrorReporterSpool.
+// Entries are reported like for WithErrorReporter, and Close flushes and closes the reporter.
+func WithNewErrorReporter(name string, open func(ReporterContext) (ErrorReporter, error)) LoggerOption {
+	return func(args *PacketLogr) {
+		if name == "" || open == nil {
+			args.errs = multierr.Append(args.errs, errors.New("WithNewErrorReporter: name must not be empty and open must not be nil"))
+			return
+		}
+		nr := args.newReporter(name)
+		nr.open, nr.enabled = open, true
+	}
+}
+
+// WithErrorReporterEnabled turns the error reporter named name on or off, it is set up like for WithNewErrorReporter with the open of
+// WithErrorReporterSetup, or else with open, such as one using the reporter's environment variables.
+// Together they let packages offer an enable option apart from their config option, such as sentryreporter.WithEnableSentry
+// and sentryreporter.WithSentryConfig, which are given in any order like WithEnableRollbar and WithRollbarConfig.
+func WithErrorReporterEnabled(name string, enable bool, open func(ReporterContext) (ErrorReporter, error)) LoggerOption {
+	return func(args *PacketLogr) {
+		if name == "" || open == nil {
+			args.errs = multierr.Append(args.errs, errors.New("WithErrorReporterEnabled: name must not be empty and open must not be nil"))
+			return
+		}
+		nr := args.newReporter(name)
+		nr.defaultOpen, nr.enabled = open, enable
+	}
+}
+
+// WithErrorReporterSetup sets how the error reporter named name is set up once it is turned on with WithErrorReporterEnabled,
+// it doesn't turn it on
+func WithErrorReporterSetup(name string, open func(ReporterContext) (ErrorReporter, error)) LoggerOption {
+	return func(args *PacketLogr) {
+		if name == "" || open == nil {
+			args.errs = multierr.Append(args.errs, errors.New("WithErrorReporterSetup: name must not be empty and open must not be nil"))
+			return
+		}
+		args.newReporter(name).open = open
+	}
+}
+
+// newReporter returns the reporter named name, adding it when there is none yet
+func (p *PacketLogr) newReporter(name string) *newReporter {
+	for i := range p.newReporters {
+		if p.newReporters[i].name == name {
+			return &p.newReporters[i]
+		}
+	}
+	p.newReporters = append(p.newReporters, newReporter{name: name})
+	return &p.newReporters[len(p.newReporters)-1]
+}
+
+// openReporter sets up the error reporter of WithNewErrorReporter, with a queue when there is WithErrorReporterSpool
+func (p *PacketLogr) openReporter(nr newReporter, deploy DeployInfo) (ErrorReporter, error) {
+	spool, err := p.openReporterSpool(nr.name)
+	if err != nil {
+		return nil, err
+	}
+	rc := ReporterContext{ServiceName: p.serviceName, Deploy: deploy}
+	if spool != nil {
+		rc.Queue = &ReporterQueue{r: newHTTPReporter(nr.name, spool)}
+	}
+	r, err := nr.setup()(rc)
+	if err == nil && r == nil {
+		err = errors.New("reporter must not be nil")
+	}
+	if err != nil {
+		if rc.Queue != nil {
+			err = multierr.Append(err, rc.Queue.r.close(context.Background()))
+		}
+		return nil, errors.WithMessagef(err, "%s reporter", nr.name)
+	}
+	if rc.Queue != nil {
+		p.reporterQueues = append(p.reporterQueues, rc.Queue.r)
+	}
+	return r, nil
+}
+
+// reportTo tees a reporterCore for each of reporters, which only report the entries logLevel enables
+func reportTo(reporters []ErrorReporter, logLevel zapcore.LevelEnabler) zap.Option {
+	cores := make([]zapcore.Core, 0, len(reporters))
+	for _, r := range reporters {
+		enabler, ok := r.(zapcore.LevelEnabler)
+		if !ok {
+			enabler = zapcore.ErrorLevel
+		}
+		cores = append(cores, &reporterCore{LevelEnabler: enabler, logLevel: logLevel, reporter: r})
+	}
+	return zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(append([]zapcore.Core{c}, cores...)...)
+	})
+}
+
+// reporterCore sends the entries at or above its level and the log level to an error reporter, including the fields added with With
+type reporterCore struct {
+	zapcore.LevelEnabler
+	// logLevel is the logger's level, the core is teed next to the level checks of the logger's own cores
+	logLevel zapcore.LevelEnabler
+	fields   []zapcore.Field
+	reporter ErrorReporter
+}
+
+func (c *reporterCore) Enabled(lvl zapcore.Level) bool {
+	return c.LevelEnabler.Enabled(lvl) && c.logLevel.Enabled(lvl)
+}
+
+func (c *reporterCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	return &clone
+}
+
+func (c *reporterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *reporterCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.reporter.Report(ent, append(c.fields[:len(c.fields):len(c.fields)], fields...))
+}
+
+func (c *reporterCore) Sync() error { return nil }
+
+// reportFields encodes fields as a map and returns the last error among them
+func reportFields(fields []zapcore.Field) (map[string]interface{}, error) {
+	enc := zapcore.NewMapObjectEncoder()
+	var err error
+	for _, f := range fields {
+		f.AddTo(enc)
+		if f.Type == zapcore.ErrorType {
+			err, _ = f.Interface.(error)
+		}
+	}
+	return enc.Fields, err
+}
+
+// stackTracer is implemented by the errors made by github.com/pkg/errors, which record the stack where they were made
+type stackTracer interface {
+	StackTrace() errors.StackTrace
+}
+
+// errorStack returns the stack recorded by the innermost error in err's chain that has one, which is where the error was made,
+// or nil when none of them have one
+func errorStack(err error) []runtime.Frame {
+	var st stackTracer
+	for err != nil {
+		if s, ok := err.(stackTracer); ok {
+			st = s
+		}
+		err = unwrapError(err)
+	}
+	if st == nil {
+		return nil
+	}
+	return stackFrames(st.StackTrace())
+}
+
+// unwrapError returns the error wrapped by err, with either Unwrap or pkg/errors' Cause
+func unwrapError(err error) error {
+	if wrapped := errors.Unwrap(err); wrapped != nil {
+		return wrapped
+	}
+	if c, ok := err.(interface{ Cause() error }); ok {
+		return c.Cause()
+	}
+	return nil
+}
+
+// stackFrames resolves the frames of a pkg/errors stack
+func stackFrames(stack errors.StackTrace) []runtime.Frame {
+	pcs := make([]uintptr, len(stack))
+	for i, f := range stack {
+		// a pkg/errors frame is a return address, like the ones runtime.Callers returns
+		pcs[i] = uintptr(f)
+	}
+	frames := runtime.CallersFrames(pcs)
+	resolved := make([]runtime.Frame, 0, len(pcs))
+	for {
+		frame, more := frames.Next()
+		resolved = append(resolved, frame)
+		if !more {
+			break
+		}
+	}
+	return resolved
+}
+
+// httpReporter sends the requests made for the events of an error reporter from a background goroutine, in order.
+// Delivery errors are returned by the next flush. With a spool, events that can't be sent because the endpoint is unreachable
+// are spooled to disk and sent once it is reachable again, see WithErrorReporterSpool.
+type httpReporter struct {
+	name   string
+	client *http.Client
+	queue  *queue[reporterItem]
+	err    lastErr
+	spool  *diskSpool
+	// ctx is the one of the requests, it is canceled when close gives up so the events left are spooled or dropped
+	ctx    context.Context
+	cancel context.CancelFunc
+	// offlineUntil is when to try the endpoint again after failing to reach it, events are spooled until then
+	offlineUntil time.Time
+}
+
+// reporterItem is a request to send, or a flush that is done once the requests queued before it are sent
+type reporterItem struct {
+	req     *http.Request
+	flushed chan struct{}
+}
+
+// newHTTPReporter starts sending the events of the error reporter name, spooling them to spool when it isn't nil
+func newHTTPReporter(name string, spool *diskSpool) *httpReporter {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &httpReporter{
+		ctx:    ctx,
+		cancel: cancel,
+		name:   name,
+		client: &http.Client{Timeout: reporterRequestTimeout, Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}},
+		queue:  newQueue[reporterItem](reporterQueueSize),
+		spool:  spool,
+	}
+	r.queue.start(r.run)
+	return r
+}
+
+func (r *httpReporter) run(queue <-chan reporterItem) {
+	var retry <-chan time.Time
+	if r.spool != nil {
+		ticker := time.NewTicker(reporterSpoolRetryInterval)
+		defer ticker.Stop()
+		retry = ticker.C
+		// send what was left by a previous process
+		r.replay()
+	}
+	for {
+		select {
+		case item, ok := <-queue:
+			if !ok {
+				return
+			}
+			r.handle(item)
+		case <-retry:
+			if r.spool.pending() && !time.Now().Before(r.offlineUntil) {
+				r.replay()
+			}
+		}
+	}
+}
+
+func (r *httpReporter) handle(item reporterItem) {
+	if item.flushed != nil {
+		close(item.flushed)
+		return
+	}
+	if r.ctx.Err() != nil {
+		if r.spool != nil {
+			r.spill(item.req)
+		} else {
+			r.queue.dropped.Add(1)
+		}
+		return
+	}
+	// the spooled events are sent first, and while the endpoint is unreachable the new ones are spooled after them
+	if r.spool != nil && r.spool.pending() && (time.Now().Before(r.offlineUntil) || !r.replay()) {
+		r.spill(item.req)
+		return
+	}
+	temporary, err := r.do(item.req)
+	if err != nil && temporary && r.spool != nil {
+		r.offlineUntil = time.Now().Add(reporterSpoolRetryInterval)
+		r.spill(item.req)
+		return
+	}
+	if err != nil {
+		r.err.set(err)
+	}
+}
+
+// do sends req, the error is temporary when the endpoint couldn't be reached or is unavailable, so req can be sent again later
+func (r *httpReporter) do(req *http.Request) (temporary bool, err error) {
+	resp, err := r.client.Do(req.WithContext(r.ctx))
+	if err != nil {
+		return true, errors.Wrapf(err, "failed to send event to %s", r.name)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		temporary = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		return temporary, errors.Errorf("failed to send event to %s: %s", r.name, resp.Status)
+	}
+	return false, nil
+}
+
+// send queues req, dropping it when the queue is full or the reporter is closed
+func (r *httpReporter) send(req *http.Request) {
+	if r.queue.put(reporterItem{req: req}, 0) != queued {
+		r.queue.dropped.Add(1)
+	}
+}
+
+// flush waits for the queued events to be sent, giving up when ctx is done, and returns the last delivery error
+func (r *httpReporter) flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	switch r.queue.putContext(ctx, reporterItem{flushed: flushed}) {
+	case queueClosed:
+		return r.takeErr()
+	case queueFull:
+		return errors.Wrapf(ctx.Err(), "waiting for %s events to be sent", r.name)
+	}
+	select {
+	case <-flushed:
+		return r.takeErr()
+	case <-ctx.Done():
+		return errors.Wrapf(ctx.Err(), "waiting for %s events to be sent", r.name)
+	}
+}
+
+func (r *httpReporter) takeErr() error {
+	err := r.err.take()
+	if dropped := r.queue.dropped.Swap(0); dropped > 0 {
+		err = multierr.Append(err, fmt.Errorf("%s dropped %d events", r.name, dropped))
+	}
+	return err
+}
+
+// close sends the queued events and stops the background goroutine, once closed events are dropped.
+// When ctx is done first the events being sent are canceled, and the ones left are spooled, or dropped when there is no spool.
+func (r *httpReporter) close(ctx context.Context) error {
+	r.queue.close()
+	var err error
+	select {
+	case <-r.queue.stopped:
+	case <-ctx.Done():
+		r.cancel()
+		<-r.queue.stopped
+		err = multierr.Append(errors.Wrapf(ctx.Err(), "gave up sending %s events", r.name), r.takeErr())
+	}
+	r.cancel()
+	if r.spool == nil {
+		return err
+	}
+	return multierr.Append(err, r.spool.close())
+}
+
+// contextCloser is implemented by the error reporters that can give up closing when a context is done
+type contextCloser interface {
+	close(ctx context.Context) error
+}
+
+// closeReporter closes r, giving up when ctx is done. The reporters that can't be given a ctx are left to close in the background.
+func closeReporter(ctx context.Context, r ErrorReporter) error {
+	if c, ok := r.(contextCloser); ok {
+		return c.close(ctx)
+	}
+	done := make(chan error, 1)
+	go func() { done <- r.Close() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "gave up closing an error reporter")
+	}
+}
+
+// reporterFlushContext limits ctx to timeout, or to def when it is 0
+func reporterFlushContext(ctx context.Context, timeout, def time.Duration) (context.Context, context.CancelFunc) {
+	if timeout == 0 {
+		timeout = def
+	}
+	return context.WithTimeout(ctx, timeout)
+}
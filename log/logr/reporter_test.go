@@ -0,0 +1,337 @@
+package logr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+)
+
+// testReporter is an in-house error reporter that records what it is given
+type testReporter struct {
+	err error
+
+	mu      sync.Mutex
+	entries []zapcore.Entry
+	fields  [][]zapcore.Field
+	flushed int
+	closed  int
+}
+
+// leveledTestReporter is a testReporter with its own level
+type leveledTestReporter struct {
+	*testReporter
+	zapcore.LevelEnabler
+}
+
+func (r *testReporter) Report(entry zapcore.Entry, fields []zapcore.Field) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+	r.fields = append(r.fields, fields)
+	return r.err
+}
+
+func (r *testReporter) Flush(context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flushed++
+	return nil
+}
+
+func (r *testReporter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed++
+	return nil
+}
+
+func TestPacketLogrErrorReporter(t *testing.T) {
+	r := &testReporter{}
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithErrorReporter(leveledTestReporter{r, zapcore.WarnLevel}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("not reported")
+	l.Zap().Warn("reported")
+	l.WithName("provisioner").WithValues("device", "d1").Error(errors.New("boom"), "provisioning failed")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.entries) != 2 || r.entries[0].Message != "reported" || r.entries[1].Message != "provisioning failed" {
+		t.Fatalf("expected the warn and error entries to be reported, got: %v", r.entries)
+	}
+	if r.entries[1].LoggerName != "provisioner" {
+		t.Fatalf("expected the logger name, got: %v", r.entries[1].LoggerName)
+	}
+	fields, _ := reportFields(r.fields[1])
+	if fields["device"] != "d1" || fields["error"] != "boom" || fields["service"] != "not/set" {
+		t.Fatalf("expected the fields added with WithValues, got: %v", fields)
+	}
+	if r.flushed != 1 || r.closed != 1 {
+		t.Fatalf("expected Close to flush and close the reporter once, got: %d flushes and %d closes", r.flushed, r.closed)
+	}
+}
+
+func TestPacketLogrErrorReporterDefaultLevel(t *testing.T) {
+	r := &testReporter{}
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithErrorReporter(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Zap().Warn("not reported")
+	l.Error(errors.New("boom"), "reported")
+	if err := l.FlushErrorReporters(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.entries) != 1 || r.entries[0].Message != "reported" || r.flushed != 1 {
+		t.Fatalf("expected only the error entry to be reported and flushed, got: %v", r.entries)
+	}
+}
+
+func TestPacketLogrErrorReporterLogLevel(t *testing.T) {
+	r := &testReporter{}
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithLogLevel("dpanic"), WithErrorReporter(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Error(errors.New("boom"), "not reported")
+	l.AtomicLevel().SetLevel(zapcore.ErrorLevel)
+	l.Error(errors.New("boom"), "reported")
+	if len(r.entries) != 1 || r.entries[0].Message != "reported" {
+		t.Fatalf("expected only the entries enabled by the log level to be reported, got: %v", r.entries)
+	}
+}
+
+func TestPacketLogrErrorReporterError(t *testing.T) {
+	errOut := filepath.Join(t.TempDir(), "err.log")
+	r := &testReporter{err: errors.New("tracker is down")}
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithErrorOutputPaths([]string{errOut}), WithErrorReporter(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Error(errors.New("boom"), "reported")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(errOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); !strings.Contains(got, "tracker is down") {
+		t.Fatalf("expected the report error in the error output, got: %v", got)
+	}
+}
+
+func TestWithErrorReporterNil(t *testing.T) {
+	_, err := New(WithErrorReporter(nil))
+	if err == nil || !strings.Contains(err.Error(), "WithErrorReporter") {
+		t.Fatalf("expected an error for a nil reporter, got: %v", err)
+	}
+}
+
+func TestPacketLogrNewErrorReporter(t *testing.T) {
+	setReporterSpoolRetryInterval(t, 10*time.Millisecond)
+	url, up, bodies := unavailableServer(t)
+	r := &testReporter{}
+	var rc ReporterContext
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithServiceName("billing"), WithDeployInfo(DeployInfo{Env: "staging"}),
+		WithErrorReporterSpool(t.TempDir(), 1<<20),
+		WithNewErrorReporter("tracker", func(c ReporterContext) (ErrorReporter, error) {
+			rc = c
+			return r, nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc.ServiceName != "billing" || rc.Deploy.Env != "staging" || rc.Queue == nil {
+		t.Fatalf("expected the service, deploy info, and a queue, got: %+v", rc)
+	}
+	l.Error(errors.New("boom"), "reported")
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader("event"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.Queue.Send(req)
+	if err := rc.Queue.Flush(context.Background()); err != nil {
+		t.Fatalf("expected the request to be spooled, got: %v", err)
+	}
+	if stats := l.SpoolStats(); len(stats) != 1 || stats[0].Sink != "tracker" || stats[0].Entries != 1 {
+		t.Fatalf("expected the spooled request in the stats, got: %+v", stats)
+	}
+
+	up()
+	if got := string(<-bodies); got != "event" {
+		t.Fatalf("expected the spooled request to be sent, got: %v", got)
+	}
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.entries) != 1 || r.flushed != 1 || r.closed != 1 {
+		t.Fatalf("expected the error to be reported, and the reporter flushed and closed, got: %v", r.entries)
+	}
+}
+
+func TestWithNewErrorReporterInvalid(t *testing.T) {
+	_, err := New(WithNewErrorReporter("", nil))
+	if err == nil || !strings.Contains(err.Error(), "WithNewErrorReporter") {
+		t.Fatalf("expected an error for the missing name and open, got: %v", err)
+	}
+
+	_, err = New(WithNewErrorReporter("tracker", func(ReporterContext) (ErrorReporter, error) { return nil, errors.New("no token") }))
+	if err == nil || !strings.Contains(err.Error(), "tracker reporter: no token") {
+		t.Fatalf("expected the error of open, got: %v", err)
+	}
+}
+
+func TestWithErrorReporterEnabled(t *testing.T) {
+	opener := func(name string, opened *string) func(ReporterContext) (ErrorReporter, error) {
+		return func(ReporterContext) (ErrorReporter, error) {
+			*opened = name
+			return &testReporter{}, nil
+		}
+	}
+	for name, tc := range map[string]struct {
+		opts     func(opened *string) []LoggerOption
+		expected string
+	}{
+		"setup only": {func(opened *string) []LoggerOption {
+			return []LoggerOption{WithErrorReporterSetup("tracker", opener("setup", opened))}
+		}, ""},
+		"enabled only": {func(opened *string) []LoggerOption {
+			return []LoggerOption{WithErrorReporterEnabled("tracker", true, opener("default", opened))}
+		}, "default"},
+		"enabled then setup": {func(opened *string) []LoggerOption {
+			return []LoggerOption{WithErrorReporterEnabled("tracker", true, opener("default", opened)), WithErrorReporterSetup("tracker", opener("setup", opened))}
+		}, "setup"},
+		"setup then enabled": {func(opened *string) []LoggerOption {
+			return []LoggerOption{WithErrorReporterSetup("tracker", opener("setup", opened)), WithErrorReporterEnabled("tracker", true, opener("default", opened))}
+		}, "setup"},
+		"disabled": {func(opened *string) []LoggerOption {
+			return []LoggerOption{WithNewErrorReporter("tracker", opener("new", opened)), WithErrorReporterEnabled("tracker", false, opener("default", opened))}
+		}, ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var opened string
+			l, err := New(append([]LoggerOption{WithOutputPaths([]string{os.DevNull})}, tc.opts(&opened)...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close(context.Background())
+			if opened != tc.expected {
+				t.Fatalf("expected the reporter to be set up by %q, got: %q", tc.expected, opened)
+			}
+		})
+	}
+}
+
+func TestNewClosesReportersOnError(t *testing.T) {
+	r := &testReporter{}
+	_, err := New(WithOutputPaths([]string{os.DevNull}), WithAsyncBuffer(16, time.Second), WithErrorReporter(r),
+		WithNewErrorReporter("tracker", func(ReporterContext) (ErrorReporter, error) { return nil, errors.New("no token") }))
+	if err == nil || !strings.Contains(err.Error(), "tracker reporter: no token") {
+		t.Fatalf("expected the error of open, got: %v", err)
+	}
+	if r.closed != 1 {
+		t.Fatalf("expected the reporters opened so far to be closed when New fails, got: %d closes", r.closed)
+	}
+}
+
+// newStackError makes an error that records the stack it was made at
+func newStackError() error {
+	return pkgerrors.New("no capacity")
+}
+
+func TestErrorStack(t *testing.T) {
+	err := fmt.Errorf("provisioning: %w", pkgerrors.Wrap(newStackError(), "reserving"))
+	frames := errorStack(err)
+	if len(frames) == 0 || !strings.HasSuffix(frames[0].Function, ".newStackError") || !strings.HasSuffix(frames[0].File, "reporter_test.go") {
+		t.Fatalf("expected the stack of where the innermost error was made, got: %+v", frames)
+	}
+	if frames := errorStack(errors.New("boom")); frames != nil {
+		t.Fatalf("expected no stack for errors without one, got: %+v", frames)
+	}
+}
+
+func TestHTTPReporterCloseGivesUp(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { <-release }))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+
+	for name, spool := range map[string]bool{"drops": false, "spools": true} {
+		t.Run(name, func(t *testing.T) {
+			var s *diskSpool
+			if spool {
+				var err error
+				if s, err = newDiskSpool(t.TempDir(), 1<<20); err != nil {
+					t.Fatal(err)
+				}
+			}
+			r := newHTTPReporter("test", s)
+			for i := 0; i < 3; i++ {
+				req, err := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(fmt.Sprintf(`{"event":%d}`, i)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				r.send(req)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			err := r.close(ctx)
+			if took := time.Since(start); took > time.Second {
+				t.Fatalf("expected close to give up when ctx is done, took: %s", took)
+			}
+			if err == nil || !strings.Contains(err.Error(), "gave up sending test events") {
+				t.Fatalf("expected close to give up, got: %v", err)
+			}
+			if !spool {
+				// the one being sent is canceled, the ones left are dropped
+				if !strings.Contains(err.Error(), "test dropped 2 events") {
+					t.Fatalf("expected the events left to be dropped, got: %v", err)
+				}
+				return
+			}
+			if stats, _ := r.spoolStats(); stats.Entries != 3 || stats.Dropped != 0 {
+				t.Fatalf("expected the events to be spooled, got: %+v", stats)
+			}
+		})
+	}
+}
+
+// blockingReporter is a testReporter whose Close blocks until release is closed
+type blockingReporter struct {
+	*testReporter
+	release chan struct{}
+}
+
+func (r blockingReporter) Close() error {
+	<-r.release
+	return r.testReporter.Close()
+}
+
+func TestPacketLogrCloseGivesUpOnReporters(t *testing.T) {
+	r := blockingReporter{testReporter: &testReporter{}, release: make(chan struct{})}
+	defer close(r.release)
+	l, err := New(WithOutputPaths([]string{filepath.Join(t.TempDir(), "log")}), WithErrorReporter(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := l.Close(ctx); err == nil || !strings.Contains(err.Error(), "gave up closing an error reporter") {
+		t.Fatalf("expected Close to give up on the reporter, got: %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+package logr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rollbar/rollbar-go"
+)
+
+// reporterSpoolRetryInterval is how long an error reporter waits after failing to reach its endpoint before trying it again,
+// the events reported in between are spooled
+var reporterSpoolRetryInterval = 30 * time.Second
+
+// WithErrorReporterSpool spools the events of the error reporters, such as Rollbar and the ones of WithNewErrorReporter, to files in dir when
+// their endpoint is unreachable, times out, or answers 429 or 5xx, instead of dropping them. They are sent, oldest first,
+// once it is reachable again, including by the next process using dir. Each reporter spools to its own subdirectory,
+// such as dir/rollbar, and drops events once it holds maxBytes. SpoolStats includes the reporters, and a warning with the spool
+// depth is logged every minute while they have events spooled, like for WithSinkSpool.
+func WithErrorReporterSpool(dir string, maxBytes int64) LoggerOption {
+	return func(args *PacketLogr) {
+		args.reporterSpoolDir = dir
+		args.reporterSpoolBytes = maxBytes
+	}
+}
+
+// validateReporterSpool checks the config of WithErrorReporterSpool
+func (p *PacketLogr) validateReporterSpool() error {
+	if p.reporterSpoolDir == "" && p.reporterSpoolBytes == 0 {
+		return nil
+	}
+	if p.reporterSpoolDir == "" || p.reporterSpoolBytes < 1 {
+		return errors.Errorf("error reporter spool dir must not be empty and max bytes must be > 0, got: %q and %d", p.reporterSpoolDir, p.reporterSpoolBytes)
+	}
+	return nil
+}
+
+// openReporterSpool opens the spool of the error reporter name, it is nil when there is no WithErrorReporterSpool
+func (p *PacketLogr) openReporterSpool(name string) (*diskSpool, error) {
+	if p.reporterSpoolDir == "" {
+		return nil, nil
+	}
+	spool, err := newDiskSpool(filepath.Join(p.reporterSpoolDir, name), p.reporterSpoolBytes)
+	return spool, errors.WithMessagef(err, "%s reporter", name)
+}
+
+// spooledRequest is a request spooled by an httpReporter
+type spooledRequest struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+func encodeSpooledRequest(req *http.Request) ([]byte, error) {
+	sr := spooledRequest{Method: req.Method, URL: req.URL.String(), Header: req.Header}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read request body")
+		}
+		defer body.Close()
+		if sr.Body, err = io.ReadAll(body); err != nil {
+			return nil, errors.Wrap(err, "failed to read request body")
+		}
+	}
+	return json.Marshal(sr)
+}
+
+func decodeSpooledRequest(msg []byte) (*http.Request, error) {
+	var sr spooledRequest
+	if err := json.Unmarshal(msg, &sr); err != nil {
+		return nil, errors.Wrap(err, "failed to decode spooled request")
+	}
+	req, err := http.NewRequest(sr.Method, sr.URL, bytes.NewReader(sr.Body))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode spooled request")
+	}
+	req.Header = sr.Header
+	return req, nil
+}
+
+// spill spools req, which couldn't be sent
+func (r *httpReporter) spill(req *http.Request) {
+	msg, err := encodeSpooledRequest(req)
+	if err == nil {
+		err = r.spool.push(msg)
+	}
+	if err != nil {
+		r.err.set(errors.WithMessagef(err, "failed to spool %s event", r.name))
+	}
+}
+
+// replay sends the spooled events, oldest first, and returns false when the endpoint is still unreachable
+func (r *httpReporter) replay() bool {
+	for r.spool.pending() {
+		f, err := r.spool.pop()
+		if err != nil {
+			r.err.set(err)
+			return false
+		}
+		if f == nil {
+			return true
+		}
+		for msg, ok := f.next(); ok; msg, ok = f.next() {
+			req, err := decodeSpooledRequest(msg)
+			if err != nil {
+				r.err.set(err)
+				continue
+			}
+			// the spool file is only removed once all of it is sent, so what was already sent is sent again after a failure
+			if temporary, err := r.do(req); err != nil {
+				if temporary {
+					r.offlineUntil = time.Now().Add(reporterSpoolRetryInterval)
+					_ = f.close()
+					return false
+				}
+				r.err.set(err)
+			}
+		}
+		if err := f.done(); err != nil {
+			r.err.set(err)
+			return false
+		}
+	}
+	return true
+}
+
+func (r *httpReporter) spoolStats() (SpoolStats, bool) {
+	if r.spool == nil {
+		return SpoolStats{}, false
+	}
+	stats := r.spool.stats(r.name)
+	stats.Dropped += r.queue.dropped.Load()
+	return stats, true
+}
+
+// rollbarTransport sends the items of a Rollbar client with an httpReporter, so they are spooled while Rollbar is unreachable
+type rollbarTransport struct {
+	*httpReporter
+	endpoint string
+}
+
+func newRollbarTransport(spool *diskSpool) *rollbarTransport {
+	return &rollbarTransport{httpReporter: newHTTPReporter("rollbar", spool)}
+}
+
+// Send queues body, the token is in it
+func (t *rollbarTransport) Send(body map[string]interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode rollbar item")
+	}
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err, "failed to make rollbar request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	t.send(req)
+	return nil
+}
+
+// Wait waits for the queued items to be sent, keeping the delivery errors for the next flush
+func (t *rollbarTransport) Wait() {
+	if err := t.flush(context.Background()); err != nil {
+		t.err.set(err)
+	}
+}
+
+func (t *rollbarTransport) Close() error { return t.close(context.Background()) }
+
+func (t *rollbarTransport) SetEndpoint(endpoint string) { t.endpoint = endpoint }
+
+// the token is sent in the body, and retries and logging are the httpReporter's
+func (t *rollbarTransport) SetToken(string)                {}
+func (t *rollbarTransport) SetLogger(rollbar.ClientLogger) {}
+func (t *rollbarTransport) SetRetryAttempts(int)           {}
+func (t *rollbarTransport) SetPrintPayloadOnError(bool)    {}
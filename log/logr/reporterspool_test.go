@@ -0,0 +1,142 @@
+package logr
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rollbar/rollbar-go"
+)
+
+// unavailableServer answers 503 until up is called, and then receives the bodies
+func unavailableServer(t *testing.T) (url string, up func(), bodies <-chan []byte) {
+	t.Helper()
+	var available atomic.Bool
+	received := make(chan []byte, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if !available.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received <- b
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, func() { available.Store(true) }, received
+}
+
+func setReporterSpoolRetryInterval(t *testing.T, d time.Duration) {
+	prev := reporterSpoolRetryInterval
+	reporterSpoolRetryInterval = d
+	t.Cleanup(func() { reporterSpoolRetryInterval = prev })
+}
+
+func TestPacketLogrErrorReporterSpool(t *testing.T) {
+	setReporterSpoolRetryInterval(t, 10*time.Millisecond)
+	url, up, bodies := unavailableServer(t)
+	config := NewBugsnagConfig("apikey", "staging", "v1.2.3")
+	config.Endpoint = url
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithEnableBugsnag(true), WithBugsnagConfig(config),
+		WithErrorReporterSpool(t.TempDir(), 1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Error(errors.New("boom"), "while unavailable")
+	if err := l.FlushErrorReporters(context.Background()); err != nil {
+		t.Fatalf("expected the event to be spooled, got: %v", err)
+	}
+	if stats := l.SpoolStats(); len(stats) != 1 || stats[0].Sink != "bugsnag" || stats[0].Entries != 1 {
+		t.Fatalf("expected the spooled event in the stats, got: %+v", stats)
+	}
+
+	up()
+	time.Sleep(20 * time.Millisecond)
+	l.Error(errors.New("boom"), "once available")
+	if err := l.FlushErrorReporters(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"while unavailable", "once available"} {
+		if got := string(<-bodies); !strings.Contains(got, want) {
+			t.Fatalf("expected %q to be sent in order, got: %v", want, got)
+		}
+	}
+	if stats := l.SpoolStats(); stats[0].Entries != 0 {
+		t.Fatalf("expected the spool to be empty, got: %+v", stats)
+	}
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPacketLogrErrorReporterSpoolNextProcess(t *testing.T) {
+	url, up, bodies := unavailableServer(t)
+	dir := t.TempDir()
+	config := NewHoneybadgerConfig("apikey", "staging")
+	config.Endpoint = url
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithEnableHoneybadger(true), WithHoneybadgerConfig(config), WithErrorReporterSpool(dir, 1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Error(errors.New("boom"), "before restarting")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	up()
+	l, err = New(WithOutputPaths([]string{os.DevNull}), WithEnableHoneybadger(true), WithHoneybadgerConfig(config), WithErrorReporterSpool(dir, 1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case body := <-bodies:
+		if !strings.Contains(string(body), "before restarting") {
+			t.Fatalf("expected the event spooled by the previous logger, got: %s", body)
+		}
+	default:
+		t.Fatal("expected the spooled event to be sent on start")
+	}
+}
+
+func TestPacketLogrRollbarSpool(t *testing.T) {
+	setReporterSpoolRetryInterval(t, 10*time.Millisecond)
+	url, up, bodies := unavailableServer(t)
+	prev := rollbar.Endpoint()
+	rollbar.SetEndpoint(url + "/")
+	t.Cleanup(func() { rollbar.SetEndpoint(prev) })
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithEnableRollbar(true), WithRollbarConfig(NewRollbarConfig("token", "test", "v1")),
+		WithErrorReporterSpool(t.TempDir(), 1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Error(errors.New("boom"), "while unavailable")
+	if stats := l.SpoolStats(); len(stats) != 1 || stats[0].Sink != "rollbar" || stats[0].Entries != 1 {
+		t.Fatalf("expected the spooled item in the stats, got: %+v", stats)
+	}
+
+	up()
+	time.Sleep(20 * time.Millisecond)
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(<-bodies); !strings.Contains(got, `"access_token":"token"`) || !strings.Contains(got, "boom") {
+		t.Fatalf("expected the spooled item to be sent, got: %v", got)
+	}
+}
+
+func TestWithErrorReporterSpoolInvalid(t *testing.T) {
+	_, err := New(WithErrorReporterSpool("", 1))
+	if err == nil || !strings.Contains(err.Error(), "error reporter spool dir must not be empty") {
+		t.Fatalf("expected an error for the spool config, got: %v", err)
+	}
+}
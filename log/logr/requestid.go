@@ -0,0 +1,168 @@
+package logr
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// RequestIDHeader is the header request IDs are read from and set on by default
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDKey is the field request IDs are logged as
+	RequestIDKey = "request_id"
+	// maxRequestIDLength is the longest incoming request ID that is kept, longer ones are replaced
+	maxRequestIDLength = 128
+)
+
+// requestIDContextKey is the context key of the request ID
+type requestIDContextKey struct{}
+
+// RequestIDOption customizes RequestIDMiddleware
+type RequestIDOption func(*requestIDConfig)
+
+type requestIDConfig struct {
+	header   string
+	generate func() string
+}
+
+// WithRequestIDHeader reads and sets request IDs on header instead of X-Request-ID, for the gRPC interceptors of the middleware package it is the metadata key
+func WithRequestIDHeader(header string) RequestIDOption {
+	return func(c *requestIDConfig) { c.header = header }
+}
+
+// WithRequestIDGenerator generates request IDs with generate instead of NewRequestID, such as NewULID
+func WithRequestIDGenerator(generate func() string) RequestIDOption {
+	return func(c *requestIDConfig) { c.generate = generate }
+}
+
+// RequestIDMiddleware gives each request an ID: the one in its X-Request-ID header when it has a valid one, so the ID
+// follows a request across services, or else a new one. The ID is set on the response header and on the request's context,
+// see RequestIDFromContext, and the logger FromContext returns for it logs the ID as request_id.
+func (p *PacketLogr) RequestIDMiddleware(next http.Handler, opts ...RequestIDOption) http.Handler {
+	c := newRequestIDConfig(opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := c.id(r.Header.Get(c.header))
+		w.Header().Set(c.header, id)
+		next.ServeHTTP(w, r.WithContext(p.withRequestID(r.Context(), id)))
+	})
+}
+
+func newRequestIDConfig(opts []RequestIDOption) requestIDConfig {
+	c := requestIDConfig{header: RequestIDHeader, generate: NewRequestID}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// id is incoming when it is a valid request ID, or else a new one
+func (c requestIDConfig) id(incoming string) string {
+	if validRequestID(incoming) {
+		return incoming
+	}
+	return c.generate()
+}
+
+// RequestIDSource reads and makes request IDs like RequestIDMiddleware does, for the middlewares of other transports, such as gRPC
+type RequestIDSource struct {
+	c requestIDConfig
+}
+
+// NewRequestIDSource returns the source of request IDs customized with opts
+func NewRequestIDSource(opts ...RequestIDOption) RequestIDSource {
+	return RequestIDSource{c: newRequestIDConfig(opts)}
+}
+
+// Header is the header, or gRPC metadata key, request IDs are read from and sent back on
+func (s RequestIDSource) Header() string { return s.c.header }
+
+// ID is incoming when it is a valid request ID, or else a new one
+func (s RequestIDSource) ID(incoming string) string { return s.c.id(incoming) }
+
+// withRequestID returns a copy of ctx with the request ID id, and with a logger that logs it
+func (p *PacketLogr) withRequestID(ctx context.Context, id string) context.Context {
+	return p.NewContextWithValues(ContextWithRequestID(ctx, id), RequestIDKey, id)
+}
+
+// validRequestID is whether id, from a request header, is short and printable so it is safe to log and pass on
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// ContextWithRequestID returns a copy of ctx holding the request ID id
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID of ctx, it is empty when there is none
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// RequestIDFields returns the request ID of ctx as keys and values, for WithContextFields on loggers
+// that aren't the one RequestIDMiddleware adds to the context, which already has it
+func RequestIDFields(ctx context.Context) []interface{} {
+	if id := RequestIDFromContext(ctx); id != "" {
+		return []interface{}{RequestIDKey, id}
+	}
+	return nil
+}
+
+// SetRequestIDHeader sets the request ID of ctx on the name header of an outgoing request, RequestIDHeader or the Header
+// of a RequestIDSource, so the service it is sent to logs the same ID
+func SetRequestIDHeader(ctx context.Context, header http.Header, name string) {
+	if id := RequestIDFromContext(ctx); id != "" {
+		header.Set(name, id)
+	}
+}
+
+// NewRequestID returns a new UUIDv7, which sorts by the time it was made, see RFC 9562
+func NewRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[6:])
+	putMillis(b[:6], time.Now())
+	b[6] = 0x70 | b[6]&0x0f
+	b[8] = 0x80 | b[8]&0x3f
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// crockford is the alphabet of ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID returns a new ULID, which sorts by the time it was made, see https://github.com/ulid/spec
+func NewULID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[6:])
+	putMillis(b[:6], time.Now())
+	// the 128 bits are 26 characters of 5 bits, the first one only has 3
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var s [26]byte
+	for i := 25; i >= 0; i-- {
+		s[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(s[:])
+}
+
+// putMillis puts the 48 bit unix time in milliseconds of t into b
+func putMillis(b []byte, t time.Time) {
+	ms := uint64(t.UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+}
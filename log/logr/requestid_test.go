@@ -0,0 +1,88 @@
+package logr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var ids []string
+	capturedOutput := captureOutput(func() {
+		pl, err := New(WithServiceName("api"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := pl.RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ids = append(ids, RequestIDFromContext(r.Context()))
+			pl.FromContext(r.Context()).Info("handled")
+		}))
+		for _, incoming := range []string{"from-upstream", "", strings.Repeat("x", 129), "bad id"} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if incoming != "" {
+				req.Header.Set(RequestIDHeader, incoming)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got := rec.Header().Get(RequestIDHeader); got != ids[len(ids)-1] {
+				t.Fatalf("expected the request ID on the response, got: %v", got)
+			}
+		}
+	})
+	if ids[0] != "from-upstream" {
+		t.Fatalf("expected the incoming request ID to be kept, got: %v", ids[0])
+	}
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	for _, id := range ids[1:] {
+		if !uuid.MatchString(id) {
+			t.Fatalf("expected a new UUIDv7 for missing and invalid request IDs, got: %v", id)
+		}
+	}
+	if want := `"msg":"handled","service":"api","request_id":"from-upstream"`; !strings.Contains(capturedOutput, want) {
+		t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+	}
+}
+
+func TestRequestIDMiddlewareOptions(t *testing.T) {
+	pl, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := pl.RequestIDMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}), WithRequestIDHeader("X-Correlation-ID"), WithRequestIDGenerator(NewULID))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := rec.Header().Get("X-Correlation-ID"); !regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`).MatchString(got) {
+		t.Fatalf("expected a ULID on the custom header, got: %v", got)
+	}
+}
+
+func TestNewULIDSorts(t *testing.T) {
+	a := NewULID()
+	b := NewULID()
+	if a[:10] > b[:10] || a[0] > '7' {
+		t.Fatalf("expected ULIDs to sort by their time, got: %v and %v", a, b)
+	}
+}
+
+func TestSetRequestIDHeader(t *testing.T) {
+	header := http.Header{}
+	SetRequestIDHeader(context.Background(), header, RequestIDHeader)
+	if len(header) != 0 {
+		t.Fatalf("expected no header without a request ID, got: %v", header)
+	}
+	SetRequestIDHeader(ContextWithRequestID(context.Background(), "r1"), header, RequestIDHeader)
+	if got := header.Get(RequestIDHeader); got != "r1" {
+		t.Fatalf("expected the request ID header, got: %v", got)
+	}
+	header = http.Header{}
+	SetRequestIDHeader(ContextWithRequestID(context.Background(), "r1"), header, NewRequestIDSource(WithRequestIDHeader("X-Correlation-ID")).Header())
+	if got := header.Get("X-Correlation-ID"); got != "r1" || len(header) != 1 {
+		t.Fatalf("expected the request ID on the configured header, got: %v", header)
+	}
+	if fields := RequestIDFields(ContextWithRequestID(context.Background(), "r1")); len(fields) != 2 || fields[1] != "r1" {
+		t.Fatalf("expected the request ID fields, got: %v", fields)
+	}
+}
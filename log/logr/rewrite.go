@@ -0,0 +1,82 @@
+package logr
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// rewriteCore rewrites the message and fields of every entry before they reach the wrapped core.
+// It still calls the wrapped core's Check so any filtering, sampling, or teeing underneath keeps working.
+type rewriteCore struct {
+	zapcore.Core
+	message func(string) string
+	fields  func([]zapcore.Field) []zapcore.Field
+	// extra returns the fields added to an entry on Write, it is nil when none are
+	extra func(zapcore.Entry) []zapcore.Field
+}
+
+// rewrite wraps the core with a rewriteCore, either func can be nil to leave that part of the entry alone
+func rewrite(message func(string) string, fields func([]zapcore.Field) []zapcore.Field) zap.Option {
+	if message == nil {
+		message = func(msg string) string { return msg }
+	}
+	if fields == nil {
+		fields = keepFields
+	}
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &rewriteCore{Core: core, message: message, fields: fields}
+	})
+}
+
+// keepFields leaves the fields of an entry alone
+func keepFields(fields []zapcore.Field) []zapcore.Field { return fields }
+
+func (c *rewriteCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(c.fields(fields))
+	return &clone
+}
+
+func (c *rewriteCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	ent.Message = c.message(ent.Message)
+	inner := c.Core.Check(ent, nil)
+	if inner == nil {
+		return ce
+	}
+	w := &rewriteWriter{inner: inner, fields: c.fields}
+	if c.extra != nil {
+		w.extra = c.extra(ent)
+	}
+	ce = ce.AddCore(ent, w)
+	w.outer = ce
+	return ce
+}
+
+// rewriteWriter is added to the CheckedEntry in place of the wrapped core so it gets to see the fields on Write
+type rewriteWriter struct {
+	inner  *zapcore.CheckedEntry
+	outer  *zapcore.CheckedEntry
+	fields func([]zapcore.Field) []zapcore.Field
+	extra  []zapcore.Field
+}
+
+func (w *rewriteWriter) Enabled(zapcore.Level) bool { return true }
+
+func (w *rewriteWriter) With([]zapcore.Field) zapcore.Core { return w }
+
+func (w *rewriteWriter) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, w)
+}
+
+func (w *rewriteWriter) Write(_ zapcore.Entry, fields []zapcore.Field) error {
+	// write errors are reported by the inner entry so make sure it reports them to the same place
+	w.inner.ErrorOutput = w.outer.ErrorOutput
+	fields = w.fields(fields)
+	if len(w.extra) > 0 {
+		fields = append(fields[:len(fields):len(fields)], w.extra...)
+	}
+	w.inner.Write(fields...)
+	return nil
+}
+
+func (w *rewriteWriter) Sync() error { return nil }
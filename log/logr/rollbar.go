@@ -1,17 +1,43 @@
 package logr
 
 import (
-	"github.com/jacobweinstock/rollzap"
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
 	"github.com/rollbar/rollbar-go"
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
 )
 
-// rollbarConfig if enabled
-type rollbarConfig struct {
-	token   string
-	env     string
-	version string
+// RollbarConfig is where and how error logs are reported to Rollbar when WithEnableRollbar is set, see WithRollbarConfig
+type RollbarConfig struct {
+	// Token is the project's post_server_item access token.
+	// When it is empty it is read from TokenFile, or else from the ROLLBAR_TOKEN environment variable,
+	// or the file named by ROLLBAR_TOKEN_FILE, and New fails when none of them are set.
+	Token string
+	// TokenFile is a file holding the token, such as a mounted Kubernetes secret
+	TokenFile string
+	// Env is the environment occurrences are reported in, such as production or staging
+	Env string
+	// Version is the release of the service, it is added to every occurrence as the version custom field
+	// and is the code version when CodeVersion is empty
+	Version string
+	// ServerRoot is the path the code is checked out at on the server, so Rollbar can link stack frames
+	// to the repository, the service name when empty
+	ServerRoot string
+	// CodeVersion is the revision of the code, usually the git SHA, which Rollbar uses to link to the source and track deploys
+	CodeVersion string
+	// FlushTimeout is how long Flush and Close wait for pending items to be sent, 5s when 0
+	FlushTimeout time.Duration
+}
+
+// defaultRollbarFlushTimeout is how long Flush and Close wait for pending Rollbar items by default
+const defaultRollbarFlushTimeout = 5 * time.Second
+
+// NewRollbarConfig returns the config for reporting to Rollbar with token in env, as version of the service.
+// Set ServerRoot and CodeVersion on it for source links.
+func NewRollbarConfig(token, env, version string) RollbarConfig {
+	return RollbarConfig{Token: token, Env: env, Version: version}
 }
 
 // rollbarLogger for implementing the rollbar client logger
@@ -19,20 +45,102 @@ type rollbarLogger struct {
 	*zap.Logger
 }
 
-func (c rollbarConfig) setupRollbar(service string, logger *zap.Logger) zap.Option {
-	rollbar.SetToken(c.token)
-	rollbar.SetEnvironment(c.env)
-	rollbar.SetCodeVersion(c.version)
-	rollbar.SetServerRoot(service)
+// token finds the access token, see Token
+func (c RollbarConfig) token() (string, error) {
+	var err error
+	token := c.Token
+	if token == "" {
+		token, err = readRollbarToken(c.TokenFile)
+	}
+	if token == "" && err == nil {
+		token = getEnv("ROLLBAR_TOKEN")
+	}
+	if token == "" && err == nil {
+		token, err = readRollbarToken(getEnv("ROLLBAR_TOKEN_FILE"))
+	}
+	if err != nil {
+		return "", errors.WithMessage(err, "rollbar")
+	}
+	if token == "" {
+		return "", errors.New("rollbar is enabled but no token is set, set the config's Token or TokenFile, ROLLBAR_TOKEN, or ROLLBAR_TOKEN_FILE")
+	}
+	return token, nil
+}
+
+func readRollbarToken(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	return (&fileToken{path: path}).get()
+}
+
+// setupRollbar sets up the global Rollbar client for r, and with a spool a client of r's own that spools to it
+func (c RollbarConfig) setupRollbar(service string, logger *zap.Logger, r *rollbarReporter, spool *diskSpool) {
+	// the token was already checked by validate
+	token, _ := c.token()
+	codeVersion := c.CodeVersion
+	if codeVersion == "" {
+		codeVersion = c.Version
+	}
+	serverRoot := c.ServerRoot
+	if serverRoot == "" {
+		serverRoot = service
+	}
+	rollbar.SetToken(token)
+	rollbar.SetEnvironment(c.Env)
+	rollbar.SetCodeVersion(codeVersion)
+	rollbar.SetServerRoot(serverRoot)
+	if c.Version != "" {
+		rollbar.SetCustom(map[string]interface{}{"version": c.Version})
+	}
+	headers := scrubHeaders(append([]string{"Authorization"}, r.scrubHeaders...))
+	rollbar.SetScrubHeaders(headers)
 	rollbar.SetLogger(rollbarLogger{logger})
+	rollbar.SetTransform(moveRollbarFingerprint)
+	rollbar.SetStackTracer(rollbarStackTracer)
+	r.config = c
+	if spool == nil {
+		return
+	}
 
-	rollbarCore := rollzap.NewRollbarCore(zapcore.ErrorLevel)
-	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
-		return zapcore.NewTee(core, rollbarCore)
-	})
+	client := rollbar.NewAsync(token, c.Env, codeVersion, "", serverRoot)
+	_ = client.Transport.Close()
+	// the transport is replaced first so that the endpoint is set on it
+	r.transport = newRollbarTransport(spool)
+	client.Transport = r.transport
+	client.SetEndpoint(rollbar.Endpoint())
+	if c.Version != "" {
+		client.SetCustom(map[string]interface{}{"version": c.Version})
+	}
+	client.SetScrubHeaders(headers)
+	client.SetLogger(rollbarLogger{logger})
+	client.SetTransform(moveRollbarFingerprint)
+	client.SetStackTracer(rollbarStackTracer)
+	r.client = client
 }
 
 // Printf for internal rollbar errors
 func (r rollbarLogger) Printf(format string, args ...interface{}) {
 	r.Sugar().Infof(format, args...)
 }
+
+// flushRollbar waits for the pending items of the global Rollbar client to be sent, giving up after the flush timeout or when ctx is done
+func (c RollbarConfig) flushRollbar(ctx context.Context) error {
+	timeout := c.FlushTimeout
+	if timeout == 0 {
+		timeout = defaultRollbarFlushTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		rollbar.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "waiting for rollbar items to be sent")
+	}
+}
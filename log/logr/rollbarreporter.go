@@ -0,0 +1,324 @@
+package logr
+
+import (
+	"context"
+	"crypto/sha1" //nolint:gosec // only used to shorten fingerprints
+	"fmt"
+	"net/http"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rollbar/rollbar-go"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// The fields the person of an occurrence is read from by default, see DefaultRollbarContext
+const (
+	RollbarPersonIDKey       = "user_id"
+	RollbarPersonUsernameKey = "username"
+	RollbarPersonEmailKey    = "email"
+)
+
+// RollbarPerson is the user an occurrence happened to, Rollbar only attaches it when ID is set
+type RollbarPerson struct {
+	ID       string
+	Username string
+	Email    string
+}
+
+// RollbarContext is the person and the HTTP request attached to an occurrence, see WithRollbarContext
+type RollbarContext struct {
+	Person RollbarPerson
+	// Request has its method, URL, and headers attached, with the Authorization header and the ones set with
+	// WithRollbarScrubHeaders, and the query parameters and form values that look like secrets, redacted
+	Request *http.Request
+}
+
+// WithRollbarFingerprinter groups the occurrences reported to Rollbar by the fingerprint returned for each entry,
+// instead of by Rollbar's own grouping on the message and stack, so that grouping can key on stable identifiers such as
+// an error code or endpoint. fields include the ones added with WithValues. An empty fingerprint leaves the grouping to Rollbar,
+// and ones longer than the 40 characters Rollbar accepts are hashed.
+func WithRollbarFingerprinter(fingerprint func(entry zapcore.Entry, fields []zapcore.Field) string) LoggerOption {
+	return func(args *PacketLogr) { args.rollbarReporter.fingerprint = fingerprint }
+}
+
+// WithRollbarContext sets how the person and the request attached to the occurrences reported to Rollbar are found
+// from an entry and its fields, including the ones added with WithValues. DefaultRollbarContext is used when it isn't set,
+// an extractor that only adds to it can call it first.
+func WithRollbarContext(extract func(entry zapcore.Entry, fields []zapcore.Field) RollbarContext) LoggerOption {
+	return func(args *PacketLogr) { args.rollbarReporter.extract = extract }
+}
+
+// WithRollbarLevel sets the minimum level of the entries reported to Rollbar, error by default.
+// It is one of the levels WithLogLevel takes, and entries below the log level aren't reported either.
+func WithRollbarLevel(level string) LoggerOption {
+	return func(args *PacketLogr) {
+		lvl, err := parseLevel(level)
+		if err != nil {
+			args.errs = multierr.Append(args.errs, errors.WithMessage(err, "WithRollbarLevel"))
+			return
+		}
+		args.rollbarReporter.LevelEnabler = lvl
+	}
+}
+
+// WithRollbarSeverities maps log levels, as WithLogLevel takes them, to the Rollbar levels entries are reported as:
+// debug, info, warning, error, or critical. Levels that aren't mapped keep the default: trace and debug are debug,
+// info is info, warn is warning, error is error, and dpanic, panic, and fatal are critical.
+func WithRollbarSeverities(severities map[string]string) LoggerOption {
+	return func(args *PacketLogr) {
+		var err error
+		levels := make(map[zapcore.Level]string, len(severities))
+		for level, severity := range severities {
+			lvl, lerr := parseLevel(level)
+			if lerr != nil {
+				err = multierr.Append(err, lerr)
+				continue
+			}
+			switch severity {
+			case rollbar.DEBUG, rollbar.INFO, rollbar.WARN, rollbar.ERR, rollbar.CRIT:
+				levels[lvl] = severity
+			default:
+				err = multierr.Append(err, errors.Errorf("unknown rollbar level for %s: %q", level, severity))
+			}
+		}
+		if err != nil {
+			args.errs = multierr.Append(args.errs, errors.WithMessage(err, "WithRollbarSeverities"))
+			return
+		}
+		args.rollbarReporter.severities = levels
+	}
+}
+
+// WithRollbarScrubHeaders redacts headers, matched case insensitively, from the requests attached to Rollbar occurrences,
+// on top of Authorization
+func WithRollbarScrubHeaders(headers ...string) LoggerOption {
+	return func(args *PacketLogr) { args.rollbarReporter.scrubHeaders = headers }
+}
+
+// DefaultRollbarContext reads the person from the user_id, username, and email fields,
+// and the request from a value wrapped with RollbarRequest, logged under any key.
+func DefaultRollbarContext(_ zapcore.Entry, fields []zapcore.Field) RollbarContext {
+	var rc RollbarContext
+	for _, f := range fields {
+		if r, ok := f.Interface.(rollbarRequest); ok && f.Type == zapcore.ObjectMarshalerType {
+			rc.Request = r.Request
+			continue
+		}
+		var value *string
+		switch f.Key {
+		case RollbarPersonIDKey:
+			value = &rc.Person.ID
+		case RollbarPersonUsernameKey:
+			value = &rc.Person.Username
+		case RollbarPersonEmailKey:
+			value = &rc.Person.Email
+		default:
+			continue
+		}
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		if v, ok := enc.Fields[f.Key]; ok {
+			*value = fmt.Sprint(v)
+		}
+	}
+	return rc
+}
+
+// RollbarRequest wraps r to be logged as a value, such as with WithValues("request", RollbarRequest(r)),
+// so that it is attached to the occurrences reported to Rollbar. The other sinks only get its method and URL,
+// without the password it might have.
+func RollbarRequest(r *http.Request) zapcore.ObjectMarshaler {
+	return rollbarRequest{r}
+}
+
+type rollbarRequest struct {
+	*http.Request
+}
+
+func (r rollbarRequest) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	if r.Request == nil {
+		return nil
+	}
+	enc.AddString("method", r.Method)
+	if r.URL != nil {
+		enc.AddString("url", r.URL.Redacted())
+	}
+	return nil
+}
+
+// scrubHeaders matches the whole of any of headers, ignoring case
+func scrubHeaders(headers []string) *regexp.Regexp {
+	quoted := make([]string, 0, len(headers))
+	for _, h := range headers {
+		quoted = append(quoted, regexp.QuoteMeta(h))
+	}
+	return regexp.MustCompile(`(?i)^(` + strings.Join(quoted, "|") + `)$`)
+}
+
+// rollbarReporter reports entries to Rollbar, with their fields as custom data
+type rollbarReporter struct {
+	zapcore.LevelEnabler
+	config      RollbarConfig
+	fingerprint func(zapcore.Entry, []zapcore.Field) string
+	extract     func(zapcore.Entry, []zapcore.Field) RollbarContext
+	severities  map[zapcore.Level]string
+	throttle    *rollbarThrottle
+	// scrubHeaders are set up globally by setupRollbar
+	scrubHeaders []string
+	// client is the one items are reported with when they are spooled, see WithErrorReporterSpool, the global one when nil
+	client    *rollbar.Client
+	transport *rollbarTransport
+}
+
+// Report sends the entry and waits for it to be sent
+func (c *rollbarReporter) Report(ent zapcore.Entry, fields []zapcore.Field) error {
+	var fp string
+	if c.fingerprint != nil {
+		fp = c.fingerprint(ent, fields)
+	}
+	var suppressed int
+	if c.throttle != nil {
+		key := fp
+		if key == "" {
+			key = fmt.Sprintf("%s|%s|%s", ent.Level, ent.LoggerName, ent.Message)
+		}
+		var ok bool
+		if ok, suppressed = c.throttle.allow(ent.Level, key); !ok {
+			return nil
+		}
+	}
+
+	extras, err := reportFields(fields)
+	if ent.LoggerName != "" {
+		extras["logger"] = ent.LoggerName
+	}
+	if path := ent.Caller.TrimmedPath(); path != "" {
+		extras["file"] = path
+	}
+	if fp != "" {
+		extras[rollbarFingerprintKey] = fp
+	}
+	if suppressed > 0 {
+		extras[rollbarSuppressedKey] = suppressed
+	}
+
+	extract := c.extract
+	if extract == nil {
+		extract = DefaultRollbarContext
+	}
+	rc := extract(ent, fields)
+	ctx := context.Background()
+	if p := rc.Person; p.ID != "" {
+		ctx = rollbar.NewPersonContext(ctx, &rollbar.Person{Id: p.ID, Username: p.Username, Email: p.Email})
+	}
+	if c.client == nil {
+		args := []interface{}{ctx, ent.Message, extras}
+		if rc.Request != nil {
+			args = append(args, rc.Request)
+		}
+		// the error's message is the title when there is one
+		if err != nil {
+			args = append(args, err)
+		}
+		rollbar.Log(c.severity(ent.Level), args...)
+		rollbar.Wait()
+		return nil
+	}
+
+	// the same as rollbar.Log does, skipping the frames up to its caller
+	const skip = 2
+	level := c.severity(ent.Level)
+	switch {
+	case err != nil && rc.Request != nil:
+		c.client.RequestErrorWithStackSkipWithExtrasAndContext(ctx, level, rc.Request, err, skip, extras)
+	case err != nil:
+		c.client.ErrorWithStackSkipWithExtrasAndContext(ctx, level, err, skip, extras)
+	case rc.Request != nil:
+		c.client.RequestMessageWithExtrasAndContext(ctx, level, rc.Request, ent.Message, extras)
+	default:
+		c.client.MessageWithExtrasAndContext(ctx, level, ent.Message, extras)
+	}
+	c.client.Wait()
+	return nil
+}
+
+// Flush waits for the pending items to be sent, giving up after the flush timeout or when ctx is done
+func (c *rollbarReporter) Flush(ctx context.Context) error {
+	if c.client == nil {
+		return c.config.flushRollbar(ctx)
+	}
+	ctx, cancel := reporterFlushContext(ctx, c.config.FlushTimeout, defaultRollbarFlushTimeout)
+	defer cancel()
+	return c.transport.flush(ctx)
+}
+
+// Close stops the client that spools, the global Rollbar client is left to the application
+func (c *rollbarReporter) Close() error {
+	return c.close(context.Background())
+}
+
+func (c *rollbarReporter) close(ctx context.Context) error {
+	if c.transport == nil {
+		return nil
+	}
+	return c.transport.close(ctx)
+}
+
+func (c *rollbarReporter) spoolStats() (SpoolStats, bool) {
+	if c.transport == nil {
+		return SpoolStats{}, false
+	}
+	return c.transport.spoolStats()
+}
+
+// severity is the Rollbar level entries at lvl are reported as
+func (c *rollbarReporter) severity(lvl zapcore.Level) string {
+	if severity, ok := c.severities[lvl]; ok {
+		return severity
+	}
+	switch {
+	case lvl < zapcore.InfoLevel:
+		return rollbar.DEBUG
+	case lvl == zapcore.InfoLevel:
+		return rollbar.INFO
+	case lvl == zapcore.WarnLevel:
+		return rollbar.WARN
+	case lvl == zapcore.ErrorLevel:
+		return rollbar.ERR
+	default:
+		return rollbar.CRIT
+	}
+}
+
+// rollbarStackTracer is Rollbar's stack tracer, with the stacks recorded by pkg/errors too,
+// so that occurrences show where the error was made instead of where it was logged
+func rollbarStackTracer(err error) ([]runtime.Frame, bool) {
+	if frames, ok := rollbar.DefaultStackTracer(err); ok {
+		return frames, true
+	}
+	if st, ok := err.(stackTracer); ok {
+		return stackFrames(st.StackTrace()), true
+	}
+	return nil, false
+}
+
+// rollbarFingerprintKey carries an entry's fingerprint in the item's custom data until moveRollbarFingerprint moves it
+const rollbarFingerprintKey = "rollbar_fingerprint"
+
+// moveRollbarFingerprint is the Rollbar transform that moves the fingerprint added by rollbarReporter to the item
+func moveRollbarFingerprint(data map[string]interface{}) {
+	custom, _ := data["custom"].(map[string]interface{})
+	fp, ok := custom[rollbarFingerprintKey].(string)
+	if !ok {
+		return
+	}
+	delete(custom, rollbarFingerprintKey)
+	if len(fp) > 40 {
+		fp = fmt.Sprintf("%x", sha1.Sum([]byte(fp)))
+	}
+	data["fingerprint"] = fp
+}
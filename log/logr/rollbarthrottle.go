@@ -0,0 +1,123 @@
+package logr
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxRollbarThrottleKeys bounds the number of distinct fingerprints tracked before expired ones are swept
+const maxRollbarThrottleKeys = 10000
+
+// rollbarSuppressedKey is the custom data field holding how many occurrences were dropped by the rate limit
+// in the previous minute, added to the next occurrence reported with the same fingerprint
+const rollbarSuppressedKey = "rollbar_suppressed"
+
+// WithRollbarRateLimit reports at most perMinute occurrences a minute of each fingerprint to Rollbar, so that a hot
+// error loop doesn't use up the quota. The fingerprint is the one returned by WithRollbarFingerprinter, or else the
+// level, logger name, and message of the entry. How many were dropped is added to the next occurrence reported,
+// as the rollbar_suppressed custom field. Entries that panic or exit are always reported.
+func WithRollbarRateLimit(perMinute int) LoggerOption {
+	return func(args *PacketLogr) {
+		if perMinute <= 0 {
+			err := errors.Errorf("occurrences per minute must be > 0, got: %d", perMinute)
+			args.errs = multierr.Append(args.errs, errors.WithMessage(err, "WithRollbarRateLimit"))
+			return
+		}
+		args.rollbarReporter.throttler().perMinute = perMinute
+	}
+}
+
+// WithRollbarSampling reports a random fraction, rate, of the occurrences to Rollbar, before they are rate limited.
+// Entries that panic or exit are always reported.
+func WithRollbarSampling(rate float64) LoggerOption {
+	return func(args *PacketLogr) {
+		if rate <= 0 || rate > 1 {
+			err := errors.Errorf("sampling rate must be > 0 and <= 1, got: %v", rate)
+			args.errs = multierr.Append(args.errs, errors.WithMessage(err, "WithRollbarSampling"))
+			return
+		}
+		args.rollbarReporter.throttler().rate = rate
+	}
+}
+
+// throttler returns the throttle of c, adding it when c doesn't have one yet
+func (c *rollbarReporter) throttler() *rollbarThrottle {
+	if c.throttle == nil {
+		c.throttle = &rollbarThrottle{
+			now:    time.Now,
+			random: rand.Float64, //nolint:gosec // sampling doesn't need to be unpredictable
+			seen:   map[string]*rollbarWindow{},
+		}
+	}
+	return c.throttle
+}
+
+// rollbarThrottle samples and rate limits the occurrences reported to Rollbar
+type rollbarThrottle struct {
+	perMinute int
+	rate      float64
+	now       func() time.Time
+	random    func() float64
+
+	mu   sync.Mutex
+	seen map[string]*rollbarWindow
+}
+
+// rollbarWindow counts the occurrences of a fingerprint in the minute from start
+type rollbarWindow struct {
+	start      time.Time
+	reported   int
+	suppressed int
+}
+
+// allow reports whether an occurrence with fingerprint key should be reported, and if so how many occurrences
+// of it were dropped by the rate limit in the previous minute
+func (t *rollbarThrottle) allow(lvl zapcore.Level, key string) (bool, int) {
+	// entries that panic or exit are never dropped
+	if lvl >= zapcore.DPanicLevel {
+		return true, 0
+	}
+	if t.rate > 0 && t.rate < 1 && t.random() >= t.rate {
+		return false, 0
+	}
+	if t.perMinute == 0 {
+		return true, 0
+	}
+
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	w, ok := t.seen[key]
+	if ok && now.Sub(w.start) < time.Minute {
+		if w.reported >= t.perMinute {
+			w.suppressed++
+			return false, 0
+		}
+		w.reported++
+		return true, 0
+	}
+	var suppressed int
+	if ok {
+		suppressed = w.suppressed
+	}
+	t.seen[key] = &rollbarWindow{start: now, reported: 1}
+	if len(t.seen) > maxRollbarThrottleKeys {
+		t.sweep(now)
+	}
+	return true, suppressed
+}
+
+// sweep forgets about the fingerprints whose minute has passed, t.mu must be held.
+// Their dropped occurrences aren't counted on the next one reported.
+func (t *rollbarThrottle) sweep(now time.Time) {
+	for key, w := range t.seen {
+		if now.Sub(w.start) >= time.Minute {
+			delete(t.seen, key)
+		}
+	}
+}
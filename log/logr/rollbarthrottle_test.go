@@ -0,0 +1,85 @@
+package logr
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPacketLogrRollbarRateLimit(t *testing.T) {
+	items, release := rollbarServer(t)
+	release()
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithEnableRollbar(true), WithRollbarConfig(NewRollbarConfig("token", "test", "v1")),
+		WithRollbarRateLimit(2), WithRollbarSampling(0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close(context.Background())
+	now := time.Now()
+	random := 0.0
+	l.rollbarReporter.throttle.now = func() time.Time { return now }
+	l.rollbarReporter.throttle.random = func() float64 { return random }
+
+	next := func() map[string]interface{} {
+		t.Helper()
+		select {
+		case b := <-items:
+			var item struct {
+				Data struct {
+					Title  string                 `json:"title"`
+					Custom map[string]interface{} `json:"custom"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(b, &item); err != nil {
+				t.Fatal(err)
+			}
+			item.Data.Custom["title"] = item.Data.Title
+			return item.Data.Custom
+		default:
+			return nil
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		l.Error(nil, "hot loop")
+	}
+	l.Error(nil, "other")
+	for _, want := range []string{"hot loop", "hot loop", "other"} {
+		if got := next(); got == nil || got["title"] != want {
+			t.Fatalf("expected: %v, got: %v", want, got)
+		}
+	}
+	if got := next(); got != nil {
+		t.Fatalf("expected the rest of the loop to be dropped, got: %v", got)
+	}
+
+	random = 0.5
+	now = now.Add(time.Minute)
+	l.Error(nil, "hot loop")
+	if got := next(); got != nil {
+		t.Fatalf("expected the entry to be sampled out, got: %v", got)
+	}
+	random = 0.4
+	l.Error(nil, "hot loop")
+	if got := next(); got == nil || got[rollbarSuppressedKey] != 3.0 {
+		t.Fatalf("expected the count of dropped occurrences, got: %v", got)
+	}
+}
+
+func TestPacketLogrRollbarThrottleInvalid(t *testing.T) {
+	_, err := New(WithRollbarRateLimit(0), WithRollbarSampling(1.5))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		"WithRollbarRateLimit: occurrences per minute must be > 0, got: 0",
+		"WithRollbarSampling: sampling rate must be > 0 and <= 1, got: 1.5",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package logr
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// WithRotatingFile also writes to the file at path, rotating it once it reaches maxSizeMB, 100 when 0.
+// Up to maxBackups rotated files are kept for up to maxAgeDays, 0 keeps them all, and compress gzips them.
+// Entries are written using the same encoding and level as the output paths.
+func WithRotatingFile(path string, maxSizeMB, maxBackups, maxAgeDays int, compress bool) LoggerOption {
+	return func(args *PacketLogr) {
+		var err error
+		if path == "" {
+			err = multierr.Append(err, errors.New("path must not be empty"))
+		}
+		if maxSizeMB < 0 || maxBackups < 0 || maxAgeDays < 0 {
+			err = multierr.Append(err, errors.Errorf("max size, backups, and age must be >= 0, got: %d, %d, and %d", maxSizeMB, maxBackups, maxAgeDays))
+		}
+		if err != nil {
+			args.errs = multierr.Append(args.errs, errors.WithMessage(err, "WithRotatingFile"))
+			return
+		}
+		args.newSinks = append(args.newSinks, writerSink(func() (zapcore.WriteSyncer, io.Closer, error) {
+			l := &lumberjack.Logger{
+				Filename:   path,
+				MaxSize:    maxSizeMB,
+				MaxBackups: maxBackups,
+				MaxAge:     maxAgeDays,
+				Compress:   compress,
+			}
+			return zapcore.AddSync(l), l, nil
+		}))
+	}
+}
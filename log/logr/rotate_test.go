@@ -0,0 +1,50 @@
+package logr
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPacketLogrRotatingFile(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "rotating.log")
+	var pl *PacketLogr
+	capturedOutput := captureOutput(func() {
+		var err error
+		pl, err = New(WithRotatingFile(logFile, 1, 2, 7, false), WithLogLevel("warn"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pl.Info("filtered by level")
+		pl.Zap().Warn("rotated message")
+		if err := pl.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	})
+	b, err := ioutil.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, out := range []string{capturedOutput, string(b)} {
+		if !strings.Contains(out, `"msg":"rotated message"`) {
+			t.Fatalf("expected the entry in stdout and the file, got: %v", out)
+		}
+		if strings.Contains(out, "filtered by level") {
+			t.Fatalf("expected the level to apply, got: %v", out)
+		}
+	}
+}
+
+func TestPacketLogrRotatingFileInvalid(t *testing.T) {
+	_, err := New(WithRotatingFile("", -1, 0, 0, false))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"WithRotatingFile: path must not be empty", "max size, backups, and age must be >= 0"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, err)
+		}
+	}
+}
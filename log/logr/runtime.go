@@ -0,0 +1,57 @@
+package logr
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+)
+
+// WithRuntimeFields adds hostname, pid, go_version, and container_id, when running in a container, fields
+func WithRuntimeFields() LoggerOption {
+	return func(args *PacketLogr) { args.keysAndValues = append(args.keysAndValues, runtimeFields()...) }
+}
+
+func runtimeFields() []interface{} {
+	kvs := []interface{}{"pid", os.Getpid(), "go_version", runtime.Version()}
+	if hostname, err := os.Hostname(); err == nil {
+		kvs = append(kvs, "hostname", hostname)
+	}
+	if id := containerID(); id != "" {
+		kvs = append(kvs, "container_id", id)
+	}
+	return kvs
+}
+
+// containerIDPattern matches the 64 hex character ids used by docker, containerd, and cri-o where they appear in cgroup paths,
+// such as /docker/<id> and cri-containerd-<id>.scope, and in the mounts of the container's files, /containers/<id>/.
+// The ids of the overlayfs layers in the mounts are the same length, so the id alone isn't matched.
+var containerIDPattern = regexp.MustCompile(`(?:/docker/|docker-|cri-containerd-|crio-|/containers/)([0-9a-f]{64})(?:\.scope|/|$)`)
+
+// containerID looks for the container id in the cgroup paths, which is where cgroup v1 puts it,
+// and then in the mounts, which is where it shows up with cgroup v2
+func containerID() string {
+	for _, path := range []string{"/proc/self/cgroup", "/proc/self/mountinfo"} {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		id := findContainerID(f)
+		f.Close()
+		if id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+func findContainerID(r io.Reader) string {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if m := containerIDPattern.FindStringSubmatch(scanner.Text()); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
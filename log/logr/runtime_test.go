@@ -0,0 +1,72 @@
+package logr
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFindContainerID(t *testing.T) {
+	const (
+		id    = "4f2c8c6d2b0e7c1f9a3e5d7b6c4a2e0f1d3b5a7c9e8f6d4b2a0c1e3f5d7b9a8c"
+		layer = "9b1d7e5c3a8f6d4b2e0c1a3f5d7b9e8c6a4f2d0b1e3c5a7f9d8b6e4c2a0f1d3b"
+	)
+	// the overlayfs mount comes first, with the ids of the image layers
+	overlay := "651 583 0:56 / / rw,relatime master:306 - overlay overlay rw,lowerdir=/var/lib/docker/overlay2/l/6Y5IM2XC7TSNIJZZFLJCS4AAAA," +
+		"upperdir=/var/lib/docker/overlay2/" + layer + "/diff,workdir=/var/lib/docker/overlay2/" + layer + "/work\n"
+	tests := map[string]struct {
+		input string
+		want  string
+	}{
+		"docker cgroup v1":      {input: "12:pids:/docker/" + id + "\n11:cpu:/docker/" + id, want: id},
+		"docker systemd cgroup": {input: "0::/system.slice/docker-" + id + ".scope", want: id},
+		"kubernetes cgroup v1":  {input: "4:memory:/kubepods/burstable/pod1234/cri-containerd-" + id + ".scope", want: id},
+		"cri-o cgroup":          {input: "0::/kubepods.slice/kubepods-besteffort.slice/crio-" + id + ".scope", want: id},
+		"docker mountinfo": {
+			input: overlay + "668 651 254:1 /var/lib/docker/containers/" + id + "/resolv.conf /etc/resolv.conf rw,relatime - ext4 /dev/vda1 rw\n" +
+				"669 651 254:1 /var/lib/docker/containers/" + id + "/hostname /etc/hostname rw,relatime - ext4 /dev/vda1 rw",
+			want: id,
+		},
+		"containerd mountinfo": {
+			input: "1362 1300 0:250 / / rw,relatime - overlay overlay rw,lowerdir=/var/lib/containerd/io.containerd.snapshotter.v1.overlayfs/snapshots/41/fs," +
+				"upperdir=/var/lib/containerd/io.containerd.snapshotter.v1.overlayfs/snapshots/42/fs\n" +
+				"1370 1362 254:1 /var/lib/containerd/io.containerd.grpc.v1.cri/sandboxes/" + layer + "/hostname /etc/hostname rw - ext4 /dev/vda1 rw\n" +
+				"1371 1362 254:1 /var/lib/kubelet/pods/4b1c/containers/app/1a2b /dev/termination-log rw - ext4 /dev/vda1 rw",
+			want: "",
+		},
+		"only overlay layers": {input: overlay, want: ""},
+		"not a container":     {input: "0::/user.slice/user-1000.slice/session-1.scope", want: ""},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := findContainerID(strings.NewReader(tc.input)); got != tc.want {
+				t.Fatalf("expected: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPacketLogrRuntimeFields(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		l, _, err := NewPacketLogr(WithRuntimeFields())
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Info("runtime fields")
+	})
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		fmt.Sprintf(`"pid":%d`, os.Getpid()),
+		fmt.Sprintf(`"go_version":"%s"`, runtime.Version()),
+		fmt.Sprintf(`"hostname":"%s"`, hostname),
+	} {
+		if !strings.Contains(capturedOutput, want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+		}
+	}
+}
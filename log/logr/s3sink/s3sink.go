@@ -0,0 +1,417 @@
+// Package s3sink writes the entries of a logr.PacketLogr to compressed segment files that are uploaded to S3,
+// or an S3-compatible store such as MinIO, on a schedule:
+//
+//	l, err := logr.New(s3sink.WithSpool("logs", "/var/spool/logs"))
+package s3sink
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/packethost/pkg/log/logr"
+	// segments can be compressed with zstd
+	_ "github.com/packethost/pkg/log/logr/zstd"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// s3UploadInterval is how often the current segment is closed and uploaded
+	s3UploadInterval = 5 * time.Minute
+	// s3SegmentSize is how many bytes of entries, before compression, a segment holds before it is closed early
+	s3SegmentSize = 64 * 1024 * 1024
+	// s3CloseTimeout is how long Close waits for the segments to be uploaded
+	s3CloseTimeout = 30 * time.Second
+	// s3SegmentLayout is the name of a segment, the time it was started
+	s3SegmentLayout = "20060102T150405.000000000Z"
+	s3SegmentExt    = ".log"
+	// s3PartialExt is added to the segment being written
+	s3PartialExt = ".part"
+)
+
+// s3PrefixPlaceholders are the placeholders a prefix layout can have
+var s3PrefixPlaceholders = regexp.MustCompile(`\{[^}]*\}`)
+
+// s3Compressions are the compressions segments can have, segments are uploaded whichever they have
+// so that what was spooled before the compression was changed is still uploaded
+var s3Compressions = []logr.Compression{{Algorithm: logr.CompressionGzip}, {Algorithm: logr.CompressionZstd}}
+
+// Option configures WithSpool
+type Option func(*s3Config)
+
+type s3Config struct {
+	bucket      string
+	dir         string
+	prefix      string
+	interval    time.Duration
+	segmentSize int64
+	region      string
+	endpoint    string
+	client      s3Client
+	compression logr.Compression
+	service     string
+}
+
+// WithPrefix sets the layout of the prefix segments are uploaded under, {service}/{date}/{hour} by default.
+// {service} is the service name, {host} the hostname, and {date} and {hour} the UTC date, as YYYY-MM-DD, and hour the segment was started.
+func WithPrefix(layout string) Option {
+	return func(c *s3Config) { c.prefix = layout }
+}
+
+// WithUpload sets how often the current segment is closed and uploaded, 5m by default,
+// and how many bytes of entries before compression a segment holds before it is closed and uploaded early, 64MiB by default.
+func WithUpload(interval time.Duration, maxSegmentBytes int64) Option {
+	return func(c *s3Config) {
+		c.interval = interval
+		c.segmentSize = maxSegmentBytes
+	}
+}
+
+// WithRegion sets the region of the bucket, by default the one from the environment or shared config
+func WithRegion(region string) Option {
+	return func(c *s3Config) { c.region = region }
+}
+
+// WithEndpoint uploads to an S3-compatible store, such as MinIO, at url using path-style addressing
+func WithEndpoint(url string) Option {
+	return func(c *s3Config) { c.endpoint = url }
+}
+
+// WithCompression sets how segments are compressed, gzip at its default level by default
+func WithCompression(compression logr.Compression) Option {
+	return func(c *s3Config) { c.compression = compression }
+}
+
+// WithClient uploads using client instead of one created from the default AWS config
+func WithClient(client *s3.Client) Option {
+	return func(c *s3Config) { c.client = client }
+}
+
+// WithSpool also writes entries to compressed segment files in dir, see WithCompression, which are uploaded to bucket on a schedule, see WithUpload,
+// and removed once they are. Segments that fail to upload, or that are left from before a restart, are uploaded with the next ones.
+// Entries are written using the same encoding and level as the output paths, and credentials come from the default AWS config.
+func WithSpool(bucket, dir string, opts ...Option) logr.LoggerOption {
+	c := s3Config{
+		bucket:      bucket,
+		dir:         dir,
+		prefix:      "{service}/{date}/{hour}",
+		interval:    s3UploadInterval,
+		segmentSize: s3SegmentSize,
+		compression: logr.Compression{Algorithm: logr.CompressionGzip},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	err := c.compression.Validate()
+	if bucket == "" {
+		err = multierr.Append(err, errors.New("bucket must not be empty"))
+	}
+	if dir == "" {
+		err = multierr.Append(err, errors.New("dir must not be empty"))
+	}
+	if c.interval <= 0 || c.segmentSize < 1 {
+		err = multierr.Append(err, errors.Errorf("upload interval and max segment bytes must be > 0, got: %s and %d", c.interval, c.segmentSize))
+	}
+	for _, p := range s3PrefixPlaceholders.FindAllString(c.prefix, -1) {
+		switch p {
+		case "{service}", "{host}", "{date}", "{hour}":
+		default:
+			err = multierr.Append(err, errors.Errorf("unknown placeholder in prefix: %s", p))
+		}
+	}
+	if err != nil {
+		return logr.WithOptionError(errors.WithMessage(err, "s3sink.WithSpool"))
+	}
+	return logr.WithSink(c.open)
+}
+
+// open starts the spool, writing entries with the same encoding and level as the output paths
+func (c s3Config) open(sc logr.SinkContext) (zapcore.Core, io.Closer, error) {
+	encoder, err := logr.NewEncoder(sc.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.service = sc.ServiceName
+	client := c.client
+	if client == nil {
+		if client, err = c.newClient(); err != nil {
+			return nil, nil, err
+		}
+	}
+	s, err := newS3Spool(c, client)
+	if err != nil {
+		return nil, nil, err
+	}
+	return zapcore.NewCore(encoder, sc.Wrap(s), sc.Config.Level), s, nil
+}
+
+// s3Client is the part of s3.Client the spool uses
+type s3Client interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+func (c s3Config) newClient() (s3Client, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if c.region != "" {
+		opts = append(opts, awsconfig.WithRegion(c.region))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load AWS config")
+	}
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if c.endpoint != "" {
+			o.BaseEndpoint = aws.String(c.endpoint)
+			o.UsePathStyle = true
+		}
+	}), nil
+}
+
+// s3Spool writes entries to the current segment and uploads the segments that are done from a background goroutine
+type s3Spool struct {
+	config s3Config
+	client s3Client
+	host   string
+	err    lastErr
+
+	// mu guards the current segment
+	mu    sync.Mutex
+	file  *os.File
+	w     logr.CompressWriter
+	start time.Time
+	size  int64
+
+	// uploadMu makes sure a segment is only uploaded once at a time
+	uploadMu sync.Mutex
+	full     chan struct{}
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+func newS3Spool(c s3Config, client s3Client) (*s3Spool, error) {
+	if err := os.MkdirAll(c.dir, 0o750); err != nil {
+		return nil, errors.Wrap(err, "failed to create spool dir")
+	}
+	// segments that were being written when the process stopped are uploaded with the rest, readers get what was flushed
+	partial, err := filepath.Glob(filepath.Join(c.dir, "*"+s3SegmentExt+".*"+s3PartialExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range partial {
+		if err := os.Rename(path, strings.TrimSuffix(path, s3PartialExt)); err != nil {
+			return nil, errors.Wrap(err, "failed to recover spooled segment")
+		}
+	}
+	host, _ := os.Hostname()
+	s := &s3Spool{
+		config:  c,
+		client:  client,
+		host:    host,
+		full:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+func (s *s3Spool) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.err.take()
+	if s.file == nil {
+		if openErr := s.open(); openErr != nil {
+			return 0, multierr.Append(err, openErr)
+		}
+	}
+	n, writeErr := s.w.Write(p)
+	if s.size += int64(n); s.size >= s.config.segmentSize {
+		writeErr = multierr.Append(writeErr, s.finish())
+		select {
+		case s.full <- struct{}{}:
+		default:
+		}
+	}
+	return n, multierr.Append(err, writeErr)
+}
+
+// Sync flushes the current segment to disk
+func (s *s3Spool) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	return multierr.Append(s.w.Flush(), s.file.Sync())
+}
+
+// open starts a new segment, mu must be held
+func (s *s3Spool) open() error {
+	start := time.Now().UTC()
+	name := start.Format(s3SegmentLayout) + s3SegmentExt + s.config.compression.Ext() + s3PartialExt
+	f, err := os.OpenFile(filepath.Join(s.config.dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return errors.Wrap(err, "failed to create spool segment")
+	}
+	w, err := s.config.compression.NewWriter(f)
+	if err != nil {
+		return multierr.Append(errors.Wrap(err, "failed to create spool segment"), f.Close())
+	}
+	s.file, s.w, s.start, s.size = f, w, start, 0
+	return nil
+}
+
+// finish closes the current segment so it can be uploaded, mu must be held
+func (s *s3Spool) finish() error {
+	if s.file == nil {
+		return nil
+	}
+	path := s.file.Name()
+	err := multierr.Combine(s.w.Close(), s.file.Close(), os.Rename(path, strings.TrimSuffix(path, s3PartialExt)))
+	s.file, s.w = nil, nil
+	return errors.Wrap(err, "failed to finish spool segment")
+}
+
+func (s *s3Spool) run() {
+	defer close(s.stopped)
+	ticker := time.NewTicker(s.config.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			err := s.finish()
+			s.mu.Unlock()
+			if err != nil {
+				s.err.set(err)
+			}
+		case <-s.full:
+		}
+		if err := s.upload(context.Background()); err != nil {
+			s.err.set(err)
+		}
+	}
+}
+
+// key is where the segment started at start, compressed with c, is uploaded
+func (s *s3Spool) key(start time.Time, c logr.Compression) string {
+	prefix := strings.NewReplacer(
+		"{service}", s.config.service,
+		"{host}", s.host,
+		"{date}", start.Format("2006-01-02"),
+		"{hour}", start.Format("15"),
+	).Replace(s.config.prefix)
+	name := start.Format(s3SegmentLayout) + s3SegmentExt + c.Ext()
+	if s.host != "" {
+		name = s.host + "-" + name
+	}
+	if prefix = strings.Trim(prefix, "/"); prefix != "" {
+		return prefix + "/" + name
+	}
+	return name
+}
+
+// upload uploads the finished segments, oldest first, removing them once they are uploaded.
+// It stops at the first failure so that segments are uploaded in order.
+func (s *s3Spool) upload(ctx context.Context) error {
+	s.uploadMu.Lock()
+	defer s.uploadMu.Unlock()
+	paths, err := filepath.Glob(filepath.Join(s.config.dir, "*"+s3SegmentExt+".*"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		start, c, ok := parseS3Segment(filepath.Base(path))
+		if !ok {
+			// not one of ours, or still being written
+			continue
+		}
+		if err := s.uploadSegment(ctx, path, s.key(start, c), c); err != nil {
+			return errors.WithMessagef(err, "failed to upload spool segment %s", filepath.Base(path))
+		}
+		if err := os.Remove(path); err != nil {
+			return errors.Wrap(err, "failed to remove uploaded spool segment")
+		}
+	}
+	return nil
+}
+
+// parseS3Segment gets when a finished segment was started and how it is compressed from its name
+func parseS3Segment(name string) (time.Time, logr.Compression, bool) {
+	for _, c := range s3Compressions {
+		ext := s3SegmentExt + c.Ext()
+		if !strings.HasSuffix(name, ext) {
+			continue
+		}
+		start, err := time.Parse(s3SegmentLayout, strings.TrimSuffix(name, ext))
+		return start, c, err == nil
+	}
+	return time.Time{}, logr.Compression{}, false
+}
+
+func (s *s3Spool) uploadSegment(ctx context.Context, path, key string, c logr.Compression) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.config.bucket),
+		Key:         aws.String(key),
+		Body:        f,
+		ContentType: aws.String(c.ContentType()),
+	})
+	return err
+}
+
+// Close finishes the current segment and waits up to s3CloseTimeout for the segments to be uploaded
+func (s *s3Spool) Close() error {
+	select {
+	case <-s.stop:
+		return nil
+	default:
+	}
+	close(s.stop)
+	<-s.stopped
+
+	s.mu.Lock()
+	err := s.finish()
+	s.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), s3CloseTimeout)
+	defer cancel()
+	return multierr.Combine(s.err.take(), err, s.upload(ctx))
+}
+
+// lastErr holds the last error of the background uploads until the next write returns it
+type lastErr struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (e *lastErr) set(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.err = err
+}
+
+func (e *lastErr) take() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	err := e.err
+	e.err = nil
+	return err
+}
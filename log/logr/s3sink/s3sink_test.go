@@ -0,0 +1,212 @@
+package s3sink
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/klauspost/compress/zstd"
+	"github.com/packethost/pkg/log/logr"
+	"github.com/pkg/errors"
+)
+
+type fakeS3Client struct {
+	mu      sync.Mutex
+	err     error
+	objects map[string]string
+	types   map[string]string
+}
+
+func (c *fakeS3Client) PutObject(_ context.Context, params *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	var r io.Reader
+	var err error
+	if strings.HasSuffix(aws.ToString(params.Key), ".zst") {
+		var d *zstd.Decoder
+		if d, err = zstd.NewReader(params.Body); err == nil {
+			defer d.Close()
+			r = d
+		}
+	} else {
+		r, err = gzip.NewReader(params.Body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.types == nil {
+		c.types = map[string]string{}
+	}
+	c.types[aws.ToString(params.Key)] = aws.ToString(params.ContentType)
+	b, err := io.ReadAll(r)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	if c.objects == nil {
+		c.objects = map[string]string{}
+	}
+	c.objects[aws.ToString(params.Bucket)+"/"+aws.ToString(params.Key)] = string(b)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func testS3Config(dir string) s3Config {
+	return s3Config{bucket: "logs", dir: dir, prefix: "{service}/{date}/{hour}", interval: time.Hour, segmentSize: s3SegmentSize, compression: logr.Compression{Algorithm: logr.CompressionGzip}, service: "billing"}
+}
+
+func TestS3Spool(t *testing.T) {
+	dir := t.TempDir()
+	c := testS3Config(dir)
+	c.segmentSize = 10
+	client := &fakeS3Client{}
+	s, err := newS3Spool(c, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range []string{"first entry\n", "second entry\n"} {
+		if _, err := s.Write([]byte(entry)); err != nil {
+			t.Fatal(err)
+		}
+		// segments start on the nanosecond, make sure the next is later
+		time.Sleep(time.Millisecond)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.objects) != 2 {
+		t.Fatalf("expected a segment for each entry, got: %v", client.objects)
+	}
+	var got []string
+	for key, body := range client.objects {
+		if now := time.Now().UTC(); !strings.HasPrefix(key, "logs/billing/"+now.Format("2006-01-02")+"/") || !strings.HasSuffix(key, s3SegmentExt+".gz") {
+			t.Fatalf("expected the service and date in the key, got: %v", key)
+		}
+		got = append(got, body)
+	}
+	if strings.Join(got, "") != "first entry\nsecond entry\n" && strings.Join(got, "") != "second entry\nfirst entry\n" {
+		t.Fatalf("expected the entries to be uploaded, got: %q", got)
+	}
+	if left, _ := filepath.Glob(filepath.Join(dir, "*")); len(left) != 0 {
+		t.Fatalf("expected uploaded segments to be removed, got: %v", left)
+	}
+}
+
+func TestS3SpoolFailedUpload(t *testing.T) {
+	dir := t.TempDir()
+	client := &fakeS3Client{err: errors.New("bucket unavailable")}
+	s, err := newS3Spool(testS3Config(dir), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Write([]byte("kept\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err == nil || !strings.Contains(err.Error(), "bucket unavailable") {
+		t.Fatalf("expected the upload error, got: %v", err)
+	}
+
+	// the segment is still spooled and is uploaded by the next spool using the dir
+	client.err = nil
+	s, err = newS3Spool(testS3Config(dir), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.objects) != 1 {
+		t.Fatalf("expected the spooled segment to be uploaded, got: %v", client.objects)
+	}
+}
+
+func TestS3SpoolRecoversPartialSegments(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC)
+	f, err := os.Create(filepath.Join(dir, start.Format(s3SegmentLayout)+s3SegmentExt+".gz"+s3PartialExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	_, _ = gz.Write([]byte("before the crash\n"))
+	// flushed but never closed, as if the process was killed
+	_ = gz.Flush()
+	_ = f.Close()
+
+	client := &fakeS3Client{}
+	s, err := newS3Spool(testS3Config(dir), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	host, _ := os.Hostname()
+	key := "logs/billing/2020-03-04/05/" + host + "-" + start.Format(s3SegmentLayout) + s3SegmentExt + ".gz"
+	if got := client.objects[key]; got != "before the crash\n" {
+		t.Fatalf("expected the partial segment at %v, got: %v", key, client.objects)
+	}
+}
+
+func TestWithSpoolInvalid(t *testing.T) {
+	_, err := logr.New(WithSpool("", "", WithPrefix("{service}/{day}"), WithUpload(0, 0)))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"s3sink.WithSpool", "bucket must not be empty", "dir must not be empty", "upload interval and max segment bytes must be > 0", "unknown placeholder in prefix: {day}"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, err)
+		}
+	}
+}
+
+func TestS3SpoolCompression(t *testing.T) {
+	dir := t.TempDir()
+	client := &fakeS3Client{}
+	s, err := newS3Spool(testS3Config(dir), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Write([]byte("gzipped\n")); err != nil {
+		t.Fatal(err)
+	}
+	client.err = errors.New("unavailable")
+	if err := s.Close(); err == nil {
+		t.Fatal("expected the upload to fail")
+	}
+
+	// changing the compression still uploads what was spooled before
+	client.err = nil
+	c := testS3Config(dir)
+	c.compression = logr.Compression{Algorithm: logr.CompressionZstd, Level: 19}
+	if s, err = newS3Spool(c, client); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Write([]byte("zstd\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for key, body := range client.objects {
+		ext := key[strings.LastIndex(key, "."):]
+		got = append(got, ext+" "+client.types[strings.TrimPrefix(key, "logs/")]+" "+body)
+	}
+	sort.Strings(got)
+	if want := ".gz application/gzip gzipped\n,.zst application/zstd zstd\n"; strings.Join(got, ",") != want {
+		t.Fatalf("expected: %q, got: %q", want, got)
+	}
+}
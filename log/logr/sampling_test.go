@@ -0,0 +1,55 @@
+package logr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPacketLogrSampling(t *testing.T) {
+	tests := map[string]struct {
+		opts []LoggerOption
+		want int
+	}{
+		"default":            {want: 100},
+		"default and stderr": {opts: []LoggerOption{WithEnableErrLogsToStderr(true)}, want: 100},
+		"custom":             {opts: []LoggerOption{WithSampling(5, 50)}, want: 7},
+		"disabled":           {opts: []LoggerOption{WithEnableSampling(false)}, want: 150},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			capturedOutput := captureOutput(func() {
+				l, _, err := NewPacketLogr(tc.opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := 0; i < 150; i++ {
+					l.Info("sampled message")
+				}
+			})
+			if got := strings.Count(capturedOutput, "sampled message"); got != tc.want {
+				t.Fatalf("expected %d lines, got: %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPacketLogrSamplingInvalid(t *testing.T) {
+	if _, _, err := NewPacketLogr(WithSampling(0, 10)); err == nil {
+		t.Fatal("expected an error for invalid sampling")
+	}
+}
+
+func TestPacketLogrSamplingTrace(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		l, _, err := NewPacketLogr(WithLogLevel("trace"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 150; i++ {
+			l.V(2).Info("trace message")
+		}
+	})
+	if got := strings.Count(capturedOutput, "trace message"); got != 150 {
+		t.Fatalf("expected trace entries to bypass sampling, got: %d lines", got)
+	}
+}
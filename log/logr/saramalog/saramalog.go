@@ -0,0 +1,125 @@
+// Package saramalog logs the produce and consume events, rebalances, and errors of sarama Kafka clients with a logr.PacketLogr,
+// with the fields topic, partition, offset, and consumer_group:
+//
+//	config.Producer.Interceptors = []sarama.ProducerInterceptor{saramalog.NewProducerInterceptor(l)}
+//	config.Consumer.Interceptors = []sarama.ConsumerInterceptor{saramalog.NewConsumerInterceptor(l, "provisioner")}
+//	config.Consumer.Return.Errors = true
+//	group, err := sarama.NewConsumerGroup(brokers, "provisioner", config)
+//	go saramalog.LogConsumerErrors(l, "provisioner", group.Errors())
+//	err = group.Consume(ctx, topics, saramalog.WrapConsumerGroupHandler(l, "provisioner", handler))
+package saramalog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"github.com/go-logr/logr"
+	packetlogr "github.com/packethost/pkg/log/logr"
+)
+
+// debug is the verbosity records are logged at, as there is one entry per record
+const debug = 1
+
+// ProducerInterceptor logs the records being produced at debug, their partition isn't known yet
+type ProducerInterceptor struct {
+	p *packetlogr.PacketLogr
+}
+
+// NewProducerInterceptor returns the ProducerInterceptor logging with p, for the Producer.Interceptors of a sarama.Config
+func NewProducerInterceptor(p *packetlogr.PacketLogr) *ProducerInterceptor {
+	return &ProducerInterceptor{p: p}
+}
+
+// OnSend logs msg
+func (i *ProducerInterceptor) OnSend(msg *sarama.ProducerMessage) {
+	i.p.V(debug).Info("producing record", "topic", msg.Topic)
+}
+
+// ConsumerInterceptor logs the records consumed at debug
+type ConsumerInterceptor struct {
+	p     *packetlogr.PacketLogr
+	group string
+}
+
+// NewConsumerInterceptor returns the ConsumerInterceptor logging with p, for the Consumer.Interceptors of a sarama.Config.
+// group is the consumer group of the consumer, it is left out when it is empty.
+func NewConsumerInterceptor(p *packetlogr.PacketLogr, group string) *ConsumerInterceptor {
+	return &ConsumerInterceptor{p: p, group: group}
+}
+
+// OnConsume logs msg
+func (i *ConsumerInterceptor) OnConsume(msg *sarama.ConsumerMessage) {
+	i.p.V(debug).Info("consumed record", groupFields(i.group, "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset)...)
+}
+
+// WrapConsumerGroupHandler returns a handler logging the partitions claimed by the session of the consumer group group
+// when it is set up, and revoked from it when it is cleaned up, on each rebalance, around the calls to h
+func WrapConsumerGroupHandler(p *packetlogr.PacketLogr, group string, h sarama.ConsumerGroupHandler) sarama.ConsumerGroupHandler {
+	return &consumerGroupHandler{ConsumerGroupHandler: h, p: p, group: group}
+}
+
+type consumerGroupHandler struct {
+	sarama.ConsumerGroupHandler
+	p     *packetlogr.PacketLogr
+	group string
+}
+
+func (h *consumerGroupHandler) Setup(session sarama.ConsumerGroupSession) error {
+	h.logRebalance("partitions assigned", session)
+	return h.ConsumerGroupHandler.Setup(session)
+}
+
+func (h *consumerGroupHandler) Cleanup(session sarama.ConsumerGroupSession) error {
+	err := h.ConsumerGroupHandler.Cleanup(session)
+	h.logRebalance("partitions revoked", session)
+	return err
+}
+
+func (h *consumerGroupHandler) logRebalance(msg string, session sarama.ConsumerGroupSession) {
+	h.p.Info(msg, groupFields(h.group, "member_id", session.MemberID(), "generation", session.GenerationID(), "partitions", session.Claims())...)
+}
+
+// LogProducerErrors logs the errors of an async producer until errs is closed, it needs Producer.Return.Errors, which is the default
+func LogProducerErrors(p *packetlogr.PacketLogr, errs <-chan *sarama.ProducerError) {
+	for err := range errs {
+		p.Error(err.Err, "failed to produce record", "topic", err.Msg.Topic, "partition", err.Msg.Partition)
+	}
+}
+
+// LogConsumerErrors logs the errors of a consumer, or of the consumer group group, until errs is closed, it needs Consumer.Return.Errors
+func LogConsumerErrors(p *packetlogr.PacketLogr, group string, errs <-chan error) {
+	for err := range errs {
+		var consumerErr *sarama.ConsumerError
+		if errors.As(err, &consumerErr) {
+			p.Error(consumerErr.Err, "failed to consume records", groupFields(group, "topic", consumerErr.Topic, "partition", consumerErr.Partition)...)
+			continue
+		}
+		p.Error(err, "failed to consume records", groupFields(group)...)
+	}
+}
+
+// NewStdLogger returns a logger for sarama.Logger, logging sarama's internal logs at debug as entries named "sarama"
+func NewStdLogger(p *packetlogr.PacketLogr) sarama.StdLogger {
+	return stdLogger{l: p.WithName("sarama").V(debug)}
+}
+
+type stdLogger struct {
+	l logr.Logger
+}
+
+// print logs msg without the trailing newline sarama's messages often have
+func (s stdLogger) print(msg string) { s.l.Info(strings.TrimSuffix(msg, "\n")) }
+
+func (s stdLogger) Print(v ...interface{})                 { s.print(fmt.Sprint(v...)) }
+func (s stdLogger) Printf(format string, v ...interface{}) { s.print(fmt.Sprintf(format, v...)) }
+func (s stdLogger) Println(v ...interface{})               { s.print(fmt.Sprintln(v...)) }
+
+// groupFields are kvs with group, when it isn't empty
+func groupFields(group string, kvs ...interface{}) []interface{} {
+	if group != "" {
+		kvs = append(kvs, "consumer_group", group)
+	}
+	return kvs
+}
@@ -0,0 +1,69 @@
+package saramalog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+)
+
+// fakeSession is a sarama.ConsumerGroupSession with claims
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+}
+
+func (fakeSession) Claims() map[string][]int32 { return map[string][]int32{"events": {0, 1}} }
+func (fakeSession) MemberID() string           { return "m1" }
+func (fakeSession) GenerationID() int32        { return 3 }
+
+type nopHandler struct{}
+
+func (nopHandler) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (nopHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+func (nopHandler) ConsumeClaim(sarama.ConsumerGroupSession, sarama.ConsumerGroupClaim) error {
+	return nil
+}
+
+func TestSaramalog(t *testing.T) {
+	l, logged := logtest.New(t, logr.WithLogLevel("debug"))
+	NewProducerInterceptor(l).OnSend(&sarama.ProducerMessage{Topic: "events"})
+	NewConsumerInterceptor(l, "provisioner").OnConsume(&sarama.ConsumerMessage{Topic: "events", Partition: 2, Offset: 42})
+	h := WrapConsumerGroupHandler(l, "provisioner", nopHandler{})
+	if err := h.Setup(fakeSession{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Cleanup(fakeSession{}); err != nil {
+		t.Fatal(err)
+	}
+
+	producerErrs := make(chan *sarama.ProducerError, 1)
+	producerErrs <- &sarama.ProducerError{Msg: &sarama.ProducerMessage{Topic: "events", Partition: 1}, Err: errors.New("message too large")}
+	close(producerErrs)
+	LogProducerErrors(l, producerErrs)
+	consumerErrs := make(chan error, 2)
+	consumerErrs <- &sarama.ConsumerError{Topic: "events", Partition: 2, Err: errors.New("offset out of range")}
+	consumerErrs <- errors.New("coordinator not available")
+	close(consumerErrs)
+	LogConsumerErrors(l, "provisioner", consumerErrs)
+	NewStdLogger(l).Printf("client/metadata fetching metadata for %v\n", []string{"events"})
+
+	got := logged()
+	for _, want := range []string{
+		`"msg":"producing record","service":"not/set","topic":"events"`,
+		`"msg":"consumed record","service":"not/set","topic":"events","partition":2,"offset":42,"consumer_group":"provisioner"`,
+		`"msg":"partitions assigned","service":"not/set","member_id":"m1","generation":3,"partitions":{"events":[0,1]},"consumer_group":"provisioner"`,
+		`"msg":"partitions revoked"`,
+		`"msg":"failed to produce record","service":"not/set","topic":"events","partition":1,"error":"message too large"`,
+		`"msg":"failed to consume records","service":"not/set","topic":"events","partition":2,"consumer_group":"provisioner","error":"offset out of range"`,
+		`"msg":"failed to consume records","service":"not/set","consumer_group":"provisioner","error":"coordinator not available"`,
+		`"logger":"sarama","caller":`,
+		`"msg":"client/metadata fetching metadata for [events]","service"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %s to be logged, got: %v", want, got)
+		}
+	}
+}
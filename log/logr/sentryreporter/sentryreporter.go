@@ -0,0 +1,325 @@
+// Package sentryreporter reports the error logs of a logr.PacketLogr to Sentry:
+//
+//	l, err := logr.New(sentryreporter.WithEnableSentry(true), sentryreporter.WithSentryConfig(sentryreporter.NewSentryConfig(dsn, "production", "v1.2.3")))
+package sentryreporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/packethost/pkg/log/logr"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// reporterName is the name of the reporter in errors, SpoolStats, and the spool directory
+	reporterName = "sentry"
+	// defaultFlushTimeout is how long the reporter waits for pending Sentry events by default
+	defaultFlushTimeout = 5 * time.Second
+)
+
+// SentryConfig is where and how error logs are reported to Sentry when WithEnableSentry is set, see WithSentryConfig
+type SentryConfig struct {
+	// DSN is the project's client key, when it is empty it is read from the SENTRY_DSN environment variable
+	DSN string
+	// Env is the environment events are reported in, such as production or staging, the one of logr.WithDeployInfo when empty
+	Env string
+	// Release is the version of the service events are attributed to, the one of logr.WithDeployInfo when empty
+	Release string
+	// TracesSampleRate is the fraction of transactions sent for performance monitoring, between 0 and 1.
+	// Tracing is off when it is 0.
+	TracesSampleRate float64
+	// FlushTimeout is how long flushing and closing the logger wait for pending events to be sent,
+	// and how long panic and fatal entries wait before the logger panics or exits, 5s when 0
+	FlushTimeout time.Duration
+}
+
+// NewSentryConfig returns the config for reporting to Sentry with dsn in env, as release of the service
+func NewSentryConfig(dsn, env, release string) SentryConfig {
+	return SentryConfig{DSN: dsn, Env: env, Release: release}
+}
+
+// WithEnableSentry sends error logs to Sentry, along with or instead of the other error reporters.
+// Entries at the dpanic, panic, and fatal levels are sent before the logger panics or exits.
+// With logr.WithErrorReporterSpool the events are spooled while Sentry is unreachable.
+func WithEnableSentry(enable bool) logr.LoggerOption {
+	return logr.WithErrorReporterEnabled(reporterName, enable, SentryConfig{}.setup)
+}
+
+// WithSentryConfig customizes the Sentry details, see NewSentryConfig
+func WithSentryConfig(config SentryConfig) logr.LoggerOption {
+	return logr.WithErrorReporterSetup(reporterName, config.setup)
+}
+
+// FromEnv is WithEnableSentry when SENTRY_DSN is set, it does nothing otherwise, such as for logr.NewFromEnv
+func FromEnv() logr.LoggerOption {
+	if os.Getenv("SENTRY_DSN") == "" {
+		return func(*logr.PacketLogr) {}
+	}
+	return WithEnableSentry(true)
+}
+
+func (c SentryConfig) validate() error {
+	if c.DSN == "" {
+		return errors.New("sentry is enabled but no DSN is set, set the config's DSN or SENTRY_DSN")
+	}
+	var err error
+	if _, derr := sentry.NewDsn(c.DSN); derr != nil {
+		err = multierr.Append(err, errors.Wrap(derr, "invalid sentry DSN"))
+	}
+	if c.TracesSampleRate < 0 || c.TracesSampleRate > 1 {
+		err = multierr.Append(err, errors.Errorf("sentry traces sample rate must be between 0 and 1, got: %v", c.TracesSampleRate))
+	}
+	if c.FlushTimeout < 0 {
+		err = multierr.Append(err, errors.Errorf("sentry flush timeout must be >= 0, got: %s", c.FlushTimeout))
+	}
+	return err
+}
+
+func (c SentryConfig) flushTimeout() time.Duration {
+	if c.FlushTimeout == 0 {
+		return defaultFlushTimeout
+	}
+	return c.FlushTimeout
+}
+
+// withDeploy fills in the empty fields from d
+func (c SentryConfig) withDeploy(d logr.DeployInfo) SentryConfig {
+	if c.Env == "" {
+		c.Env = d.Env
+	}
+	if c.Release == "" {
+		c.Release = d.Version
+	}
+	if c.Release == "" {
+		c.Release = d.Revision
+	}
+	return c
+}
+
+// setup sets up the global Sentry hub, so that tracing and the sentry package's own helpers report to it too
+func (c SentryConfig) setup(rc logr.ReporterContext) (logr.ErrorReporter, error) {
+	if c.DSN == "" {
+		c.DSN = os.Getenv("SENTRY_DSN")
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	c = c.withDeploy(rc.Deploy)
+	opts := sentry.ClientOptions{
+		Dsn:              c.DSN,
+		Environment:      c.Env,
+		Release:          c.Release,
+		EnableTracing:    c.TracesSampleRate > 0,
+		TracesSampleRate: c.TracesSampleRate,
+		Tags:             map[string]string{"service": rc.ServiceName},
+	}
+	r := &reporter{config: c}
+	if rc.Queue != nil {
+		r.transport = &transport{queue: rc.Queue}
+		opts.Transport = r.transport
+	}
+	if err := sentry.Init(opts); err != nil {
+		return nil, errors.Wrap(err, "failed to set up sentry")
+	}
+	r.hub = sentry.CurrentHub()
+	return r, nil
+}
+
+// reporter reports entries to Sentry, with their fields as extra data
+type reporter struct {
+	config SentryConfig
+	hub    *sentry.Hub
+	// transport spools the events, see logr.WithErrorReporterSpool, it is nil for sentry's own
+	transport *transport
+}
+
+// Report captures the entry, waiting for it to be sent when the logger panics or exits afterwards
+func (r *reporter) Report(ent zapcore.Entry, fields []zapcore.Field) error {
+	client := r.hub.Client()
+	if client == nil {
+		return nil
+	}
+	extra, err := reportFields(fields)
+
+	level := sentryLevel(ent.Level)
+	var event *sentry.Event
+	if err != nil {
+		event = client.EventFromException(err, level)
+		event.Message = ent.Message
+	} else {
+		event = client.EventFromMessage(ent.Message, level)
+	}
+	event.Timestamp = ent.Time
+	event.Logger = ent.LoggerName
+	event.Extra = extra
+	if path := ent.Caller.TrimmedPath(); path != "" {
+		event.Extra["file"] = path
+	}
+	r.hub.CaptureEvent(event)
+
+	// the logger panics or exits after writing these
+	if ent.Level >= zapcore.DPanicLevel {
+		r.hub.Flush(r.config.flushTimeout())
+	}
+	return nil
+}
+
+// Flush waits for the pending events to be sent, giving up after the flush timeout or when ctx is done
+func (r *reporter) Flush(ctx context.Context) error {
+	timeout := r.config.flushTimeout()
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	var err error
+	if !sentry.Flush(timeout) {
+		err = errors.New("waiting for sentry events to be sent: timed out")
+	}
+	if r.transport != nil {
+		err = multierr.Append(err, r.transport.takeErr())
+	}
+	return err
+}
+
+// Close does nothing as the Sentry hub is global, and the spooling queue is closed by the logger
+func (r *reporter) Close() error {
+	return nil
+}
+
+// reportFields encodes fields as a map and returns the last error among them
+func reportFields(fields []zapcore.Field) (map[string]interface{}, error) {
+	enc := zapcore.NewMapObjectEncoder()
+	var err error
+	for _, f := range fields {
+		f.AddTo(enc)
+		if f.Type == zapcore.ErrorType {
+			err, _ = f.Interface.(error)
+		}
+	}
+	return enc.Fields, err
+}
+
+func sentryLevel(lvl zapcore.Level) sentry.Level {
+	switch {
+	case lvl < zapcore.InfoLevel:
+		return sentry.LevelDebug
+	case lvl == zapcore.InfoLevel:
+		return sentry.LevelInfo
+	case lvl == zapcore.WarnLevel:
+		return sentry.LevelWarning
+	case lvl == zapcore.ErrorLevel:
+		return sentry.LevelError
+	default:
+		return sentry.LevelFatal
+	}
+}
+
+// transport sends the events of the Sentry client as envelopes with the logger's queue, so they are spooled while Sentry is unreachable
+type transport struct {
+	queue *logr.ReporterQueue
+	dsn   *sentry.Dsn
+	err   lastErr
+}
+
+func (t *transport) Configure(options sentry.ClientOptions) {
+	// the DSN was already checked by sentry.Init
+	t.dsn, _ = sentry.NewDsn(options.Dsn)
+}
+
+func (t *transport) SendEvent(event *sentry.Event) {
+	if t.dsn == nil {
+		return
+	}
+	req, err := t.request(event)
+	if err != nil {
+		t.err.set(err)
+		return
+	}
+	t.queue.Send(req)
+}
+
+// request makes the envelope of event, see https://develop.sentry.dev/sdk/envelopes/
+func (t *transport) request(event *sentry.Event) (*http.Request, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode sentry event")
+	}
+	itemType := event.Type
+	if itemType == "" {
+		itemType = "event"
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	err = enc.Encode(map[string]interface{}{
+		"event_id": event.EventID,
+		"sent_at":  time.Now(),
+		"dsn":      t.dsn.String(),
+		"sdk":      map[string]string{"name": event.Sdk.Name, "version": event.Sdk.Version},
+	})
+	if err == nil {
+		err = enc.Encode(map[string]interface{}{"type": itemType, "length": len(body)})
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode sentry envelope")
+	}
+	b.Write(body)
+	b.WriteByte('\n')
+
+	req, err := http.NewRequest(http.MethodPost, t.dsn.GetAPIURL().String(), &b)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make sentry request")
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s/%s, sentry_key=%s", event.Sdk.Name, event.Sdk.Version, t.dsn.GetPublicKey()))
+	return req, nil
+}
+
+// Flush waits for the queued events to be sent, keeping the delivery errors for the next flush of the reporter
+func (t *transport) Flush(timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := t.queue.Flush(ctx); err != nil {
+		if ctx.Err() != nil {
+			return false
+		}
+		t.err.set(err)
+	}
+	return true
+}
+
+// Close does nothing, the queue is closed by the logger
+func (t *transport) Close() {}
+
+// takeErr returns the errors making and delivering the events since the last call
+func (t *transport) takeErr() error {
+	return multierr.Append(t.err.take(), t.queue.Err())
+}
+
+// lastErr holds the last error of the transport until the next flush returns it
+type lastErr struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (e *lastErr) set(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.err = err
+}
+
+func (e *lastErr) take() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	err := e.err
+	e.err = nil
+	return err
+}
@@ -0,0 +1,184 @@
+package sentryreporter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/packethost/pkg/log/logr"
+	pkgerrors "github.com/pkg/errors"
+)
+
+// sentryServer is a Sentry API endpoint that passes on the events it receives, it returns the DSN to report to it
+func sentryServer(t *testing.T) (dsn string, events <-chan map[string]interface{}) {
+	t.Helper()
+	received := make(chan map[string]interface{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		// an envelope is a header, then item headers each followed by their payload, one per line
+		scanner := bufio.NewScanner(bytes.NewReader(b))
+		scanner.Buffer(nil, 1<<20)
+		var itemType string
+		for i := 0; scanner.Scan(); i++ {
+			var v map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+				continue
+			}
+			switch {
+			case i == 0:
+			case i%2 == 1:
+				itemType, _ = v["type"].(string)
+			case itemType == "event":
+				received <- v
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "http://key@" + strings.TrimPrefix(srv.URL, "http://") + "/1", received
+}
+
+func TestWithEnableSentry(t *testing.T) {
+	dsn, events := sentryServer(t)
+	l, err := logr.New(logr.WithOutputPaths([]string{os.DevNull}), logr.WithServiceName("github.com/packethost/pkg"),
+		WithEnableSentry(true), WithSentryConfig(NewSentryConfig(dsn, "test", "v1.2.3")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("not reported")
+	l.WithValues("device", "d1").Error(errors.New("boom"), "provisioning failed")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	event := <-events
+	if event["message"] != "provisioning failed" || event["level"] != "error" || event["environment"] != "test" || event["release"] != "v1.2.3" {
+		t.Fatalf("expected the error event, got: %v", event)
+	}
+	if extra, _ := event["extra"].(map[string]interface{}); extra["device"] != "d1" {
+		t.Fatalf("expected the fields as extra data, got: %v", event["extra"])
+	}
+	if tags, _ := event["tags"].(map[string]interface{}); tags["service"] != "github.com/packethost/pkg" {
+		t.Fatalf("expected the service tag, got: %v", event["tags"])
+	}
+	exception, _ := event["exception"].([]interface{})
+	if len(exception) == 0 || exception[len(exception)-1].(map[string]interface{})["value"] != "boom" {
+		t.Fatalf("expected the error as the exception, got: %v", event["exception"])
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("expected only the error to be reported, got: %v", event)
+	default:
+	}
+}
+
+func TestWithSentryInvalid(t *testing.T) {
+	t.Setenv("SENTRY_DSN", "")
+	_, err := logr.New(WithEnableSentry(true))
+	if err == nil || !strings.Contains(err.Error(), "sentry reporter: sentry is enabled but no DSN is set") {
+		t.Fatalf("expected a missing DSN error, got: %v", err)
+	}
+
+	config := NewSentryConfig("not a dsn", "test", "v1")
+	config.TracesSampleRate, config.FlushTimeout = 2, -1
+	l, err := logr.New(logr.WithOutputPaths([]string{os.DevNull}), WithSentryConfig(config))
+	if err != nil {
+		t.Fatalf("expected the config to be ignored unless sentry is enabled, got: %v", err)
+	}
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_, err = logr.New(WithSentryConfig(config), WithEnableSentry(true))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		"invalid sentry DSN",
+		"sentry traces sample rate must be between 0 and 1, got: 2",
+		"sentry flush timeout must be >= 0, got: -1ns",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, err)
+		}
+	}
+}
+
+func TestWithSentryErrorStack(t *testing.T) {
+	dsn, events := sentryServer(t)
+	l, err := logr.New(logr.WithOutputPaths([]string{os.DevNull}), WithEnableSentry(true), WithSentryConfig(NewSentryConfig(dsn, "test", "v1.2.3")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Error(newStackError(), "provisioning failed")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	event := <-events
+	exception, _ := event["exception"].([]interface{})
+	if len(exception) == 0 {
+		t.Fatalf("expected the error as the exception, got: %v", event)
+	}
+	stacktrace, _ := exception[len(exception)-1].(map[string]interface{})["stacktrace"].(map[string]interface{})
+	frames, _ := stacktrace["frames"].([]interface{})
+	// sentry orders frames from the outermost call
+	if len(frames) == 0 || frames[len(frames)-1].(map[string]interface{})["function"] != "newStackError" {
+		t.Fatalf("expected the stack of where the error was made, got: %v", stacktrace)
+	}
+}
+
+// newStackError makes an error that records the stack it was made at
+func newStackError() error {
+	return pkgerrors.New("no capacity")
+}
+
+func TestWithSentrySpool(t *testing.T) {
+	dsn, events := sentryServer(t)
+	l, err := logr.New(logr.WithOutputPaths([]string{os.DevNull}), WithEnableSentry(true), WithSentryConfig(NewSentryConfig(dsn, "test", "v1.2.3")),
+		logr.WithErrorReporterSpool(t.TempDir(), 1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats := l.SpoolStats(); len(stats) != 1 || stats[0].Sink != "sentry" {
+		t.Fatalf("expected the spool of the sentry reporter, got: %+v", stats)
+	}
+	l.Error(errors.New("boom"), "provisioning failed")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if event := <-events; event["message"] != "provisioning failed" || event["release"] != "v1.2.3" {
+		t.Fatalf("expected the event to be sent by the spooling transport, got: %v", event)
+	}
+}
+
+func TestWithSentryDeployInfo(t *testing.T) {
+	if c := (SentryConfig{}).withDeploy(logr.DeployInfo{Env: "staging", Revision: "abc123"}); c.Env != "staging" || c.Release != "abc123" {
+		t.Fatalf("expected the revision as the sentry release without a version, got: %+v", c)
+	}
+	if c := NewSentryConfig("dsn", "test", "v2").withDeploy(logr.DeployInfo{Env: "staging", Version: "v1.2.3"}); c.Env != "test" || c.Release != "v2" {
+		t.Fatalf("expected the sentry config to be kept, got: %+v", c)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("SENTRY_DSN", "")
+	l, err := logr.New(logr.WithOutputPaths([]string{os.DevNull}), FromEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SENTRY_DSN", "not a dsn")
+	if _, err := logr.New(FromEnv()); err == nil || !strings.Contains(err.Error(), "invalid sentry DSN") {
+		t.Fatalf("expected SENTRY_DSN to be used, got: %v", err)
+	}
+}
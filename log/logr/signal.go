@@ -0,0 +1,38 @@
+package logr
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// toggleLevelOnSignal starts a goroutine that calls toggleLevel every time the configured signal is received
+func (p *PacketLogr) toggleLevelOnSignal(logger *zap.Logger) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, p.toggleSignal)
+	var once sync.Once
+	p.stopSignalToggle = func() {
+		once.Do(func() {
+			signal.Stop(sigs)
+			close(sigs)
+		})
+	}
+	go func() {
+		for range sigs {
+			p.toggleLevel()
+			logger.Info("log level toggled", zap.String("level", p.Level()))
+		}
+	}()
+}
+
+// toggleLevel switches to info if debug logs are currently enabled, otherwise it switches to debug
+func (p *PacketLogr) toggleLevel() {
+	if p.level.Enabled(zapcore.DebugLevel) {
+		p.level.SetLevel(zapcore.InfoLevel)
+		return
+	}
+	p.level.SetLevel(zapcore.DebugLevel)
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+// +build !windows
+
+package logr
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestPacketLogrSignalLevelToggle(t *testing.T) {
+	l, _, err := NewPacketLogr(WithSignalLevelToggle(syscall.SIGUSR1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl := l.(*PacketLogr)
+
+	waitForLevel := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for pl.Level() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected level to be: %v, got: %v", want, pl.Level())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	waitForLevel("debug")
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	waitForLevel("info")
+}
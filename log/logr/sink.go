@@ -0,0 +1,124 @@
+package logr
+
+import (
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newSink sets up an output added by an option, alongside the output paths.
+// It is given the config the logger is built from, so it can use the same encoder and level,
+// and wrap, which must be applied to any WriteSyncer so that WithAsyncBuffer covers it too.
+// The returned closer, if any, is closed by Close.
+type newSink func(c zap.Config, wrap func(zapcore.WriteSyncer) zapcore.WriteSyncer) (zapcore.Core, io.Closer, error)
+
+// SinkContext is what the outputs added with WithSink are opened with when the logger is built
+type SinkContext struct {
+	// Config is the config the logger is built from, so the sink can use the same encoder, see NewEncoder, and level
+	Config zap.Config
+	// Wrap must be applied to any WriteSyncer the sink writes encoded entries to, so that WithAsyncBuffer and WithLogCounter cover it too
+	Wrap func(zapcore.WriteSyncer) zapcore.WriteSyncer
+	// ServiceName is the service name of the logger, see WithServiceName
+	ServiceName string
+	// BatchPolicy is the one set with WithBatchPolicy, it is nil when it isn't set
+	BatchPolicy *BatchPolicy
+}
+
+// WithSink also writes entries to the core open returns when the logger is built, alongside the output paths,
+// for the sinks of other packages, such as the one of github.com/packethost/pkg/log/logr/fluentdsink.
+// The returned closer, if any, is closed by Close. When it is a NetworkSink or BatchSink, or embeds one, the sink is included
+// in SpoolStats and it can be the primary sink of WithSinkFailover, like the built-in remote sinks.
+func WithSink(open func(SinkContext) (zapcore.Core, io.Closer, error)) LoggerOption {
+	return func(args *PacketLogr) {
+		if open == nil {
+			args.errs = multierr.Append(args.errs, errors.New("WithSink: open must not be nil"))
+			return
+		}
+		args.newSinks = append(args.newSinks, func(c zap.Config, wrap func(zapcore.WriteSyncer) zapcore.WriteSyncer) (zapcore.Core, io.Closer, error) {
+			sc := SinkContext{Config: c, Wrap: wrap, ServiceName: args.serviceName}
+			if args.batchPolicy != nil {
+				sc.BatchPolicy = &BatchPolicy{MaxEntries: args.batchPolicy.size, MaxBytes: args.batchPolicy.bytes, MaxInterval: args.batchPolicy.interval}
+			}
+			return open(sc)
+		})
+	}
+}
+
+// writerSink is a newSink for outputs that only need the encoded entries written to them
+func writerSink(open func() (zapcore.WriteSyncer, io.Closer, error)) newSink {
+	return func(c zap.Config, wrap func(zapcore.WriteSyncer) zapcore.WriteSyncer) (zapcore.Core, io.Closer, error) {
+		ws, closer, err := open()
+		if err != nil {
+			return nil, nil, err
+		}
+		encoder, err := NewEncoder(c)
+		if err != nil {
+			return nil, nil, multierr.Append(err, closeSink(closer))
+		}
+		return zapcore.NewCore(encoder, wrap(ws), c.Level), closer, nil
+	}
+}
+
+// sinks tees the cores of the sinks in with the core built from the output paths
+func (p *PacketLogr) sinks(c zap.Config, wrap func(zapcore.WriteSyncer) zapcore.WriteSyncer) (zap.Option, error) {
+	cores := make([]zapcore.Core, 0, len(p.newSinks))
+	for _, newSink := range p.newSinks {
+		core, closer, err := newSink(c, wrap)
+		if err != nil {
+			return nil, multierr.Append(err, p.closeSinks())
+		}
+		cores = append(cores, core)
+		if closer != nil {
+			p.sinkClosers = append(p.sinkClosers, closer)
+		}
+	}
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(append([]zapcore.Core{core}, cores...)...)
+	}), nil
+}
+
+// closeSinks closes every sink that has been opened
+func (p *PacketLogr) closeSinks() error {
+	var err error
+	for _, closer := range p.sinkClosers {
+		err = multierr.Append(err, closeSink(closer))
+	}
+	p.sinkClosers = nil
+	return err
+}
+
+func closeSink(closer io.Closer) error {
+	if closer == nil {
+		return nil
+	}
+	return closer.Close()
+}
+
+// closerFunc lets a func be used as a sink's io.Closer
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+// lastErr holds the last error reported from the background by a sink until the next write returns it
+type lastErr struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (e *lastErr) set(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.err = err
+}
+
+func (e *lastErr) take() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	err := e.err
+	e.err = nil
+	return err
+}
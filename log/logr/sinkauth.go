@@ -0,0 +1,165 @@
+package logr
+
+import (
+	"crypto/tls"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+)
+
+// SinkAuth is how a sink authenticates to the remote end, see WithSinkAuth
+type SinkAuth struct {
+	// Token is sent as a bearer token, only sinks that use HTTP support it
+	Token string
+	// TokenFile is read for the token instead, and read again whenever it changes
+	TokenFile string
+	// CertFile and KeyFile are the PEM encoded client certificate and key used for mutual TLS,
+	// they are loaded again whenever either changes so that rotated certificates are picked up without a restart
+	CertFile string
+	KeyFile  string
+}
+
+// WithSinkTLS connects using TLS configured by config
+func WithSinkTLS(config *tls.Config) NetworkOption {
+	return func(c *netSinkConfig) { c.tls = config }
+}
+
+// WithSinkAuth authenticates using a bearer token or a client certificate, or both.
+// A client certificate turns TLS on, using the system roots unless WithSinkTLS is also used.
+func WithSinkAuth(auth SinkAuth) NetworkOption {
+	return func(c *netSinkConfig) {
+		c.auth = auth
+		c.cert, c.token = nil, nil
+		if auth.CertFile != "" || auth.KeyFile != "" {
+			c.cert = &fileCert{certFile: auth.CertFile, keyFile: auth.KeyFile}
+		}
+		if auth.TokenFile != "" {
+			c.token = &fileToken{path: auth.TokenFile}
+		}
+	}
+}
+
+// validateAuth checks the auth config and loads the certificate and token so that problems are found by New
+func (c netSinkConfig) validateAuth() error {
+	var err error
+	if c.auth.Token != "" && c.auth.TokenFile != "" {
+		err = multierr.Append(err, errors.New("only one of token and token file can be set"))
+	}
+	if (c.auth.Token != "" || c.auth.TokenFile != "") && !c.http {
+		err = multierr.Append(err, errors.New("token auth is only supported by sinks that use HTTP"))
+	}
+	if c.cert != nil {
+		if c.auth.CertFile == "" || c.auth.KeyFile == "" {
+			err = multierr.Append(err, errors.New("both the cert file and key file are required"))
+		} else if _, certErr := c.cert.get(nil); certErr != nil {
+			err = multierr.Append(err, certErr)
+		}
+	}
+	if c.token != nil {
+		if _, tokenErr := c.token.get(); tokenErr != nil {
+			err = multierr.Append(err, tokenErr)
+		}
+	}
+	return err
+}
+
+// tlsConfig is the TLS config to connect with, nil when TLS isn't used
+func (c netSinkConfig) tlsConfig() *tls.Config {
+	if c.cert == nil {
+		return c.tls
+	}
+	config := &tls.Config{}
+	if c.tls != nil {
+		config = c.tls.Clone()
+	}
+	config.GetClientCertificate = c.cert.get
+	return config
+}
+
+// bearerToken is the token to authenticate HTTP requests with, empty when there isn't one
+func (c netSinkConfig) bearerToken() (string, error) {
+	if c.token != nil {
+		return c.token.get()
+	}
+	return c.auth.Token, nil
+}
+
+// modTime is when the file at path was last changed
+func modTime(path string) (time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// fileCert is a client certificate that is loaded again when its files change.
+// The files are checked on each handshake, which are rare enough for a stat to not matter,
+// and the previous certificate is kept when loading fails, such as while the files are being replaced.
+type fileCert struct {
+	certFile, keyFile string
+
+	mu              sync.Mutex
+	cert            *tls.Certificate
+	certMod, keyMod time.Time
+}
+
+func (f *fileCert) get(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	certMod, certErr := modTime(f.certFile)
+	keyMod, keyErr := modTime(f.keyFile)
+	err := multierr.Append(certErr, keyErr)
+	if err == nil && f.cert != nil && certMod.Equal(f.certMod) && keyMod.Equal(f.keyMod) {
+		return f.cert, nil
+	}
+	if err == nil {
+		var cert tls.Certificate
+		if cert, err = tls.LoadX509KeyPair(f.certFile, f.keyFile); err == nil {
+			f.cert, f.certMod, f.keyMod = &cert, certMod, keyMod
+			return f.cert, nil
+		}
+	}
+	if f.cert != nil {
+		return f.cert, nil
+	}
+	return nil, errors.Wrap(err, "failed to load client certificate")
+}
+
+// fileToken is a token that is read again when its file changes, like a Kubernetes projected service account token
+type fileToken struct {
+	path string
+
+	mu    sync.Mutex
+	token string
+	mod   time.Time
+}
+
+func (f *fileToken) get() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	mod, err := modTime(f.path)
+	if err == nil && mod.Equal(f.mod) && f.token != "" {
+		return f.token, nil
+	}
+	var b []byte
+	if err == nil {
+		b, err = os.ReadFile(f.path)
+	}
+	token := strings.TrimSpace(string(b))
+	if err == nil && token == "" {
+		err = errors.Errorf("%s is empty", f.path)
+	}
+	if err != nil {
+		if f.token != "" {
+			return f.token, nil
+		}
+		return "", errors.Wrap(err, "failed to read token file")
+	}
+	f.token, f.mod = token, mod
+	return f.token, nil
+}
@@ -0,0 +1,229 @@
+package logr
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a self-signed certificate and key for name to dir
+func writeTestCert(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// touch moves the modification time of paths forward so that a change is seen even within the file system's time resolution
+func touch(t *testing.T, paths ...string) {
+	t.Helper()
+	later := time.Now().Add(time.Minute)
+	for _, path := range paths {
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func commonName(t *testing.T, cert *tls.Certificate) string {
+	t.Helper()
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Subject.CommonName
+}
+
+func TestFileCertRotation(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, "first")
+	f := &fileCert{certFile: certFile, keyFile: keyFile}
+	cert, err := f.get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := commonName(t, cert); got != "first" {
+		t.Fatalf("expected the first certificate, got: %v", got)
+	}
+
+	writeTestCert(t, dir, "second")
+	touch(t, certFile, keyFile)
+	if cert, err = f.get(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := commonName(t, cert); got != "second" {
+		t.Fatalf("expected the rotated certificate, got: %v", got)
+	}
+
+	// a half written rotation keeps the certificate that was working
+	if err := os.WriteFile(certFile, []byte("not a cert"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, certFile)
+	if cert, err = f.get(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := commonName(t, cert); got != "second" {
+		t.Fatalf("expected the previous certificate, got: %v", got)
+	}
+}
+
+func TestFileTokenRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f := &fileToken{path: path}
+	if got, err := f.get(); err != nil || got != "first" {
+		t.Fatalf("expected the token without the newline, got: %q, %v", got, err)
+	}
+	if err := os.WriteFile(path, []byte("second"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, path)
+	if got, err := f.get(); err != nil || got != "second" {
+		t.Fatalf("expected the rotated token, got: %q, %v", got, err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := f.get(); err != nil || got != "second" {
+		t.Fatalf("expected the previous token, got: %q, %v", got, err)
+	}
+}
+
+func TestPacketLogrSinkClientCert(t *testing.T) {
+	dir := t.TempDir()
+	serverCert, serverKey := writeTestCert(t, t.TempDir(), "server")
+	certFile, keyFile := writeTestCert(t, dir, "client")
+	cert, err := tls.LoadX509KeyPair(serverCert, serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peers := make(chan string, 1)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAnyClientCert,
+		VerifyPeerCertificate: func(raw [][]byte, _ [][]*x509.Certificate) error {
+			parsed, err := x509.ParseCertificate(raw[0])
+			if err == nil {
+				peers <- parsed.Subject.CommonName
+			}
+			return err
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 10)
+	go lineServer(ln, 0, lines)
+
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithLogstash(ln.Addr().String(),
+		WithSinkTLS(&tls.Config{InsecureSkipVerify: true}), //nolint:gosec // the server certificate is self-signed
+		WithSinkAuth(SinkAuth{CertFile: certFile, KeyFile: keyFile}),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("over mtls")
+	defer l.Close(context.Background())
+
+	select {
+	case line := <-lines:
+		if !strings.Contains(line, `"msg":"over mtls"`) {
+			t.Fatalf("expected the entry, got: %v", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the entry")
+	}
+	if got := <-peers; got != "client" {
+		t.Fatalf("expected the client certificate, got: %v", got)
+	}
+}
+
+func TestPacketLogrSinkToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("secret-token"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	auths := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auths <- r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"errors":false}`))
+	}))
+	defer srv.Close()
+
+	l, err := New(WithOutputPaths([]string{os.DevNull}), WithElasticsearch([]string{srv.URL},
+		WithElasticsearchNetwork(WithSinkAuth(SinkAuth{TokenFile: path}))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("with a token")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-auths; got != "Bearer secret-token" {
+		t.Fatalf("expected the bearer token, got: %v", got)
+	}
+}
+
+func TestPacketLogrSinkAuthInvalid(t *testing.T) {
+	_, err := New(
+		WithLogstash("127.0.0.1:1", WithSinkAuth(SinkAuth{Token: "t", TokenFile: "/nonexistent", KeyFile: "key.pem"})),
+		WithGELF("127.0.0.1:1", WithGELFNetwork(WithSinkTLS(&tls.Config{}))),
+	)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		"only one of token and token file can be set",
+		"token auth is only supported by sinks that use HTTP",
+		"both the cert file and key file are required",
+		"failed to read token file",
+		"WithGELF: TLS and client certificates need TCP",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, err)
+		}
+	}
+}
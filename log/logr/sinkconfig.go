@@ -0,0 +1,90 @@
+package logr
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// WithSinkConfig adds an output with its own level and encoding, alongside the output paths, so that for example
+// debug JSON goes to a local file, info console output to stdout, and only errors to a remote sink at the same time.
+// paths are opened like output paths, so they can be files, stdout or stderr, or schemes added with RegisterSink.
+// An empty level or encoding is the logger's, and the level doesn't change with SetLevel when it is set.
+// name identifies the sink in errors and must be unique.
+//
+// The output paths are still written to at the log level, set them to nil with WithOutputPaths to
+// only write to the sink configs. When WithComponentLevels is used, entries must also pass the component's level.
+func WithSinkConfig(name, level, encoding string, paths []string) LoggerOption {
+	return func(args *PacketLogr) {
+		err := validateSinkConfig(name, level, encoding, paths)
+		for _, other := range args.sinkConfigs {
+			if other == name {
+				err = multierr.Append(err, errors.Errorf("sink %q is already configured", name))
+			}
+		}
+		if err != nil {
+			args.errs = multierr.Append(args.errs, errors.WithMessage(err, "WithSinkConfig"))
+			return
+		}
+		args.sinkConfigs = append(args.sinkConfigs, name)
+		args.newSinks = append(args.newSinks, func(c zap.Config, wrap func(zapcore.WriteSyncer) zapcore.WriteSyncer) (zapcore.Core, io.Closer, error) {
+			core, closer, err := newConfiguredSink(c, wrap, level, encoding, paths)
+			return core, closer, errors.WithMessagef(err, "sink %q", name)
+		})
+	}
+}
+
+func validateSinkConfig(name, level, encoding string, paths []string) error {
+	var err error
+	if name == "" {
+		err = multierr.Append(err, errors.New("sink name must not be empty"))
+	}
+	if level != "" {
+		if _, lerr := parseLevel(level); lerr != nil {
+			err = multierr.Append(err, lerr)
+		}
+	}
+	if encoding != "" {
+		if _, eerr := NewEncoder(zap.Config{Encoding: encoding}); eerr != nil {
+			err = multierr.Append(err, eerr)
+		}
+	}
+	if len(paths) == 0 {
+		err = multierr.Append(err, errors.New("at least one sink path is required"))
+	}
+	for _, path := range paths {
+		if path == "" {
+			err = multierr.Append(err, errors.New("sink paths must not be empty"))
+			break
+		}
+	}
+	return errors.WithMessagef(err, "sink %q", name)
+}
+
+func newConfiguredSink(c zap.Config, wrap func(zapcore.WriteSyncer) zapcore.WriteSyncer, level, encoding string, paths []string) (zapcore.Core, io.Closer, error) {
+	if level != "" {
+		lvl, err := parseLevel(level)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.Level = zap.NewAtomicLevelAt(lvl)
+	}
+	if encoding != "" {
+		c.Encoding = encoding
+	}
+	encoder, err := NewEncoder(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	ws, closePaths, err := zap.Open(sliceDedupe(paths)...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to open sink paths")
+	}
+	return zapcore.NewCore(encoder, wrap(ws), c.Level), closerFunc(func() error {
+		closePaths()
+		return nil
+	}), nil
+}
@@ -0,0 +1,76 @@
+package logr
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPacketLogrSinkConfig(t *testing.T) {
+	dir := t.TempDir()
+	debugFile, infoFile, errorFile := filepath.Join(dir, "debug.log"), filepath.Join(dir, "info.log"), filepath.Join(dir, "error.log")
+	l, err := New(
+		WithOutputPaths(nil),
+		WithSinkConfig("debug", "debug", "json", []string{debugFile}),
+		WithSinkConfig("info", "", "console", []string{infoFile}),
+		WithSinkConfig("errors", "error", "", []string{errorFile}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.V(1).Info("debugging")
+	l.Info("informing")
+	l.Error(nil, "failing")
+	if err := l.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(path string) string {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	debug, info, errs := read(debugFile), read(infoFile), read(errorFile)
+	for _, want := range []string{`"msg":"debugging"`, `"msg":"informing"`, `"msg":"failing"`} {
+		if !strings.Contains(debug, want) {
+			t.Fatalf("expected the debug sink to contain: %v, got: %v", want, debug)
+		}
+	}
+	if strings.Contains(info, "debugging") || !strings.Contains(info, "\tinfo\t") || !strings.Contains(info, "informing") {
+		t.Fatalf("expected info and above in console encoding, got: %v", info)
+	}
+	if strings.Contains(errs, "informing") || !strings.Contains(errs, `"msg":"failing"`) {
+		t.Fatalf("expected only errors, got: %v", errs)
+	}
+}
+
+func TestPacketLogrSinkConfigInvalid(t *testing.T) {
+	_, err := New(
+		WithSinkConfig("a", "", "", []string{os.DevNull}),
+		WithSinkConfig("a", "", "", []string{os.DevNull}),
+		WithSinkConfig("b", "loud", "yaml", nil),
+		WithSinkConfig("", "", "", []string{""}),
+	)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		`WithSinkConfig: sink "b": failed to parse log level: unrecognized level: "loud"`,
+		`unknown encoding: "yaml"`,
+		"at least one sink path is required",
+		`sink "a" is already configured`,
+		"sink name must not be empty",
+		"sink paths must not be empty",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, err)
+		}
+	}
+
+	if _, err := New(WithOutputPaths(nil)); err == nil || !strings.Contains(err.Error(), "at least one output path is required") {
+		t.Fatalf("expected output paths to be required without sink configs, got: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package logr
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPacketLogrSlogHandler(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		pl, err := New(WithServiceName("slog"), WithLogLevel("info"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := slog.New(pl.SlogHandler())
+		l.Debug("not logged")
+		l.With("request", 1).WithGroup("http").Info("slog message",
+			"status", 200,
+			"took", time.Second,
+			slog.Group("client", "ip", "127.0.0.1"),
+			slog.Group("", "inlined", true),
+		)
+		l.Warn("slog warning")
+		l.Error("slog error", "err", errors.New("boom"))
+	})
+	if strings.Count(capturedOutput, `"stacktrace"`) != 1 {
+		t.Fatalf("expected only the error to have a stacktrace, got: %v", capturedOutput)
+	}
+	if strings.Contains(capturedOutput, "not logged") {
+		t.Fatalf("expected debug to be filtered, got: %v", capturedOutput)
+	}
+	for _, want := range []string{
+		`"level":"info"`,
+		`"service":"slog"`,
+		`"caller":"logr/slog_test.go:`,
+		`"msg":"slog message","service":"slog","request":1,"http":{"status":200,"took":1,"client":{"ip":"127.0.0.1"},"inlined":true}`,
+		`"level":"warn","ts":`,
+		`"level":"error","ts":`,
+		`"err":"boom"`,
+		`"stacktrace":"github.com/packethost/pkg/log/logr.TestPacketLogrSlogHandler.func1\n\t`,
+	} {
+		if !strings.Contains(capturedOutput, want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+		}
+	}
+}
+
+func TestSlogLevel(t *testing.T) {
+	for level, want := range map[slog.Level]string{
+		slog.LevelDebug - 1: "trace",
+		slog.LevelDebug:     "debug",
+		slog.LevelInfo:      "info",
+		slog.LevelInfo + 1:  "info",
+		slog.LevelWarn:      "warn",
+		slog.LevelError:     "error",
+		slog.LevelError + 4: "error",
+	} {
+		if got := levelName(slogLevel(level)); got != want {
+			t.Fatalf("slog level %v: expected %v, got %v", level, want, got)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package logr
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// spoolSegmentSize is how big a spool file gets before the next one is started, files are removed once all of it is replayed
+	spoolSegmentSize = 4 * 1024 * 1024
+	spoolExt         = ".spool"
+	// spoolHeaderSize is the length prefix of each message
+	spoolHeaderSize = 4
+)
+
+// spoolReportInterval is how often a warning is logged while sinks have entries spooled
+var spoolReportInterval = time.Minute
+
+// SpoolStats are how many entries a remote sink has spooled to disk, see WithSinkSpool
+type SpoolStats struct {
+	Sink    string
+	Entries int
+	Bytes   int64
+	// Dropped is how many entries the sink has dropped, whether because the spool was full or the queue was and there is no spool
+	Dropped uint64
+}
+
+// WithSinkSpool writes entries to files in dir, instead of dropping them, when the queue is full, such as while the remote end
+// is unreachable, and when Close gives up waiting for them to be sent. They are sent once the queue has been emptied,
+// including by the next process using dir, so they arrive after newer entries. Entries are dropped once the spool holds maxBytes.
+// Every sink needs its own dir. A warning with the spool depth is logged every minute while there are entries spooled, see SpoolStats.
+func WithSinkSpool(dir string, maxBytes int64) NetworkOption {
+	return func(c *netSinkConfig) {
+		c.spoolDir = dir
+		c.spoolBytes = maxBytes
+	}
+}
+
+// validateSpool checks the spool config
+func (c netSinkConfig) validateSpool() error {
+	if c.spoolDir == "" && c.spoolBytes == 0 {
+		return nil
+	}
+	if c.spoolDir == "" || c.spoolBytes < 1 {
+		return errors.Errorf("spool dir must not be empty and max bytes must be > 0, got: %q and %d", c.spoolDir, c.spoolBytes)
+	}
+	return nil
+}
+
+// openSpool opens the spool configured by WithSinkSpool, it is nil when there isn't one
+func (c netSinkConfig) openSpool() (*diskSpool, error) {
+	if c.spoolDir == "" {
+		return nil, nil
+	}
+	return newDiskSpool(c.spoolDir, c.spoolBytes)
+}
+
+// diskSpool is a bounded queue of messages in files, each message is prefixed by its length.
+// Messages are pushed to the newest file and files are popped whole, oldest first.
+type diskSpool struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	w       *bufio.Writer
+	f       *os.File
+	fSize   int64
+	seq     uint64
+	bytes   int64
+	entries int
+	dropped atomic.Uint64
+}
+
+func newDiskSpool(dir string, maxBytes int64) (*diskSpool, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, errors.Wrap(err, "failed to create spool dir")
+	}
+	s := &diskSpool{dir: dir, maxBytes: maxBytes}
+	files, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+	// count what was left by a previous process so it is replayed and counts against the limit
+	for _, file := range files {
+		r, err := s.openFile(file.path)
+		if err != nil {
+			return nil, err
+		}
+		for r.skip() {
+		}
+		_ = r.close()
+		s.entries += r.entries
+		s.bytes += r.size
+		s.seq = file.seq
+	}
+	return s, nil
+}
+
+type spoolFile struct {
+	path string
+	seq  uint64
+}
+
+// files are the spool files, oldest first
+func (s *diskSpool) files() ([]spoolFile, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*"+spoolExt))
+	if err != nil {
+		return nil, err
+	}
+	files := make([]spoolFile, 0, len(paths))
+	for _, path := range paths {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), spoolExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		files = append(files, spoolFile{path: path, seq: seq})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].seq < files[j].seq })
+	return files, nil
+}
+
+// spoolReader reads the messages of a spool file one at a time. It stops at the first one cut short, such as by a crash,
+// or with a length that can't be right, longer than the spool or the rest of the file, as what follows can't be trusted.
+type spoolReader struct {
+	s         *diskSpool
+	path      string
+	f         *os.File
+	r         *bufio.Reader
+	remaining int64
+	header    []byte
+	// entries and size are what has been read so far
+	entries int
+	size    int64
+}
+
+func (s *diskSpool) openFile(path string) (*spoolReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open spool file")
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrap(err, "failed to stat spool file")
+	}
+	return &spoolReader{s: s, path: path, f: f, r: bufio.NewReader(f), remaining: info.Size(), header: make([]byte, spoolHeaderSize)}, nil
+}
+
+// length reads the header of the next message, ok is false once there are no more to trust
+func (r *spoolReader) length() (n int64, ok bool) {
+	if r.remaining < spoolHeaderSize {
+		return 0, false
+	}
+	if _, err := io.ReadFull(r.r, r.header); err != nil {
+		return 0, false
+	}
+	r.remaining -= spoolHeaderSize
+	n = int64(binary.BigEndian.Uint32(r.header))
+	if n > r.remaining || n+spoolHeaderSize > r.s.maxBytes {
+		r.remaining = 0
+		return 0, false
+	}
+	return n, true
+}
+
+// next returns the next message, ok is false once there are no more
+func (r *spoolReader) next() (msg []byte, ok bool) {
+	n, ok := r.length()
+	if !ok {
+		return nil, false
+	}
+	msg = make([]byte, n)
+	if _, err := io.ReadFull(r.r, msg); err != nil {
+		r.remaining = 0
+		return nil, false
+	}
+	r.read(n)
+	return msg, true
+}
+
+// skip skips the next message, ok is false once there are no more
+func (r *spoolReader) skip() bool {
+	n, ok := r.length()
+	if !ok {
+		return false
+	}
+	if _, err := r.r.Discard(int(n)); err != nil {
+		r.remaining = 0
+		return false
+	}
+	r.read(n)
+	return true
+}
+
+func (r *spoolReader) read(n int64) {
+	r.remaining -= n
+	r.entries++
+	r.size += spoolHeaderSize + n
+}
+
+// close closes the file without removing it, so it is replayed again
+func (r *spoolReader) close() error {
+	return r.f.Close()
+}
+
+// done closes and removes the file once all of its messages have been sent
+func (r *spoolReader) done() error {
+	for r.skip() {
+	}
+	r.f.Close()
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.entries -= r.entries
+	r.s.bytes -= r.size
+	return errors.Wrap(os.Remove(r.path), "failed to remove replayed spool file")
+}
+
+// push adds msg to the newest file, it is dropped when the spool is full
+func (s *diskSpool) push(msg []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	size := int64(spoolHeaderSize + len(msg))
+	if s.bytes+size > s.maxBytes {
+		s.dropped.Add(1)
+		return errors.Errorf("spool is full with %d bytes, dropped the message", s.bytes)
+	}
+	if s.f == nil || s.fSize >= spoolSegmentSize {
+		if err := s.closeFile(); err != nil {
+			return err
+		}
+		s.seq++
+		f, err := os.OpenFile(filepath.Join(s.dir, fmt.Sprintf("%020d%s", s.seq, spoolExt)), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+		if err != nil {
+			return errors.Wrap(err, "failed to create spool file")
+		}
+		s.f, s.w, s.fSize = f, bufio.NewWriter(f), 0
+	}
+	header := make([]byte, spoolHeaderSize)
+	binary.BigEndian.PutUint32(header, uint32(len(msg)))
+	if _, err := s.w.Write(header); err != nil {
+		return errors.Wrap(err, "failed to write to spool")
+	}
+	if _, err := s.w.Write(msg); err != nil {
+		return errors.Wrap(err, "failed to write to spool")
+	}
+	s.fSize += size
+	s.bytes += size
+	s.entries++
+	return nil
+}
+
+// closeFile flushes and closes the file being pushed to, mu must be held
+func (s *diskSpool) closeFile() error {
+	if s.f == nil {
+		return nil
+	}
+	err := s.w.Flush()
+	if closeErr := s.f.Close(); err == nil {
+		err = closeErr
+	}
+	s.f, s.w = nil, nil
+	return errors.Wrap(err, "failed to close spool file")
+}
+
+// pending is whether there are messages to replay
+func (s *diskSpool) pending() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries > 0
+}
+
+// pop opens the oldest file for its messages to be read, it is removed by calling done on the reader once they have been sent.
+// The reader is nil when there are no files.
+func (s *diskSpool) pop() (*spoolReader, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files, err := s.files()
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+	file := files[0]
+	if s.f != nil && s.f.Name() == file.path {
+		if err := s.closeFile(); err != nil {
+			return nil, err
+		}
+	}
+	return s.openFile(file.path)
+}
+
+func (s *diskSpool) stats(name string) SpoolStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SpoolStats{Sink: name, Entries: s.entries, Bytes: s.bytes, Dropped: s.dropped.Load()}
+}
+
+// close flushes what has been pushed to disk
+func (s *diskSpool) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeFile()
+}
+
+// spooler is a sink that can spool to disk
+type spooler interface {
+	spoolStats() (SpoolStats, bool)
+}
+
+// SpoolStats returns how many entries each remote sink that uses WithSinkSpool has spooled to disk,
+// and how many events each error reporter has with WithErrorReporterSpool
+func (p *PacketLogr) SpoolStats() []SpoolStats {
+	return spoolStats(p.spoolers())
+}
+
+func (p *PacketLogr) spoolers() []spooler {
+	var spoolers []spooler
+	add := func(v interface{}) {
+		if s, ok := v.(spooler); ok {
+			if _, ok := s.spoolStats(); ok {
+				spoolers = append(spoolers, s)
+			}
+		}
+	}
+	for _, closer := range p.sinkClosers {
+		add(closer)
+	}
+	for _, r := range p.reporters {
+		if d, ok := r.(*dedupeReporter); ok {
+			r = d.ErrorReporter
+		}
+		add(r)
+	}
+	for _, q := range p.reporterQueues {
+		add(q)
+	}
+	return spoolers
+}
+
+func spoolStats(spoolers []spooler) []SpoolStats {
+	var stats []SpoolStats
+	for _, s := range spoolers {
+		st, _ := s.spoolStats()
+		stats = append(stats, st)
+	}
+	return stats
+}
+
+// reportSpools logs a warning every spoolReportInterval while sinks have entries spooled, until stopSpoolReports is called
+func (p *PacketLogr) reportSpools() {
+	spoolers := p.spoolers()
+	if len(spoolers) == 0 {
+		return
+	}
+	stop := make(chan struct{})
+	var once sync.Once
+	p.stopSpoolReports = func() { once.Do(func() { close(stop) }) }
+	go func() {
+		ticker := time.NewTicker(spoolReportInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				for _, st := range spoolStats(spoolers) {
+					if st.Entries > 0 {
+						p.zap.Warn("remote sink has entries spooled to disk", zap.String("sink", st.Sink), zap.Int("spooled_entries", st.Entries), zap.Int64("spooled_bytes", st.Bytes), zap.Uint64("dropped", st.Dropped))
+					}
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,199 @@
+package logr
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDiskSpool(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newDiskSpool(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []string{"one", "two", "three"} {
+		if err := s.push([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new spool picks up what the previous one left
+	s, err = newDiskSpool(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.stats("test"); got.Entries != 3 || got.Bytes != 23 {
+		t.Fatalf("expected 3 entries in 23 bytes, got: %+v", got)
+	}
+	if err := s.push(make([]byte, 100)); err == nil || !strings.Contains(err.Error(), "spool is full") {
+		t.Fatalf("expected the spool to be full, got: %v", err)
+	}
+	if err := s.push([]byte("four")); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for s.pending() {
+		r, err := s.pop()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for msg, ok := r.next(); ok; msg, ok = r.next() {
+			got = append(got, string(msg))
+		}
+		if err := r.done(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strings.Join(got, ",") != "one,two,three,four" {
+		t.Fatalf("expected the messages oldest first, got: %v", got)
+	}
+	if stats := s.stats("test"); stats.Entries != 0 || stats.Bytes != 0 || stats.Dropped != 1 {
+		t.Fatalf("expected an empty spool with 1 dropped, got: %+v", stats)
+	}
+	if files, _ := s.files(); len(files) != 0 {
+		t.Fatalf("expected the files to be removed, got: %v", files)
+	}
+}
+
+func TestDiskSpoolCorrupt(t *testing.T) {
+	for name, tail := range map[string][]byte{
+		"cut short":        {0, 0, 0, 9, 'c', 'u', 't'},
+		"longer than file": {0, 0, 0, 4, 'b', 'a', 'd', '!', 0, 0, 0, 200, 'x'},
+		"longer than max":  {0xff, 0xff, 0xff, 0xff, 'x'},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			// "ok" and then the records that can't be trusted
+			file := append([]byte{0, 0, 0, 2, 'o', 'k'}, tail...)
+			if err := os.WriteFile(filepath.Join(dir, "00000000000000000001"+spoolExt), file, 0o640); err != nil {
+				t.Fatal(err)
+			}
+			s, err := newDiskSpool(dir, 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := SpoolStats{Sink: "test", Entries: 1, Bytes: 6}
+			if name == "longer than file" {
+				want = SpoolStats{Sink: "test", Entries: 2, Bytes: 14}
+			}
+			if got := s.stats("test"); got != want {
+				t.Fatalf("expected: %+v, got: %+v", want, got)
+			}
+			r, err := s.pop()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for msg, ok := r.next(); ok; msg, ok = r.next() {
+				got = append(got, string(msg))
+			}
+			if err := r.done(); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != want.Entries || got[0] != "ok" {
+				t.Fatalf("expected the messages up to the bad one, got: %q", got)
+			}
+			if stats := s.stats("test"); stats.Entries != 0 || stats.Bytes != 0 {
+				t.Fatalf("expected an empty spool, got: %+v", stats)
+			}
+		})
+	}
+}
+
+func TestNetSinkSpoolReplays(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 10)
+	go lineServer(ln, 0, lines)
+
+	var up atomic.Bool
+	c := newNetSinkConfig([]NetworkOption{WithNetworkQueue(1, time.Millisecond), WithSinkSpool(t.TempDir(), 1024)})
+	dial := c.dialer("tcp", ln.Addr().String())
+	s, err := newNetSink("test", c, func() (net.Conn, error) {
+		if !up.Load() {
+			return nil, net.ErrClosed
+		}
+		return dial()
+	}, func(conn net.Conn, msg []byte) error {
+		_, err := conn.Write(msg)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	want := []string{"1\n", "2\n", "3\n", "4\n", "5\n"}
+	for _, msg := range want {
+		// the dial errors are returned, but nothing is dropped
+		_ = s.write([]byte(msg))
+	}
+	if stats, _ := s.spoolStats(); stats.Entries != 3 || stats.Dropped != 0 {
+		t.Fatalf("expected 3 spooled entries, got: %+v", stats)
+	}
+
+	up.Store(true)
+	var got []string
+	for range want {
+		select {
+		case line := <-lines:
+			got = append(got, line)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the spooled entries, got: %v", got)
+		}
+	}
+	sort.Strings(got)
+	if strings.Join(got, "") != strings.Join(want, "") {
+		t.Fatalf("expected: %v, got: %v", want, got)
+	}
+	if stats, _ := s.spoolStats(); stats.Entries != 0 {
+		t.Fatalf("expected the spool to be empty, got: %+v", stats)
+	}
+}
+
+func TestPacketLogrSpoolReports(t *testing.T) {
+	defer func(interval time.Duration) { spoolReportInterval = interval }(spoolReportInterval)
+	spoolReportInterval = 10 * time.Millisecond
+	out, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+
+	dir := t.TempDir()
+	l, err := New(WithOutputPaths([]string{out.Name()}), WithLogstash("127.0.0.1:1", WithNetworkQueue(1, time.Millisecond), WithSinkSpool(dir, 1<<20)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		l.Info("spooled")
+	}
+	stats := l.SpoolStats()
+	if len(stats) != 1 || stats[0].Sink != "logstash" || stats[0].Entries == 0 {
+		t.Fatalf("expected spooled entries, got: %+v", stats)
+	}
+	time.Sleep(50 * time.Millisecond)
+	_ = l.Close(context.Background())
+
+	b, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"msg":"remote sink has entries spooled to disk"`) || !strings.Contains(string(b), `"sink":"logstash","spooled_entries":`) {
+		t.Fatalf("expected the spool warning, got: %v", string(b))
+	}
+}
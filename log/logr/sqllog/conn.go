@@ -0,0 +1,187 @@
+package sqllog
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+)
+
+// wrappedConn logs the queries of a driver.Conn. It implements the optional interfaces of database/sql, falling back like
+// database/sql does when the wrapped conn doesn't, so wrapping it changes nothing else.
+type wrappedConn struct {
+	driver.Conn
+	c config
+}
+
+func (w *wrappedConn) Prepare(query string) (driver.Stmt, error) {
+	return w.PrepareContext(context.Background(), query)
+}
+
+func (w *wrappedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := w.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = w.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedStmt{Stmt: stmt, conn: w, query: query, c: w.c}, nil
+}
+
+func (w *wrappedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := w.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != 0 || opts.ReadOnly {
+		return nil, errors.New("sqllog: driver does not support non-default transaction options")
+	}
+	return w.Conn.Begin() //nolint:staticcheck // the fallback of drivers without BeginTx
+}
+
+func (w *wrappedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := w.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := e.ExecContext(ctx, query, args)
+	w.c.log(ctx, start, query, args, rowsAffected(res, err), err)
+	return res, err
+}
+
+func (w *wrappedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := w.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	w.c.log(ctx, start, query, args, -1, err)
+	return rows, err
+}
+
+func (w *wrappedConn) Ping(ctx context.Context) error {
+	if p, ok := w.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (w *wrappedConn) ResetSession(ctx context.Context) error {
+	if r, ok := w.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (w *wrappedConn) IsValid() bool {
+	if v, ok := w.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (w *wrappedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if c, ok := w.Conn.(driver.NamedValueChecker); ok {
+		return c.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// wrappedStmt logs the execs and queries of a prepared statement
+type wrappedStmt struct {
+	driver.Stmt
+	conn  *wrappedConn
+	query string
+	c     config
+}
+
+func (w *wrappedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return w.ExecContext(context.Background(), namedValues(args))
+}
+
+func (w *wrappedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return w.QueryContext(context.Background(), namedValues(args))
+}
+
+func (w *wrappedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var res driver.Result
+	var err error
+	if e, ok := w.Stmt.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else if values, vErr := values(args); vErr != nil {
+		err = vErr
+	} else {
+		res, err = w.Stmt.Exec(values) //nolint:staticcheck // the fallback of drivers without ExecContext
+	}
+	w.c.log(ctx, start, w.query, args, rowsAffected(res, err), err)
+	return res, err
+}
+
+func (w *wrappedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if q, ok := w.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else if values, vErr := values(args); vErr != nil {
+		err = vErr
+	} else {
+		rows, err = w.Stmt.Query(values) //nolint:staticcheck // the fallback of drivers without QueryContext
+	}
+	w.c.log(ctx, start, w.query, args, -1, err)
+	return rows, err
+}
+
+// CheckNamedValue is the wrapped stmt's, or else the conn's, as database/sql only asks the conn when the stmt has none
+func (w *wrappedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if c, ok := w.Stmt.(driver.NamedValueChecker); ok {
+		return c.CheckNamedValue(nv)
+	}
+	return w.conn.CheckNamedValue(nv)
+}
+
+// ColumnConverter is the wrapped stmt's, or else the default one database/sql uses without one
+func (w *wrappedStmt) ColumnConverter(idx int) driver.ValueConverter {
+	if c, ok := w.Stmt.(driver.ColumnConverter); ok { //nolint:staticcheck // for the drivers that still have one
+		return c.ColumnConverter(idx)
+	}
+	return driver.DefaultParameterConverter
+}
+
+// rowsAffected is the rows affected of res, or -1 when it doesn't have them
+func rowsAffected(res driver.Result, err error) int64 {
+	if err != nil || res == nil {
+		return -1
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+// values is args without their names, like database/sql does for drivers that don't take named values
+func values(args []driver.NamedValue) ([]driver.Value, error) {
+	vs := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("sqllog: driver does not support the use of Named Parameters")
+		}
+		vs[i] = arg.Value
+	}
+	return vs, nil
+}
@@ -0,0 +1,135 @@
+// Package sqllog logs the queries of database/sql drivers with the logger of their context, from logr.FromContext,
+// so the queries made while handling a request have its fields, by wrapping the driver:
+//
+//	sql.Register("postgres-logged", sqllog.Wrap(&pq.Driver{}))
+//	db, err := sql.Open("postgres-logged", dsn)
+//
+// or, for drivers with a connector:
+//
+//	db := sql.OpenDB(sqllog.WrapConnector(connector))
+package sqllog
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+
+	"github.com/packethost/pkg/log/logr"
+)
+
+// defaultSlowThreshold is how long a query takes before it is logged as slow, see WithSlowThreshold
+const defaultSlowThreshold = 500 * time.Millisecond
+
+// debug is the verbosity queries are logged at, the slow ones are logged at warn
+const debug = 1
+
+// redacted replaces the args of queries unless WithArgs is used
+const redacted = "[REDACTED]"
+
+// Option customizes Wrap and WrapConnector
+type Option func(*config)
+
+type config struct {
+	slow time.Duration
+	args bool
+}
+
+func newConfig(opts []Option) config {
+	c := config{slow: defaultSlowThreshold}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// WithSlowThreshold logs the queries taking longer than d as slow queries, at warn, instead of the ones taking longer than 500ms.
+// 0 turns it off.
+func WithSlowThreshold(d time.Duration) Option {
+	return func(c *config) { c.slow = d }
+}
+
+// WithArgs logs the values of the args of queries, they are redacted by default as they may be personal data or secrets
+func WithArgs() Option {
+	return func(c *config) { c.args = true }
+}
+
+// Wrap returns a driver opening the connections of d, and logging their queries, execs, and prepared statements once they
+// are done at debug, with the query, args, rows_affected for execs, and duration, and the error when they failed.
+// The queries that are slower than the threshold of WithSlowThreshold are logged at warn instead.
+func Wrap(d driver.Driver, opts ...Option) driver.Driver {
+	return &wrappedDriver{Driver: d, c: newConfig(opts)}
+}
+
+// WrapConnector returns a connector whose connections log their queries like the ones of Wrap do, for sql.OpenDB
+func WrapConnector(connector driver.Connector, opts ...Option) driver.Connector {
+	return &wrappedConnector{Connector: connector, c: newConfig(opts)}
+}
+
+type wrappedDriver struct {
+	driver.Driver
+	c config
+}
+
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedConn{Conn: conn, c: d.c}, nil
+}
+
+type wrappedConnector struct {
+	driver.Connector
+	c config
+}
+
+func (c *wrappedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedConn{Conn: conn, c: c.c}, nil
+}
+
+// Driver returns the wrapped driver, its Open doesn't log as sql.OpenDB doesn't use it
+func (c *wrappedConnector) Driver() driver.Driver { return c.Connector.Driver() }
+
+// log logs query, which took since start and failed with err when it isn't nil. rowsAffected is logged when it isn't negative.
+func (c config) log(ctx context.Context, start time.Time, query string, args []driver.NamedValue, rowsAffected int64, err error) {
+	if err == driver.ErrSkip {
+		// database/sql makes it again another way
+		return
+	}
+	duration := time.Since(start)
+	kvs := []interface{}{"query", query, "args", c.logArgs(args), "duration", duration}
+	if rowsAffected >= 0 {
+		kvs = append(kvs, "rows_affected", rowsAffected)
+	}
+	if err != nil {
+		kvs = append(kvs, "error", err.Error())
+	}
+	l := logr.FromContext(ctx)
+	if c.slow > 0 && duration > c.slow {
+		logr.Warn(l, "slow query", kvs...)
+		return
+	}
+	l.V(debug).Info("query", kvs...)
+}
+
+// logArgs are the args that are logged, redacted unless WithArgs is used
+func (c config) logArgs(args []driver.NamedValue) []interface{} {
+	logged := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch v := arg.Value.(type) {
+		case nil:
+		case []byte:
+			logged[i] = string(v)
+		default:
+			logged[i] = v
+		}
+		if !c.args && arg.Value != nil {
+			logged[i] = redacted
+		}
+	}
+	return logged
+}
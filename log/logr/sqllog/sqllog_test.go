@@ -0,0 +1,122 @@
+package sqllog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/internal/logtest"
+)
+
+// fakeDriver opens fakeConns, which only have prepared statements
+type fakeDriver struct{ execer bool }
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	if d.execer {
+		return execerConn{}, nil
+	}
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("no transactions") }
+
+// execerConn has ExecContext, so execs aren't prepared first
+type execerConn struct{ fakeConn }
+
+func (execerConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return fakeStmt{query: query}.Exec(nil)
+}
+
+type fakeStmt struct{ query string }
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	if strings.Contains(s.query, "fail") {
+		return nil, errors.New("syntax error")
+	}
+	if strings.Contains(s.query, "slow") {
+		time.Sleep(20 * time.Millisecond)
+	}
+	return driver.RowsAffected(2), nil
+}
+
+func (fakeStmt) Query([]driver.Value) (driver.Rows, error) { return fakeRows{}, nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string         { return []string{"id"} }
+func (fakeRows) Close() error              { return nil }
+func (fakeRows) Next([]driver.Value) error { return io.EOF }
+
+type fakeConnector struct{ d fakeDriver }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return c.d.Open("") }
+func (c fakeConnector) Driver() driver.Driver                        { return c.d }
+
+func TestWrap(t *testing.T) {
+	l, logged := logtest.New(t, logr.WithLogLevel("debug"))
+	sql.Register("sqllog-fake", Wrap(fakeDriver{}, WithSlowThreshold(10*time.Millisecond)))
+	db, err := sql.Open("sqllog-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := l.NewContextWithValues(context.Background(), "request_id", "r1")
+	if _, err := db.ExecContext(ctx, "update devices set state = ? where id = ?", "active", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "fail"); err == nil {
+		t.Fatal("expected the exec to fail")
+	}
+	if _, err := db.ExecContext(ctx, "slow", nil); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.QueryContext(ctx, "select id from devices")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	got := logged()
+	for _, want := range []string{
+		`"level":"debug","ts":`,
+		`"msg":"query","service":"not/set","request_id":"r1","query":"update devices set state = ? where id = ?","args":["[REDACTED]","[REDACTED]"]`,
+		`"rows_affected":2`,
+		`"query":"fail","args":[]`,
+		`"error":"syntax error"`,
+		`"level":"warn"`,
+		`"msg":"slow query","service":"not/set","request_id":"r1","query":"slow","args":[null]`,
+		`"query":"select id from devices"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %s to be logged, got: %v", want, got)
+		}
+	}
+}
+
+func TestWrapConnector(t *testing.T) {
+	l, logged := logtest.New(t, logr.WithLogLevel("debug"))
+	defer l.SetAsFallback()()
+	db := sql.OpenDB(WrapConnector(fakeConnector{fakeDriver{execer: true}}, WithArgs(), WithSlowThreshold(0)))
+	defer db.Close()
+	if _, err := db.Exec("slow", "active", []byte("raw")); err != nil {
+		t.Fatal(err)
+	}
+	if got := logged(); !strings.Contains(got, `"msg":"query","service":"not/set","query":"slow","args":["active","raw"]`) ||
+		strings.Contains(got, "slow query") || strings.Count(got, `"msg":"query"`) != 1 {
+		t.Fatalf("expected the exec to be logged once, with its args, by the fallback, got: %v", got)
+	}
+}
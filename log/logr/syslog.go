@@ -0,0 +1,198 @@
+package logr
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// syslogFacilities are the facility codes from RFC 5424
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogLocalSockets are where the local syslog daemon usually listens
+var syslogLocalSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogOption configures WithSyslog
+type SyslogOption func(*syslogConfig)
+
+type syslogConfig struct {
+	network   string
+	addr      string
+	facility  int
+	tag       string
+	rfc5424   bool
+	netConfig netSinkConfig
+}
+
+// WithSyslogRFC5424 formats messages using RFC 5424 instead of the default RFC 3164 (BSD syslog).
+// Over TCP they are framed using octet counting, RFC 3164 messages are newline terminated.
+func WithSyslogRFC5424() SyslogOption {
+	return func(c *syslogConfig) { c.rfc5424 = true }
+}
+
+// WithSyslogNetwork configures TLS and client certificates, which need the tcp network, and queueing, see NetworkOption
+func WithSyslogNetwork(opts ...NetworkOption) SyslogOption {
+	return func(c *syslogConfig) {
+		for _, opt := range opts {
+			opt(&c.netConfig)
+		}
+	}
+}
+
+// WithSyslog also sends entries to syslog, network and addr are as for net.Dial, leave both empty to use the local syslog daemon.
+// facility is one of kern, user, mail, daemon, auth, syslog, lpr, news, uucp, cron, authpriv, ftp, or local0 to local7,
+// and tag is the app name, the name of the binary when empty. The message is the encoded entry and the severity is mapped from its level.
+func WithSyslog(network, addr, facility, tag string, opts ...SyslogOption) LoggerOption {
+	return func(args *PacketLogr) {
+		code, ok := syslogFacilities[facility]
+		if !ok {
+			args.errs = multierr.Append(args.errs, errors.Errorf("WithSyslog: unknown facility: %q", facility))
+			return
+		}
+		if tag == "" {
+			tag = filepath.Base(os.Args[0])
+		}
+		c := syslogConfig{network: network, addr: addr, facility: code, tag: tag, netConfig: newNetSinkConfig(nil)}
+		for _, opt := range opts {
+			opt(&c)
+		}
+		if err := c.netConfig.validate(); err != nil {
+			args.errs = multierr.Append(args.errs, errors.WithMessage(err, "WithSyslog"))
+			return
+		}
+		args.newSinks = append(args.newSinks, c.newSink)
+	}
+}
+
+func (c syslogConfig) newSink(zc zap.Config, _ func(zapcore.WriteSyncer) zapcore.WriteSyncer) (zapcore.Core, io.Closer, error) {
+	encoder, err := NewEncoder(zc)
+	if err != nil {
+		return nil, nil, err
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	s, err := newNetSink("syslog", c.netConfig, c.dial, c.send)
+	if err != nil {
+		return nil, nil, err
+	}
+	f := syslogFormatter{syslogConfig: c, hostname: hostname, pid: os.Getpid()}
+	return &syslogCore{LevelEnabler: zc.Level, enc: encoder, s: s, f: f}, s, nil
+}
+
+// syslogSeverity maps zap levels to syslog severities, capped at crit for dpanic, panic, and fatal as emerg and alert
+// are for the whole system, rsyslog sends emerg to every terminal by default
+func syslogSeverity(level zapcore.Level) int {
+	switch {
+	case level >= zapcore.DPanicLevel:
+		return 2 // crit
+	case level == zapcore.ErrorLevel:
+		return 3 // err
+	case level == zapcore.WarnLevel:
+		return 4 // warning
+	case level == zapcore.InfoLevel:
+		return 6 // info
+	}
+	return 7 // debug
+}
+
+// syslogCore encodes entries and writes them to syslog with their severity
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	s   *netSink
+	f   syslogFormatter
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &syslogCore{LevelEnabler: c.LevelEnabler, enc: c.enc.Clone(), s: c.s, f: c.f}
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+	}
+	return clone
+}
+
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	return c.s.write(c.f.format(syslogSeverity(ent.Level), ent.Time, strings.TrimSuffix(buf.String(), "\n")))
+}
+
+func (c *syslogCore) Sync() error { return nil }
+
+// syslogFormatter formats messages with the syslog header
+type syslogFormatter struct {
+	syslogConfig
+	hostname string
+	pid      int
+}
+
+// local is true when writing to the local syslog daemon, which adds the hostname itself
+func (f syslogFormatter) local() bool {
+	return f.network == "" && f.addr == ""
+}
+
+func (f syslogFormatter) format(severity int, t time.Time, msg string) []byte {
+	priority := f.facility*8 + severity
+	if f.rfc5424 {
+		return []byte(fmt.Sprintf("<%d>1 %s %s %s %d - - %s", priority, t.Format("2006-01-02T15:04:05.000000Z07:00"), f.hostname, f.tag, f.pid, msg))
+	}
+	if f.local() {
+		return []byte(fmt.Sprintf("<%d>%s %s[%d]: %s", priority, t.Format(time.Stamp), f.tag, f.pid, msg))
+	}
+	return []byte(fmt.Sprintf("<%d>%s %s %s[%d]: %s", priority, t.Format(time.Stamp), f.hostname, f.tag, f.pid, msg))
+}
+
+// dial connects to the syslog daemon at the configured address, or the local one
+func (c syslogConfig) dial() (net.Conn, error) {
+	if c.network != "" || c.addr != "" {
+		return c.netConfig.dialer(c.network, c.addr)()
+	}
+	dialer := &net.Dialer{Timeout: netSinkDialTimeout}
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range syslogLocalSockets {
+			if conn, err := dialer.Dial(network, path); err == nil {
+				return conn, nil
+			}
+		}
+	}
+	return nil, errors.New("failed to connect to the local syslog daemon")
+}
+
+// send frames msg when the connection is a stream, with octet counting for RFC 5424 or a newline for RFC 3164
+func (c syslogConfig) send(conn net.Conn, msg []byte) error {
+	switch conn.LocalAddr().Network() {
+	case "tcp", "tcp4", "tcp6", "unix":
+		if c.rfc5424 {
+			msg = append([]byte(fmt.Sprintf("%d ", len(msg))), msg...)
+		} else {
+			msg = append(msg, '\n')
+		}
+	}
+	_, err := conn.Write(msg)
+	return err
+}
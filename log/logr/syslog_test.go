@@ -0,0 +1,115 @@
+package logr
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestPacketLogrSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	pl, err := New(WithSyslog("udp", conn.LocalAddr().String(), "local3", "myapp"), WithOutputPaths([]string{os.DevNull}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pl.Close(context.Background())
+	pl.Error(nil, "syslog error")
+
+	buf := make([]byte, 4096)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(buf[:n])
+	// local3 is 19, error is 3
+	for _, want := range []string{"<155>", fmt.Sprintf(" myapp[%d]: {", os.Getpid()), `"msg":"syslog error"`} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, got)
+		}
+	}
+	if strings.HasSuffix(got, "\n") {
+		t.Fatalf("expected datagrams not to be framed, got: %q", got)
+	}
+}
+
+func TestPacketLogrSyslogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			var n int
+			if _, err := fmt.Fscanf(r, "%d ", &n); err != nil {
+				return
+			}
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(r, msg); err != nil {
+				return
+			}
+			lines <- string(msg)
+		}
+	}()
+
+	pl, err := New(WithSyslog("tcp", ln.Addr().String(), "daemon", "", WithSyslogRFC5424()), WithOutputPaths([]string{os.DevNull}), WithLogLevel("debug"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pl.Close(context.Background())
+	pl.V(1).Info("first")
+	pl.Zap().Warn("second")
+
+	// daemon is 3, debug is 7 and warning is 4
+	for _, want := range []string{"<31>1 ", "<28>1 "} {
+		got := <-lines
+		if !strings.HasPrefix(got, want) {
+			t.Fatalf("expected prefix: %v, got: %v", want, got)
+		}
+		if !strings.Contains(got, fmt.Sprintf(" %d - - {", os.Getpid())) {
+			t.Fatalf("expected RFC 5424 header, got: %v", got)
+		}
+	}
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	for level, want := range map[zapcore.Level]int{
+		traceLevel:          7,
+		zapcore.DebugLevel:  7,
+		zapcore.InfoLevel:   6,
+		zapcore.WarnLevel:   4,
+		zapcore.ErrorLevel:  3,
+		zapcore.DPanicLevel: 2,
+		zapcore.PanicLevel:  2,
+		zapcore.FatalLevel:  2,
+	} {
+		if got := syslogSeverity(level); got != want {
+			t.Fatalf("level %v: expected %d, got %d", level, want, got)
+		}
+	}
+}
+
+func TestPacketLogrSyslogInvalid(t *testing.T) {
+	if _, err := New(WithSyslog("udp", "127.0.0.1:514", "nope", "")); err == nil || !strings.Contains(err.Error(), `unknown facility: "nope"`) {
+		t.Fatalf("expected an unknown facility error, got: %v", err)
+	}
+}
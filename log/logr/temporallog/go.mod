@@ -0,0 +1,22 @@
+module github.com/packethost/pkg/log/logr/temporallog
+
+go 1.21
+
+require (
+	github.com/packethost/pkg/log/logr v0.1.0
+	go.temporal.io/sdk v1.22.2
+	go.uber.org/zap v1.16.0
+)
+
+require (
+	github.com/go-logr/logr v0.2.1 // indirect
+	github.com/go-logr/zapr v0.2.0 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/rollbar/rollbar-go v1.2.0 // indirect
+	go.uber.org/atomic v1.9.0 // indirect
+	go.uber.org/multierr v1.6.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
+)
+
+replace github.com/packethost/pkg/log/logr => ../
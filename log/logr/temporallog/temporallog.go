@@ -0,0 +1,73 @@
+// Package temporallog adapts a logr.PacketLogr to the logger of the Temporal SDK, so workflow and activity workers log like
+// the rest of the service instead of with the SDK's default logger:
+//
+//	c, err := client.Dial(client.Options{Logger: temporallog.NewLogger(l)})
+//
+// The Cadence client takes a *zap.Logger, for it use the one of logr.PacketLogr's Zap.
+package temporallog
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/packethost/pkg/log/logr"
+	"go.temporal.io/sdk/log"
+	"go.uber.org/zap"
+)
+
+// Logger is a Temporal SDK logger writing entries named "temporal" with a logr.PacketLogr. The keys of the SDK's fields,
+// such as WorkflowID, RunID, and ActivityID, are logged in snake case, workflow_id, run_id, and activity_id, like the fields
+// of the rest of the service. Error entries are sent to the error reporters, with the Error field as the error.
+type Logger struct {
+	sugar *zap.SugaredLogger
+}
+
+var (
+	_ log.Logger     = (*Logger)(nil)
+	_ log.WithLogger = (*Logger)(nil)
+)
+
+// NewLogger returns the Logger writing with p, for the Logger of the client options
+func NewLogger(p *logr.PacketLogr) *Logger {
+	return &Logger{sugar: p.Zap().Named("temporal").WithOptions(zap.AddCallerSkip(1)).Sugar()}
+}
+
+func (l *Logger) Debug(msg string, keyvals ...interface{}) { l.sugar.Debugw(msg, fields(keyvals)...) }
+func (l *Logger) Info(msg string, keyvals ...interface{})  { l.sugar.Infow(msg, fields(keyvals)...) }
+func (l *Logger) Warn(msg string, keyvals ...interface{})  { l.sugar.Warnw(msg, fields(keyvals)...) }
+func (l *Logger) Error(msg string, keyvals ...interface{}) { l.sugar.Errorw(msg, fields(keyvals)...) }
+
+// With returns a Logger adding keyvals to its entries, the SDK uses it for the fields of workflows and activities
+func (l *Logger) With(keyvals ...interface{}) log.Logger {
+	return &Logger{sugar: l.sugar.With(fields(keyvals)...)}
+}
+
+// fields is keyvals with the keys that start with an upper case letter, the style of the SDK's fields, in snake case
+func fields(keyvals []interface{}) []interface{} {
+	kvs := make([]interface{}, len(keyvals))
+	copy(kvs, keyvals)
+	for i := 0; i < len(kvs); i += 2 {
+		if key, ok := kvs[i].(string); ok && key != "" && unicode.IsUpper(rune(key[0])) {
+			kvs[i] = snakeCase(key)
+		}
+	}
+	return kvs
+}
+
+// snakeCase converts a key such as WorkflowID or TaskFirstEventID to workflow_id or task_first_event_id
+func snakeCase(key string) string {
+	runes := []rune(key)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1])
+			// the last capital of an acronym followed by a word, such as the R of IDRun, starts the word
+			acronymEnd := unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || acronymEnd {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
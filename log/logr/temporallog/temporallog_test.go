@@ -0,0 +1,57 @@
+package temporallog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/packethost/pkg/log/logr"
+	"go.temporal.io/sdk/log"
+)
+
+func TestLogger(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.log")
+	l, err := logr.New(logr.WithOutputPaths([]string{out}), logr.WithLogLevel("debug"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := log.With(NewLogger(l), "Namespace", "default", "WorkflowID", "provision-42", "RunID", "r1")
+	logger.Debug("workflow task started", "TaskFirstEventID", 3)
+	log.With(logger, "ActivityID", "5", "ActivityType", "InstallOS").Error("activity error", "Error", errors.New("timed out"), "Attempt", 2, "device_id", "d1")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+	for _, want := range []string{
+		`"level":"debug","ts":`,
+		`"logger":"temporal","caller":"temporallog/temporallog_test.go:`,
+		`"msg":"workflow task started","service":"not/set","namespace":"default","workflow_id":"provision-42","run_id":"r1","task_first_event_id":3`,
+		`"level":"error"`,
+		`"msg":"activity error","service":"not/set","namespace":"default","workflow_id":"provision-42","run_id":"r1","activity_id":"5","activity_type":"InstallOS","error":"timed out","attempt":2,"device_id":"d1"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %s to be logged, got: %v", want, got)
+		}
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for key, want := range map[string]string{
+		"WorkflowID":                   "workflow_id",
+		"TaskFirstEventID":             "task_first_event_id",
+		"CachedPreviousStartedEventID": "cached_previous_started_event_id",
+		"IDRun":                        "id_run",
+		"Error":                        "error",
+	} {
+		if got := snakeCase(key); got != want {
+			t.Fatalf("expected %s to be %s, got: %s", key, want, got)
+		}
+	}
+}
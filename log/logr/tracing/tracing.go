@@ -0,0 +1,179 @@
+// Package tracing is the opinionated OpenTelemetry setup, the same way logr.New is for logging. New returns a
+// TracerProvider exporting spans over OTLP, with the service's name and resource attributes, and the func shutting it down:
+//
+//	tp, shutdown, err := tracing.New(tracing.WithServiceName("billing"), tracing.WithOTLPEndpoint("otel-collector:4317"), tracing.WithGlobal())
+//	defer shutdown(context.Background())
+//
+// The spans of contexts are logged as trace_id and span_id by the loggers of logr.FromContext with otellog.WithTraceFields.
+package tracing
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.uber.org/multierr"
+)
+
+// Option customizes New
+type Option func(*config)
+
+type config struct {
+	serviceName string
+	endpoint    string
+	headers     map[string]string
+	sampler     sdktrace.Sampler
+	attrs       []attribute.KeyValue
+	exporter    sdktrace.SpanExporter
+	global      bool
+	logger      *logr.PacketLogr
+	errs        error
+}
+
+// WithServiceName sets the service.name resource attribute, by default it is OTEL_SERVICE_NAME
+func WithServiceName(name string) Option {
+	return func(c *config) {
+		if name == "" {
+			c.errs = multierr.Append(c.errs, errors.New("WithServiceName: name must not be empty"))
+			return
+		}
+		c.serviceName = name
+	}
+}
+
+// WithOTLPEndpoint exports the spans over OTLP with gRPC to endpoint, a host and port. The connection uses TLS,
+// unless endpoint starts with http://. By default spans are exported to OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or
+// OTEL_EXPORTER_OTLP_ENDPOINT, and aren't exported when neither is set.
+func WithOTLPEndpoint(endpoint string) Option {
+	return func(c *config) {
+		if endpoint == "" {
+			c.errs = multierr.Append(c.errs, errors.New("WithOTLPEndpoint: endpoint must not be empty"))
+			return
+		}
+		c.endpoint = endpoint
+	}
+}
+
+// WithOTLPHeaders sends headers with the OTLP exports, such as the API key of a tracing vendor
+func WithOTLPHeaders(headers map[string]string) Option {
+	return func(c *config) { c.headers = headers }
+}
+
+// WithSampler decides which spans are recorded, by default those whose parent is, and every root span
+func WithSampler(sampler sdktrace.Sampler) Option {
+	return func(c *config) { c.sampler = sampler }
+}
+
+// WithResourceAttrs adds attributes to the resource, such as the deployment.environment or service.version,
+// they take precedence over the ones detected and from OTEL_RESOURCE_ATTRIBUTES
+func WithResourceAttrs(attrs ...attribute.KeyValue) Option {
+	return func(c *config) { c.attrs = append(c.attrs, attrs...) }
+}
+
+// WithExporter exports the spans with exporter instead of over OTLP
+func WithExporter(exporter sdktrace.SpanExporter) Option {
+	return func(c *config) { c.exporter = exporter }
+}
+
+// WithGlobal makes the TracerProvider the global one instrumentation libraries use, and propagates the
+// W3C trace context and baggage. The shutdown func restores the previous global TracerProvider.
+func WithGlobal() Option {
+	return func(c *config) { c.global = true }
+}
+
+// WithLogger logs the errors of exporting spans with p, as OpenTelemetry's global error handler
+func WithLogger(p *logr.PacketLogr) Option {
+	return func(c *config) { c.logger = p }
+}
+
+// New returns a TracerProvider exporting the spans it records in batches, and the func flushing and shutting it down
+func New(opts ...Option) (*sdktrace.TracerProvider, func(context.Context) error, error) {
+	c := config{sampler: sdktrace.ParentBased(sdktrace.AlwaysSample())}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.errs != nil {
+		return nil, nil, c.errs
+	}
+
+	ctx := context.Background()
+	attrs := c.attrs
+	if c.serviceName != "" {
+		attrs = append([]attribute.KeyValue{semconv.ServiceNameKey.String(c.serviceName)}, attrs...)
+	}
+	res, err := resource.New(ctx, resource.WithFromEnv(), resource.WithTelemetrySDK(), resource.WithHost(), resource.WithAttributes(attrs...))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to detect the resource")
+	}
+
+	tpOpts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res), sdktrace.WithSampler(c.sampler)}
+	exporter, err := c.newExporter(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if exporter != nil {
+		tpOpts = append(tpOpts, sdktrace.WithBatcher(exporter))
+	}
+	tp := sdktrace.NewTracerProvider(tpOpts...)
+
+	if c.logger != nil {
+		l := c.logger.Named("tracing")
+		otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) { l.Error(err, "tracing failed") }))
+	}
+	restore := func() {}
+	if c.global {
+		prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+		otel.SetTracerProvider(tp)
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+		restore = func() {
+			otel.SetTracerProvider(prevProvider)
+			otel.SetTextMapPropagator(prevPropagator)
+		}
+	}
+	shutdown := func(ctx context.Context) error {
+		restore()
+		return errors.Wrap(tp.Shutdown(ctx), "failed to shut down tracing")
+	}
+	return tp, shutdown, nil
+}
+
+// newExporter is the exporter of the config, it is nil when spans aren't exported
+func (c config) newExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	if c.exporter != nil {
+		return c.exporter, nil
+	}
+	var opts []otlptracegrpc.Option
+	if c.endpoint != "" {
+		if strings.HasPrefix(strings.ToLower(c.endpoint), "http://") {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		opts = append(opts, otlptracegrpc.WithEndpoint(trimScheme(c.endpoint)))
+	} else if os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" {
+		return nil, nil
+	}
+	if len(c.headers) > 0 {
+		opts = append(opts, otlptracegrpc.WithHeaders(c.headers))
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the OTLP exporter")
+	}
+	return exporter, nil
+}
+
+// trimScheme removes the http:// or https:// endpoint starts with
+func trimScheme(endpoint string) string {
+	if i := strings.Index(endpoint, "://"); i >= 0 {
+		return endpoint[i+3:]
+	}
+	return endpoint
+}
@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/packethost/pkg/log/logr"
+	"github.com/packethost/pkg/log/logr/otellog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+func TestNew(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp, shutdown, err := New(WithServiceName("billing"), WithResourceAttrs(attribute.String("deployment.environment", "test")), WithExporter(exporter), WithGlobal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otel.GetTracerProvider() != tp {
+		t.Fatal("expected WithGlobal to set the global TracerProvider")
+	}
+
+	dir := t.TempDir()
+	p, err := logr.New(logr.WithOutputPaths([]string{dir + "/log"}), otellog.WithTraceFields())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, span := otel.Tracer("test").Start(context.Background(), "charge")
+	p.FromContext(ctx).Info("charging")
+	span.End()
+	_ = p.Close(context.Background())
+
+	// ForceFlush only exports the spans the batcher has taken off its queue so far
+	spans := exporter.GetSpans()
+	for deadline := time.Now().Add(5 * time.Second); len(spans) == 0 && time.Now().Before(deadline); spans = exporter.GetSpans() {
+		if err := tp.ForceFlush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(spans) != 1 || spans[0].Name != "charge" {
+		t.Fatalf("expected the span to be exported, got: %v", spans)
+	}
+	attrs := map[attribute.Key]string{}
+	for _, kv := range spans[0].Resource.Attributes() {
+		attrs[kv.Key] = kv.Value.Emit()
+	}
+	if attrs[semconv.ServiceNameKey] != "billing" || attrs["deployment.environment"] != "test" {
+		t.Fatalf("expected the resource attributes, got: %v", attrs)
+	}
+	log, _ := os.ReadFile(dir + "/log")
+	if !strings.Contains(string(log), `"trace_id":"`+spans[0].SpanContext.TraceID().String()+`"`) {
+		t.Fatalf("expected the trace_id to be logged, got: %s", log)
+	}
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if otel.GetTracerProvider() == tp {
+		t.Fatal("expected shutdown to restore the global TracerProvider")
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	if _, _, err := New(WithServiceName(""), WithOTLPEndpoint("")); err == nil || !strings.Contains(err.Error(), "WithOTLPEndpoint") {
+		t.Fatalf("expected the errors of the options, got: %v", err)
+	}
+}
+
+func TestNewOTLP(t *testing.T) {
+	tp, shutdown, err := New(WithOTLPEndpoint("http://127.0.0.1:4317"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tp == nil {
+		t.Fatal("expected a TracerProvider")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// nothing was recorded so there is nothing to export
+	_ = shutdown(ctx)
+}
@@ -0,0 +1,102 @@
+package logr
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// validate checks the options for mistakes, all of the problems found are returned together
+func (p *PacketLogr) validate() error {
+	err := p.errs
+
+	if _, lerr := parseLevel(p.logLevel); lerr != nil {
+		err = multierr.Append(err, lerr)
+	}
+	for name, level := range p.componentLevels {
+		if _, lerr := parseLevel(level); lerr != nil {
+			err = multierr.Append(err, errors.WithMessagef(lerr, "component %q", name))
+		}
+	}
+
+	if len(p.outputPaths) == 0 && len(p.sinkConfigs) == 0 {
+		err = multierr.Append(err, errors.New("at least one output path is required"))
+	}
+	for _, path := range append(append([]string{}, p.outputPaths...), p.errorOutputPaths...) {
+		if path == "" {
+			err = multierr.Append(err, errors.New("output paths must not be empty"))
+			break
+		}
+	}
+
+	if p.enableSampling && (p.samplingConfig.Initial < 1 || p.samplingConfig.Thereafter < 1) {
+		err = multierr.Append(err, errors.Errorf("sampling initial and thereafter must be > 0, got: %d and %d", p.samplingConfig.Initial, p.samplingConfig.Thereafter))
+	}
+	if p.callerSkip < 0 {
+		err = multierr.Append(err, errors.Errorf("caller skip must be >= 0, got: %d", p.callerSkip))
+	}
+	if p.dedupeWindow < 0 {
+		err = multierr.Append(err, errors.Errorf("deduplication window must be >= 0, got: %s", p.dedupeWindow))
+	}
+	if p.reportDedupeWindow < 0 {
+		err = multierr.Append(err, errors.Errorf("error report deduplication window must be >= 0, got: %s", p.reportDedupeWindow))
+	}
+	err = multierr.Append(err, p.validateReporterSpool())
+	if p.enableAsync && (p.asyncSize < 1 || p.asyncFlushInterval <= 0) {
+		err = multierr.Append(err, errors.Errorf("async buffer size and flush interval must be > 0, got: %d and %s", p.asyncSize, p.asyncFlushInterval))
+	}
+	if p.dropWhenFull && !p.enableAsync {
+		err = multierr.Append(err, errors.New("dropping entries when full requires an async buffer"))
+	}
+	if p.dropSummaryInterval < 0 {
+		err = multierr.Append(err, errors.Errorf("drop summary interval must be >= 0, got: %s", p.dropSummaryInterval))
+	}
+	if p.hookTimeout < 0 {
+		err = multierr.Append(err, errors.Errorf("hook timeout must be >= 0, got: %s", p.hookTimeout))
+	}
+	if p.enableRollbar {
+		if _, terr := p.rollbarConfig.token(); terr != nil {
+			err = multierr.Append(err, terr)
+		}
+		if p.rollbarConfig.FlushTimeout < 0 {
+			err = multierr.Append(err, errors.Errorf("rollbar flush timeout must be >= 0, got: %s", p.rollbarConfig.FlushTimeout))
+		}
+	}
+	if p.enableBugsnag {
+		err = multierr.Append(err, p.bugsnagConfig.validate())
+	}
+	if p.enableHoneybadger {
+		err = multierr.Append(err, p.honeybadgerConfig.validate())
+	}
+	if p.enableDatadog {
+		err = multierr.Append(err, p.datadogConfig.validate())
+	}
+
+	return errors.WithMessage(err, "invalid logger options")
+}
+
+// validateKeysAndValues makes sure kvs can be used as logr key/value pairs
+func validateKeysAndValues(kvs []interface{}) error {
+	var err error
+	if len(kvs)%2 != 0 {
+		err = multierr.Append(err, errors.Errorf("odd number of keys and values: %d", len(kvs)))
+	}
+	for i := 0; i < len(kvs); i += 2 {
+		switch key := kvs[i].(type) {
+		case string:
+		case zap.Field:
+			err = multierr.Append(err, errors.Errorf("strongly-typed zap field passed as key: %s", key.Key))
+		default:
+			err = multierr.Append(err, errors.Errorf("non-string key: %s", fmt.Sprint(key)))
+		}
+	}
+	return err
+}
+
+// WithOptionError makes New fail with err, for the options of other packages that find their arguments invalid.
+// It does nothing when err is nil.
+func WithOptionError(err error) LoggerOption {
+	return func(args *PacketLogr) { args.errs = multierr.Append(args.errs, err) }
+}
@@ -0,0 +1,63 @@
+package logr
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+func TestPacketLogrValidation(t *testing.T) {
+	tests := map[string]struct {
+		opts []LoggerOption
+		want string
+	}{
+		"log level":       {opts: []LoggerOption{WithLogLevel("noisy")}, want: "failed to parse log level"},
+		"component level": {opts: []LoggerOption{WithComponentLevels(map[string]string{"db": "x"})}, want: `component "db"`},
+		"no output paths": {opts: []LoggerOption{WithOutputPaths(nil)}, want: "at least one output path is required"},
+		"empty path":      {opts: []LoggerOption{WithErrorOutputPaths([]string{""})}, want: "output paths must not be empty"},
+		"odd kvs":         {opts: []LoggerOption{WithKeysAndValues([]interface{}{"a", 1, "b"})}, want: "WithKeysAndValues: odd number of keys and values: 3"},
+		"split odd kvs":   {opts: []LoggerOption{WithKeysAndValues([]interface{}{"a"}), WithKeysAndValues([]interface{}{1})}, want: "odd number of keys and values: 1"},
+		"non-string key":  {opts: []LoggerOption{WithKeysAndValues([]interface{}{1, 1})}, want: "non-string key: 1"},
+		"zap field key":   {opts: []LoggerOption{WithKeysAndValues([]interface{}{zap.Int("a", 1), 1})}, want: "strongly-typed zap field passed as key: a"},
+		"sampling":        {opts: []LoggerOption{WithSampling(0, 0)}, want: "sampling initial and thereafter must be > 0"},
+		"caller skip":     {opts: []LoggerOption{WithCallerSkip(-1)}, want: "caller skip must be >= 0"},
+		"dedupe window":   {opts: []LoggerOption{WithDeduplication(-1)}, want: "deduplication window must be >= 0"},
+		"async buffer":    {opts: []LoggerOption{WithAsyncBuffer(0, time.Second)}, want: "async buffer size and flush interval must be > 0"},
+		"drop when full":  {opts: []LoggerOption{WithDropWhenFull(time.Second)}, want: "dropping entries when full requires an async buffer"},
+		"drop summary":    {opts: []LoggerOption{WithAsyncBuffer(1, time.Second), WithDropWhenFull(-1)}, want: "drop summary interval must be >= 0"},
+		"hook timeout":    {opts: []LoggerOption{WithHookTimeout(-time.Second)}, want: "hook timeout must be >= 0"},
+		"rollbar token":   {opts: []LoggerOption{WithEnableRollbar(true), WithRollbarConfig(RollbarConfig{})}, want: "rollbar is enabled but no token is set"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := NewPacketLogr(tc.opts...)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected to contain: %v, got: %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestPacketLogrValidationAggregated(t *testing.T) {
+	_, _, err := NewPacketLogr(
+		WithLogLevel("noisy"),
+		WithOutputPaths([]string{}),
+		WithKeysAndValues([]interface{}{"a"}),
+	)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.HasPrefix(err.Error(), "invalid logger options: ") {
+		t.Fatalf("expected a descriptive prefix, got: %v", err)
+	}
+	if got := len(multierr.Errors(errors.Cause(err))); got != 3 {
+		t.Fatalf("expected 3 errors, got %d: %v", got, err)
+	}
+}
@@ -0,0 +1,43 @@
+package logr
+
+import (
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+)
+
+// Warn logs msg with keysAndValues at the warn level, which logr.Logger has no method for
+func (p *PacketLogr) Warn(msg string, keysAndValues ...interface{}) {
+	l := p.zap.WithOptions(zap.AddCallerSkip(1))
+	l.Warn(msg, handleFields(l, keysAndValues)...)
+}
+
+// Warn logs msg with keysAndValues at the warn level with l, which logr.Logger has no method for. l is one of the loggers
+// of a PacketLogr, such as the ones of Named and FromContext and the ones derived from them, the entry has its name and values.
+// Other loggers log it at info.
+func Warn(l logr.Logger, msg string, keysAndValues ...interface{}) {
+	if w, ok := l.(warnLogger); ok {
+		z := w.zap.WithOptions(zap.AddCallerSkip(1))
+		z.Warn(msg, handleFields(z, keysAndValues)...)
+		return
+	}
+	l.Info(msg, keysAndValues...)
+}
+
+// warnLogger is the logr.Logger of a PacketLogr, it keeps the zap logger it writes to, with the same name and values,
+// so Warn can write to it
+type warnLogger struct {
+	logr.Logger
+	zap *zap.Logger
+}
+
+func (l warnLogger) V(level int) logr.Logger {
+	return warnLogger{Logger: l.Logger.V(level), zap: l.zap}
+}
+
+func (l warnLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return warnLogger{Logger: l.Logger.WithValues(keysAndValues...), zap: l.zap.With(handleFields(l.zap, keysAndValues)...)}
+}
+
+func (l warnLogger) WithName(name string) logr.Logger {
+	return warnLogger{Logger: l.Logger.WithName(name), zap: l.zap.Named(name)}
+}
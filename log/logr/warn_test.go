@@ -0,0 +1,32 @@
+package logr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPacketLogrWarn(t *testing.T) {
+	capturedOutput := captureOutput(func() {
+		pl, err := New(WithLogLevel("warn"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pl.Info("info message")
+		pl.Warn("direct warning", "k", "v")
+		Warn(pl.Named("db").WithValues("table", "users"), "named warning", "rows", 3)
+	})
+	if strings.Contains(capturedOutput, "info message") {
+		t.Fatalf("expected info to be filtered at warn, got: %v", capturedOutput)
+	}
+	for _, want := range []string{
+		`"level":"warn"`,
+		`"msg":"direct warning","service":"not/set","k":"v"`,
+		`"logger":"db"`,
+		`"component":"db","table":"users","rows":3`,
+		"logr/warn_test.go",
+	} {
+		if !strings.Contains(capturedOutput, want) {
+			t.Fatalf("expected to contain: %v, got: %v", want, capturedOutput)
+		}
+	}
+}
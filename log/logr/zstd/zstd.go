@@ -0,0 +1,34 @@
+// Package zstd registers zstd with logr.RegisterCompression, so that logr.Compression can use logr.CompressionZstd
+// once it is imported:
+//
+//	import _ "github.com/packethost/pkg/log/logr/zstd"
+package zstd
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/packethost/pkg/log/logr"
+)
+
+// maxLevel is the highest zstd level, levels are mapped to the ones the zstd package implements: fastest, default, better, and best
+const maxLevel = 22
+
+func init() {
+	if err := logr.RegisterCompression(logr.CompressionZstd, logr.Compressor{
+		NewWriter:   newWriter,
+		MaxLevel:    maxLevel,
+		Ext:         ".zst",
+		ContentType: "application/zstd",
+	}); err != nil {
+		panic(err)
+	}
+}
+
+func newWriter(w io.Writer, level int) (logr.CompressWriter, error) {
+	var opts []zstd.EOption
+	if level != 0 {
+		opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	}
+	return zstd.NewWriter(w, opts...)
+}
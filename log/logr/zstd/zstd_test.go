@@ -0,0 +1,50 @@
+package zstd
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/packethost/pkg/log/logr"
+)
+
+func TestCompression(t *testing.T) {
+	in := []byte(strings.Repeat(`{"level":"info","msg":"compress me"}`+"\n", 100))
+	for _, c := range []logr.Compression{{Algorithm: logr.CompressionZstd}, {Algorithm: logr.CompressionZstd, Level: 22}} {
+		if err := c.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if c.Ext() != ".zst" || c.ContentType() != "application/zstd" {
+			t.Fatalf("expected the zstd ext and content type, got: %v and %v", c.Ext(), c.ContentType())
+		}
+		var b bytes.Buffer
+		w, err := c.NewWriter(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(in); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if b.Len() >= len(in) {
+			t.Fatalf("expected %+v to compress, got: %d bytes from %d", c, b.Len(), len(in))
+		}
+		d, err := zstd.NewReader(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := io.ReadAll(d)
+		d.Close()
+		if err != nil || !bytes.Equal(out, in) {
+			t.Fatalf("expected %+v to round trip, got: %d bytes, %v", c, len(out), err)
+		}
+	}
+
+	if err := (logr.Compression{Algorithm: logr.CompressionZstd, Level: 23}).Validate(); err == nil || !strings.Contains(err.Error(), "zstd compression level must be between 1 and 22") {
+		t.Fatalf("expected the level to be checked, got: %v", err)
+	}
+}